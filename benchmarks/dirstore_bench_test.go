@@ -0,0 +1,94 @@
+package benchmarks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// BenchmarkListFiles measures paging through every file of a flat directory.
+func BenchmarkListFiles(b *testing.B) {
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("files=%d", size), func(b *testing.B) {
+			skipLargeInShort(b, size, 10_000)
+			base := b.TempDir()
+			for i := range size {
+				p := filepath.Join(base, fmt.Sprintf("file%07d.json", i))
+				if err := os.WriteFile(p, []byte(`{}`), 0o600); err != nil {
+					b.Fatal(err)
+				}
+			}
+			mds, err := mapstore.NewMapDirectoryStore(
+				base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+			cfg := mapstore.ListingConfig{SortOrder: mapstore.SortOrderAscending, PageSize: 1_000}
+			b.ResetTimer()
+			for range b.N {
+				seen := 0
+				token := ""
+				for {
+					entries, next, err := mds.ListFiles(cfg, token)
+					if err != nil {
+						b.Fatal(err)
+					}
+					seen += len(entries)
+					if next == "" {
+						break
+					}
+					token = next
+				}
+				if seen != size {
+					b.Fatalf("listed %d files, want %d", seen, size)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkListPartitions measures paging through every partition of a base directory, 100 per page.
+func BenchmarkListPartitions(b *testing.B) {
+	for _, size := range []int{1_000, 10_000} {
+		b.Run(fmt.Sprintf("partitions=%d", size), func(b *testing.B) {
+			base := b.TempDir()
+			for i := range size {
+				if err := os.Mkdir(filepath.Join(base, fmt.Sprintf("p%06d", i)), 0o700); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// Age the directory like a long lived store, so its listing can be cached.
+			old := time.Now().Add(-time.Hour)
+			if err := os.Chtimes(base, old, old); err != nil {
+				b.Fatal(err)
+			}
+			p := &dirpartition.MonthPartitionProvider{}
+			b.ResetTimer()
+			for range b.N {
+				seen := 0
+				token := ""
+				for {
+					parts, next, err := p.ListPartitions(base, mapstore.SortOrderDescending, token, 100)
+					if err != nil {
+						b.Fatal(err)
+					}
+					seen += len(parts)
+					if next == "" {
+						break
+					}
+					token = next
+				}
+				if seen != size {
+					b.Fatalf("listed %d partitions, want %d", seen, size)
+				}
+			}
+		})
+	}
+}
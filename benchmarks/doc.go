@@ -0,0 +1,8 @@
+// Package benchmarks holds reproducible performance benchmarks for the file store, the directory store and the
+// full text search engine. It has no API, run it with:
+//
+//	go test ./benchmarks -run '^$' -bench . -benchmem
+//
+// Fixtures are generated from fixed seeds so numbers are comparable across runs and machines.
+// The largest fixtures (100k files, 1M rows) take a while to build and are skipped with -short.
+package benchmarks
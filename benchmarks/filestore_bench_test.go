@@ -0,0 +1,120 @@
+package benchmarks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// BenchmarkSetKey measures SetKey throughput against the number of keys already in the file.
+// Every SetKey rewrites the whole file, so cost grows with file size, see BenchmarkSetKeySegmented.
+func BenchmarkSetKey(b *testing.B) {
+	for _, size := range []int{10, 1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("keys=%d", size), func(b *testing.B) {
+			skipLargeInShort(b, size, 10_000)
+			st, err := mapstore.NewMapFileStore(
+				filepath.Join(b.TempDir(), "bench.json"),
+				flatMap(newRand(), size),
+				jsonencdec.JSONEncoderDecoder{},
+				mapstore.WithCreateIfNotExists(true),
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+			keys := []string{"bench", "counter"}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if err := st.SetKey(keys, i); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSetKeyNoAutoFlush is the in-memory baseline for BenchmarkSetKey.
+func BenchmarkSetKeyNoAutoFlush(b *testing.B) {
+	st, err := mapstore.NewMapFileStore(
+		filepath.Join(b.TempDir(), "bench.json"),
+		flatMap(newRand(), 10_000),
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true),
+		mapstore.WithFileAutoFlush(false),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys := []string{"bench", "counter"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if err := st.SetKey(keys, i); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSetKeySegmented compares SetKey next to one large top level key with and without segmented storage.
+// Segmented storage only rewrites the small changed segment and the manifest.
+func BenchmarkSetKeySegmented(b *testing.B) {
+	for _, segmented := range []bool{false, true} {
+		b.Run(fmt.Sprintf("segmented=%v", segmented), func(b *testing.B) {
+			st, err := mapstore.NewMapFileStore(
+				filepath.Join(b.TempDir(), "bench.json"),
+				map[string]any{"docs": flatMap(newRand(), 10_000), "bench": map[string]any{}},
+				jsonencdec.JSONEncoderDecoder{},
+				mapstore.WithCreateIfNotExists(true),
+				mapstore.WithSegmentedStorage(segmented),
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+			keys := []string{"bench", "counter"}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if err := st.SetKey(keys, i); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLoadLargeFile measures opening a store on a large file of records with encoding/json and with the
+// pooled FastDecode parser. The 50 MB file is skipped with -short.
+func BenchmarkLoadLargeFile(b *testing.B) {
+	for _, records := range []int{10_000, 200_000} {
+		skipLargeInShort(b, records, 10_000)
+		p := filepath.Join(b.TempDir(), "large.json")
+		seed, err := mapstore.NewMapFileStore(
+			p, recordMap(newRand(), records), jsonencdec.JSONEncoderDecoder{}, mapstore.WithCreateIfNotExists(true),
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+		seed.Close()
+		info, err := os.Stat(p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, fast := range []bool{false, true} {
+			b.Run(fmt.Sprintf("mb=%d/fast=%v", info.Size()>>20, fast), func(b *testing.B) {
+				b.SetBytes(info.Size())
+				b.ReportAllocs()
+				for range b.N {
+					st, err := mapstore.NewMapFileStore(p, nil, jsonencdec.JSONEncoderDecoder{FastDecode: fast})
+					if err != nil {
+						b.Fatal(err)
+					}
+					st.Close()
+				}
+			})
+		}
+	}
+}
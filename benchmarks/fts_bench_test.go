@@ -0,0 +1,86 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ppipada/mapstore-go/ftsengine"
+)
+
+const upsertChunk = 10_000
+
+func newEngine(b *testing.B) *ftsengine.Engine {
+	b.Helper()
+	e, err := ftsengine.NewEngine(ftsengine.Config{
+		BaseDir:    b.TempDir(),
+		DBFileName: "bench.sqlite",
+		Table:      "docs",
+		Columns: []ftsengine.Column{
+			{Name: "title", Weight: 1},
+			{Name: "body", Weight: 5},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = e.Close() })
+	return e
+}
+
+func docs(r *rand.Rand, start, n int) map[string]map[string]string {
+	out := make(map[string]map[string]string, n)
+	for i := start; i < start+n; i++ {
+		out[fmt.Sprintf("doc%08d", i)] = map[string]string{
+			"title": sentence(r, 3),
+			"body":  sentence(r, 30),
+		}
+	}
+	return out
+}
+
+// BenchmarkSearch measures one page of search results against the number of indexed rows.
+func BenchmarkSearch(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			skipLargeInShort(b, size, 10_000)
+			e := newEngine(b)
+			r := newRand()
+			for start := 0; start < size; start += upsertChunk {
+				if err := e.BatchUpsert(ctx, docs(r, start, min(upsertChunk, size-start))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ResetTimer()
+			for i := range b.N {
+				q := words[i%len(words)] + " " + words[(i*7)%len(words)]
+				if _, _, err := e.Search(ctx, q, "", 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkBatchUpsert measures one transaction of new documents against the batch size.
+func BenchmarkBatchUpsert(b *testing.B) {
+	ctx := context.Background()
+	for _, batch := range []int{10, 100, 1_000, 10_000} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			e := newEngine(b)
+			r := newRand()
+			b.ResetTimer()
+			for i := range b.N {
+				b.StopTimer()
+				d := docs(r, i*batch, batch)
+				b.StartTimer()
+				if err := e.BatchUpsert(ctx, d); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batch)/b.Elapsed().Seconds(), "docs/s")
+		})
+	}
+}
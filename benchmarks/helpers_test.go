@@ -0,0 +1,58 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// words is the vocabulary for generated documents.
+var words = strings.Fields(`alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima mike
+november oscar papa quebec romeo sierra tango uniform victor whiskey xray yankee zulu amber basil cedar
+dune ember fern grove harbor iris jade kestrel lotus maple nectar onyx pearl quartz raven sage thistle`)
+
+// newRand returns a deterministic generator so fixtures are identical across runs.
+func newRand() *rand.Rand {
+	return rand.New(rand.NewPCG(42, 1024))
+}
+
+func sentence(r *rand.Rand, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[r.IntN(len(words))]
+	}
+	return strings.Join(parts, " ")
+}
+
+// flatMap returns a map with n keys of short string values.
+func flatMap(r *rand.Rand, n int) map[string]any {
+	m := make(map[string]any, n)
+	for i := range n {
+		m[fmt.Sprintf("key%06d", i)] = sentence(r, 4)
+	}
+	return m
+}
+
+func skipLargeInShort(b *testing.B, size, limit int) {
+	b.Helper()
+	if testing.Short() && size > limit {
+		b.Skipf("size %d skipped in short mode", size)
+	}
+}
+
+// recordMap returns a map with n record objects of a few typed fields each, about 170 bytes of JSON per record.
+func recordMap(r *rand.Rand, n int) map[string]any {
+	m := make(map[string]any, n)
+	for i := range n {
+		m[fmt.Sprintf("rec%07d", i)] = map[string]any{
+			"title":  sentence(r, 6),
+			"tags":   []any{words[r.IntN(len(words))], words[r.IntN(len(words))]},
+			"count":  float64(r.IntN(100_000)),
+			"score":  r.Float64(),
+			"active": r.IntN(2) == 0,
+			"owner":  map[string]any{"name": words[r.IntN(len(words))], "id": float64(r.IntN(1000))},
+		}
+	}
+	return m
+}
@@ -0,0 +1,254 @@
+// Package blobstore is a content-addressable blob store with reference counting.
+//
+// Blobs are stored once under their SHA-256 digest, however often they are put. Every Put adds a reference
+// and every Release drops one; GC removes blobs without references. Reference counts live in a MapFileStore
+// file and are updated under its cross-process lock, so several processes can share a store.
+//
+// A Store satisfies mapstore.BlobStore, so attachments of a MapDirectoryStore can be deduplicated with
+// mapstore.WithDirAttachmentBlobStore.
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+const (
+	blobsDir = "blobs"
+	tmpDir   = "tmp"
+	refsFile = "refs.json"
+	refsKey  = "refs"
+)
+
+// ErrNotFound is returned for digests that are not stored.
+var ErrNotFound = errors.New("blobstore: blob not found")
+
+// Store keeps blobs below a directory.
+type Store struct {
+	dir         string
+	refs        *mapstore.MapFileStore
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	// Serializes GC against Put inside one process.
+	mu sync.RWMutex
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithGCGracePeriod keeps unreferenced blobs younger than d, one hour by default. Put refreshes the age of a
+// blob it deduplicates, so a GC in another process cannot remove a blob between its write and its reference.
+func WithGCGracePeriod(d time.Duration) Option {
+	return func(s *Store) {
+		s.gracePeriod = d
+	}
+}
+
+// New opens (or creates) a blob store rooted at dir.
+func New(dir string, opts ...Option) (*Store, error) {
+	for _, sub := range []string{blobsDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o770); err != nil {
+			return nil, err
+		}
+	}
+	refs, err := mapstore.NewMapFileStore(
+		filepath.Join(dir, refsFile),
+		map[string]any{refsKey: map[string]any{}},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, refs: refs, gracePeriod: time.Hour, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.gracePeriod < 0 {
+		return nil, errors.Join(errors.New("blobstore: negative grace period"), refs.Close())
+	}
+	return s, nil
+}
+
+// Put stores the content of r, unless a blob with the same content exists, and adds a reference to it.
+// It returns the hex SHA-256 digest and the size of the content.
+func (s *Store) Put(r io.Reader) (digest string, size int64, err error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, tmpDir), "blob-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("blobstore: write blob: %w", err)
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.blobPath(digest)
+	if _, err := os.Stat(p); err == nil {
+		// Deduplicated. Refresh the age so that a concurrent GC keeps it.
+		now := s.now()
+		if err := os.Chtimes(p, now, now); err != nil {
+			return "", 0, err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(p), 0o770); err != nil {
+			return "", 0, err
+		}
+		if err := os.Rename(tmp.Name(), p); err != nil {
+			return "", 0, err
+		}
+	}
+	if _, err := s.refs.Increment([]string{refsKey, digest}, 1); err != nil {
+		return "", 0, err
+	}
+	return digest, size, nil
+}
+
+// Open returns the content of the blob. The caller must close the reader.
+func (s *Store) Open(digest string) (io.ReadCloser, error) {
+	if err := validateDigest(digest); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.blobPath(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	return f, err
+}
+
+// Retain adds a reference to a stored blob.
+func (s *Store) Retain(digest string) error {
+	if err := validateDigest(digest); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.blobPath(digest)); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	_, err := s.refs.Increment([]string{refsKey, digest}, 1)
+	return err
+}
+
+// Release drops a reference to the blob. Blobs without references stay until the next GC.
+func (s *Store) Release(digest string) error {
+	if err := validateDigest(digest); err != nil {
+		return err
+	}
+	n, err := s.refs.Increment([]string{refsKey, digest}, -1)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		// Released more often than put, do not let the count go negative.
+		_, _ = s.refs.Increment([]string{refsKey, digest}, 1)
+		return fmt.Errorf("blobstore: blob %s has no references", digest)
+	}
+	return nil
+}
+
+// RefCount returns the number of references to the blob.
+func (s *Store) RefCount(digest string) (int64, error) {
+	if err := validateDigest(digest); err != nil {
+		return 0, err
+	}
+	all, err := s.refs.GetAll(true)
+	if err != nil {
+		return 0, err
+	}
+	refs, _ := all[refsKey].(map[string]any)
+	return toInt64(refs[digest]), nil
+}
+
+// GC removes blobs without references that are older than the grace period and returns how many it removed.
+func (s *Store) GC() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.refs.GetAll(true)
+	if err != nil {
+		return 0, err
+	}
+	refs, _ := all[refsKey].(map[string]any)
+	cutoff := s.now().Add(-s.gracePeriod)
+
+	removed := 0
+	root := filepath.Join(s.dir, blobsDir)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+		digest := d.Name()
+		if toInt64(refs[digest]) > 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		if _, ok := refs[digest]; ok {
+			if err := s.refs.DeleteKey([]string{refsKey, digest}); err != nil &&
+				!errors.Is(err, mapstore.ErrFileConflict) {
+				return err
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// Close releases the reference count file.
+func (s *Store) Close() error {
+	return s.refs.Close()
+}
+
+// blobPath fans blobs out over 256 directories by the first digest byte.
+func (s *Store) blobPath(digest string) string {
+	return filepath.Join(s.dir, blobsDir, digest[:2], digest)
+}
+
+func validateDigest(digest string) error {
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size || hex.EncodeToString(b) != digest {
+		return fmt.Errorf("blobstore: invalid digest %q", digest)
+	}
+	return nil
+}
+
+// toInt64 reads a count that is an int64 in memory and a float64 after a JSON round trip.
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_PutDedupAndGC(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, WithGCGracePeriod(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	d1, size, err := s.Put(strings.NewReader("hello"))
+	if err != nil || size != 5 {
+		t.Fatalf("put: %v, size %d", err, size)
+	}
+	d2, _, err := s.Put(strings.NewReader("hello"))
+	if err != nil || d2 != d1 {
+		t.Fatalf("second put: %q, %v", d2, err)
+	}
+	other, _, err := s.Put(strings.NewReader("world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.RefCount(d1); err != nil || n != 2 {
+		t.Fatalf("refcount %d, err %v", n, err)
+	}
+	var blobs int
+	_ = filepath.WalkDir(filepath.Join(dir, blobsDir), func(_ string, d os.DirEntry, _ error) error {
+		if d != nil && !d.IsDir() {
+			blobs++
+		}
+		return nil
+	})
+	if blobs != 2 {
+		t.Fatalf("%d blobs on disk, want 2", blobs)
+	}
+
+	rc, err := s.Open(d1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("open: %q, %v", b, err)
+	}
+
+	if err := s.Release(d1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.GC(); err != nil || n != 0 {
+		t.Fatalf("gc with references removed %d, err %v", n, err)
+	}
+	if err := s.Release(d1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(d1); err == nil {
+		t.Fatal("want error for a release without reference")
+	}
+	if n, err := s.GC(); err != nil || n != 1 {
+		t.Fatalf("gc removed %d, err %v", n, err)
+	}
+	if _, err := s.Open(d1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("open collected blob: %v", err)
+	}
+	if _, err := s.Open(other); err != nil {
+		t.Fatalf("referenced blob collected: %v", err)
+	}
+	if _, err := s.Open("../refs.json"); err == nil {
+		t.Fatal("want error for an invalid digest")
+	}
+}
+
+func TestStore_GCGracePeriod(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	d, _, err := s.Put(strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(d); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.GC(); err != nil || n != 0 {
+		t.Fatalf("young blob collected: %d, %v", n, err)
+	}
+	now = now.Add(2 * time.Hour)
+	if n, err := s.GC(); err != nil || n != 1 {
+		t.Fatalf("old blob not collected: %d, %v", n, err)
+	}
+}
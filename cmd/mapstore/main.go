@@ -0,0 +1,88 @@
+// Command mapstore is an operator tool for mapstore data.
+//
+// Usage:
+//
+//	mapstore fts verify   --db <path> --table <t>
+//	mapstore fts optimize --db <path> --table <t>
+//	mapstore fts rebuild  --db <path> --table <t>
+//
+// verify runs the FTS5 integrity check and a SQLite quick check, optimize merges the index segments and rebuild
+// rebuilds the index from the stored content. Commands work on the index file alone, no Config is needed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ppipada/mapstore-go/ftsengine"
+)
+
+const usage = `usage: mapstore fts <verify|optimize|rebuild> --db <path> --table <t>`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command line and returns the exit code: 0 on success, 1 on failure and 2 on bad usage.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) < 2 || args[0] != "fts" {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+	cmd := args[1]
+	fs := flag.NewFlagSet("mapstore fts "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbPath := fs.String("db", "", "path of the sqlite database file")
+	table := fs.String("table", "", "name of the fts table")
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+	if *dbPath == "" || *table == "" || fs.NArg() != 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	switch cmd {
+	case "verify", "optimize", "rebuild":
+	default:
+		fmt.Fprintf(stderr, "unknown fts command %q\n%s\n", cmd, usage)
+		return 2
+	}
+
+	m, err := ftsengine.OpenMaintenance(*dbPath, *table)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer m.Close()
+
+	start := time.Now()
+	switch cmd {
+	case "verify":
+		err = m.Verify(ctx)
+	case "optimize":
+		err = m.Optimize(ctx)
+	default:
+		err = m.RebuildIndex(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, ftsengine.ErrIntegrity) {
+			fmt.Fprintf(stderr, "%s: %v\n", cmd, err)
+			fmt.Fprintln(stderr, `run "mapstore fts rebuild" to rebuild the index from its content`)
+		} else {
+			fmt.Fprintf(stderr, "%s: %v\n", cmd, err)
+		}
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s %s: ok (%s)\n", cmd, *table, time.Since(start).Round(time.Millisecond))
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go/ftsengine"
+)
+
+func TestRun_FTS(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := ftsengine.NewEngine(ftsengine.Config{
+		BaseDir:    dir,
+		DBFileName: "fts.db",
+		Table:      "docs",
+		Columns:    []ftsengine.Column{{Name: "title"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Upsert(t.Context(), "a", map[string]string{"title": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "fts.db")
+
+	for _, tc := range []struct {
+		args []string
+		code int
+		out  string
+	}{
+		{[]string{"fts", "verify", "--db", dbPath, "--table", "docs"}, 0, "verify docs: ok"},
+		{[]string{"fts", "optimize", "--db", dbPath, "--table", "docs"}, 0, "optimize docs: ok"},
+		{[]string{"fts", "rebuild", "--db", dbPath, "--table", "docs"}, 0, "rebuild docs: ok"},
+		{[]string{"fts", "verify", "--db", dbPath, "--table", "other"}, 1, `no table "other"`},
+		{[]string{"fts", "explode", "--db", dbPath, "--table", "docs"}, 2, "unknown fts command"},
+		{[]string{"fts", "verify", "--db", dbPath}, 2, "usage"},
+		{[]string{"kv"}, 2, "usage"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(t.Context(), tc.args, &stdout, &stderr)
+		if code != tc.code || !strings.Contains(stdout.String()+stderr.String(), tc.out) {
+			t.Errorf("%v: code %d, out %q %q", tc.args, code, stdout.String(), stderr.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package dirpartition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+// mtimeGranularity is the coarsest directory mtime resolution we expect (FAT has 2 seconds).
+// A listing taken within this window of the last modification may miss a change that keeps the mtime,
+// so it is not reused.
+const mtimeGranularity = 2 * time.Second
+
+// dirListCache keeps the sorted subdirectory names of one directory.
+// Adding or removing an entry updates the directory mtime, which invalidates the cache, so paging through
+// thousands of partitions reads the directory once instead of once per page.
+type dirListCache struct {
+	mu      sync.Mutex
+	dir     string
+	modTime time.Time
+	builtAt time.Time
+	names   []string
+}
+
+// pageToken is the position after which the next page starts. Tokens name the last returned partition instead
+// of an offset, so partitions added or removed between pages do not shift the listing.
+type pageToken struct {
+	After string `json:"after"`
+}
+
+// list returns a paginated and sorted list of directories in dir.
+func (c *dirListCache) list(
+	dir string,
+	sortOrder string,
+	token string,
+	pageSize int,
+) (dirs []string, nextPageToken string, err error) {
+	desc := false
+	switch strings.ToLower(sortOrder) {
+	case mapstore.SortOrderAscending:
+	case mapstore.SortOrderDescending:
+		desc = true
+	default:
+		return nil, "", fmt.Errorf("invalid sort order: %s", sortOrder)
+	}
+
+	names, err := c.sortedNames(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Find the start of the page in the ascending names.
+	start, end := 0, len(names)
+	if token != "" {
+		after, offset, err := decodePageToken(token)
+		if err != nil {
+			return nil, "", err
+		}
+		switch {
+		case offset >= 0 && desc:
+			end = max(len(names)-offset, 0)
+		case offset >= 0:
+			start = min(offset, len(names))
+		case desc:
+			end, _ = slices.BinarySearch(names, after)
+		default:
+			start, _ = slices.BinarySearch(names, after)
+			if start < len(names) && names[start] == after {
+				start++
+			}
+		}
+	}
+
+	var page []string
+	if desc {
+		for i := end - 1; i >= 0 && len(page) < pageSize; i-- {
+			page = append(page, names[i])
+		}
+		if len(page) > 0 && end-len(page) > 0 {
+			nextPageToken = encodePageToken(page[len(page)-1])
+		}
+	} else {
+		page = slices.Clone(names[start:min(start+max(pageSize, 0), end)])
+		if len(page) > 0 && start+len(page) < end {
+			nextPageToken = encodePageToken(page[len(page)-1])
+		}
+	}
+	return page, nextPageToken, nil
+}
+
+// sortedNames returns the ascending subdirectory names of dir, from the cache if dir did not change.
+// The returned slice must not be modified.
+func (c *dirListCache) sortedNames(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base directory: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.names != nil && c.dir == dir && info.ModTime().Equal(c.modTime) &&
+		c.builtAt.Sub(c.modTime) > mtimeGranularity {
+		return c.names, nil
+	}
+
+	builtAt := time.Now()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base directory: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	// ReadDir returns entries sorted by name.
+	c.dir, c.modTime, c.builtAt, c.names = dir, info.ModTime(), builtAt, names
+	return names, nil
+}
+
+func encodePageToken(after string) string {
+	b, _ := json.Marshal(pageToken{After: after})
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// decodePageToken returns the name to continue after, or for tokens of earlier versions, which held the number
+// of partitions already returned, that offset. The offset is -1 for name tokens.
+func decodePageToken(token string) (after string, offset int, err error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid page token: %w", err)
+	}
+	var t pageToken
+	if err := json.Unmarshal(data, &t); err == nil {
+		return t.After, -1, nil
+	}
+	if err := json.Unmarshal(data, &offset); err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid page token: %s", token)
+	}
+	return "", offset, nil
+}
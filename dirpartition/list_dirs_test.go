@@ -0,0 +1,108 @@
+package dirpartition
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func mkdirs(t *testing.T, base string, names ...string) {
+	t.Helper()
+	for _, n := range names {
+		if err := os.Mkdir(filepath.Join(base, n), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func listAll(t *testing.T, c *dirListCache, base, order string, pageSize int) []string {
+	t.Helper()
+	var all []string
+	token := ""
+	for {
+		page, next, err := c.list(base, order, token, pageSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, page...)
+		if next == "" {
+			return all
+		}
+		token = next
+	}
+}
+
+func TestDirListCache_Pages(t *testing.T) {
+	base := t.TempDir()
+	mkdirs(t, base, "202401", "202402", "202403", "202404", "202405")
+	if err := os.WriteFile(filepath.Join(base, "file.json"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var c dirListCache
+
+	asc := []string{"202401", "202402", "202403", "202404", "202405"}
+	if got := listAll(t, &c, base, mapstore.SortOrderAscending, 2); !slices.Equal(got, asc) {
+		t.Fatalf("ascending = %v", got)
+	}
+	desc := slices.Clone(asc)
+	slices.Reverse(desc)
+	if got := listAll(t, &c, base, mapstore.SortOrderDescending, 2); !slices.Equal(got, desc) {
+		t.Fatalf("descending = %v", got)
+	}
+
+	// A partition added between pages is neither skipped nor repeated before the token position.
+	page, next, err := c.list(base, mapstore.SortOrderAscending, "", 2)
+	if err != nil || !slices.Equal(page, asc[:2]) {
+		t.Fatalf("first page = %v, %v", page, err)
+	}
+	mkdirs(t, base, "202312", "202402a")
+	page, _, err = c.list(base, mapstore.SortOrderAscending, next, 2)
+	if err != nil || !slices.Equal(page, []string{"202402a", "202403"}) {
+		t.Fatalf("second page = %v, %v", page, err)
+	}
+
+	// Offset tokens of earlier versions keep working.
+	legacy := base64.StdEncoding.EncodeToString([]byte("2"))
+	page, _, err = c.list(base, mapstore.SortOrderDescending, legacy, 1)
+	if err != nil || !slices.Equal(page, []string{"202403"}) {
+		t.Fatalf("legacy token page = %v, %v", page, err)
+	}
+	if _, _, err := c.list(base, mapstore.SortOrderAscending, "!!", 1); err == nil {
+		t.Fatal("want error for invalid token")
+	}
+}
+
+func TestDirListCache_Invalidation(t *testing.T) {
+	base := t.TempDir()
+	mkdirs(t, base, "a", "b")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(base, old, old); err != nil {
+		t.Fatal(err)
+	}
+	var c dirListCache
+	if got := listAll(t, &c, base, mapstore.SortOrderAscending, 10); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("listing = %v", got)
+	}
+
+	// Remove a directory behind the cache's back but restore the mtime: the cached listing is served.
+	if err := os.Remove(filepath.Join(base, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(base, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got := listAll(t, &c, base, mapstore.SortOrderAscending, 10); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("cached listing = %v", got)
+	}
+
+	// A regular change updates the mtime and is seen.
+	mkdirs(t, base, "c")
+	if got := listAll(t, &c, base, mapstore.SortOrderAscending, 10); !slices.Equal(got, []string{"a", "c"}) {
+		t.Fatalf("listing after change = %v", got)
+	}
+}
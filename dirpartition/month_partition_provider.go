@@ -1,12 +1,7 @@
 package dirpartition
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"os"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/ppipada/mapstore-go"
@@ -18,6 +13,9 @@ type TimeExtractor func(key mapstore.FileKey) (time.Time, error)
 // MonthPartitionProvider decides directories yyyyMM from TimeExtractor.
 type MonthPartitionProvider struct {
 	TimeFn TimeExtractor
+
+	// listing caches the sorted partition names between pages.
+	listing dirListCache
 }
 
 // GetPartitionDir implements the PartitionProvider interface.
@@ -36,57 +34,16 @@ func (p *MonthPartitionProvider) ListPartitions(
 	pageToken string,
 	pageSize int,
 ) (partitions []string, nextPageToken string, err error) {
-	return listDirs(baseDir, sortOrder, pageToken, pageSize)
+	return p.listing.list(baseDir, sortOrder, pageToken, pageSize)
 }
 
-// listDirs returns a paginated and sorted list of directories in the base directory.
-func listDirs(
-	baseDir string,
-	sortOrder string,
-	pageToken string,
-	pageSize int,
-) (dirs []string, nextPageToken string, err error) {
-	entries, err := os.ReadDir(baseDir)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to read base directory: %w", err)
+// ValidatePartitionName implements the mapstore.PartitionNameValidator interface, accepting yyyyMM names.
+func (p *MonthPartitionProvider) ValidatePartitionName(name string) error {
+	if len(name) != len("200601") {
+		return &mapstore.InvalidPartitionNameError{Name: name, Reason: "want yyyyMM"}
 	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			dirs = append(dirs, entry.Name())
-		}
+	if _, err := time.Parse("200601", name); err != nil {
+		return &mapstore.InvalidPartitionNameError{Name: name, Reason: "want yyyyMM"}
 	}
-
-	// Sort partitions.
-	switch strings.ToLower(sortOrder) {
-	case mapstore.SortOrderAscending:
-		sort.Strings(dirs)
-	case mapstore.SortOrderDescending:
-		sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
-	default:
-		return nil, "", fmt.Errorf("invalid sort order: %s", sortOrder)
-	}
-
-	// Decode page token.
-	start := 0
-	if pageToken != "" {
-		tokenData, err := base64.StdEncoding.DecodeString(pageToken)
-		if err != nil {
-			return nil, "", fmt.Errorf("invalid page token: %w", err)
-		}
-		if err := json.Unmarshal(tokenData, &start); err != nil {
-			return nil, "", fmt.Errorf("invalid page token: %w", err)
-		}
-	}
-
-	// Apply pagination.
-	end := min(start+pageSize, len(dirs))
-
-	// Generate next page token.
-	if end < len(dirs) {
-		nextpageTokenData, _ := json.Marshal(end)
-		nextPageToken = base64.StdEncoding.EncodeToString(nextpageTokenData)
-	}
-
-	return dirs[start:end], nextPageToken, nil
+	return nil
 }
@@ -19,3 +19,12 @@ func (p *NoPartitionProvider) ListPartitions(
 ) (partitions []string, nextPageToken string, err error) {
 	return []string{""}, "", nil
 }
+
+// ValidatePartitionName implements the mapstore.PartitionNameValidator interface, accepting only the empty
+// name of the base directory.
+func (p *NoPartitionProvider) ValidatePartitionName(name string) error {
+	if name != "" {
+		return &mapstore.InvalidPartitionNameError{Name: name, Reason: "store is not partitioned"}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package dirpartition
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/uuidv7filename"
+)
+
+// Factory builds a partition provider from string parameters, as read from a configuration file.
+type Factory func(params map[string]string) (mapstore.PartitionProvider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"none":  newNoPartitionProvider,
+		"month": newMonthPartitionProvider,
+	}
+)
+
+// Register makes a partition provider available by name to NewPartitionProviderFromConfig.
+// The providers "none" and "month" are registered by default.
+// Register panics if factory is nil or name is empty or already registered, like database/sql.Register.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || factory == nil {
+		panic("dirpartition: Register needs a name and a factory")
+	}
+	if _, dup := registry[name]; dup {
+		panic("dirpartition: Register called twice for provider " + name)
+	}
+	registry[name] = factory
+}
+
+// Providers returns the sorted names of the registered partition providers.
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewPartitionProviderFromConfig builds the partition provider registered under name.
+func NewPartitionProviderFromConfig(name string, params map[string]string) (mapstore.PartitionProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown partition provider: %q", name)
+	}
+	p, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for partition provider %q: %w", name, err)
+	}
+	return p, nil
+}
+
+func newNoPartitionProvider(params map[string]string) (mapstore.PartitionProvider, error) {
+	for k := range params {
+		return nil, fmt.Errorf("unknown parameter: %s", k)
+	}
+	return &NoPartitionProvider{}, nil
+}
+
+// newMonthPartitionProvider builds a MonthPartitionProvider. The "time" parameter selects where the time of a
+// file comes from; the only built in source is "uuidv7", the default, which reads it from uuidv7filename names.
+func newMonthPartitionProvider(params map[string]string) (mapstore.PartitionProvider, error) {
+	for k, v := range params {
+		if k != "time" {
+			return nil, fmt.Errorf("unknown parameter: %s", k)
+		}
+		if v != "uuidv7" {
+			return nil, fmt.Errorf("unknown time source: %s", v)
+		}
+	}
+	return &MonthPartitionProvider{
+		TimeFn: func(key mapstore.FileKey) (time.Time, error) {
+			info, err := uuidv7filename.Parse(key.FileName)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return info.Time, nil
+		},
+	}, nil
+}
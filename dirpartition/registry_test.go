@@ -0,0 +1,67 @@
+package dirpartition
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/uuidv7filename"
+)
+
+func TestNewPartitionProviderFromConfig(t *testing.T) {
+	p, err := NewPartitionProviderFromConfig("none", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*NoPartitionProvider); !ok {
+		t.Fatalf("none: got %T", p)
+	}
+
+	p, err = NewPartitionProviderFromConfig("month", map[string]string{"time": "uuidv7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := uuidv7filename.NewUUIDv7String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := uuidv7filename.Build(id, "doc", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := p.GetPartitionDir(mapstore.FileKey{FileName: info.FileName})
+	if err != nil || dir != info.Time.Format("200601") {
+		t.Fatalf("month partition = %q, %v", dir, err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		params map[string]string
+	}{
+		{"missing", nil},
+		{"none", map[string]string{"x": "1"}},
+		{"month", map[string]string{"time": "mtime"}},
+	} {
+		if _, err := NewPartitionProviderFromConfig(tc.name, tc.params); err == nil {
+			t.Errorf("%s %v: want error", tc.name, tc.params)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-single", func(map[string]string) (mapstore.PartitionProvider, error) {
+		return &NoPartitionProvider{}, nil
+	})
+	if !slices.Contains(Providers(), "test-single") {
+		t.Fatalf("providers = %v", Providers())
+	}
+	if _, err := NewPartitionProviderFromConfig("test-single", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate Register did not panic")
+		}
+	}()
+	Register("month", newMonthPartitionProvider)
+}
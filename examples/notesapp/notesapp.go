@@ -0,0 +1,280 @@
+// Package notesapp is an example application built from the packages of this module, and the test bed for
+// their interactions. Notes are files of a mapstore.MapDirectoryStore, named after UUIDv7 ids and partitioned
+// by the month of their id. The body of a note is encrypted with a passphrase, and a listener on the store
+// events keeps an in memory ftsengine index of titles and bodies, so plaintext never reaches the disk.
+package notesapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/ftsengine"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+	"github.com/ppipada/mapstore-go/passphraseencdec"
+	"github.com/ppipada/mapstore-go/uuidv7filename"
+)
+
+const (
+	// fileSuffix is the suffix of every note file name, so the name follows from the id.
+	fileSuffix = "note"
+	fileExt    = "json"
+	searchPage = 20
+)
+
+// Note is one note. ID is a UUIDv7 and Created the time it encodes.
+type Note struct {
+	ID      string
+	Title   string
+	Body    string
+	Created time.Time
+	Updated time.Time
+}
+
+// Config configures an App.
+type Config struct {
+	// Dir holds the notes, one directory per month.
+	Dir string
+	// Passphrase encrypts the note bodies.
+	Passphrase passphraseencdec.PassphraseSource
+	// KDFParams are the argon2id parameters for new bodies, passphraseencdec.DefaultParams if zero.
+	KDFParams passphraseencdec.Params
+}
+
+// App stores and searches notes.
+type App struct {
+	store *mapstore.MapDirectoryStore
+	index *ftsengine.Engine
+}
+
+// Open opens the notes in cfg.Dir, creating it if needed, and indexes them.
+func Open(cfg Config) (*App, error) {
+	var encOpts []passphraseencdec.Option
+	if cfg.KDFParams != (passphraseencdec.Params{}) {
+		encOpts = append(encOpts, passphraseencdec.WithParams(cfg.KDFParams))
+	}
+	enc, err := passphraseencdec.NewEncryptedStringValueEncoderDecoder(cfg.Passphrase, encOpts...)
+	if err != nil {
+		return nil, err
+	}
+	index, err := ftsengine.NewEngine(ftsengine.Config{
+		BaseDir: ftsengine.MemoryDBBaseDir,
+		Table:   "notes",
+		Columns: []ftsengine.Column{{Name: "title", Weight: 2}, {Name: "body"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	app := &App{index: index}
+	app.store, err = mapstore.NewMapDirectoryStore(
+		cfg.Dir,
+		true,
+		&dirpartition.MonthPartitionProvider{TimeFn: noteTime},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirTimestamps(nil, []string{"updated"}),
+		mapstore.WithDirFileOptions(mapstore.WithValueEncDecGetter(func(path []string) mapstore.IOEncoderDecoder {
+			if slices.Equal(path, []string{"body"}) {
+				return enc
+			}
+			return nil
+		})),
+		mapstore.WithDirFileListeners(app.indexEvent),
+	)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	if err := app.reindex(); err != nil {
+		app.Close()
+		return nil, err
+	}
+	return app, nil
+}
+
+// Close closes the store and the index.
+func (a *App) Close() error {
+	return errors.Join(a.store.Close(), a.index.Close())
+}
+
+// Create adds a note and returns it.
+func (a *App) Create(title, body string) (Note, error) {
+	id, err := uuidv7filename.NewUUIDv7String()
+	if err != nil {
+		return Note{}, err
+	}
+	key, err := fileKey(id)
+	if err != nil {
+		return Note{}, err
+	}
+	if err := a.store.SetFileData(key, map[string]any{"id": id, "title": title, "body": body}); err != nil {
+		return Note{}, err
+	}
+	return a.Get(id)
+}
+
+// Update replaces the title and body of a note.
+func (a *App) Update(id, title, body string) error {
+	key, err := fileKey(id)
+	if err != nil {
+		return err
+	}
+	st, err := a.store.OpenFile(key, false, nil)
+	if err != nil {
+		return err
+	}
+	return st.MergeAll(map[string]any{"title": title, "body": body})
+}
+
+// Delete removes a note.
+func (a *App) Delete(id string) error {
+	key, err := fileKey(id)
+	if err != nil {
+		return err
+	}
+	return a.store.DeleteFile(key)
+}
+
+// Get returns a note.
+func (a *App) Get(id string) (Note, error) {
+	key, err := fileKey(id)
+	if err != nil {
+		return Note{}, err
+	}
+	data, err := a.store.GetFileData(key, false)
+	if err != nil {
+		return Note{}, err
+	}
+	return noteFromData(data)
+}
+
+// Search returns the notes best matching query, at most one page.
+func (a *App) Search(ctx context.Context, query string) ([]Note, error) {
+	hits, _, err := a.index.Search(ctx, query, "", searchPage)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]Note, 0, len(hits))
+	for _, h := range hits {
+		n, err := a.Get(h.ID)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, nil
+}
+
+// Month returns the notes created in the month of t, in UTC like the partitions, oldest first.
+func (a *App) Month(t time.Time) ([]Note, error) {
+	var notes []Note
+	err := a.eachNote([]string{t.UTC().Format("200601")}, func(n Note) {
+		notes = append(notes, n)
+	})
+	return notes, err
+}
+
+// reindex indexes all notes, e.g. after opening, as the index is not persisted.
+func (a *App) reindex() error {
+	docs := map[string]map[string]string{}
+	err := a.eachNote(nil, func(n Note) {
+		docs[n.ID] = map[string]string{"title": n.Title, "body": n.Body}
+	})
+	if err != nil {
+		return err
+	}
+	return a.index.BatchUpsert(context.Background(), docs)
+}
+
+// eachNote calls fn with every note of the partitions, all if none are given, oldest first.
+func (a *App) eachNote(partitions []string, fn func(Note)) error {
+	token := ""
+	for {
+		entries, next, err := a.store.ListFiles(
+			mapstore.ListingConfig{SortOrder: "asc", FilterPartitions: partitions},
+			token,
+		)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			st, err := a.store.OpenFileEntry(e)
+			if err != nil {
+				return err
+			}
+			data, err := st.GetAll(false)
+			if err != nil {
+				return err
+			}
+			n, err := noteFromData(data)
+			if err != nil {
+				return fmt.Errorf("note %s: %w", e.BaseRelativePath, err)
+			}
+			fn(n)
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// indexEvent keeps the index in sync with the store. Listeners cannot fail a write, so errors are logged; the
+// next Open indexes everything again.
+func (a *App) indexEvent(e mapstore.FileEvent) {
+	info, err := uuidv7filename.Parse(filepath.Base(e.File))
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	switch {
+	case e.Op == mapstore.OpDeleteFile:
+		err = a.index.Delete(ctx, info.ID)
+	case e.Data != nil:
+		title, _ := e.Data["title"].(string)
+		body, _ := e.Data["body"].(string)
+		err = a.index.Upsert(ctx, info.ID, map[string]string{"title": title, "body": body})
+	}
+	if err != nil {
+		slog.Warn("notesapp: indexing failed", "id", info.ID, "op", e.Op, "error", err)
+	}
+}
+
+func fileKey(id string) (mapstore.FileKey, error) {
+	info, err := uuidv7filename.Build(id, fileSuffix, fileExt)
+	if err != nil {
+		return mapstore.FileKey{}, err
+	}
+	return mapstore.FileKey{FileName: info.FileName}, nil
+}
+
+// noteTime returns the time of the id in the file name, which selects the month partition.
+func noteTime(key mapstore.FileKey) (time.Time, error) {
+	info, err := uuidv7filename.Parse(key.FileName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.Time, nil
+}
+
+func noteFromData(data map[string]any) (Note, error) {
+	id, _ := data["id"].(string)
+	info, err := uuidv7filename.Build(id, fileSuffix, fileExt)
+	if err != nil {
+		return Note{}, err
+	}
+	n := Note{ID: id, Created: info.Time}
+	n.Title, _ = data["title"].(string)
+	n.Body, _ = data["body"].(string)
+	if s, ok := data["updated"].(string); ok {
+		if n.Updated, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return Note{}, fmt.Errorf("invalid update time: %w", err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+package notesapp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go/passphraseencdec"
+)
+
+// testParams keep key derivation fast in tests.
+var testParams = passphraseencdec.Params{Time: 1, MemoryKiB: 64, Threads: 1}
+
+func openApp(t *testing.T, dir, passphrase string) *App {
+	t.Helper()
+	app, err := Open(Config{
+		Dir:        dir,
+		Passphrase: func() ([]byte, error) { return []byte(passphrase), nil },
+		KDFParams:  testParams,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func searchTitles(t *testing.T, app *App, query string) []string {
+	t.Helper()
+	notes, err := app.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := make([]string, 0, len(notes))
+	for _, n := range notes {
+		titles = append(titles, n.Title)
+	}
+	return titles
+}
+
+func TestNotesApp(t *testing.T) {
+	dir := t.TempDir()
+	app := openApp(t, dir, "correct horse")
+
+	groceries, err := app.Create("Groceries", "milk, eggs and saffron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trip, err := app.Create("Trip", "book the ferry to the island")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groceries.Updated.IsZero() || time.Since(groceries.Created) > time.Minute {
+		t.Fatalf("unexpected times: %+v", groceries)
+	}
+
+	// The note lives in the partition of its id's month, with the body encrypted.
+	month := groceries.Created.Format("200601")
+	raw, err := os.ReadFile(filepath.Join(dir, month, groceries.ID+"_note.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "Groceries") || strings.Contains(string(raw), "saffron") {
+		t.Fatalf("file content: %s", raw)
+	}
+	notes, err := app.Month(groceries.Created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].ID != groceries.ID || notes[1].ID != trip.ID {
+		t.Fatalf("Month = %+v", notes)
+	}
+
+	// Events keep the index in sync with creates, updates and deletes.
+	if got := searchTitles(t, app, "saffron"); len(got) != 1 || got[0] != "Groceries" {
+		t.Fatalf("search saffron = %v", got)
+	}
+	if err := app.Update(trip.ID, "Trip", "book the train to the mountains"); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchTitles(t, app, "ferry"); len(got) != 0 {
+		t.Fatalf("search ferry after update = %v", got)
+	}
+	if got := searchTitles(t, app, "mountains"); len(got) != 1 || got[0] != "Trip" {
+		t.Fatalf("search mountains = %v", got)
+	}
+	updated, err := app.Get(trip.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Updated.After(trip.Updated) {
+		t.Fatalf("update time %v not after %v", updated.Updated, trip.Updated)
+	}
+	if err := app.Delete(groceries.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchTitles(t, app, "saffron"); len(got) != 0 {
+		t.Fatalf("search saffron after delete = %v", got)
+	}
+	if err := app.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening decrypts the notes and rebuilds the index.
+	app = openApp(t, dir, "correct horse")
+	if got := searchTitles(t, app, "train"); len(got) != 1 || got[0] != "Trip" {
+		t.Fatalf("search train after reopen = %v", got)
+	}
+	if _, err := app.Get(groceries.ID); err == nil {
+		t.Fatal("deleted note still readable")
+	}
+	if err := app.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wrong passphrase cannot open the notes.
+	if _, err := Open(Config{
+		Dir:        dir,
+		Passphrase: func() ([]byte, error) { return []byte("wrong"), nil },
+		KDFParams:  testParams,
+	}); err == nil {
+		t.Fatal("opened with the wrong passphrase")
+	}
+}
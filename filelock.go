@@ -0,0 +1,57 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	lockFileSuffix      = ".lock"
+	defaultLockTimeout  = 10 * time.Second
+	lockRetryMinBackoff = time.Millisecond
+	lockRetryMaxBackoff = 50 * time.Millisecond
+)
+
+// ErrLockTimeout is returned when a cross-process file lock could not be acquired in time.
+var ErrLockTimeout = errors.New("timed out waiting for file lock")
+
+// fileLock is an advisory, cross-process lock implemented as an exclusively created sidecar file.
+// It only coordinates writers that take the lock, plain SetKey style writers still rely on the optimistic CAS.
+type fileLock struct {
+	path string
+}
+
+// acquireFileLock creates "<filename>.lock" exclusively, retrying until timeout.
+func acquireFileLock(filename string, timeout time.Duration) (*fileLock, error) {
+	path := filename + lockFileSuffix
+	deadline := time.Now().Add(timeout)
+	backoff := lockRetryMinBackoff
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			// The owner pid is informational, it helps when debugging stuck locks.
+			_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+			f.Close()
+			return &fileLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLockTimeout, path)
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, lockRetryMaxBackoff)
+	}
+}
+
+// release removes the lock file.
+func (l *fileLock) release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file %s: %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// Package flags evaluates feature flags kept in a MapFileStore file.
+//
+// The file holds one entry per flag below the "flags" key:
+//
+//	{
+//	  "flags": {
+//	    "new-editor": {
+//	      "enabled": true,
+//	      "rules": [{"attr": "plan", "values": ["pro", "team"]}],
+//	      "percentage": 25,
+//	      "bucketBy": "userID"
+//	    }
+//	  }
+//	}
+//
+// A flag is on when it is enabled, every rule matches the attributes and the caller falls into the rollout
+// percentage. Unknown flags are off. The file is reloaded when it changes on disk, so flags can be flipped
+// without a restart.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+const (
+	rootKey = "flags"
+
+	// DefaultBucketBy is the attribute that places callers into a percentage rollout.
+	DefaultBucketBy = "id"
+)
+
+// Rule matches when the attribute has one of the values, or with Negate when it has none of them.
+type Rule struct {
+	Attr   string   `json:"attr"`
+	Values []string `json:"values"`
+	Negate bool     `json:"negate,omitempty"`
+}
+
+// Flag is the definition of one flag.
+type Flag struct {
+	// Enabled is the master switch, a disabled flag is off for everyone.
+	Enabled bool `json:"enabled"`
+	// Rules must all match the attributes.
+	Rules []Rule `json:"rules,omitempty"`
+	// Percentage of callers the flag is on for, 0 to 100. Nil means everyone.
+	Percentage *float64 `json:"percentage,omitempty"`
+	// BucketBy names the attribute hashed for the rollout, DefaultBucketBy if empty.
+	BucketBy string `json:"bucketBy,omitempty"`
+}
+
+// Store evaluates the flags of one file.
+type Store struct {
+	file           *mapstore.MapFileStore
+	reloadInterval time.Duration
+	now            func() time.Time
+
+	mu         sync.Mutex
+	flags      map[string]Flag
+	lastReload time.Time
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithReloadInterval sets how often IsEnabled checks the file for changes, one second by default.
+// A check is a stat of the file; the file is only parsed again when it changed. Zero checks on every call.
+func WithReloadInterval(d time.Duration) Option {
+	return func(s *Store) {
+		s.reloadInterval = d
+	}
+}
+
+// New opens the flags file, creating an empty one if it does not exist.
+func New(filename string, opts ...Option) (*Store, error) {
+	file, err := mapstore.NewMapFileStore(
+		filename,
+		map[string]any{rootKey: map[string]any{}},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{file: file, reloadInterval: time.Second, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.Reload(); err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	return s, nil
+}
+
+// IsEnabled reports whether flag is on for the caller described by attrs.
+func (s *Store) IsEnabled(ctx context.Context, flag string, attrs map[string]string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	s.mu.Lock()
+	if s.now().Sub(s.lastReload) >= s.reloadInterval {
+		if err := s.reloadLocked(); err != nil {
+			// Keep serving the last good flags.
+			slog.Warn("flags: reload failed", "error", err)
+		}
+	}
+	f, ok := s.flags[flag]
+	s.mu.Unlock()
+	return ok && f.evaluate(flag, attrs)
+}
+
+// Reload reads the file again if it changed on disk. A file that fails to parse leaves the current flags in place.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+// SetFlag stores the definition of flag.
+func (s *Store) SetFlag(name string, f Flag) error {
+	if name == "" {
+		return errors.New("flags: empty flag name")
+	}
+	if err := f.validate(); err != nil {
+		return fmt.Errorf("flags: %s: %w", name, err)
+	}
+	raw, err := toMap(f)
+	if err != nil {
+		return err
+	}
+	if err := s.file.SetKey([]string{rootKey, name}, raw); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// Close releases the underlying store.
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+func (s *Store) reloadLocked() error {
+	s.lastReload = s.now()
+	all, err := s.file.GetAll(true)
+	if err != nil {
+		return err
+	}
+	parsed, err := parseFlags(all[rootKey])
+	if err != nil {
+		return err
+	}
+	s.flags = parsed
+	return nil
+}
+
+func (f Flag) evaluate(name string, attrs map[string]string) bool {
+	if !f.Enabled {
+		return false
+	}
+	for _, r := range f.Rules {
+		v, ok := attrs[r.Attr]
+		if (ok && slices.Contains(r.Values, v)) == r.Negate {
+			return false
+		}
+	}
+	if f.Percentage == nil {
+		return true
+	}
+	pct := *f.Percentage
+	if pct >= 100 {
+		return true
+	}
+	bucketBy := f.BucketBy
+	if bucketBy == "" {
+		bucketBy = DefaultBucketBy
+	}
+	key, ok := attrs[bucketBy]
+	if !ok {
+		return false
+	}
+	return bucket(name, key) < pct
+}
+
+func (f Flag) validate() error {
+	if f.Percentage != nil && (*f.Percentage < 0 || *f.Percentage > 100) {
+		return fmt.Errorf("percentage %v out of range", *f.Percentage)
+	}
+	for _, r := range f.Rules {
+		if r.Attr == "" {
+			return errors.New("rule without attr")
+		}
+	}
+	return nil
+}
+
+// bucket maps key to a stable value in [0, 100). The flag name is hashed in, so rollouts of different flags
+// reach different callers first.
+func bucket(flag, key string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(flag))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum64()%10000) / 100
+}
+
+// parseFlags converts the raw "flags" value of the file into definitions.
+func parseFlags(raw any) (map[string]Flag, error) {
+	if raw == nil {
+		return map[string]Flag{}, nil
+	}
+	entries, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("flags: %q is not an object", rootKey)
+	}
+	out := make(map[string]Flag, len(entries))
+	for name, v := range entries {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("flags: %s: %w", name, err)
+		}
+		var f Flag
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("flags: %s: %w", name, err)
+		}
+		if err := f.validate(); err != nil {
+			return nil, fmt.Errorf("flags: %s: %w", name, err)
+		}
+		out[name] = f
+	}
+	return out, nil
+}
+
+// toMap converts a definition into the generic form stored in the file.
+func toMap(f Flag) (map[string]any, error) {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,105 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestStore_IsEnabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	content := `{"flags": {
+		"on": {"enabled": true},
+		"off": {"enabled": false},
+		"pro": {"enabled": true, "rules": [{"attr": "plan", "values": ["pro", "team"]}]},
+		"not-eu": {"enabled": true, "rules": [{"attr": "region", "values": ["eu"], "negate": true}]},
+		"quarter": {"enabled": true, "percentage": 25, "bucketBy": "user"}
+	}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := t.Context()
+
+	for _, tc := range []struct {
+		flag  string
+		attrs map[string]string
+		want  bool
+	}{
+		{"on", nil, true},
+		{"off", nil, false},
+		{"missing", nil, false},
+		{"pro", map[string]string{"plan": "team"}, true},
+		{"pro", map[string]string{"plan": "free"}, false},
+		{"pro", nil, false},
+		{"not-eu", map[string]string{"region": "us"}, true},
+		{"not-eu", nil, true},
+		{"not-eu", map[string]string{"region": "eu"}, false},
+		{"quarter", nil, false},
+	} {
+		if got := s.IsEnabled(ctx, tc.flag, tc.attrs); got != tc.want {
+			t.Errorf("IsEnabled(%q, %v) = %v, want %v", tc.flag, tc.attrs, got, tc.want)
+		}
+	}
+
+	on := 0
+	for i := range 2000 {
+		attrs := map[string]string{"user": strconv.Itoa(i)}
+		first := s.IsEnabled(ctx, "quarter", attrs)
+		if first != s.IsEnabled(ctx, "quarter", attrs) {
+			t.Fatal("rollout is not stable")
+		}
+		if first {
+			on++
+		}
+	}
+	if on < 400 || on > 600 {
+		t.Fatalf("rollout reached %d of 2000, want about 500", on)
+	}
+}
+
+func TestStore_ReloadAndSetFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	s, err := New(path, WithReloadInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := t.Context()
+
+	if s.IsEnabled(ctx, "beta", nil) {
+		t.Fatal("unknown flag enabled")
+	}
+	if err := s.SetFlag("beta", Flag{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsEnabled(ctx, "beta", nil) {
+		t.Fatal("flag not enabled after SetFlag")
+	}
+	bad := 120.0
+	if err := s.SetFlag("beta", Flag{Enabled: true, Percentage: &bad}); err == nil {
+		t.Fatal("want error for percentage over 100")
+	}
+
+	// An edit on disk is picked up, a broken one keeps the last good flags.
+	if err := os.WriteFile(path, []byte(`{"flags": {"beta": {"enabled": false}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if s.IsEnabled(ctx, "beta", nil) {
+		t.Fatal("external change not picked up")
+	}
+	if err := os.WriteFile(path, []byte(`{"flags": {"beta": {"enabled": "yes"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Fatal("want error for malformed flag")
+	}
+	if s.IsEnabled(ctx, "beta", nil) {
+		t.Fatal("malformed file replaced the last good flags")
+	}
+}
@@ -0,0 +1,37 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCanceled matches any CanceledError via errors.Is.
+var ErrCanceled = errors.New("ftsengine: batch canceled")
+
+// CanceledError is returned when the context ends in the middle of a batch.
+// The uncommitted part was rolled back, so a resumable importer can retry the items after the first Committed ones.
+type CanceledError struct {
+	// Number of leading input items whose changes were committed.
+	Committed int
+	// Number of input items of the batch.
+	Total int
+	// Context error, context.Canceled or context.DeadlineExceeded.
+	Cause error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("ftsengine: batch canceled after %d of %d items: %v", e.Committed, e.Total, e.Cause)
+}
+
+// Unwrap lets errors.Is match both ErrCanceled and the context error.
+func (e *CanceledError) Unwrap() []error { return []error{ErrCanceled, e.Cause} }
+
+// canceledErr returns a CanceledError if ctx is done, else err unchanged.
+// It is used both before a chunk and for statement errors that the canceled context caused.
+func canceledErr(ctx context.Context, committed, total int, err error) error {
+	if cerr := ctx.Err(); cerr != nil {
+		return &CanceledError{Committed: committed, Total: total, Cause: cerr}
+	}
+	return err
+}
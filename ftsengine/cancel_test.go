@@ -0,0 +1,102 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBatchCancellation(t *testing.T) {
+	newEngine := func(t *testing.T) *Engine {
+		t.Helper()
+		e, err := NewEngine(Config{
+			BaseDir: MemoryDBBaseDir,
+			Table:   "docs",
+			Columns: []Column{{Name: "title"}},
+			Limits:  Limits{MaxSQLVariables: 4},
+		})
+		if err != nil {
+			t.Fatalf("NewEngine: %v", err)
+		}
+		t.Cleanup(func() { _ = e.Close() })
+		return e
+	}
+	docs := func(n int) (map[string]map[string]string, []string) {
+		m := make(map[string]map[string]string, n)
+		ids := make([]string, 0, n)
+		for i := range n {
+			id := fmt.Sprintf("d%02d", i)
+			m[id] = map[string]string{"title": "hello " + id}
+			ids = append(ids, id)
+		}
+		return m, ids
+	}
+	count := func(t *testing.T, e *Engine) int {
+		t.Helper()
+		rows, _, err := e.BatchList(t.Context(), "", nil, "", 0)
+		if err != nil {
+			t.Fatalf("BatchList: %v", err)
+		}
+		return len(rows)
+	}
+
+	t.Run("upsert rolls back", func(t *testing.T) {
+		e := newEngine(t)
+		m, _ := docs(10)
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		err := e.BatchUpsert(ctx, m)
+		var ce *CanceledError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expected CanceledError, got %v", err)
+		}
+		if ce.Committed != 0 || ce.Total != 10 {
+			t.Fatalf("unexpected progress %+v", ce)
+		}
+		if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("error should match ErrCanceled and context.Canceled: %v", err)
+		}
+		if n := count(t, e); n != 0 {
+			t.Fatalf("expected nothing written, got %d rows", n)
+		}
+	})
+
+	t.Run("delete reports committed chunks", func(t *testing.T) {
+		e := newEngine(t)
+		m, ids := docs(10)
+		if err := e.BatchUpsert(t.Context(), m); err != nil {
+			t.Fatalf("BatchUpsert: %v", err)
+		}
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		err := e.BatchDelete(ctx, ids)
+		var ce *CanceledError
+		if !errors.As(err, &ce) || ce.Committed != 0 || ce.Total != 10 {
+			t.Fatalf("expected CanceledError with no progress, got %v", err)
+		}
+		if n := count(t, e); n != 10 {
+			t.Fatalf("expected all rows kept, got %d", n)
+		}
+
+		// Resuming from Committed finishes the job.
+		if err := e.BatchDelete(t.Context(), ids[ce.Committed:]); err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if n := count(t, e); n != 0 {
+			t.Fatalf("expected empty table, got %d rows", n)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		e := newEngine(t)
+		m, _ := docs(3)
+		ctx, cancel := context.WithTimeout(t.Context(), 0)
+		defer cancel()
+		<-ctx.Done()
+		if err := e.BatchUpsert(ctx, m); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrCanceled) {
+			t.Fatalf("expected deadline CanceledError, got %v", err)
+		}
+	})
+}
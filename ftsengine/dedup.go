@@ -0,0 +1,27 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeduplicateIDs deletes all but the most recently inserted row (highest rowid) of every externalID and returns
+// the number of deleted rows. Upsert never creates duplicates, they can only come from writes that bypass it.
+// The engine also runs this check when it opens an existing table.
+func (e *Engine) DeduplicateIDs(ctx context.Context) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	defer e.cache.invalidate()
+	return e.deduplicateIDs(ctx, e.db)
+}
+
+// deduplicateIDs needs one scan; FTS5 tables cannot carry a unique index on externalid.
+func (e *Engine) deduplicateIDs(ctx context.Context, exec sqlExec) (int, error) {
+	const sqlDedup = `DELETE FROM %[1]s WHERE %[2]s NOT IN (SELECT max(%[2]s) FROM %[1]s GROUP BY %[3]s);`
+	res, err := exec.ExecContext(ctx, fmt.Sprintf(sqlDedup, quote(e.cfg.Table), ColNameRowID, ColNameExternalID))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
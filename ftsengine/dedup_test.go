@@ -0,0 +1,94 @@
+package ftsengine
+
+import (
+	"context"
+	"testing"
+)
+
+// insertRaw bypasses Upsert, the way duplicate ids can appear.
+func insertRaw(t *testing.T, e *Engine, id, title string) {
+	t.Helper()
+	if _, err := e.db.ExecContext(t.Context(),
+		`INSERT INTO "docs"(externalid,title,body) VALUES(?,?,'')`, id, title); err != nil {
+		t.Fatalf("raw insert: %v", err)
+	}
+}
+
+func titlesOf(t *testing.T, e *Engine) map[string][]string {
+	t.Helper()
+	rows, _, err := e.BatchList(t.Context(), "", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string][]string{}
+	for _, r := range rows {
+		out[r.ID] = append(out[r.ID], r.Values["title"])
+	}
+	return out
+}
+
+func TestDeduplicateIDs(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEngine(t)
+	defer e.Close()
+
+	insertRaw(t, e, "a", "old")
+	insertRaw(t, e, "a", "new")
+	insertRaw(t, e, "b", "only")
+
+	// Lookups and upserts act on the newest row.
+	existing, err := e.lookupRowIDs(ctx, e.db, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newest int64
+	row := e.db.QueryRowContext(ctx, `SELECT max(rowid) FROM "docs" WHERE externalid='a'`)
+	if err := row.Scan(&newest); err != nil {
+		t.Fatal(err)
+	}
+	if existing["a"] != newest {
+		t.Fatalf("lookup picked rowid %d, want newest %d", existing["a"], newest)
+	}
+
+	n, err := e.DeduplicateIDs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("removed %d rows, want 1", n)
+	}
+	got := titlesOf(t, e)
+	if len(got["a"]) != 1 || got["a"][0] != "new" || len(got["b"]) != 1 {
+		t.Fatalf("after dedup: %v", got)
+	}
+	if n, err := e.DeduplicateIDs(ctx); err != nil || n != 0 {
+		t.Fatalf("second run removed %d, err %v", n, err)
+	}
+}
+
+func TestDeduplicateIDsOnOpen(t *testing.T) {
+	cfg := Config{
+		BaseDir:    t.TempDir(),
+		DBFileName: "fts.sqlite",
+		Table:      "docs",
+		Columns:    []Column{{Name: "title"}, {Name: "body"}},
+	}
+	e, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertRaw(t, e, "x", "first")
+	insertRaw(t, e, "x", "second")
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = NewEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if got := titlesOf(t, e); len(got["x"]) != 1 || got["x"][0] != "second" {
+		t.Fatalf("duplicates survived reopening: %v", got)
+	}
+}
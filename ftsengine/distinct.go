@@ -0,0 +1,49 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+)
+
+// DistinctValues returns the unique non-empty stored values of column that start with prefix, in ascending order.
+// It is meant for small value sets like tags or kinds kept in unindexed columns, e.g. to build pickers and filters.
+// The prefix match is case sensitive. Limit is resolved like a BatchList page size, see Limits.
+func (e *Engine) DistinctValues(ctx context.Context, column, prefix string, limit int) ([]string, error) {
+	known := false
+	for _, c := range e.cfg.Columns {
+		if c.Name == column {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("ftsengine: unknown column %q", column)
+	}
+	limit = e.cfg.Limits.listPageSize(limit)
+
+	// The substr compare avoids LIKE, which is case insensitive and treats % and _ as wildcards.
+	q := fmt.Sprintf(
+		`SELECT DISTINCT %[1]s FROM %[2]s
+		 WHERE %[1]s IS NOT NULL AND %[1]s<>'' AND substr(%[1]s,1,length(?1))=?1
+		 ORDER BY %[1]s LIMIT ?2;`,
+		quote(column), quote(e.cfg.Table),
+	)
+	rows, err := e.db.QueryContext(ctx, q, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package ftsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistinctValues(t *testing.T) {
+	e := newBatchTestEngine(t)
+	ctx := t.Context()
+
+	docs := map[string]map[string]string{
+		"1": {"title": "a", "tag": "go"},
+		"2": {"title": "b", "tag": "golang"},
+		"3": {"title": "c", "tag": "go"},
+		"4": {"title": "d", "tag": "rust"},
+		"5": {"title": "e", "tag": "Go"},
+		"6": {"title": "f", "tag": "50%_off"},
+		"7": {"title": "g"},
+	}
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"all values", "", 0, []string{"50%_off", "Go", "go", "golang", "rust"}},
+		{"prefix", "go", 0, []string{"go", "golang"}},
+		{"prefix is case sensitive", "G", 0, []string{"Go"}},
+		{"wildcards are literal", "50%", 0, []string{"50%_off"}},
+		{"percent does not match everything", "%", 0, []string{}},
+		{"limit", "", 2, []string{"50%_off", "Go"}},
+		{"no match", "zzz", 0, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := e.DistinctValues(ctx, "tag", tc.prefix, tc.limit)
+			if err != nil {
+				t.Fatalf("DistinctValues: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := e.DistinctValues(ctx, "nope", "", 0); err == nil {
+		t.Fatal("expected error for unknown column")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !ftsengine_cgo
+
+package ftsengine
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// DriverName names the SQLite driver compiled into the engine.
+// The default is the pure go glebarez/go-sqlite, build with the ftsengine_cgo tag for mattn/go-sqlite3.
+const DriverName = "glebarez/go-sqlite"
+
+// openDB opens path with the glebarez driver, which runs each _pragma DSN parameter on connect.
+func openDB(path string, o SQLiteOptions) (*sql.DB, error) {
+	q := make([]string, 0, 5)
+	for _, p := range o.pragmas() {
+		q = append(q, "_pragma="+url.QueryEscape(p))
+	}
+	return sql.Open("sqlite", path+"?"+strings.Join(q, "&"))
+}
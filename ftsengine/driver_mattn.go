@@ -0,0 +1,48 @@
+//go:build ftsengine_cgo
+
+package ftsengine
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName names the SQLite driver compiled into the engine.
+// Mattn/go-sqlite3 needs CGO and its own sqlite_fts5 build tag for FTS5 support.
+const DriverName = "mattn/go-sqlite3"
+
+var (
+	// Pragmas are per engine, so each distinct pragma set gets its own registered driver.
+	driversMu sync.Mutex
+	drivers   = map[string]string{}
+)
+
+// openDB opens path with a mattn driver whose connect hook runs the configured pragmas.
+func openDB(path string, o SQLiteOptions) (*sql.DB, error) {
+	pragmas := o.pragmas()
+	key := strings.Join(pragmas, ";")
+
+	driversMu.Lock()
+	name, ok := drivers[key]
+	if !ok {
+		name = "ftsengine_sqlite3_" + strconv.Itoa(len(drivers))
+		sql.Register(name, &sqlite3.SQLiteDriver{
+			ConnectHook: func(c *sqlite3.SQLiteConn) error {
+				for _, p := range pragmas {
+					if _, err := c.Exec("PRAGMA "+p, nil); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+		drivers[key] = name
+	}
+	driversMu.Unlock()
+
+	return sql.Open(name, path)
+}
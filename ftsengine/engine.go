@@ -12,12 +12,9 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 	"sync"
 	"unicode"
-
-	_ "github.com/glebarez/go-sqlite"
 )
 
 const (
@@ -31,10 +28,18 @@ type Engine struct {
 	hsh string
 	// Serializes write-queries.
 	mu sync.Mutex
+	// Search result cache, nil if disabled.
+	cache *searchCache
 }
 
 func NewEngine(cfg Config) (*Engine, error) {
-	err := validateConfig(cfg)
+	cfg.Limits = cfg.Limits.withDefaults()
+	cfg.SQLite = cfg.SQLite.withDefaults()
+	cfg, err := withLanguageColumn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	err = validateConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -51,16 +56,24 @@ func NewEngine(cfg Config) (*Engine, error) {
 		cfg.DBFileName,
 	)
 
-	db, err := sql.Open("sqlite", dataSourceName+"?busy_timeout=5000&_pragma=journal_mode(WAL)")
+	db, err := openDB(dataSourceName, cfg.SQLite)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(2)
-	db.SetMaxIdleConns(2)
+	if cfg.BaseDir == MemoryDBBaseDir {
+		// Every connection to :memory: opens its own empty database, keep the one that holds the table.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	} else {
+		db.SetMaxOpenConns(cfg.SQLite.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.SQLite.MaxIdleConns)
+	}
 
-	e := &Engine{db: db, cfg: cfg}
+	e := &Engine{db: db, cfg: cfg, cache: newSearchCache(cfg.SearchCacheSize)}
 	e.hsh = schemaChecksum(e.cfg, tokenizerOptions)
-	slog.Info("ftsengine bootstrap", "dbPath", dataSourceName)
+	slog.Info("ftsengine bootstrap", "dbPath", dataSourceName, "driver", DriverName)
 	if err := e.bootstrap(context.Background()); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -84,30 +97,37 @@ func (e *Engine) Delete(ctx context.Context, id string) error {
 	const sqlDel = `DELETE FROM %s WHERE %s=?`
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	defer e.cache.invalidate()
 	_, err := e.db.ExecContext(ctx,
-		fmt.Sprintf(sqlDel, quote(e.cfg.Table), ColNameExternalID), id)
+		fmt.Sprintf(sqlDel, quote(e.cfg.Table), ColNameExternalID), e.NormalizeID(id))
 	return err
 }
 
+// BatchDelete deletes the rows of all ids, in chunks of Limits.MaxSQLVariables ids.
+// Each chunk commits on its own; if ctx ends midway a CanceledError reports how many leading ids were deleted.
 func (e *Engine) BatchDelete(ctx context.Context, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
-	// SQLite default.
-	const maxVars = 999
+	maxVars := e.cfg.Limits.MaxSQLVariables
 	toAny := func(ss []string) []any {
 		out := make([]any, len(ss))
 		for i, s := range ss {
-			out[i] = s
+			out[i] = e.NormalizeID(s)
 		}
 		return out
 	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	defer e.cache.invalidate()
 
+	total, done := len(ids), 0
 	for len(ids) != 0 {
+		if err := canceledErr(ctx, done, total, nil); err != nil {
+			return err
+		}
 		n := min(len(ids), maxVars)
 		part := ids[:n]
 		ids = ids[n:]
@@ -123,8 +143,9 @@ func (e *Engine) BatchDelete(ctx context.Context, ids []string) error {
 		sqlQ := fmt.Sprintf(sqlDelete, quote(e.cfg.Table), ColNameExternalID, b.String())
 
 		if _, err := e.db.ExecContext(ctx, sqlQ, toAny(part)...); err != nil {
-			return err
+			return canceledErr(ctx, done, total, err)
 		}
+		done += n
 	}
 	return nil
 }
@@ -135,13 +156,18 @@ func (e *Engine) Close() error { return e.db.Close() }
 // The logic works with every SQLite ≥ 3.9 because it uses INSERT and INSERT OR REPLACE, both supported by FTS5.
 // This is not multi process safe as this is serialized at application level.
 func (e *Engine) Upsert(ctx context.Context, id string, vals map[string]string) error {
+	if err := e.checkSize(ctx); err != nil {
+		return err
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	return e.internalUpsert(ctx, nil, id, vals)
+	defer e.cache.invalidate()
+	return e.internalUpsert(ctx, nil, e.NormalizeID(id), vals)
 }
 
 // BatchUpsert writes / updates all docs inside ONE transaction.
 // The map key is the externalID, the value is the column map.
+// If ctx ends midway the transaction is rolled back and a CanceledError with Committed 0 is returned.
 func (e *Engine) BatchUpsert(
 	ctx context.Context,
 	docs map[string]map[string]string,
@@ -149,20 +175,28 @@ func (e *Engine) BatchUpsert(
 	if len(docs) == 0 {
 		return nil
 	}
+	docs = e.normalizeDocIDs(docs)
+	if err := e.checkSize(ctx); err != nil {
+		return err
+	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	defer e.cache.invalidate()
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return canceledErr(ctx, 0, len(docs), err)
 	}
 	commit := func(err error) error {
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return canceledErr(ctx, 0, len(docs), err)
 		}
-		return tx.Commit()
+		if err := tx.Commit(); err != nil {
+			return canceledErr(ctx, 0, len(docs), err)
+		}
+		return nil
 	}
 
 	// Gather existing rowids in one probe.
@@ -176,6 +210,9 @@ func (e *Engine) BatchUpsert(
 	}
 
 	for id, vals := range docs {
+		if err := ctx.Err(); err != nil {
+			return commit(err)
+		}
 		if err := e.internalUpsert(ctx, tx, id, vals, existing[id]); err != nil {
 			return commit(err)
 		}
@@ -197,12 +234,28 @@ func (e *Engine) BatchList(
 	pageToken string,
 	pageSize int,
 ) (rows []ListResult, nextToken string, err error) {
-	if pageSize <= 0 {
-		pageSize = 1000
-	}
-	if pageSize > 10000 {
-		pageSize = 10000
+	var order []OrderBy
+	if compareColumn != "" && compareColumn != ColNameRowID {
+		order = append(order, OrderBy{Column: compareColumn})
 	}
+	return e.BatchListOrdered(ctx, order, wantedCols, pageToken, pageSize)
+}
+
+// BatchListOrdered pages over the whole table ordered by the given terms, e.g.
+// {mtime DESC, rowid DESC} for "most recently modified first".
+// Rowid is always the final tie breaker. If it is not listed it is appended with the direction of the
+// last term. Rowid may only appear as the last term.
+// Columns are compared as text and NULL sorts like "".
+// Tokens encode the last row's sort key, so a token is only honoured with the same order terms;
+// a token that does not fit restarts from the first page.
+func (e *Engine) BatchListOrdered(
+	ctx context.Context,
+	order []OrderBy,
+	wantedCols []string,
+	pageToken string,
+	pageSize int,
+) (rows []ListResult, nextToken string, err error) {
+	pageSize = e.cfg.Limits.listPageSize(pageSize)
 
 	// Validate / canonicalise wantedCols.
 	colExists := func(name string) bool {
@@ -226,70 +279,115 @@ func (e *Engine) BatchList(
 		}
 	}
 
-	if compareColumn == "" {
-		compareColumn = ColNameRowID
-	} else if compareColumn != ColNameRowID && !colExists(compareColumn) {
-		return nil, "", fmt.Errorf("ftsengine: unknown compare column %q", compareColumn)
+	// Split order into text columns and the trailing rowid direction.
+	var (
+		cmpCols []OrderBy
+		ridDesc bool
+		ridSeen bool
+	)
+	for _, o := range order {
+		if ridSeen {
+			return nil, "", errors.New("ftsengine: rowid must be the last order term")
+		}
+		if o.Column == ColNameRowID {
+			ridDesc = o.Desc
+			ridSeen = true
+			continue
+		}
+		if !colExists(o.Column) {
+			return nil, "", fmt.Errorf("ftsengine: unknown compare column %q", o.Column)
+		}
+		cmpCols = append(cmpCols, o)
+	}
+	if !ridSeen && len(cmpCols) > 0 {
+		ridDesc = cmpCols[len(cmpCols)-1].Desc
 	}
 
-	// Decode continuation token.
+	// Decode continuation token. Invalid tokens restart from the first page.
 	var (
-		// TEXT comparison   (rowid compare: unused).
-		lastCmp string
-		// Always included to disambiguate duplicates.
-		lastRID int64
+		lastCmp   []string
+		lastRID   int64
+		hasCursor bool
 	)
 	if pageToken != "" {
-		var t struct {
-			C string `json:"c"`
-			R int64  `json:"r"`
-		}
-		if b, _ := base64.StdEncoding.DecodeString(pageToken); len(b) > 0 {
-			_ = json.Unmarshal(b, &t)
-			lastCmp = t.C
-			lastRID = t.R
+		var t listToken
+		if b, _ := base64.StdEncoding.DecodeString(pageToken); len(b) > 0 && json.Unmarshal(b, &t) == nil {
+			if t.V == nil && t.C != "" {
+				// Tokens written before multi-column ordering.
+				t.V = []string{t.C}
+			}
+			if len(t.V) == len(cmpCols) {
+				lastCmp = t.V
+				lastRID = t.R
+				hasCursor = true
+			}
 		}
 	}
 
-	// Build SELECT list.
-	selectCols := []string{ColNameRowID, ColNameExternalID}
-	needCmpInSelect := compareColumn != ColNameRowID
-	if needCmpInSelect {
-		selectCols = append(selectCols, quote(compareColumn))
+	// Build SELECT list: rowid, externalid, the sort key columns, then the wanted columns.
+	cmpExprs := make([]string, len(cmpCols))
+	for i, o := range cmpCols {
+		cmpExprs[i] = fmt.Sprintf("COALESCE(%s,'')", quote(o.Column))
 	}
-	wantedColsNoCompare := make([]string, 0, len(wantedCols))
+	selectCols := []string{ColNameRowID, ColNameExternalID}
+	selectCols = append(selectCols, cmpExprs...)
 	for _, c := range wantedCols {
-		if c == compareColumn {
-			continue
-		}
 		selectCols = append(selectCols, quote(c))
-		wantedColsNoCompare = append(wantedColsNoCompare, c)
 	}
 
-	// Build WHERE + ORDER BY.
-	var where string
+	// Build WHERE: a row comes after the cursor if it is beyond it on the first differing term.
+	// (k1 op v1) OR (k1 = v1 AND k2 op v2) OR ... OR (k1 = v1 AND ... AND rowid op r).
+	where := "1"
 	var args []any
-	if compareColumn == ColNameRowID {
-		where = ColNameRowID + ">?"
-		args = append(args, lastRID)
-	} else {
-		// Actual: (cmp > lastCmp) OR (cmp = lastCmp AND rowid > lastRID).
-		where = fmt.Sprintf("(%s>? OR (%s=? AND %s>?))",
-			quote(compareColumn), quote(compareColumn), ColNameRowID)
-		args = append(args, lastCmp, lastCmp, lastRID)
+	if hasCursor {
+		cmpOp := func(desc bool) string {
+			if desc {
+				return "<"
+			}
+			return ">"
+		}
+		var ors []string
+		for i := 0; i <= len(cmpExprs); i++ {
+			var ands []string
+			for j := range i {
+				ands = append(ands, cmpExprs[j]+"=?")
+				args = append(args, lastCmp[j])
+			}
+			if i < len(cmpExprs) {
+				ands = append(ands, cmpExprs[i]+cmpOp(cmpCols[i].Desc)+"?")
+				args = append(args, lastCmp[i])
+			} else {
+				ands = append(ands, ColNameRowID+cmpOp(ridDesc)+"?")
+				args = append(args, lastRID)
+			}
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+		where = strings.Join(ors, " OR ")
 	}
 
+	// Build ORDER BY.
+	dir := func(desc bool) string {
+		if desc {
+			return " DESC"
+		}
+		return ""
+	}
+	orderTerms := make([]string, 0, len(cmpExprs)+1)
+	for i, x := range cmpExprs {
+		orderTerms = append(orderTerms, x+dir(cmpCols[i].Desc))
+	}
+	orderTerms = append(orderTerms, ColNameRowID+dir(ridDesc))
+
 	// We fetch one extra row to know if more data exists.
 	limitRows := pageSize + 1
 	args = append(args, limitRows)
 
-	const sqlSelect = `SELECT %s FROM %s WHERE %s ORDER BY %s,%s LIMIT ?;`
+	const sqlSelect = `SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT ?;`
 	sqlQ := fmt.Sprintf(sqlSelect,
 		strings.Join(selectCols, ","),
 		quote(e.cfg.Table),
 		where,
-		quote(compareColumn),
-		ColNameRowID,
+		strings.Join(orderTerms, ","),
 	)
 
 	// One read-only tx per page.
@@ -306,29 +404,17 @@ func (e *Engine) BatchList(
 	defer r.Close()
 
 	// Prepare scan dest.
-	destCount := len(selectCols)
-	dest := make([]any, destCount)
-
+	dest := make([]any, 0, len(selectCols))
 	var ridHolder int64
 	var idHolder string
-	dest[0] = &ridHolder
-	dest[1] = &idHolder
-
-	cmpIdx := -1
-	if needCmpInSelect {
-		cmpIdx = 2
-		dest[cmpIdx] = new(sql.NullString)
-	}
-
-	// Remaining wanted columns.
-	valHolders := make([]sql.NullString, len(wantedColsNoCompare))
-	// Position after rowid, externalID (+ maybe compareCol).
-	off := 2
-	if needCmpInSelect {
-		off++
+	dest = append(dest, &ridHolder, &idHolder)
+	cmpHolders := make([]string, len(cmpCols))
+	for i := range cmpHolders {
+		dest = append(dest, &cmpHolders[i])
 	}
+	valHolders := make([]sql.NullString, len(wantedCols))
 	for i := range valHolders {
-		dest[off+i] = &valHolders[i]
+		dest = append(dest, &valHolders[i])
 	}
 
 	var haveMore bool
@@ -344,29 +430,14 @@ func (e *Engine) BatchList(
 		}
 
 		vals := make(map[string]string, len(wantedCols))
-		j := 0
-		for _, col := range wantedCols {
-			if col == compareColumn {
-				// If user requested compareColumn, get it from cmpIdx.
-				if cmpIdx >= 0 {
-					if nv, ok := dest[cmpIdx].(*sql.NullString); ok && nv.Valid {
-						vals[col] = nv.String
-					}
-				}
-			} else {
-				if valHolders[j].Valid {
-					vals[col] = valHolders[j].String
-				}
-				j++
+		for i, col := range wantedCols {
+			if valHolders[i].Valid {
+				vals[col] = valHolders[i].String
 			}
 		}
 		rows = append(rows, ListResult{ID: idHolder, Values: vals})
 		lastRID = ridHolder
-		if cmpIdx >= 0 {
-			if nv, ok := dest[cmpIdx].(*sql.NullString); ok && nv.Valid {
-				lastCmp = nv.String
-			}
-		}
+		lastCmp = append(lastCmp[:0:0], cmpHolders...)
 	}
 	if err := r.Err(); err != nil {
 		return nil, "", err
@@ -377,15 +448,21 @@ func (e *Engine) BatchList(
 
 	// Produce nextToken only if a further row exists.
 	if haveMore {
-		buf, _ := json.Marshal(struct {
-			C string `json:"c"`
-			R int64  `json:"r"`
-		}{lastCmp, lastRID})
+		buf, _ := json.Marshal(listToken{V: lastCmp, R: lastRID})
 		nextToken = base64.StdEncoding.EncodeToString(buf)
 	}
 	return rows, nextToken, nil
 }
 
+// listToken is the keyset cursor of BatchListOrdered.
+type listToken struct {
+	// Sort key values of the last row, one per non-rowid order term.
+	V []string `json:"v,omitempty"`
+	// Single compare value of older tokens.
+	C string `json:"c,omitempty"`
+	R int64  `json:"r"`
+}
+
 // Search returns one page of results and, if more results exist,
 // an opaque token for the next page.
 // The query is treated as a search literal and not a fts5 expression.
@@ -394,85 +471,147 @@ func (e *Engine) Search(
 	query string,
 	pageToken string,
 	pageSize int,
+	opts ...SearchOption,
 ) (hits []SearchResult, nextToken string, err error) {
+	page, err := e.SearchPaged(ctx, query, pageToken, pageSize, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return page.Hits, page.NextToken, nil
+}
+
+// SearchPaged is Search with options, e.g. WithTotalCount, returning a SearchPage.
+func (e *Engine) SearchPaged(
+	ctx context.Context,
+	query string,
+	pageToken string,
+	pageSize int,
+	opts ...SearchOption,
+) (page SearchPage, err error) {
 	if query == "" {
-		return nil, "", errors.New("empty query")
+		return SearchPage{}, errors.New("empty query")
+	}
+	var so searchOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+	if err := e.validateSearchOptions(so); err != nil {
+		return SearchPage{}, err
 	}
 
-	if pageSize <= 0 || pageSize > 10000 {
-		pageSize = 10
+	if pageSize <= 0 || pageSize > e.cfg.Limits.MaxPageSize {
+		pageSize = e.cfg.Limits.DefaultSearchPageSize
 	}
 
 	// Decode / reset token.
 	var offset int
 	if pageToken != "" {
-		var t struct {
-			Query  string `json:"q"`
-			Offset int    `json:"o"`
-		}
+		var t searchToken
 		b, err := base64.StdEncoding.DecodeString(pageToken)
 		if err == nil {
 			_ = json.Unmarshal(b, &t)
 		}
-		// Token belongs to same query.
-		if t.Query == query {
+		// Token belongs to same query and grouping.
+		if t.Query == query && t.Group == so.groupBy && t.Filters == so.filterKey() {
 			offset = t.Offset
 		}
 	}
 
-	// Bm25 weight parameters, one per column.
-	var weights []any
-	for _, c := range e.cfg.Columns {
-		if c.Weight == 0 {
-			weights = append(weights, float64(1))
-		} else {
-			weights = append(weights, c.Weight)
-		}
+	cacheKey := searchCacheKey(query, offset, pageSize, so)
+	cached, cacheGen, ok := e.cache.get(cacheKey)
+	if ok {
+		return cached, nil
 	}
 
+	weights := e.bm25Weights()
+
 	const sqlSearch = `SELECT %s, bm25(%s%s) AS s
-			FROM %s WHERE %s MATCH ?
+			FROM %s WHERE %s
 			ORDER BY s ASC, %s
 			LIMIT ? OFFSET ?;`
 
+	where, filterArgs := e.matchWhere(so)
 	sqlQ := fmt.Sprintf(sqlSearch, ColNameExternalID,
 		quote(e.cfg.Table), paramPlaceholders(len(weights)),
-		quote(e.cfg.Table), e.cfg.Table, ColNameRowID)
+		quote(e.cfg.Table), where, ColNameRowID)
+	if so.groupBy != "" {
+		sqlQ = e.groupedSearchSQL(so.groupBy, len(weights), where)
+	}
 
-	args := slices.Clone(weights)
+	args := weights
 	// Escape any embedded double quotes.
 	// FTS5 has special chars like - * etc that only quote for SQL, not for token.
 	cQ := cleanQueryWithOr(query)
 	if cQ == "" {
 		// Return empty result.
-		return []SearchResult{}, "", nil
+		return SearchPage{Hits: []SearchResult{}}, nil
 	}
-	args = append(args, cQ, pageSize, offset)
+	args = append(args, cQ)
+	args = append(args, filterArgs...)
+	args = append(args, pageSize, offset)
 
 	rows, err := e.db.QueryContext(ctx, sqlQ, args...)
 	if err != nil {
-		return nil, "", err
+		return SearchPage{}, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.ID, &r.Score); err != nil {
-			return nil, "", err
+		dest := []any{&r.ID, &r.Score}
+		if so.groupBy != "" {
+			dest = append(dest, &r.GroupSize)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return SearchPage{}, err
 		}
-		hits = append(hits, r)
+		page.Hits = append(page.Hits, r)
+	}
+	if err := rows.Err(); err != nil {
+		return SearchPage{}, err
 	}
 
 	// Build next token.
-	if len(hits) == pageSize {
+	if len(page.Hits) == pageSize {
 		offset += pageSize
-		buf, _ := json.Marshal(struct {
-			Query  string `json:"q"`
-			Offset int    `json:"o"`
-		}{query, offset})
-		nextToken = base64.StdEncoding.EncodeToString(buf)
+		buf, _ := json.Marshal(searchToken{Query: query, Offset: offset, Group: so.groupBy, Filters: so.filterKey()})
+		page.NextToken = base64.StdEncoding.EncodeToString(buf)
+	}
+
+	if so.countTotal {
+		page.Total, page.TotalIsEstimate, err = e.countMatches(ctx, cQ, so)
+		if err != nil {
+			return SearchPage{}, err
+		}
+	}
+	if so.explain {
+		if err := e.explainHits(ctx, cQ, page.Hits); err != nil {
+			return SearchPage{}, err
+		}
+	}
+	e.cache.put(cacheKey, cacheGen, page)
+	return page, nil
+}
+
+// bm25Weights returns the bm25 weight parameters, one per configured column.
+func (e *Engine) bm25Weights() []any {
+	weights := make([]any, 0, len(e.cfg.Columns))
+	for _, c := range e.cfg.Columns {
+		if c.Weight == 0 {
+			weights = append(weights, float64(1))
+		} else {
+			weights = append(weights, c.Weight)
+		}
 	}
-	return hits, nextToken, rows.Err()
+	return weights
+}
+
+// searchToken is the offset cursor of SearchPaged.
+type searchToken struct {
+	Query   string `json:"q"`
+	Offset  int    `json:"o"`
+	Group   string `json:"g,omitempty"`
+	Filters string `json:"f,omitempty"`
 }
 
 func (e *Engine) bootstrap(ctx context.Context) error {
@@ -480,9 +619,6 @@ func (e *Engine) bootstrap(ctx context.Context) error {
 	const sqlSelectMetaHash = `SELECT v FROM meta WHERE k='h'`
 	const sqlInsertMetaHash = `INSERT OR REPLACE INTO meta(k,v) VALUES('h',?)`
 	const sqlDropTable = `DROP TABLE IF EXISTS %s`
-	const sqlCreateVirtualTable = `CREATE VIRTUAL TABLE IF NOT EXISTS %s
-		USING fts5 (%s,
-			tokenize='%s');`
 	const sqlDeleteAllRows = `DELETE FROM %s`
 
 	// Meta for schema hash.
@@ -505,27 +641,41 @@ func (e *Engine) bootstrap(ctx context.Context) error {
 		slog.Info("fst-engine bootstrap: config checksum mismatch, create virtual table again.")
 		_, _ = e.db.ExecContext(ctx, fmt.Sprintf(sqlDropTable, quote(e.cfg.Table)))
 
-		var cols []string
-		cols = append(cols, ColNameExternalID+" UNINDEXED")
-		for _, c := range e.cfg.Columns {
-			col := c.Name
-			if c.Unindexed {
-				col += " UNINDEXED"
-			}
-			cols = append(cols, col)
-		}
-		ddl := fmt.Sprintf(sqlCreateVirtualTable,
-			quote(e.cfg.Table), strings.Join(cols, ","), tokenizerOptions)
-
-		if _, err := e.db.ExecContext(ctx, ddl); err != nil {
+		if _, err := e.db.ExecContext(ctx, e.createTableSQL(e.cfg.Table)); err != nil {
 			return err
 		}
 		_, _ = e.db.ExecContext(ctx, sqlInsertMetaHash, e.hsh)
+		return nil
+	}
 
+	// Existing table, drop duplicate ids that writes bypassing Upsert may have left.
+	n, err := e.deduplicateIDs(ctx, e.db)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.Warn("fst-engine bootstrap: removed duplicate external ids", "rows", n)
 	}
 	return nil
 }
 
+// createTableSQL returns the DDL of the FTS table for the configured columns under the given name.
+func (e *Engine) createTableSQL(table string) string {
+	const sqlCreateVirtualTable = `CREATE VIRTUAL TABLE IF NOT EXISTS %s
+		USING fts5 (%s,
+			tokenize='%s');`
+	var cols []string
+	cols = append(cols, ColNameExternalID+" UNINDEXED")
+	for _, c := range e.cfg.Columns {
+		col := c.Name
+		if c.Unindexed {
+			col += " UNINDEXED"
+		}
+		cols = append(cols, col)
+	}
+	return fmt.Sprintf(sqlCreateVirtualTable, quote(table), strings.Join(cols, ","), tokenizerOptions)
+}
+
 func (e *Engine) lookupRowIDs(
 	ctx context.Context,
 	exec sqlExec,
@@ -534,6 +684,23 @@ func (e *Engine) lookupRowIDs(
 	if len(ids) == 0 {
 		return nil, errors.New("got empty id's for lookup")
 	}
+	out := make(map[string]int64, len(ids))
+	for len(ids) != 0 {
+		n := min(len(ids), e.cfg.Limits.MaxSQLVariables)
+		if err := e.lookupRowIDsChunk(ctx, exec, ids[:n], out); err != nil {
+			return nil, err
+		}
+		ids = ids[n:]
+	}
+	return out, nil
+}
+
+func (e *Engine) lookupRowIDsChunk(
+	ctx context.Context,
+	exec sqlExec,
+	ids []string,
+	out map[string]int64,
+) error {
 	var b strings.Builder
 	for i := range ids {
 		if i > 0 {
@@ -542,8 +709,9 @@ func (e *Engine) lookupRowIDs(
 		b.WriteByte('?')
 	}
 
-	sqlQ := fmt.Sprintf(`SELECT %s,%s FROM %s WHERE %s IN (%s);`,
-		ColNameExternalID, ColNameRowID, quote(e.cfg.Table), ColNameExternalID, b.String())
+	// Ordered by rowid, so that the newest row wins should an id be duplicated.
+	sqlQ := fmt.Sprintf(`SELECT %s,%s FROM %s WHERE %s IN (%s) ORDER BY %s;`,
+		ColNameExternalID, ColNameRowID, quote(e.cfg.Table), ColNameExternalID, b.String(), ColNameRowID)
 
 	args := make([]any, len(ids))
 	for i, id := range ids {
@@ -552,20 +720,19 @@ func (e *Engine) lookupRowIDs(
 
 	rows, err := exec.QueryContext(ctx, sqlQ, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	out := make(map[string]int64, len(ids))
 	for rows.Next() {
 		var id string
 		var rid int64
 		if err := rows.Scan(&id, &rid); err != nil {
-			return nil, err
+			return err
 		}
 		out[id] = rid
 	}
-	return out, rows.Err()
+	return rows.Err()
 }
 
 // internalUpsert is shared by Upsert and BatchUpsert.
@@ -597,23 +764,31 @@ func (e *Engine) internalUpsert(
 		exists = true
 		rowid = knownRowID[0]
 	} else {
-		sqlQ := fmt.Sprintf(`SELECT %s FROM %s WHERE %s=?`, ColNameRowID, quote(e.cfg.Table), ColNameExternalID)
+		// Newest row first, matching lookupRowIDs and DeduplicateIDs.
+		sqlQ := fmt.Sprintf(`SELECT %s FROM %s WHERE %s=? ORDER BY %s DESC`,
+			ColNameRowID, quote(e.cfg.Table), ColNameExternalID, ColNameRowID)
+		// Close the probe before writing: with db instead of a tx an open result set holds a connection.
 		rows, err := exec.QueryContext(ctx, sqlQ, id)
 		if err != nil {
 			return err
 		}
-		defer rows.Close()
 		if rows.Next() {
 			if err := rows.Scan(&rowid); err != nil {
+				_ = rows.Close()
 				return err
 			}
 			exists = true
 		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
 		if err := rows.Err(); err != nil {
 			return err
 		}
 	}
 
+	vals = e.withLanguage(vals)
+
 	// Build column list, placeholders and args slice.
 	colNames := []string{ColNameExternalID}
 	marks := []string{"?"}
@@ -704,6 +879,15 @@ func validateConfig(c Config) error {
 	if len(c.Columns) == 0 {
 		return errors.New("ftsengine: need ≥1 column")
 	}
+	if c.NormalizeID&^(NormalizeIDNFC|NormalizeIDLowercase) != 0 {
+		return fmt.Errorf("ftsengine: unknown id normalization %d", c.NormalizeID)
+	}
+	if c.MaxDBSizeBytes < 0 {
+		return errors.New("ftsengine: negative MaxDBSizeBytes")
+	}
+	if c.SearchCacheSize < 0 {
+		return errors.New("ftsengine: negative search cache size")
+	}
 	if c.BaseDir == "" {
 		return errors.New("ftsengine: DB BaseDir incorrect")
 	}
@@ -727,7 +911,10 @@ func validateConfig(c Config) error {
 		}
 		seen[col.Name] = struct{}{}
 	}
-	return nil
+	if err := c.Limits.withDefaults().validate(len(c.Columns)); err != nil {
+		return err
+	}
+	return c.SQLite.withDefaults().validate()
 }
 
 func schemaChecksum(cfg Config, extra string) string {
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -708,6 +709,115 @@ func TestBatchList_OrderingAndStability(t *testing.T) {
 	}
 }
 
+func TestBatchListOrdered_DescAndMultiColumn(t *testing.T) {
+	e := newBatchTestEngine(t)
+	ctx := t.Context()
+
+	// Tag holds a sortable mtime, title a secondary key.
+	docs := map[string]map[string]string{
+		"a": {"title": "x", "tag": "2024-01-01"},
+		"b": {"title": "y", "tag": "2024-03-01"},
+		"c": {"title": "x", "tag": "2024-03-01"},
+		"d": {"title": "z", "tag": "2024-02-01"},
+		"e": {"title": "x", "tag": "2024-03-01"},
+		"f": {"title": "q"},
+	}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		if err := e.Upsert(ctx, id, docs[id]); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	listAll := func(t *testing.T, order []OrderBy, pageSize int) []string {
+		t.Helper()
+		var ids []string
+		token := ""
+		for {
+			rows, next, err := e.BatchListOrdered(ctx, order, []string{"tag"}, token, pageSize)
+			if err != nil {
+				t.Fatalf("batchlist: %v", err)
+			}
+			for _, r := range rows {
+				ids = append(ids, r.ID)
+			}
+			if next == "" {
+				return ids
+			}
+			token = next
+		}
+	}
+
+	tests := []struct {
+		name  string
+		order []OrderBy
+		want  []string
+	}{
+		{
+			name:  "rowid desc",
+			order: []OrderBy{{Column: ColNameRowID, Desc: true}},
+			want:  []string{"f", "e", "d", "c", "b", "a"},
+		},
+		{
+			name:  "mtime desc rowid desc",
+			order: []OrderBy{{Column: "tag", Desc: true}, {Column: ColNameRowID, Desc: true}},
+			want:  []string{"e", "c", "b", "d", "a", "f"},
+		},
+		{
+			name:  "mtime desc implies rowid desc",
+			order: []OrderBy{{Column: "tag", Desc: true}},
+			want:  []string{"e", "c", "b", "d", "a", "f"},
+		},
+		{
+			name:  "mtime desc rowid asc",
+			order: []OrderBy{{Column: "tag", Desc: true}, {Column: ColNameRowID}},
+			want:  []string{"b", "c", "e", "d", "a", "f"},
+		},
+		{
+			name:  "mixed directions",
+			order: []OrderBy{{Column: "title"}, {Column: "tag", Desc: true}},
+			want:  []string{"f", "e", "c", "a", "b", "d"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, size := range []int{1, 2, 4, 100} {
+				got := listAll(t, tc.order, size)
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("pageSize %d: got %v, want %v", size, got, tc.want)
+				}
+			}
+		})
+	}
+
+	t.Run("rowid not last", func(t *testing.T) {
+		_, _, err := e.BatchListOrdered(ctx, []OrderBy{{Column: ColNameRowID}, {Column: "tag"}}, nil, "", 2)
+		if err == nil {
+			t.Fatal("expected error for rowid before other terms")
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, _, err := e.BatchListOrdered(ctx, []OrderBy{{Column: "nope", Desc: true}}, nil, "", 2)
+		if err == nil {
+			t.Fatal("expected error for unknown order column")
+		}
+	})
+
+	t.Run("token from other order restarts", func(t *testing.T) {
+		_, token, err := e.BatchListOrdered(ctx, []OrderBy{{Column: "tag"}, {Column: "title"}}, nil, "", 2)
+		if err != nil || token == "" {
+			t.Fatalf("first page: token=%q err=%v", token, err)
+		}
+		rows, _, err := e.BatchListOrdered(ctx, []OrderBy{{Column: "tag", Desc: true}}, nil, token, 1)
+		if err != nil {
+			t.Fatalf("batchlist: %v", err)
+		}
+		if len(rows) != 1 || rows[0].ID != "e" {
+			t.Fatalf("expected restart from first row, got %+v", rows)
+		}
+	})
+}
+
 func TestBatchList_EmptyTable(t *testing.T) {
 	e := newBatchTestEngine(t)
 	ctx := t.Context()
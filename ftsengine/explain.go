@@ -0,0 +1,132 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnScore explains the part of one indexed column in the rank of a hit, see WithExplain.
+type ColumnScore struct {
+	Column string
+	// A query term occurs in the column.
+	Matched bool
+	// Weight bm25 applied to the column for this ranking.
+	Weight float64
+	// Bm25 of the hit with every other column weighted 0. Lower is better, like SearchResult.Score.
+	// Column scores do not add up to the total, because bm25 saturates term frequencies across columns.
+	Score float64
+}
+
+// WithExplain adds per column scores to every hit, to debug why a document ranks where it does.
+// It costs one extra query per page.
+func WithExplain(on bool) SearchOption {
+	return func(o *searchOptions) {
+		o.explain = on
+	}
+}
+
+// explainHits fills SearchResult.Explain using bm25 with one non-zero column weight at a time.
+func (e *Engine) explainHits(ctx context.Context, cleanedQuery string, hits []SearchResult) error {
+	if len(hits) == 0 {
+		return nil
+	}
+
+	// Bm25 weights are positional over the table columns, which start with the externalid column.
+	// Column i of the config is table column i+1; columns without a passed weight get 1.
+	weights := e.bm25Weights()
+	tableWeight := func(k int) float64 {
+		if k < len(weights) {
+			w, _ := weights[k].(float64)
+			return w
+		}
+		return 1
+	}
+
+	var (
+		exprs []string
+		cols  []ColumnScore
+	)
+	for i, c := range e.cfg.Columns {
+		if c.Unindexed {
+			continue
+		}
+		w := tableWeight(i + 1)
+		// Bm25 gives columns without a passed weight 1, so pass a weight for every table column.
+		vec := make([]string, len(e.cfg.Columns)+1)
+		for j := range vec {
+			vec[j] = "0"
+		}
+		vec[i+1] = strconv.FormatFloat(w, 'g', -1, 64)
+		exprs = append(exprs, fmt.Sprintf("bm25(%s,%s)", quote(e.cfg.Table), strings.Join(vec, ",")))
+		cols = append(cols, ColumnScore{Column: c.Name, Weight: w})
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	idx := make(map[string]int, len(hits))
+	for i, h := range hits {
+		idx[h.ID] = i
+	}
+	// One variable is taken by the query.
+	chunk := e.cfg.Limits.MaxSQLVariables - 1
+	for start := 0; start < len(hits); start += chunk {
+		part := hits[start:min(start+chunk, len(hits))]
+		if err := e.explainChunk(ctx, cleanedQuery, exprs, cols, part, idx, hits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Engine) explainChunk(
+	ctx context.Context,
+	cleanedQuery string,
+	exprs []string,
+	cols []ColumnScore,
+	part []SearchResult,
+	idx map[string]int,
+	hits []SearchResult,
+) error {
+	args := []any{cleanedQuery}
+	for _, h := range part {
+		args = append(args, h.ID)
+	}
+	const sqlExplain = `SELECT %s,%s FROM %s WHERE %s MATCH ? AND %s IN (%s);`
+	sqlQ := fmt.Sprintf(sqlExplain,
+		ColNameExternalID, strings.Join(exprs, ","), quote(e.cfg.Table), e.cfg.Table,
+		ColNameExternalID, strings.TrimPrefix(paramPlaceholders(len(part)), ","))
+
+	rows, err := e.db.QueryContext(ctx, sqlQ, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scores := make([]float64, len(cols))
+	dest := make([]any, 0, len(cols)+1)
+	var id string
+	dest = append(dest, &id)
+	for i := range scores {
+		dest = append(dest, &scores[i])
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		i, ok := idx[id]
+		if !ok {
+			continue
+		}
+		explain := make([]ColumnScore, len(cols))
+		for j, c := range cols {
+			c.Score = scores[j]
+			c.Matched = scores[j] != 0
+			explain[j] = c
+		}
+		hits[i].Explain = explain
+	}
+	return rows.Err()
+}
@@ -0,0 +1,74 @@
+package ftsengine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchPaged_Explain(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine(Config{
+		BaseDir: MemoryDBBaseDir,
+		Table:   "docs",
+		Columns: []Column{{Name: "title"}, {Name: "body"}, {Name: "tag", Unindexed: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.BatchUpsert(ctx, map[string]map[string]string{
+		"both":  {"title": "alpha", "body": "alpha beta", "tag": "alpha"},
+		"title": {"title": "alpha", "body": "gamma"},
+		"body":  {"title": "gamma", "body": "alpha"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := e.SearchPaged(ctx, "alpha", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range plain.Hits {
+		if h.Explain != nil {
+			t.Fatalf("explain set without WithExplain: %+v", h)
+		}
+	}
+
+	page, err := e.SearchPaged(ctx, "alpha", "", 10, WithExplain(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Hits) != 3 {
+		t.Fatalf("hits %+v", page.Hits)
+	}
+	wantMatched := map[string][2]bool{
+		"both":  {true, true},
+		"title": {true, false},
+		"body":  {false, true},
+	}
+	for _, h := range page.Hits {
+		if len(h.Explain) != 2 || h.Explain[0].Column != "title" || h.Explain[1].Column != "body" {
+			t.Fatalf("explain of %s: %+v", h.ID, h.Explain)
+		}
+		want := wantMatched[h.ID]
+		for i, cs := range h.Explain {
+			if cs.Matched != want[i] {
+				t.Fatalf("%s column %s matched=%v, want %v", h.ID, cs.Column, cs.Matched, want[i])
+			}
+			if cs.Matched && cs.Score >= 0 {
+				t.Fatalf("%s column %s score %v, bm25 of a match is negative", h.ID, cs.Column, cs.Score)
+			}
+			if !cs.Matched && cs.Score != 0 {
+				t.Fatalf("%s column %s unmatched but scored %v", h.ID, cs.Column, cs.Score)
+			}
+			if cs.Weight != 1 {
+				t.Fatalf("%s column %s weight %v", h.ID, cs.Column, cs.Weight)
+			}
+		}
+		// A single matching column explains the whole score.
+		if h.ID == "title" && h.Explain[0].Score != h.Score {
+			t.Fatalf("title-only hit: column score %v, total %v", h.Explain[0].Score, h.Score)
+		}
+	}
+}
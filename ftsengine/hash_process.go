@@ -0,0 +1,72 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ExtractColumns returns the column values of a changed file. Skip leaves the file out of the index.
+type ExtractColumns func(path string, content []byte) (vals map[string]string, skip bool, err error)
+
+// HashProcessFile returns a ProcessFile that uses the xxhash of the file content as compare value, so a file is
+// only re-extracted if its content changed, whatever its mtime says. Extract is only called for changed files.
+// Unchanged files are hashed from a stream; files that vanish during the walk are skipped.
+func HashProcessFile(extract ExtractColumns) ProcessFile {
+	return func(ctx context.Context, _, fullPath string, getPrev GetPrevCmp) (SyncDecision, error) {
+		if err := ctx.Err(); err != nil {
+			return SyncDecision{}, err
+		}
+		sum, err := hashFile(fullPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return SyncDecision{Skip: true}, nil
+		}
+		if err != nil {
+			return SyncDecision{}, err
+		}
+		if getPrev(fullPath) == sum {
+			return SyncDecision{ID: fullPath, Unchanged: true}, nil
+		}
+
+		content, err := os.ReadFile(fullPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return SyncDecision{Skip: true}, nil
+		}
+		if err != nil {
+			return SyncDecision{}, err
+		}
+		// Hash what extract sees, the file may have changed since the first read.
+		sum = formatHash(xxhash.Sum64(content))
+		vals, skip, err := extract(fullPath, content)
+		if err != nil {
+			return SyncDecision{}, err
+		}
+		if skip {
+			return SyncDecision{Skip: true}, nil
+		}
+		return SyncDecision{ID: fullPath, CmpOut: sum, Vals: vals}, nil
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := xxhash.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return formatHash(h.Sum64()), nil
+}
+
+// formatHash renders a hash as fixed width hex, so that stored values compare as strings.
+func formatHash(sum uint64) string {
+	return fmt.Sprintf("%016x", sum)
+}
@@ -0,0 +1,75 @@
+package ftsengine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashProcessFile(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db",
+		Column{Name: "body"},
+		Column{Name: "hash", Unindexed: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(docsDir, "a.txt")
+	if err := os.WriteFile(a, []byte("hello world"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "b.skip"), []byte("ignored"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var extracted []string
+	process := HashProcessFile(func(path string, content []byte) (map[string]string, bool, error) {
+		if strings.HasSuffix(path, ".skip") {
+			return nil, true, nil
+		}
+		extracted = append(extracted, filepath.Base(path))
+		return map[string]string{"body": string(content)}, false, nil
+	})
+	sync := func() {
+		t.Helper()
+		if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "hash", 10, process); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sync()
+	rows, _, err := engine.BatchList(t.Context(), "", nil, "", 0)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("rows %v, err %v", rows, err)
+	}
+	if h := rows[0].Values["hash"]; len(h) != 16 {
+		t.Fatalf("hash %q, want 16 hex chars", h)
+	}
+
+	// A touch alone does not re-extract.
+	touchFile(t, a)
+	sync()
+	if len(extracted) != 1 {
+		t.Fatalf("unchanged content extracted again: %v", extracted)
+	}
+
+	if err := os.WriteFile(a, []byte("goodbye world"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sync()
+	if len(extracted) != 2 {
+		t.Fatalf("content change not detected: %v", extracted)
+	}
+	hits, _, err := engine.Search(t.Context(), "goodbye", "", 10)
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("hits %v, err %v", hits, err)
+	}
+}
@@ -0,0 +1,53 @@
+package ftsengine
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ColNameLang is the managed, unindexed column filled by Config.DetectLanguage.
+const ColNameLang = "lang"
+
+// LanguageDetector returns the language of a document, e.g. an ISO 639-1 code like "en", or "" if unknown.
+// It sees the column values passed to Upsert.
+type LanguageDetector func(vals map[string]string) string
+
+// withLanguageColumn adds the managed language column when a detector is configured.
+// A column named ColNameLang declared by the caller is reused, it must be unindexed.
+// The column takes part in the schema checksum like any other column.
+func withLanguageColumn(cfg Config) (Config, error) {
+	if cfg.DetectLanguage == nil {
+		return cfg, nil
+	}
+	i := slices.IndexFunc(cfg.Columns, func(c Column) bool { return c.Name == ColNameLang })
+	if i >= 0 {
+		if !cfg.Columns[i].Unindexed {
+			return cfg, fmt.Errorf(
+				"ftsengine: column %q is managed by DetectLanguage and must be unindexed",
+				ColNameLang,
+			)
+		}
+		return cfg, nil
+	}
+	cfg.Columns = append(slices.Clone(cfg.Columns), Column{Name: ColNameLang, Unindexed: true})
+	return cfg, nil
+}
+
+// withLanguage returns vals with the detected language set, unless the caller already set one.
+// Vals is cloned, the caller's map is not modified.
+func (e *Engine) withLanguage(vals map[string]string) map[string]string {
+	if e.cfg.DetectLanguage == nil || vals[ColNameLang] != "" {
+		return vals
+	}
+	lang := e.cfg.DetectLanguage(vals)
+	if lang == "" {
+		return vals
+	}
+	out := maps.Clone(vals)
+	if out == nil {
+		out = make(map[string]string, 1)
+	}
+	out[ColNameLang] = lang
+	return out
+}
@@ -0,0 +1,106 @@
+package ftsengine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguageAndFilter(t *testing.T) {
+	ctx := context.Background()
+	detect := func(vals map[string]string) string {
+		switch {
+		case strings.Contains(vals["body"], " der "):
+			return "de"
+		case vals["body"] == "":
+			return ""
+		default:
+			return "en"
+		}
+	}
+	e, err := NewEngine(Config{
+		BaseDir:        MemoryDBBaseDir,
+		Table:          "docs",
+		Columns:        []Column{{Name: "body"}},
+		DetectLanguage: detect,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	input := map[string]string{"body": "the cat sat on the mat"}
+	if err := e.Upsert(ctx, "en1", input); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := input[ColNameLang]; ok {
+		t.Fatal("caller map must not be modified")
+	}
+	if err := e.BatchUpsert(ctx, map[string]map[string]string{
+		"en2": {"body": "a cat in a hat"},
+		"de1": {"body": "die cat und der hund"},
+		// An explicit value wins over detection.
+		"fr1": {"body": "le cat noir", ColNameLang: "fr"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, _, err := e.BatchList(ctx, "", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	langs := map[string]string{}
+	for _, r := range rows {
+		langs[r.ID] = r.Values[ColNameLang]
+	}
+	want := map[string]string{"en1": "en", "en2": "en", "de1": "de", "fr1": "fr"}
+	for id, l := range want {
+		if langs[id] != l {
+			t.Fatalf("lang of %s = %q, want %q", id, langs[id], l)
+		}
+	}
+
+	page, err := e.SearchPaged(ctx, "cat", "", 10, WithFilter(ColNameLang, "en"), WithTotalCount())
+	if err != nil {
+		t.Fatalf("SearchPaged: %v", err)
+	}
+	if page.Total != 2 || len(page.Hits) != 2 {
+		t.Fatalf("en filter: total %d hits %v", page.Total, page.Hits)
+	}
+	for _, h := range page.Hits {
+		if !strings.HasPrefix(h.ID, "en") {
+			t.Fatalf("unexpected hit %s", h.ID)
+		}
+	}
+	hits, _, err := e.Search(ctx, "cat", "", 10, WithFilter(ColNameLang, "de"), WithFilter("body", "nope"))
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("filters must all match: %v %v", hits, err)
+	}
+	if _, _, err := e.Search(ctx, "cat", "", 10, WithFilter("nope", "x")); err == nil {
+		t.Fatal("expected error for unknown filter column")
+	}
+}
+
+func TestDetectLanguageColumnValidation(t *testing.T) {
+	detect := func(map[string]string) string { return "en" }
+	_, err := NewEngine(Config{
+		BaseDir:        MemoryDBBaseDir,
+		Table:          "docs",
+		Columns:        []Column{{Name: "body"}, {Name: ColNameLang}},
+		DetectLanguage: detect,
+	})
+	if err == nil {
+		t.Fatal("expected error for indexed lang column")
+	}
+
+	e, err := NewEngine(Config{
+		BaseDir:        MemoryDBBaseDir,
+		Table:          "docs",
+		Columns:        []Column{{Name: "body"}, {Name: ColNameLang, Unindexed: true}},
+		DetectLanguage: detect,
+	})
+	if err != nil {
+		t.Fatalf("declared unindexed lang column should be reused: %v", err)
+	}
+	_ = e.Close()
+}
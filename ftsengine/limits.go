@@ -0,0 +1,66 @@
+package ftsengine
+
+import "fmt"
+
+// Defaults used for zero Limits fields.
+const (
+	DefaultSearchPageSize  = 10
+	DefaultListPageSize    = 1000
+	DefaultMaxPageSize     = 10000
+	DefaultMaxSQLVariables = 999
+)
+
+// Limits bounds page sizes and statement sizes. Zero fields take the package defaults.
+// Limits do not change what is stored, so they are not part of the schema checksum.
+type Limits struct {
+	// Page size of Search and SearchPaged if the caller passes <= 0 or more than MaxPageSize.
+	DefaultSearchPageSize int
+	// Page size of BatchList and DistinctValues if the caller passes <= 0.
+	DefaultListPageSize int
+	// Upper bound of any requested page size.
+	MaxPageSize int
+	// Bound variables per statement, used to chunk IN lists. SQLite's historic default is 999.
+	MaxSQLVariables int
+}
+
+// Limits returns the effective limits of the engine, with defaults filled in.
+func (e *Engine) Limits() Limits { return e.cfg.Limits }
+
+func (l Limits) withDefaults() Limits {
+	if l.DefaultSearchPageSize == 0 {
+		l.DefaultSearchPageSize = DefaultSearchPageSize
+	}
+	if l.DefaultListPageSize == 0 {
+		l.DefaultListPageSize = DefaultListPageSize
+	}
+	if l.MaxPageSize == 0 {
+		l.MaxPageSize = DefaultMaxPageSize
+	}
+	if l.MaxSQLVariables == 0 {
+		l.MaxSQLVariables = DefaultMaxSQLVariables
+	}
+	return l
+}
+
+// validate expects defaults to be filled in already.
+func (l Limits) validate(numColumns int) error {
+	if l.DefaultSearchPageSize < 0 || l.DefaultListPageSize < 0 || l.MaxPageSize < 0 || l.MaxSQLVariables < 0 {
+		return fmt.Errorf("ftsengine: negative limit in %+v", l)
+	}
+	if l.DefaultSearchPageSize > l.MaxPageSize || l.DefaultListPageSize > l.MaxPageSize {
+		return fmt.Errorf("ftsengine: default page size above MaxPageSize %d", l.MaxPageSize)
+	}
+	// An upsert binds rowid, externalid and one value per column.
+	if need := numColumns + 2; l.MaxSQLVariables < need {
+		return fmt.Errorf("ftsengine: MaxSQLVariables %d below the %d an upsert needs", l.MaxSQLVariables, need)
+	}
+	return nil
+}
+
+// listPageSize resolves a requested BatchList style page size.
+func (l Limits) listPageSize(n int) int {
+	if n <= 0 {
+		n = l.DefaultListPageSize
+	}
+	return min(n, l.MaxPageSize)
+}
@@ -0,0 +1,100 @@
+package ftsengine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLimitsDefaultsAndValidation(t *testing.T) {
+	e := newMemoryEngine(t)
+	want := Limits{
+		DefaultSearchPageSize: DefaultSearchPageSize,
+		DefaultListPageSize:   DefaultListPageSize,
+		MaxPageSize:           DefaultMaxPageSize,
+		MaxSQLVariables:       DefaultMaxSQLVariables,
+	}
+	if got := e.Limits(); got != want {
+		t.Fatalf("Limits() = %+v, want %+v", got, want)
+	}
+
+	cols := []Column{{Name: "title"}, {Name: "body"}}
+	bad := []struct {
+		name   string
+		limits Limits
+	}{
+		{"negative page size", Limits{DefaultListPageSize: -1}},
+		{"default above max", Limits{MaxPageSize: 5}},
+		{"list default above max", Limits{DefaultSearchPageSize: 5, MaxPageSize: 50}},
+		{"too few variables for an upsert", Limits{MaxSQLVariables: 3}},
+	}
+	for _, tc := range bad {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(Config{BaseDir: MemoryDBBaseDir, Table: "t", Columns: cols, Limits: tc.limits})
+			if err == nil {
+				t.Fatalf("expected validation error for %+v", tc.limits)
+			}
+		})
+	}
+
+	base := Config{BaseDir: MemoryDBBaseDir, Table: "t", Columns: cols}
+	tuned := base
+	tuned.Limits = Limits{MaxPageSize: 20, DefaultListPageSize: 3, DefaultSearchPageSize: 2}
+	if schemaChecksum(base, tokenizerOptions) != schemaChecksum(tuned, tokenizerOptions) {
+		t.Fatal("limits must not change the schema checksum")
+	}
+}
+
+func TestLimitsApplied(t *testing.T) {
+	ctx := t.Context()
+	e, err := NewEngine(Config{
+		BaseDir: MemoryDBBaseDir,
+		Table:   "docs",
+		Columns: []Column{{Name: "title"}, {Name: "body"}},
+		Limits: Limits{
+			DefaultSearchPageSize: 2,
+			DefaultListPageSize:   3,
+			MaxPageSize:           5,
+			MaxSQLVariables:       4,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	t.Cleanup(func() { _ = e.Close() })
+
+	// More docs than MaxSQLVariables exercises chunked rowid lookups and deletes.
+	docs := make(map[string]map[string]string)
+	ids := make([]string, 0, 12)
+	for i := range 12 {
+		id := fmt.Sprintf("d%02d", i)
+		docs[id] = map[string]string{"title": "common", "body": id}
+		ids = append(ids, id)
+	}
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+	// Second pass updates in place.
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatalf("BatchUpsert again: %v", err)
+	}
+
+	rows, _, err := e.BatchList(ctx, "", nil, "", 0)
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("default list page: got %d rows, err %v", len(rows), err)
+	}
+	rows, _, err = e.BatchList(ctx, "", nil, "", 100)
+	if err != nil || len(rows) != 5 {
+		t.Fatalf("capped list page: got %d rows, err %v", len(rows), err)
+	}
+	hits, _, err := e.Search(ctx, "common", "", 0)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("default search page: got %d hits, err %v", len(hits), err)
+	}
+
+	if err := e.BatchDelete(ctx, ids); err != nil {
+		t.Fatalf("BatchDelete: %v", err)
+	}
+	if empty, _ := e.IsEmpty(ctx); !empty {
+		t.Fatal("expected empty table after chunked delete")
+	}
+}
@@ -0,0 +1,135 @@
+package ftsengine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrIntegrity is returned when an integrity check finds a corrupt index or database.
+var ErrIntegrity = errors.New("ftsengine: integrity check failed")
+
+// Verify checks the FTS index against the stored content, and the database file itself.
+// Problems are reported as ErrIntegrity.
+func (e *Engine) Verify(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return verifyTable(ctx, e.db, e.cfg.Table)
+}
+
+// Optimize merges all FTS5 index segments into one, dropping deleted entries.
+// It speeds up queries and frees space after many updates, at the cost of rewriting the whole index.
+func (e *Engine) Optimize(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return ftsCommand(ctx, e.db, e.cfg.Table, "optimize")
+}
+
+// RebuildIndex rebuilds the FTS index from the stored content, e.g. after Verify failed.
+// Unlike RebuildOnline it does not need a producer, but it cannot repair lost content.
+func (e *Engine) RebuildIndex(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	defer e.cache.invalidate()
+	return ftsCommand(ctx, e.db, e.cfg.Table, "rebuild")
+}
+
+// Maintenance runs maintenance commands on an existing index without its Config, e.g. from an operator tool.
+// Writers using the same file wait for the busy timeout while a command runs.
+type Maintenance struct {
+	db    *sql.DB
+	table string
+}
+
+// OpenMaintenance opens the index table in the database file at dbPath. Both must exist.
+func OpenMaintenance(dbPath, table string) (*Maintenance, error) {
+	if table == "" {
+		return nil, errors.New("ftsengine: empty table name")
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+	db, err := openDB(dbPath, SQLiteOptions{}.withDefaults())
+	if err != nil {
+		return nil, err
+	}
+	var sqlText string
+	err = db.QueryRowContext(
+		context.Background(),
+		`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`,
+		table,
+	).Scan(&sqlText)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("ftsengine: no table %q in %s", table, dbPath)
+	} else if err == nil && !strings.Contains(strings.ToLower(sqlText), "using fts5") {
+		err = fmt.Errorf("ftsengine: table %q is not an fts5 table", table)
+	}
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return &Maintenance{db: db, table: table}, nil
+}
+
+// Verify is Engine.Verify.
+func (m *Maintenance) Verify(ctx context.Context) error {
+	return verifyTable(ctx, m.db, m.table)
+}
+
+// Optimize is Engine.Optimize.
+func (m *Maintenance) Optimize(ctx context.Context) error {
+	return ftsCommand(ctx, m.db, m.table, "optimize")
+}
+
+// RebuildIndex is Engine.RebuildIndex.
+func (m *Maintenance) RebuildIndex(ctx context.Context) error {
+	return ftsCommand(ctx, m.db, m.table, "rebuild")
+}
+
+// Close closes the database.
+func (m *Maintenance) Close() error {
+	return m.db.Close()
+}
+
+// ftsCommand runs an FTS5 special INSERT command such as 'optimize' or 'rebuild'.
+func ftsCommand(ctx context.Context, db *sql.DB, table, command string) error {
+	t := quote(table)
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s(%s) VALUES(?);`, t, t), command)
+	return err
+}
+
+func verifyTable(ctx context.Context, db *sql.DB, table string) error {
+	// With rank 1 the index is also compared against the stored content.
+	t := quote(table)
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s(%s, rank) VALUES('integrity-check', 1);`, t, t))
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s: %w", ErrIntegrity, table, err)
+	}
+	rows, err := db.QueryContext(ctx, `PRAGMA quick_check;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("%w: %s", ErrIntegrity, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package ftsengine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db", Column{Name: "title"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	ctx := t.Context()
+	docs := map[string]map[string]string{}
+	for i := range 50 {
+		docs[fmt.Sprintf("doc-%d", i)] = map[string]string{"title": fmt.Sprintf("hello world %d", i)}
+	}
+	if err := engine.BatchUpsert(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Verify(ctx); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := engine.Optimize(ctx); err != nil {
+		t.Fatalf("optimize: %v", err)
+	}
+	if err := engine.RebuildIndex(ctx); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if hits, _, err := engine.Search(ctx, "hello", "", 100); err != nil || len(hits) != 50 {
+		t.Fatalf("search after rebuild: %d hits, %v", len(hits), err)
+	}
+
+	dbPath := filepath.Join(dir, "fts.db")
+	if _, err := OpenMaintenance(dbPath, "missing"); err == nil {
+		t.Fatal("want error for a missing table")
+	}
+	if _, err := OpenMaintenance(filepath.Join(dir, "nope.db"), "docs"); err == nil {
+		t.Fatal("want error for a missing file")
+	}
+	m, err := OpenMaintenance(dbPath, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Verify(ctx); err != nil {
+		t.Fatalf("maintenance verify: %v", err)
+	}
+
+	// Drop index segments behind the engine's back.
+	if _, err := engine.db.ExecContext(ctx, `DELETE FROM "docs_data" WHERE id > 10`); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(ctx); !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("want ErrIntegrity, got %v", err)
+	}
+	if err := m.RebuildIndex(ctx); err != nil {
+		t.Fatalf("maintenance rebuild: %v", err)
+	}
+	if err := m.Verify(ctx); err != nil {
+		t.Fatalf("verify after rebuild: %v", err)
+	}
+	if err := m.Optimize(ctx); err != nil {
+		t.Fatalf("maintenance optimize: %v", err)
+	}
+}
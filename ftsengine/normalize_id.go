@@ -0,0 +1,46 @@
+package ftsengine
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// IDNormalization selects how external ids are normalized before they are stored or looked up.
+// Flags combine, e.g. NormalizeIDLowercase|NormalizeIDNFC for ids derived from file paths, so that they behave
+// the same on case-insensitive (macOS, Windows) and case-sensitive filesystems.
+type IDNormalization uint8
+
+const (
+	// NormalizeIDNFC applies Unicode NFC, macOS reports decomposed (NFD) file names.
+	NormalizeIDNFC IDNormalization = 1 << iota
+	// NormalizeIDLowercase lowercases ids.
+	NormalizeIDLowercase
+)
+
+// NormalizeID returns id as the engine stores it. Upsert, Delete and their batch variants apply it,
+// and ids returned by Search and BatchList are normalized.
+func (e *Engine) NormalizeID(id string) string {
+	n := e.cfg.NormalizeID
+	if n&NormalizeIDNFC != 0 {
+		id = norm.NFC.String(id)
+	}
+	if n&NormalizeIDLowercase != 0 {
+		id = strings.ToLower(id)
+	}
+	return id
+}
+
+// normalizeDocIDs rekeys docs by normalized id. Ids that collide keep the values of the lexically last original.
+func (e *Engine) normalizeDocIDs(docs map[string]map[string]string) map[string]map[string]string {
+	if e.cfg.NormalizeID == 0 {
+		return docs
+	}
+	out := make(map[string]map[string]string, len(docs))
+	for _, id := range slices.Sorted(maps.Keys(docs)) {
+		out[e.NormalizeID(id)] = docs[id]
+	}
+	return out
+}
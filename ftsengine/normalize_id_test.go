@@ -0,0 +1,109 @@
+package ftsengine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeID(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine(Config{
+		BaseDir:     MemoryDBBaseDir,
+		Table:       "docs",
+		Columns:     []Column{{Name: "title"}},
+		NormalizeID: NormalizeIDLowercase | NormalizeIDNFC,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// "é" decomposed (NFD, as reported by macOS) and composed (NFC).
+	const nfd, nfc = "/Docs/Café.md", "/docs/café.md"
+	if got := e.NormalizeID(nfd); got != nfc {
+		t.Fatalf("NormalizeID(%q) = %q, want %q", nfd, got, nfc)
+	}
+
+	if err := e.Upsert(ctx, nfd, map[string]string{"title": "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Upsert(ctx, "/DOCS/CAFÉ.MD", map[string]string{"title": "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.BatchUpsert(ctx, map[string]map[string]string{"/Docs/Other.md": {"title": "other"}}); err != nil {
+		t.Fatal(err)
+	}
+	rows, _, err := e.BatchList(ctx, "", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, r := range rows {
+		got[r.ID] = r.Values["title"]
+	}
+	if len(got) != 2 || got[nfc] != "second" || got["/docs/other.md"] != "other" {
+		t.Fatalf("rows %v", got)
+	}
+	hits, _, err := e.Search(ctx, "second", "", 10)
+	if err != nil || len(hits) != 1 || hits[0].ID != nfc {
+		t.Fatalf("search hits %v, err %v", hits, err)
+	}
+
+	if err := e.Delete(ctx, "/docs/CAFÉ.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.BatchDelete(ctx, []string{"/DOCS/OTHER.MD"}); err != nil {
+		t.Fatal(err)
+	}
+	if empty, _ := e.IsEmpty(ctx); !empty {
+		t.Fatal("deletes with differently cased ids should match")
+	}
+
+	if _, err := NewEngine(Config{
+		BaseDir: MemoryDBBaseDir, Table: "t", Columns: []Column{{Name: "a"}}, NormalizeID: 1 << 7,
+	}); err == nil {
+		t.Fatal("expected error for unknown normalization flag")
+	}
+}
+
+func TestNormalizeIDSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	cfg := minimalConfig(dir, "fts.db", Column{Name: "title"}, Column{Name: "mtime", Unindexed: true})
+	cfg.NormalizeID = NormalizeIDLowercase
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "Docs")
+	if err := os.MkdirAll(docsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	keep, gone := filepath.Join(docsDir, "Keep.json"), filepath.Join(docsDir, "Gone.json")
+	writeJSONFile(t, keep, map[string]any{"title": "keep"})
+	writeJSONFile(t, gone, map[string]any{"title": "gone"})
+
+	if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile); err != nil {
+		t.Fatal(err)
+	}
+	// A second pass finds the rows under their normalized ids and leaves them unchanged.
+	if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile); err != nil {
+		t.Fatal(err)
+	}
+	rows, _, err := engine.BatchList(t.Context(), "", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].ID != engine.NormalizeID(keep) {
+		t.Fatalf("rows after removing a file: %+v", rows)
+	}
+}
@@ -0,0 +1,161 @@
+package ftsengine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// rebuildTableSuffix names the table that RebuildOnline fills before the swap.
+const rebuildTableSuffix = "__rebuild"
+
+// RebuildOnline builds a fresh table from producer and then atomically replaces the current one with it, e.g. to
+// recover from a corrupted index or after a tokenizer change. Searches keep reading the old table until the swap;
+// writes wait until the rebuild is done. Returns the number of indexed documents.
+//
+// The producer sees an empty index, getPrev always returns "", so it must emit every document. Vals are stored as
+// they are, CmpOut is not used: include the compare column value in Vals. Unchanged and Skip decisions index nothing.
+// On error or cancellation the partial table is dropped and the old one stays in place.
+func (e *Engine) RebuildOnline(ctx context.Context, producer Iterate) (int, error) {
+	if producer == nil {
+		return 0, errors.New("ftsengine: nil producer")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	defer e.cache.invalidate()
+
+	start := time.Now()
+	tmp := e.cfg.Table + rebuildTableSuffix
+	dropTmp := func() {
+		// Background context, cleanup must also run after cancellation.
+		_, _ = e.db.ExecContext(context.Background(), "DROP TABLE IF EXISTS "+quote(tmp))
+	}
+	// Leftover of an interrupted rebuild.
+	dropTmp()
+	if _, err := e.db.ExecContext(ctx, e.createTableSQL(tmp)); err != nil {
+		return 0, err
+	}
+
+	n, err := e.fillTable(ctx, tmp, producer)
+	if err != nil {
+		dropTmp()
+		return 0, err
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		dropTmp()
+		return 0, err
+	}
+	swap := []string{
+		"DROP TABLE " + quote(e.cfg.Table),
+		"ALTER TABLE " + quote(tmp) + " RENAME TO " + quote(e.cfg.Table),
+	}
+	for _, q := range swap {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			_ = tx.Rollback()
+			dropTmp()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		dropTmp()
+		return 0, err
+	}
+	slog.Info("ftsengine rebuild done", "table", e.cfg.Table, "docs", n, "took", time.Since(start))
+	return n, nil
+}
+
+// fillTable inserts everything the producer emits into table, one transaction per batch.
+func (e *Engine) fillTable(ctx context.Context, table string, producer Iterate) (int, error) {
+	batchSize := e.cfg.Limits.DefaultListPageSize
+	colNames := []string{ColNameExternalID}
+	for _, c := range e.cfg.Columns {
+		colNames = append(colNames, quote(c.Name))
+	}
+	sqlInsert := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s);`,
+		quote(table), strings.Join(colNames, ","), strings.TrimPrefix(paramPlaceholders(len(colNames)), ","))
+	sqlDelete := fmt.Sprintf(`DELETE FROM %s WHERE %s=?;`, quote(table), ColNameExternalID)
+
+	var (
+		total   int
+		pending = make(map[string]map[string]string, batchSize)
+		// Ids of earlier batches, a repeated id replaces its row.
+		written = make(map[string]struct{})
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx, err := e.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := e.insertBatch(ctx, tx, sqlInsert, sqlDelete, pending, written); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		for id := range pending {
+			if _, ok := written[id]; !ok {
+				written[id] = struct{}{}
+				total++
+			}
+		}
+		pending = make(map[string]map[string]string, batchSize)
+		return nil
+	}
+
+	getPrev := func(string) string { return "" }
+	emit := func(dec SyncDecision) error {
+		if dec.Skip || dec.Unchanged || dec.ID == "" {
+			return nil
+		}
+		pending[e.NormalizeID(dec.ID)] = e.withLanguage(dec.Vals)
+		if len(pending) >= batchSize {
+			return flush()
+		}
+		return nil
+	}
+	if err := producer(getPrev, emit); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (e *Engine) insertBatch(
+	ctx context.Context,
+	tx *sql.Tx,
+	sqlInsert, sqlDelete string,
+	docs map[string]map[string]string,
+	written map[string]struct{},
+) error {
+	for id, vals := range docs {
+		if _, ok := written[id]; ok {
+			if _, err := tx.ExecContext(ctx, sqlDelete, id); err != nil {
+				return err
+			}
+		}
+		args := make([]any, 0, len(e.cfg.Columns)+1)
+		args = append(args, id)
+		for _, c := range e.cfg.Columns {
+			args = append(args, vals[c.Name])
+		}
+		if _, err := tx.ExecContext(ctx, sqlInsert, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
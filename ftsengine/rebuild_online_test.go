@@ -0,0 +1,126 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRebuildOnline(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine(Config{
+		BaseDir:    t.TempDir(),
+		DBFileName: "fts.sqlite",
+		Table:      "docs",
+		Columns:    []Column{{Name: "title"}},
+		Limits:     Limits{DefaultListPageSize: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.BatchUpsert(ctx, map[string]map[string]string{
+		"old1": {"title": "stale"},
+		"old2": {"title": "stale"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	halfway := make(chan struct{})
+	resume := make(chan struct{})
+	producer := func(getPrev GetPrevCmp, emit func(SyncDecision) error) error {
+		for i := range 10 {
+			id := fmt.Sprintf("new%d", i)
+			if getPrev(id) != "" {
+				return errors.New("rebuild producer should see an empty index")
+			}
+			if err := emit(SyncDecision{ID: id, Vals: map[string]string{"title": "fresh"}}); err != nil {
+				return err
+			}
+			if i == 5 {
+				close(halfway)
+				<-resume
+			}
+		}
+		// Skipped and repeated documents.
+		if err := emit(SyncDecision{Skip: true}); err != nil {
+			return err
+		}
+		return emit(SyncDecision{ID: "new0", Vals: map[string]string{"title": "fresh again"}})
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := e.RebuildOnline(ctx, producer)
+		done <- result{n, err}
+	}()
+
+	<-halfway
+	// Searches are served from the old table while the new one is built.
+	if hits, _, err := e.Search(ctx, "stale", "", 10); err != nil || len(hits) != 2 {
+		t.Fatalf("search during rebuild: %v %v", hits, err)
+	}
+	close(resume)
+	res := <-done
+	if res.err != nil || res.n != 10 {
+		t.Fatalf("RebuildOnline = %d, %v", res.n, res.err)
+	}
+
+	if hits, _, _ := e.Search(ctx, "stale", "", 10); len(hits) != 0 {
+		t.Fatalf("old rows survived the swap: %v", hits)
+	}
+	if hits, _, _ := e.Search(ctx, "fresh", "", 20); len(hits) != 10 {
+		t.Fatalf("rebuilt rows: %v", hits)
+	}
+	if hits, _, _ := e.Search(ctx, "again", "", 20); len(hits) != 1 || hits[0].ID != "new0" {
+		t.Fatalf("repeated id should replace its row: %v", hits)
+	}
+	// The engine keeps working on the swapped table.
+	if err := e.Upsert(ctx, "new0", map[string]string{"title": "updated"}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _, _ := e.Search(ctx, "updated", "", 10); len(hits) != 1 {
+		t.Fatalf("upsert after rebuild: %v", hits)
+	}
+}
+
+func TestRebuildOnline_FailureKeepsOldTable(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEngine(t)
+	defer e.Close()
+	if err := e.Upsert(ctx, "a", map[string]string{"title": "keep"}); err != nil {
+		t.Fatal(err)
+	}
+
+	boom := errors.New("boom")
+	_, err := e.RebuildOnline(ctx, func(_ GetPrevCmp, emit func(SyncDecision) error) error {
+		if err := emit(SyncDecision{ID: "b", Vals: map[string]string{"title": "partial"}}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected producer error, got %v", err)
+	}
+	if hits, _, _ := e.Search(ctx, "keep", "", 10); len(hits) != 1 {
+		t.Fatalf("old table lost: %v", hits)
+	}
+	var n int
+	if err := e.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM sqlite_master WHERE name=?`, "docs"+rebuildTableSuffix).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("temporary table left behind: %d %v", n, err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	noop := func(_ GetPrevCmp, _ func(SyncDecision) error) error { return nil }
+	if _, err := e.RebuildOnline(cctx, noop); err == nil {
+		t.Fatal("expected error for canceled context")
+	}
+}
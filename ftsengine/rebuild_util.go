@@ -2,9 +2,11 @@ package ftsengine
 
 import (
 	"context"
+	"errors"
 	"io/fs"
 	"log/slog"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -15,16 +17,21 @@ type SyncDecision struct {
 	ID string
 	// Value for compareColumn (mtime / hash / ...). Ignored when Unchanged.
 	CmpOut string
+	// Values of all compare columns keyed by column, with WithCompareColumns. Takes precedence over CmpOut.
+	CmpValues map[string]string
 	// Column -> text map for FTS. Ignored when Unchanged.
 	Vals map[string]string
 	// The row is already up-to-date, nothing to do.
 	Unchanged bool
 	// Ignore this document entirely (also triggers delete if it existed).
 	Skip bool
+	// The document failed. Its existing row is kept and the error is reported in SyncReport.Errors.
+	Err error
 }
 
 // GetPrevCmp allows producers to query the compareColumn value that is
 // currently stored for a specific ID ("" == not indexed yet).
+// With WithCompareColumns it returns CompareKey of all stored compare values.
 type GetPrevCmp func(id string) string
 
 // ProcessFile is the directory-walker callback.
@@ -41,33 +48,169 @@ func SyncDirToFTS(
 	compareColumn string,
 	batchSize int,
 	processFile ProcessFile,
-) error {
+	opts ...SyncOption,
+) (SyncReport, error) {
+	var so syncOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+	if err := so.validatePatterns(); err != nil {
+		return SyncReport{}, err
+	}
+
+	// Entries that could not be read under SkipAndCollect. Their rows are kept.
+	var (
+		walkErrs    []FileError
+		failedDirs  []string
+		failedFiles = map[string]struct{}{}
+	)
+	onWalkErr := func(p string, d fs.DirEntry, walkErr error) error {
+		if !so.errPolicy.Collect || d == nil || p == baseDir {
+			return walkErr
+		}
+		walkErrs = append(walkErrs, FileError{Path: p, Err: walkErr})
+		if !d.IsDir() {
+			failedFiles[engine.NormalizeID(p)] = struct{}{}
+			return nil
+		}
+		failedDirs = append(failedDirs, engine.NormalizeID(p+string(filepath.Separator)))
+		return filepath.SkipDir
+	}
+
+	// walk visits the files below baseDir that pass the include and exclude patterns.
+	walk := func(fn func(p string, d fs.DirEntry) error) error {
+		return walkTree(baseDir, so.walk, func(p string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return onWalkErr(p, d, walkErr)
+			}
+			if p != baseDir && so.filteredOut(baseDir, p, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return fn(p, d)
+		})
+	}
+
 	// Factory that converts the WalkDir stream into SyncDecision events.
 	iter := func(getPrev GetPrevCmp, emit func(SyncDecision) error) error {
-		return filepath.WalkDir(baseDir,
-			func(p string, d fs.DirEntry, walkErr error) error {
-				if walkErr != nil || d.IsDir() {
-					return walkErr
-				}
-				dec, err := processFile(ctx, baseDir, p, getPrev)
-				if err != nil {
-					return err
-				}
-				return emit(dec)
+		process := func(p string) error {
+			dec, err := processWithPolicy(ctx, so.errPolicy, func() (SyncDecision, error) {
+				return processFile(ctx, baseDir, p, getPrev)
 			})
+			if err != nil {
+				return err
+			}
+			if dec.Err != nil && dec.ID == "" {
+				dec.ID = p
+			}
+			return emit(dec)
+		}
+		if so.newestFirst {
+			return walkNewestFirst(ctx, walk, process)
+		}
+		return walk(func(p string, _ fs.DirEntry) error { return process(p) })
 	}
 
 	// A row belongs to this dataset when its ID starts with baseDir.
-	belongs := func(id string) bool { return strings.HasPrefix(id, baseDir) }
+	// Stored ids are normalized, so compare against the normalized baseDir.
+	normBase := engine.NormalizeID(baseDir)
+	belongs := func(id string) bool {
+		if !strings.HasPrefix(id, normBase) {
+			return false
+		}
+		if _, ok := failedFiles[id]; ok {
+			return false
+		}
+		for _, dir := range failedDirs {
+			if strings.HasPrefix(id, dir) {
+				return false
+			}
+		}
+		return true
+	}
 
-	return SyncIterToFTS(
+	report, err := SyncIterToFTS(
 		ctx,
 		engine,
 		compareColumn,
 		batchSize,
 		iter,
 		belongs,
+		opts...,
 	)
+	report.Errors = append(report.Errors, walkErrs...)
+	return report, err
+}
+
+// processWithPolicy runs fn with the retries of policy. A final failure is returned as error, or with Collect as
+// SyncDecision.Err.
+func processWithPolicy(
+	ctx context.Context,
+	policy ErrorPolicy,
+	fn func() (SyncDecision, error),
+) (SyncDecision, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var dec SyncDecision
+		dec, err = fn()
+		if err == nil {
+			return dec, nil
+		}
+		if ctx.Err() != nil {
+			return SyncDecision{}, err
+		}
+		if attempt >= policy.Retries {
+			break
+		}
+	}
+	if !policy.Collect {
+		return SyncDecision{}, err
+	}
+	return SyncDecision{Err: err}, nil
+}
+
+// walkNewestFirst calls fn for every file visited by walk, most recently modified first.
+// Files that vanish during the walk are left out.
+func walkNewestFirst(
+	ctx context.Context,
+	walk func(fn func(p string, d fs.DirEntry) error) error,
+	fn func(path string) error,
+) error {
+	type fileMtime struct {
+		path  string
+		mtime time.Time
+	}
+	var files []fileMtime
+	err := walk(func(p string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, fileMtime{path: p, mtime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Stable, so files with equal mtimes keep their lexical order.
+	slices.SortStableFunc(files, func(a, b fileMtime) int { return b.mtime.Compare(a.mtime) })
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(f.path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Iterate is the generic producer contract.
@@ -76,6 +219,7 @@ func SyncDirToFTS(
 type Iterate func(getPrev GetPrevCmp, emit func(SyncDecision) error) error
 
 // SyncIterToFTS. Belongs(id) must return true for all rows owned by this producer so that vanished rows can be deleted.
+// The report holds the counts of the run, also when it fails part way.
 func SyncIterToFTS(
 	ctx context.Context,
 	engine *Engine,
@@ -83,12 +227,36 @@ func SyncIterToFTS(
 	batchSize int,
 	iter Iterate,
 	belongs func(id string) bool,
-) error {
+	opts ...SyncOption,
+) (report SyncReport, err error) {
+	var so syncOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+	cmpCols := []string{compareColumn}
+	for _, c := range so.extraCmp {
+		if !slices.Contains(cmpCols, c) {
+			cmpCols = append(cmpCols, c)
+		}
+	}
+	// Stored compare state of a row, as getPrev reports it.
+	prevKey := func(row ListResult) string {
+		if len(cmpCols) == 1 {
+			return row.Values[compareColumn]
+		}
+		vals := make(map[string]string, len(cmpCols))
+		for _, c := range cmpCols {
+			vals[c] = row.Values[c]
+		}
+		return CompareKey(vals)
+	}
+
 	if batchSize <= 0 {
 		batchSize = 1000
 	}
 	const listPage = 10_000
 	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
 
 	slog.Info("fts-sync start", "cmpCol", compareColumn)
 
@@ -100,28 +268,24 @@ func SyncIterToFTS(
 		part, next, err := engine.BatchList(
 			ctx,
 			compareColumn,
-			[]string{compareColumn},
+			cmpCols,
 			token,
 			listPage,
 		)
 		if err != nil {
-			return err
+			return report, err
 		}
 		for _, row := range part {
-			existing[row.ID] = row.Values[compareColumn]
+			existing[row.ID] = prevKey(row)
 		}
 		if next == "" {
 			break
 		}
 		token = next
 	}
-	getPrev := func(id string) string { return existing[id] }
+	getPrev := func(id string) string { return existing[engine.NormalizeID(id)] }
 
 	// Incremental diff while the producer iterates over its dataset.
-	var (
-		nProcessed, nSkipped, nUnchanged, nUpserted int
-	)
-
 	seenNow := make(map[string]struct{}, 4096)
 	pending := make(map[string]map[string]string, batchSize)
 
@@ -132,22 +296,29 @@ func SyncIterToFTS(
 		if err := engine.BatchUpsert(ctx, pending); err != nil {
 			return err
 		}
-		nUpserted += len(pending)
+		report.Upserted += len(pending)
 		pending = make(map[string]map[string]string, batchSize)
 		return nil
 	}
 
 	emit := func(dec SyncDecision) error {
+		if dec.Err != nil {
+			report.Errors = append(report.Errors, FileError{Path: dec.ID, Err: dec.Err})
+			if dec.ID != "" {
+				seenNow[engine.NormalizeID(dec.ID)] = struct{}{}
+			}
+			return nil
+		}
 		if dec.Skip || dec.ID == "" {
-			nSkipped++
+			report.Skipped++
 			return nil
 		}
 
-		seenNow[dec.ID] = struct{}{}
-		nProcessed++
+		seenNow[engine.NormalizeID(dec.ID)] = struct{}{}
+		report.Processed++
 
 		if dec.Unchanged {
-			nUnchanged++
+			report.Unchanged++
 			return nil
 		}
 
@@ -155,7 +326,13 @@ func SyncIterToFTS(
 		if vals == nil {
 			vals = map[string]string{}
 		}
-		vals[compareColumn] = dec.CmpOut
+		if dec.CmpValues != nil {
+			for _, c := range cmpCols {
+				vals[c] = dec.CmpValues[c]
+			}
+		} else {
+			vals[compareColumn] = dec.CmpOut
+		}
 		pending[dec.ID] = vals
 
 		if len(pending) >= batchSize {
@@ -165,10 +342,10 @@ func SyncIterToFTS(
 	}
 
 	if err := iter(getPrev, emit); err != nil {
-		return err
+		return report, err
 	}
 	if err := flush(); err != nil {
-		return err
+		return report, err
 	}
 
 	// Delete documents that vanished from the producers dataset.
@@ -183,18 +360,13 @@ func SyncIterToFTS(
 	}
 	if len(toDelete) != 0 {
 		if err := engine.BatchDelete(ctx, toDelete); err != nil {
-			return err
+			return report, err
 		}
+		report.Deleted = len(toDelete)
 	}
 
 	// Done - statistics.
-	slog.Info("fts-sync done",
-		"took", time.Since(start),
-		"processed", nProcessed,
-		"upserted", nUpserted,
-		"unchanged", nUnchanged,
-		"skipped", nSkipped,
-		"deleted", len(toDelete),
-	)
-	return nil
+	report.Duration = time.Since(start)
+	slog.Info("fts-sync done", report.logAttrs()...)
+	return report, nil
 }
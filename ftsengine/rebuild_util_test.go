@@ -3,11 +3,13 @@ package ftsengine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -131,7 +133,7 @@ func TestSyncDirToFTS_TableDriven(t *testing.T) {
 					writeJSONFile(t, full, map[string]any{"title": f.Title})
 				}
 				// First sync.
-				err := SyncDirToFTS(
+				_, err := SyncDirToFTS(
 					t.Context(),
 					engine,
 					tmpDir,
@@ -175,7 +177,7 @@ func TestSyncDirToFTS_TableDriven(t *testing.T) {
 					engine = engine2
 				}
 				// Second sync.
-				err = SyncDirToFTS(
+				_, err = SyncDirToFTS(
 					t.Context(),
 					engine,
 					tmpDir,
@@ -259,7 +261,7 @@ func TestSyncDirToFTS_ErrorCases(t *testing.T) {
 		txtFile := filepath.Join(tmpDir, "note.txt")
 		_ = os.WriteFile(txtFile, []byte("hello"), 0o600)
 
-		err = SyncDirToFTS(t.Context(), engine, tmpDir, "mtime", 2, testProcessFile)
+		_, err = SyncDirToFTS(t.Context(), engine, tmpDir, "mtime", 2, testProcessFile)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -291,6 +293,193 @@ func TestSyncDirToFTS_ErrorCases(t *testing.T) {
 	})
 }
 
+func TestSyncDirToFTS_Report(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db",
+		Column{Name: "title"},
+		Column{Name: "mtime", Unindexed: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeJSONFile(t, filepath.Join(docsDir, "a.json"), map[string]any{"title": "a"})
+	writeJSONFile(t, filepath.Join(docsDir, "b.json"), map[string]any{"title": "b"})
+	if err := os.WriteFile(filepath.Join(docsDir, "note.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SyncReport{Processed: 2, Upserted: 2, Skipped: 1, Duration: report.Duration}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("first report %+v, want %+v", report, want)
+	}
+	if report.Duration <= 0 {
+		t.Fatalf("duration not set")
+	}
+
+	if err := os.Remove(filepath.Join(docsDir, "b.json")); err != nil {
+		t.Fatal(err)
+	}
+	report, err = SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = SyncReport{Processed: 1, Unchanged: 1, Skipped: 1, Deleted: 1, Duration: report.Duration}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("second report %+v, want %+v", report, want)
+	}
+}
+
+func TestSyncDirToFTS_NewestFirst(t *testing.T) {
+	withTempDir(t, func(tmpDir string) {
+		engine, err := NewEngine(minimalConfig(tmpDir, "fts.db",
+			Column{Name: "title"},
+			Column{Name: "mtime", Unindexed: true},
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer engine.Close()
+
+		docsDir := filepath.Join(tmpDir, "docs")
+		if err := os.MkdirAll(filepath.Join(docsDir, "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		// Lexical order is the reverse of the modification order.
+		base := time.Now().Add(-time.Hour)
+		names := []string{"a.json", "b.json", "sub/c.json", "sub/d.json"}
+		for i, n := range names {
+			p := filepath.Join(docsDir, n)
+			writeJSONFile(t, p, map[string]any{"title": n})
+			mt := base.Add(time.Duration(len(names)-i) * time.Minute)
+			if err := os.Chtimes(p, mt, mt); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var order []string
+		record := func(ctx context.Context, baseDir, fullPath string, getPrev GetPrevCmp) (SyncDecision, error) {
+			rel, _ := filepath.Rel(docsDir, fullPath)
+			order = append(order, filepath.ToSlash(rel))
+			return testProcessFile(ctx, baseDir, fullPath, getPrev)
+		}
+
+		if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 1, record); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(order, names) {
+			t.Fatalf("default walk order %v", order)
+		}
+
+		// Touch the last file, then resync newest first.
+		order = nil
+		touchFile(t, filepath.Join(docsDir, "sub/d.json"))
+		if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 1, record, WithNewestFirst()); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"sub/d.json", "a.json", "b.json", "sub/c.json"}
+		if !reflect.DeepEqual(order, want) {
+			t.Fatalf("newest first order %v, want %v", order, want)
+		}
+
+		// The index can be read back newest first too.
+		rows, _, err := engine.BatchListOrdered(t.Context(), []OrderBy{{Column: "mtime", Desc: true}}, nil, "", 1)
+		if err != nil || len(rows) != 1 || !strings.HasSuffix(rows[0].ID, "d.json") {
+			t.Fatalf("newest indexed row %v, err %v", rows, err)
+		}
+	})
+}
+
+func TestSyncDirToFTS_CompareColumns(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db",
+		Column{Name: "title"},
+		Column{Name: "mtime", Unindexed: true},
+		Column{Name: "size", Unindexed: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(docsDir, "a.json")
+	writeJSONFile(t, p, map[string]any{"title": "short"})
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var extracted int
+	process := func(_ context.Context, _, fullPath string, getPrev GetPrevCmp) (SyncDecision, error) {
+		st, err := os.Stat(fullPath)
+		if err != nil {
+			return SyncDecision{}, err
+		}
+		cmp := map[string]string{
+			"mtime": st.ModTime().UTC().Format(time.RFC3339Nano),
+			"size":  strconv.FormatInt(st.Size(), 10),
+		}
+		if getPrev(fullPath) == CompareKey(cmp) {
+			return SyncDecision{ID: fullPath, Unchanged: true}, nil
+		}
+		extracted++
+		raw, err := os.ReadFile(fullPath)
+		if err != nil {
+			return SyncDecision{}, err
+		}
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return SyncDecision{}, err
+		}
+		title, _ := m["title"].(string)
+		return SyncDecision{ID: fullPath, CmpValues: cmp, Vals: map[string]string{"title": title}}, nil
+	}
+	sync := func() {
+		t.Helper()
+		_, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, process, WithCompareColumns("size"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sync()
+	sync()
+	if extracted != 1 {
+		t.Fatalf("unchanged file extracted again: %d", extracted)
+	}
+
+	// Rewrite with a preserved mtime, as rsync -a does. Only the size tells.
+	writeJSONFile(t, p, map[string]any{"title": "a much longer title"})
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	sync()
+	if extracted != 2 {
+		t.Fatalf("size change not detected, extracted %d", extracted)
+	}
+	rows, _, err := engine.BatchList(t.Context(), "", nil, "", 0)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("rows %v, err %v", rows, err)
+	}
+	stored := rows[0].Values
+	if stored["title"] != "a much longer title" || stored["size"] == "" || stored["mtime"] == "" {
+		t.Fatalf("stored values %v", stored)
+	}
+}
+
 func TestFTSEngine_IsEmpty(t *testing.T) {
 	withTempDir(t, func(tmpDir string) {
 		cfg := minimalConfig(tmpDir, "fts.db",
@@ -463,3 +652,144 @@ func touchFile(t *testing.T, path string) {
 		t.Fatal(err)
 	}
 }
+
+func TestSyncDirToFTS_ErrorPolicy(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db",
+		Column{Name: "title"},
+		Column{Name: "mtime", Unindexed: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(filepath.Join(docsDir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bad.json", "flaky.json", "good.json", "sub/c.json"} {
+		writeJSONFile(t, filepath.Join(docsDir, name), map[string]any{"title": name})
+	}
+	if _, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bad.json", "flaky.json", "good.json", "sub/c.json"} {
+		touchFile(t, filepath.Join(docsDir, name))
+	}
+
+	errBroken := errors.New("broken")
+	newProcess := func() ProcessFile {
+		calls := map[string]int{}
+		return func(ctx context.Context, baseDir, fullPath string, getPrev GetPrevCmp) (SyncDecision, error) {
+			base := filepath.Base(fullPath)
+			calls[base]++
+			if base == "bad.json" || (base == "flaky.json" && calls[base] == 1) {
+				return SyncDecision{}, errBroken
+			}
+			return testProcessFile(ctx, baseDir, fullPath, getPrev)
+		}
+	}
+
+	_, err = SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, newProcess())
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("fail fast: got %v", err)
+	}
+	_, err = SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, newProcess(), WithErrorPolicy(RetryN(1)))
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("retry: bad file should still fail, got %v", err)
+	}
+
+	policy := RetryN(1)
+	policy.Collect = true
+	report, err := SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, newProcess(), WithErrorPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Path != filepath.Join(docsDir, "bad.json") ||
+		!errors.Is(report.Errors[0], errBroken) {
+		t.Fatalf("errors %v", report.Errors)
+	}
+	if report.Upserted != 3 || report.Deleted != 0 {
+		t.Fatalf("report %+v", report)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced for root")
+	}
+	sub := filepath.Join(docsDir, "sub")
+	if err := os.Chmod(sub, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chmod(sub, 0o755) }()
+	report, err = SyncDirToFTS(t.Context(), engine, docsDir, "mtime", 10, testProcessFile,
+		WithErrorPolicy(SkipAndCollect()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Path != sub || report.Deleted != 0 {
+		t.Fatalf("unreadable dir: report %+v", report)
+	}
+	rows, _, err := engine.BatchList(t.Context(), "", nil, "", 0)
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("rows of the unreadable dir not kept: %v, err %v", rows, err)
+	}
+}
+
+func TestSyncDirToFTS_IncludeExclude(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(minimalConfig(dir, "fts.db",
+		Column{Name: "body"},
+		Column{Name: "hash", Unindexed: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	docsDir := filepath.Join(dir, "docs")
+	for _, name := range []string{
+		"a.md", "b.txt", "c.md.tmp-1", ".git/HEAD.md", "node_modules/m.md", "sub/d.md", "sub/skip/e.md",
+	} {
+		p := filepath.Join(docsDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var visited []string
+	process := HashProcessFile(func(p string, content []byte) (map[string]string, bool, error) {
+		rel, _ := filepath.Rel(docsDir, p)
+		visited = append(visited, filepath.ToSlash(rel))
+		return map[string]string{"body": string(content)}, false, nil
+	})
+
+	_, err = SyncDirToFTS(t.Context(), engine, docsDir, "hash", 10, process,
+		WithExclude(".git", "node_modules", "*.tmp-*", "sub/skip"),
+		WithInclude("*.md"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(visited)
+	if want := []string{"a.md", "sub/d.md"}; !reflect.DeepEqual(visited, want) {
+		t.Fatalf("visited %v, want %v", visited, want)
+	}
+
+	// Newly excluded files drop out of the index.
+	report, err := SyncDirToFTS(t.Context(), engine, docsDir, "hash", 10, process,
+		WithExclude("sub"), WithNewestFirst())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 1 || report.Unchanged != 1 || report.Upserted != 4 {
+		t.Fatalf("report %+v", report)
+	}
+
+	_, err = SyncDirToFTS(t.Context(), engine, docsDir, "hash", 10, process, WithExclude("[a-"))
+	if err == nil {
+		t.Fatal("expected an error for a malformed pattern")
+	}
+}
@@ -0,0 +1,96 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// reindexPageSize is the page size used to scan the table and the size of each reindex batch.
+const reindexPageSize = 500
+
+// ErrDocumentNotFound is returned by a fetch func passed to Reindex when the source document no longer exists.
+// The row is then deleted from the index.
+var ErrDocumentNotFound = errors.New("ftsengine: document not found")
+
+// FetchDocument returns the current column values of the source document with the given id.
+type FetchDocument func(id string) (map[string]string, error)
+
+// Reindex refreshes a single row from its source, without a full sync pass.
+// If fetch returns ErrDocumentNotFound the row is deleted.
+func (e *Engine) Reindex(ctx context.Context, id string, fetch FetchDocument) error {
+	if id == "" {
+		return errors.New("ftsengine: empty id")
+	}
+	if fetch == nil {
+		return errors.New("ftsengine: nil fetch func")
+	}
+	vals, err := fetch(id)
+	if errors.Is(err, ErrDocumentNotFound) {
+		return e.Delete(ctx, id)
+	}
+	if err != nil {
+		return fmt.Errorf("ftsengine: fetch %q: %w", id, err)
+	}
+	return e.Upsert(ctx, id, vals)
+}
+
+// ReindexWhere refreshes every indexed row for which match returns true and returns how many rows were refreshed
+// or deleted. Match sees the stored values of all columns. Rows are written in batches, one transaction per batch.
+func (e *Engine) ReindexWhere(
+	ctx context.Context,
+	match func(row ListResult) bool,
+	fetch FetchDocument,
+) (int, error) {
+	if match == nil || fetch == nil {
+		return 0, errors.New("ftsengine: nil match or fetch func")
+	}
+
+	// Collect first, so that rewriting rows cannot disturb the listing.
+	var ids []string
+	token := ""
+	for {
+		rows, next, err := e.BatchList(ctx, "", nil, token, reindexPageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			if match(row) {
+				ids = append(ids, row.ID)
+			}
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	done := 0
+	for start := 0; start < len(ids); start += reindexPageSize {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		chunk := ids[start:min(start+reindexPageSize, len(ids))]
+		upserts := make(map[string]map[string]string, len(chunk))
+		var deletes []string
+		for _, id := range chunk {
+			vals, err := fetch(id)
+			if errors.Is(err, ErrDocumentNotFound) {
+				deletes = append(deletes, id)
+				continue
+			}
+			if err != nil {
+				return done, fmt.Errorf("ftsengine: fetch %q: %w", id, err)
+			}
+			upserts[id] = vals
+		}
+		if err := e.BatchUpsert(ctx, upserts); err != nil {
+			return done, err
+		}
+		if err := e.BatchDelete(ctx, deletes); err != nil {
+			return done, err
+		}
+		done += len(chunk)
+	}
+	return done, nil
+}
@@ -0,0 +1,95 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEngine(t)
+	defer e.Close()
+
+	if err := e.Upsert(ctx, "a", map[string]string{"title": "stale", "body": "old"}); err != nil {
+		t.Fatal(err)
+	}
+	source := map[string]map[string]string{"a": {"title": "fresh", "body": "new"}}
+	fetch := func(id string) (map[string]string, error) {
+		if v, ok := source[id]; ok {
+			return v, nil
+		}
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := e.Reindex(ctx, "a", fetch); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if hits, _, _ := e.Search(ctx, "fresh", "", 10); len(hits) != 1 {
+		t.Fatalf("reindexed row not found: %v", hits)
+	}
+	if hits, _, _ := e.Search(ctx, "stale", "", 10); len(hits) != 0 {
+		t.Fatalf("stale content still indexed: %v", hits)
+	}
+
+	delete(source, "a")
+	if err := e.Reindex(ctx, "a", fetch); err != nil {
+		t.Fatalf("Reindex gone doc: %v", err)
+	}
+	if empty, _ := e.IsEmpty(ctx); !empty {
+		t.Fatal("row of removed document not deleted")
+	}
+
+	boom := errors.New("boom")
+	if err := e.Reindex(ctx, "x", func(string) (map[string]string, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Fatalf("want fetch error, got %v", err)
+	}
+}
+
+func TestReindexWhere(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEngine(t)
+	defer e.Close()
+
+	docs := map[string]map[string]string{}
+	for _, id := range []string{"keep1", "keep2", "fix1", "fix2", "gone"} {
+		docs[id] = map[string]string{"title": "v1 " + id, "body": "body"}
+	}
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched := 0
+	n, err := e.ReindexWhere(ctx,
+		func(row ListResult) bool { return !strings.Contains(row.ID, "keep") },
+		func(id string) (map[string]string, error) {
+			fetched++
+			if id == "gone" {
+				return nil, ErrDocumentNotFound
+			}
+			return map[string]string{"title": "v2 " + id, "body": "body"}, nil
+		},
+	)
+	if err != nil || n != 3 || fetched != 3 {
+		t.Fatalf("ReindexWhere: n=%d fetched=%d err=%v", n, fetched, err)
+	}
+
+	rows, _, err := e.BatchList(ctx, "", nil, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, r := range rows {
+		got[r.ID] = r.Values["title"]
+	}
+	want := map[string]string{"keep1": "v1 keep1", "keep2": "v1 keep2", "fix1": "v2 fix1", "fix2": "v2 fix2"}
+	if len(got) != len(want) {
+		t.Fatalf("rows: got %v, want %v", got, want)
+	}
+	for id, title := range want {
+		if got[id] != title {
+			t.Fatalf("row %s: got %q, want %q", id, got[id], title)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package ftsengine
+
+import (
+	"container/list"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// SearchCacheStats reports the search result cache enabled by Config.SearchCacheSize.
+type SearchCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	// Maximum number of cached pages, 0 if the cache is disabled.
+	Capacity int
+}
+
+// searchCache is a small LRU of result pages. Any write clears it.
+// A nil *searchCache is a disabled cache, all methods are no-ops.
+type searchCache struct {
+	mu       sync.Mutex
+	capacity int
+	// Bumped on every invalidation, so a search that raced with a write does not store a stale page.
+	gen          uint64
+	ll           *list.List
+	items        map[string]*list.Element
+	hits, misses uint64
+}
+
+type searchCacheEntry struct {
+	key  string
+	page SearchPage
+}
+
+func newSearchCache(capacity int) *searchCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &searchCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+// searchCacheKey identifies a page by query, options and position, independent of the token encoding.
+func searchCacheKey(query string, offset, pageSize int, so searchOptions) string {
+	return fmt.Sprintf("%q|%d|%d|%t|%d|%q|%s|%t",
+		query, offset, pageSize, so.countTotal, so.estimateCap, so.groupBy, so.filterKey(), so.explain)
+}
+
+// get returns a cached page and the generation to pass to put after a miss.
+func (c *searchCache) get(key string) (page SearchPage, gen uint64, ok bool) {
+	if c == nil {
+		return SearchPage{}, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return SearchPage{}, c.gen, false
+	}
+	c.hits++
+	c.ll.MoveToFront(el)
+	page = el.Value.(*searchCacheEntry).page
+	page.Hits = cloneHits(page.Hits)
+	return page, c.gen, true
+}
+
+// put stores page unless a write happened since gen was read.
+func (c *searchCache) put(key string, gen uint64, page SearchPage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	page.Hits = cloneHits(page.Hits)
+	if el, ok := c.items[key]; ok {
+		el.Value.(*searchCacheEntry).page = page
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&searchCacheEntry{key: key, page: page})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*searchCacheEntry).key)
+	}
+}
+
+// cloneHits copies hits and their Explain scores, so that cached pages and the pages of callers share nothing.
+func cloneHits(hits []SearchResult) []SearchResult {
+	out := slices.Clone(hits)
+	for i := range out {
+		out[i].Explain = slices.Clone(out[i].Explain)
+	}
+	return out
+}
+
+func (c *searchCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.ll.Init()
+	clear(c.items)
+}
+
+// SearchCacheStats returns hit and miss counters of the search result cache.
+func (e *Engine) SearchCacheStats() SearchCacheStats {
+	c := e.cache
+	if c == nil {
+		return SearchCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SearchCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.ll.Len(), Capacity: c.capacity}
+}
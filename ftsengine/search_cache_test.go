@@ -0,0 +1,109 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSearchCache(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine(Config{
+		BaseDir:         MemoryDBBaseDir,
+		Table:           "docs",
+		Columns:         []Column{{Name: "title"}, {Name: "body"}},
+		SearchCacheSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for i := range 3 {
+		if err := e.Upsert(ctx, fmt.Sprintf("d%d", i), map[string]string{"title": "apple", "body": "pie"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Fatalf("cached page differs: %+v vs %+v", first, second)
+	}
+	if st := e.SearchCacheStats(); st.Hits != 1 || st.Misses != 1 || st.Entries != 1 || st.Capacity != 2 {
+		t.Fatalf("stats after repeat: %+v", st)
+	}
+
+	// Callers may modify returned hits without corrupting the cache.
+	second.Hits[0].ID = "mutated"
+	third, _ := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if third.Hits[0].ID == "mutated" {
+		t.Fatal("cache shares hit slices with callers")
+	}
+
+	// Also their explain scores, both of the page that was stored and of a cached page.
+	explained, err := e.SearchPaged(ctx, "pie", "", 10, WithExplain(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	explained.Hits[0].Explain[0].Column = "mutated"
+	cached, _ := e.SearchPaged(ctx, "pie", "", 10, WithExplain(true))
+	if cached.Hits[0].Explain[0].Column == "mutated" {
+		t.Fatal("cache shares explain scores with the caller that stored the page")
+	}
+	cached.Hits[0].Explain[0].Column = "mutated"
+	if again, _ := e.SearchPaged(ctx, "pie", "", 10, WithExplain(true)); again.Hits[0].Explain[0].Column == "mutated" {
+		t.Fatal("cache shares explain scores with callers")
+	}
+
+	// Different options are different entries, and the oldest one is evicted.
+	_, _ = e.SearchPaged(ctx, "apple", "", 10)
+	_, _ = e.SearchPaged(ctx, "pie", "", 10)
+	if st := e.SearchCacheStats(); st.Entries != 2 {
+		t.Fatalf("expected capacity bound, got %+v", st)
+	}
+	before := e.SearchCacheStats()
+	_, _ = e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if st := e.SearchCacheStats(); st.Misses != before.Misses+1 {
+		t.Fatalf("evicted entry should miss: %+v", st)
+	}
+
+	// Writes invalidate.
+	if err := e.Upsert(ctx, "d9", map[string]string{"title": "apple"}); err != nil {
+		t.Fatal(err)
+	}
+	if st := e.SearchCacheStats(); st.Entries != 0 {
+		t.Fatalf("write should clear the cache: %+v", st)
+	}
+	page, _ := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if page.Total != 4 {
+		t.Fatalf("stale result after write: total %d", page.Total)
+	}
+	if err := e.BatchDelete(ctx, []string{"d9"}); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount()); page.Total != 3 {
+		t.Fatalf("stale result after delete: total %d", page.Total)
+	}
+}
+
+func TestSearchCacheDisabled(t *testing.T) {
+	e := newMemoryEngine(t)
+	if _, _, err := e.Search(t.Context(), "x", "", 10); err != nil {
+		t.Fatal(err)
+	}
+	if st := e.SearchCacheStats(); st != (SearchCacheStats{}) {
+		t.Fatalf("disabled cache reports %+v", st)
+	}
+	if _, err := NewEngine(Config{
+		BaseDir: MemoryDBBaseDir, Table: "t", Columns: []Column{{Name: "a"}}, SearchCacheSize: -1,
+	}); err == nil {
+		t.Fatal("expected error for negative cache size")
+	}
+}
@@ -0,0 +1,172 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Hits []SearchResult
+	// Opaque token for the next page, "" if there are no more results.
+	NextToken string
+	// Number of matching documents, only set when requested with WithTotalCount or WithEstimate.
+	Total int
+	// True if Total is a lower bound because counting stopped at the WithEstimate cap.
+	TotalIsEstimate bool
+}
+
+// SearchOption configures SearchPaged.
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	countTotal  bool
+	estimateCap int
+	groupBy     string
+	filters     []filter
+	explain     bool
+}
+
+type filter struct {
+	column string
+	value  string
+}
+
+// WithTotalCount makes SearchPaged return the exact number of matching documents.
+// It costs one extra query that visits every match.
+func WithTotalCount() SearchOption {
+	return func(o *searchOptions) {
+		o.countTotal = true
+		o.estimateCap = 0
+	}
+}
+
+// WithEstimate makes SearchPaged count matches only up to limit, which keeps the count cheap for broad queries.
+// If there are more matches, Total is limit and TotalIsEstimate is set, good enough for "1-10 of 1,000+".
+// FTS5 has no matchinfo, so a capped count is the estimate.
+func WithEstimate(limit int) SearchOption {
+	return func(o *searchOptions) {
+		o.countTotal = true
+		o.estimateCap = max(limit, 1)
+	}
+}
+
+// WithGroupBy collapses hits that share a value of column, e.g. many chunks of one document, into the best
+// scoring one. SearchResult.GroupSize then holds the number of collapsed hits, and paging and totals count groups.
+// Hits with an empty or missing value are groups of their own.
+func WithGroupBy(column string) SearchOption {
+	return func(o *searchOptions) {
+		o.groupBy = column
+	}
+}
+
+// WithFilter restricts hits to rows whose stored value of column equals value, e.g. WithFilter(ColNameLang, "en").
+// It is meant for unindexed metadata columns. Several filters must all match.
+func WithFilter(column, value string) SearchOption {
+	return func(o *searchOptions) {
+		o.filters = append(o.filters, filter{column: column, value: value})
+	}
+}
+
+func (e *Engine) validateSearchOptions(so searchOptions) error {
+	known := func(name string) bool {
+		return slices.ContainsFunc(e.cfg.Columns, func(c Column) bool { return c.Name == name })
+	}
+	if so.groupBy != "" && !known(so.groupBy) {
+		return fmt.Errorf("ftsengine: unknown group column %q", so.groupBy)
+	}
+	for _, f := range so.filters {
+		if !known(f.column) {
+			return fmt.Errorf("ftsengine: unknown filter column %q", f.column)
+		}
+	}
+	return nil
+}
+
+// filterKey identifies the filters in page tokens, so that a token is not reused with other filters.
+func (so searchOptions) filterKey() string {
+	if len(so.filters) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(so.filters))
+	for _, f := range so.filters {
+		parts = append(parts, strconv.Quote(f.column)+"="+strconv.Quote(f.value))
+	}
+	return strings.Join(parts, "&")
+}
+
+// matchWhere returns the WHERE clause of a search and the filter arguments that follow the query argument.
+func (e *Engine) matchWhere(so searchOptions) (string, []any) {
+	where := e.cfg.Table + " MATCH ?"
+	args := make([]any, 0, len(so.filters))
+	for _, f := range so.filters {
+		where += " AND " + quote(f.column) + "=?"
+		args = append(args, f.value)
+	}
+	return where, args
+}
+
+// groupKeyExpr is the grouping key of WithGroupBy. Rows without a value fall back to their integer rowid,
+// which never equals a text value.
+func groupKeyExpr(column string) string {
+	return fmt.Sprintf("CASE WHEN COALESCE(%[1]s,'')='' THEN %[2]s ELSE %[1]s END", quote(column), ColNameRowID)
+}
+
+// groupedSearchSQL keeps the best hit per group using window functions.
+// It takes the same arguments as the plain search: the bm25 weights, the where arguments, limit and offset.
+func (e *Engine) groupedSearchSQL(column string, numWeights int, where string) string {
+	const sqlGrouped = `SELECT id, s, n FROM (
+			SELECT id, s, rid,
+				row_number() OVER (PARTITION BY g ORDER BY s ASC, rid) AS rn,
+				count(*) OVER (PARTITION BY g) AS n
+			FROM (
+				SELECT %s AS id, bm25(%s%s) AS s, %s AS rid, %s AS g
+				FROM %s WHERE %s
+			)
+		) WHERE rn=1
+		ORDER BY s ASC, rid
+		LIMIT ? OFFSET ?;`
+	return fmt.Sprintf(sqlGrouped,
+		ColNameExternalID, quote(e.cfg.Table), paramPlaceholders(numWeights), ColNameRowID, groupKeyExpr(column),
+		quote(e.cfg.Table), where)
+}
+
+// countMatches counts documents, or groups with WithGroupBy, matching the cleaned query and filters,
+// stopping at the WithEstimate cap if set. It counts one past the cap, so an exact count equal to the cap is not
+// reported as an estimate.
+func (e *Engine) countMatches(ctx context.Context, cleanedQuery string, so searchOptions) (int, bool, error) {
+	limit, groupBy := so.estimateCap, so.groupBy
+	if limit < 0 {
+		return 0, false, errors.New("ftsengine: negative count limit")
+	}
+	// One row per document or per group.
+	unit := "1"
+	if groupBy != "" {
+		unit = "DISTINCT " + groupKeyExpr(groupBy)
+	}
+	where, filterArgs := e.matchWhere(so)
+	var (
+		sqlQ string
+		args = append([]any{cleanedQuery}, filterArgs...)
+	)
+	if limit == 0 {
+		const sqlCount = `SELECT count(*) FROM (SELECT %s FROM %s WHERE %s);`
+		sqlQ = fmt.Sprintf(sqlCount, unit, quote(e.cfg.Table), where)
+	} else {
+		const sqlCountCapped = `SELECT count(*) FROM (SELECT %s FROM %s WHERE %s LIMIT ?);`
+		sqlQ = fmt.Sprintf(sqlCountCapped, unit, quote(e.cfg.Table), where)
+		args = append(args, limit+1)
+	}
+	var n int
+	if err := e.db.QueryRowContext(ctx, sqlQ, args...).Scan(&n); err != nil {
+		return 0, false, err
+	}
+	if limit > 0 && n > limit {
+		return limit, true, nil
+	}
+	return n, false, nil
+}
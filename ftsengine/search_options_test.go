@@ -0,0 +1,138 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSearchPaged_TotalCount(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEngine(t)
+	defer e.Close()
+
+	docs := map[string]map[string]string{}
+	for i := range 25 {
+		docs[fmt.Sprintf("apple%02d", i)] = map[string]string{"title": "apple", "body": "fruit"}
+	}
+	for i := range 5 {
+		docs[fmt.Sprintf("pear%02d", i)] = map[string]string{"title": "pear", "body": "fruit"}
+	}
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		opts         []SearchOption
+		wantTotal    int
+		wantEstimate bool
+	}{
+		{"no count", "apple", nil, 0, false},
+		{"exact", "apple", []SearchOption{WithTotalCount()}, 25, false},
+		{"exact or", "apple pear", []SearchOption{WithTotalCount()}, 30, false},
+		{"estimate capped", "fruit", []SearchOption{WithEstimate(20)}, 20, true},
+		{"estimate exact below cap", "pear", []SearchOption{WithEstimate(20)}, 5, false},
+		{"estimate exact at cap", "pear", []SearchOption{WithEstimate(5)}, 5, false},
+		{"estimate one over cap", "pear", []SearchOption{WithEstimate(4)}, 4, true},
+		{"no matches", "banana", []SearchOption{WithTotalCount()}, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := e.SearchPaged(ctx, tc.query, "", 10, tc.opts...)
+			if err != nil {
+				t.Fatalf("SearchPaged: %v", err)
+			}
+			if page.Total != tc.wantTotal || page.TotalIsEstimate != tc.wantEstimate {
+				t.Fatalf("total %d estimate %v, want %d %v",
+					page.Total, page.TotalIsEstimate, tc.wantTotal, tc.wantEstimate)
+			}
+			if tc.wantTotal >= 10 && (len(page.Hits) != 10 || page.NextToken == "") {
+				t.Fatalf("page: %d hits, next %q", len(page.Hits), page.NextToken)
+			}
+		})
+	}
+
+	// The total stays the same on later pages.
+	first, _ := e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	second, err := e.SearchPaged(ctx, "apple", first.NextToken, 10, WithTotalCount())
+	if err != nil || second.Total != 25 || len(second.Hits) != 10 {
+		t.Fatalf("second page: total %d hits %d err %v", second.Total, len(second.Hits), err)
+	}
+}
+
+func TestSearchPaged_GroupBy(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine(Config{
+		BaseDir: MemoryDBBaseDir,
+		Table:   "chunks",
+		Columns: []Column{{Name: "body"}, {Name: "doc", Unindexed: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	docs := map[string]map[string]string{
+		"a#1":    {"body": "apple pie recipe with many other words around it", "doc": "a"},
+		"a#2":    {"body": "apple apple apple", "doc": "a"},
+		"a#3":    {"body": "apple crumble and a long tail of unrelated words", "doc": "a"},
+		"b#1":    {"body": "apple juice", "doc": "b"},
+		"b#2":    {"body": "orange juice", "doc": "b"},
+		"b#3":    {"body": "apple cider and some more filler words", "doc": "b"},
+		"loose1": {"body": "apple tree"},
+		"loose2": {"body": "apple orchard", "doc": ""},
+	}
+	if err := e.BatchUpsert(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := e.SearchPaged(ctx, "apple", "", 10, WithGroupBy("doc"), WithTotalCount())
+	if err != nil {
+		t.Fatalf("SearchPaged: %v", err)
+	}
+	if page.Total != 4 {
+		t.Fatalf("total %d, want 4 groups", page.Total)
+	}
+	got := map[string]int{}
+	for _, h := range page.Hits {
+		got[h.ID] = h.GroupSize
+	}
+	want := map[string]int{"a#2": 3, "b#1": 2, "loose1": 1, "loose2": 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("hits %v, want %v", got, want)
+	}
+
+	// Paging walks groups, without repeating any.
+	seen := map[string]bool{}
+	token := ""
+	for {
+		hits, next, err := e.Search(ctx, "apple", token, 1, WithGroupBy("doc"))
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		for _, h := range hits {
+			if seen[h.ID] {
+				t.Fatalf("hit %s repeated", h.ID)
+			}
+			seen[h.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if len(seen) != 4 {
+		t.Fatalf("paged %d groups, want 4", len(seen))
+	}
+
+	// Without grouping every chunk is a hit.
+	page, _ = e.SearchPaged(ctx, "apple", "", 10, WithTotalCount())
+	if page.Total != 7 || page.Hits[0].GroupSize != 0 {
+		t.Fatalf("ungrouped total %d", page.Total)
+	}
+	if _, err := e.SearchPaged(ctx, "apple", "", 10, WithGroupBy("nope")); err == nil {
+		t.Fatal("expected error for unknown group column")
+	}
+}
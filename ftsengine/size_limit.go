@@ -0,0 +1,52 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrIndexFull is returned by writes while the database is at or above Config.MaxDBSizeBytes.
+var ErrIndexFull = errors.New("ftsengine: index full")
+
+// IndexFullFunc is called when a write finds the database at or above Config.MaxDBSizeBytes, e.g. to prune old
+// documents with BatchDelete. The write goes ahead if the database is below the limit afterwards.
+type IndexFullFunc func(ctx context.Context, e *Engine, sizeBytes int64) error
+
+// SizeBytes returns the bytes of database pages in use. Pages freed by deletes are reused, so the file on disk can
+// be larger than this until it is vacuumed.
+func (e *Engine) SizeBytes(ctx context.Context) (int64, error) {
+	const sqlSize = `SELECT (c.page_count - f.freelist_count) * s.page_size
+		FROM pragma_page_count() c, pragma_freelist_count() f, pragma_page_size() s;`
+	var n int64
+	if err := e.db.QueryRowContext(ctx, sqlSize).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// checkSize enforces MaxDBSizeBytes before a write. It runs without the write lock, so that OnIndexFull can delete.
+// The limit is soft: concurrent writes may all pass the check.
+func (e *Engine) checkSize(ctx context.Context) error {
+	limit := e.cfg.MaxDBSizeBytes
+	if limit <= 0 {
+		return nil
+	}
+	size, err := e.SizeBytes(ctx)
+	if err != nil || size < limit {
+		return err
+	}
+	if e.cfg.OnIndexFull != nil {
+		if err := e.cfg.OnIndexFull(ctx, e, size); err != nil {
+			return fmt.Errorf("ftsengine: prune on full index: %w", err)
+		}
+		// FTS5 deletes only add tombstones, merging the segments releases the pruned pages.
+		if err := e.Optimize(ctx); err != nil {
+			return err
+		}
+		if size, err = e.SizeBytes(ctx); err != nil || size < limit {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %d of %d bytes used", ErrIndexFull, size, limit)
+}
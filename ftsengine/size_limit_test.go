@@ -0,0 +1,99 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaxDBSizeBytes(t *testing.T) {
+	ctx := context.Background()
+	const limit = 256 << 10
+	newEngine := func(t *testing.T, onFull IndexFullFunc) *Engine {
+		t.Helper()
+		e, err := NewEngine(Config{
+			BaseDir:        MemoryDBBaseDir,
+			Table:          "docs",
+			Columns:        []Column{{Name: "body"}},
+			MaxDBSizeBytes: limit,
+			OnIndexFull:    onFull,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = e.Close() })
+		return e
+	}
+	// Distinct words, so that the index grows with every document.
+	doc := func(i int) map[string]string {
+		var b strings.Builder
+		for j := range 500 {
+			fmt.Fprintf(&b, "w%dx%d ", i, j)
+		}
+		return map[string]string{"body": b.String()}
+	}
+	fill := func(t *testing.T, e *Engine) (int, error) {
+		t.Helper()
+		for i := range 1000 {
+			if err := e.Upsert(ctx, fmt.Sprintf("d%03d", i), doc(i)); err != nil {
+				return i, err
+			}
+		}
+		t.Fatal("limit never reached")
+		return 0, nil
+	}
+
+	t.Run("rejects writes when full", func(t *testing.T) {
+		e := newEngine(t, nil)
+		n, err := fill(t, e)
+		if !errors.Is(err, ErrIndexFull) {
+			t.Fatalf("expected ErrIndexFull after %d docs, got %v", n, err)
+		}
+		size, err := e.SizeBytes(ctx)
+		if err != nil || size < limit {
+			t.Fatalf("SizeBytes = %d, %v", size, err)
+		}
+		if err := e.BatchUpsert(ctx, map[string]map[string]string{"x": doc(9999)}); !errors.Is(err, ErrIndexFull) {
+			t.Fatalf("BatchUpsert should be rejected too, got %v", err)
+		}
+
+		// Deletes still work and free space for new writes.
+		ids := make([]string, 0, n)
+		for i := range n {
+			ids = append(ids, fmt.Sprintf("d%03d", i))
+		}
+		if err := e.BatchDelete(ctx, ids); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Upsert(ctx, "after", doc(1)); err != nil {
+			t.Fatalf("upsert after delete: %v", err)
+		}
+	})
+
+	t.Run("prunes through callback", func(t *testing.T) {
+		pruned := 0
+		e := newEngine(t, func(ctx context.Context, e *Engine, _ int64) error {
+			pruned++
+			rows, _, err := e.BatchList(ctx, "", nil, "", 0)
+			if err != nil {
+				return err
+			}
+			// Drop the older half.
+			ids := make([]string, 0, len(rows)/2)
+			for _, r := range rows[:len(rows)/2] {
+				ids = append(ids, r.ID)
+			}
+			return e.BatchDelete(ctx, ids)
+		})
+		for i := range 200 {
+			if err := e.Upsert(ctx, fmt.Sprintf("d%03d", i), doc(i)); err != nil {
+				t.Fatalf("upsert %d with pruning: %v", i, err)
+			}
+		}
+		if pruned == 0 {
+			t.Fatal("pruning callback never ran")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package ftsengine
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// soakDuration is how long the soak test runs: MAPSTORE_SOAK, e.g. "10m", for long runs, else a short smoke
+// run.
+func soakDuration(t *testing.T) time.Duration {
+	t.Helper()
+	v := os.Getenv("MAPSTORE_SOAK")
+	if v == "" {
+		return 300 * time.Millisecond
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		t.Fatalf("invalid MAPSTORE_SOAK: %v", err)
+	}
+	return d
+}
+
+// TestSoakInterruptedBatches upserts batches whose contexts end at random points and checks that every batch
+// is applied completely or not at all, also after reopening the database.
+func TestSoakInterruptedBatches(t *testing.T) {
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	cfg := Config{
+		BaseDir:         t.TempDir(),
+		DBFileName:      "soak.db",
+		Table:           "docs",
+		Columns:         []Column{{Name: "title"}},
+		SearchCacheSize: 8,
+	}
+	e, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	defer func() { _ = e.Close() }()
+
+	model := map[string]string{}
+	batches, failed := 0, 0
+	for deadline := time.Now().Add(soakDuration(t)); time.Now().Before(deadline); {
+		batches++
+		if rng.IntN(5) == 0 && len(model) > 0 {
+			for id := range model {
+				if err := e.Delete(t.Context(), id); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				delete(model, id)
+				break
+			}
+		}
+
+		docs := map[string]map[string]string{}
+		for range 1 + rng.IntN(20) {
+			id := fmt.Sprintf("d%03d", rng.IntN(200))
+			docs[id] = map[string]string{"title": fmt.Sprintf("soak b%d", batches)}
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), time.Duration(rng.IntN(400))*time.Microsecond)
+		err := e.BatchUpsert(ctx, docs)
+		cancel()
+		if err != nil {
+			failed++
+		} else {
+			for id, vals := range docs {
+				model[id] = vals["title"]
+			}
+		}
+
+		if batches%10 == 0 {
+			checkSoakModel(t, e, model, batches)
+		}
+	}
+	t.Logf("%d batches, %d interrupted, %d docs", batches, failed, len(model))
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	e, err = NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	checkSoakModel(t, e, model, batches+1)
+}
+
+// checkSoakModel compares the listed rows with model and searches for one of the docs.
+func checkSoakModel(t *testing.T, e *Engine, model map[string]string, batch int) {
+	t.Helper()
+	got := map[string]string{}
+	for token := ""; ; {
+		rows, next, err := e.BatchList(t.Context(), "", nil, token, 0)
+		if err != nil {
+			t.Fatalf("BatchList: %v", err)
+		}
+		for _, r := range rows {
+			got[r.ID] = r.Values["title"]
+		}
+		if token = next; token == "" {
+			break
+		}
+	}
+	if len(got) != len(model) {
+		t.Fatalf("batch %d: %d rows, want %d", batch, len(got), len(model))
+	}
+	for id, title := range model {
+		if got[id] != title {
+			t.Fatalf("batch %d: %s has title %q, want %q", batch, id, got[id], title)
+		}
+	}
+
+	// The index agrees with the rows: a doc is found by its batch marker.
+	for id, title := range model {
+		marker := strings.Fields(title)[1]
+		hits, _, err := e.Search(t.Context(), marker, "", 100)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if !slices.ContainsFunc(hits, func(h SearchResult) bool { return h.ID == id }) {
+			t.Fatalf("batch %d: search %q did not find %s", batch, marker, id)
+		}
+		break
+	}
+}
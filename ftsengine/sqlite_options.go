@@ -0,0 +1,99 @@
+package ftsengine
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used for zero SQLiteOptions fields.
+const (
+	DefaultBusyTimeout  = 5 * time.Second
+	DefaultJournalMode  = "WAL"
+	DefaultMaxOpenConns = 2
+)
+
+var (
+	journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	syncModes    = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// SQLiteOptions are connection pragmas and pool settings.
+// They change how the database file is accessed, not what is stored, so none are part of the schema checksum.
+type SQLiteOptions struct {
+	// How long a statement waits on a locked database, default DefaultBusyTimeout.
+	BusyTimeout time.Duration
+	// PRAGMA journal_mode, one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF. Default DefaultJournalMode.
+	JournalMode string
+	// PRAGMA synchronous, one of OFF, NORMAL, FULL, EXTRA. Empty keeps the SQLite default.
+	Synchronous string
+	// PRAGMA cache_size, pages if positive, KiB if negative. 0 keeps the SQLite default.
+	CacheSize int
+	// PRAGMA mmap_size in bytes. 0 keeps the SQLite default.
+	MmapSize int64
+	// Pool size, default DefaultMaxOpenConns. In-memory engines always use a single connection.
+	MaxOpenConns int
+	// Idle pool size, default MaxOpenConns.
+	MaxIdleConns int
+}
+
+func (o SQLiteOptions) withDefaults() SQLiteOptions {
+	if o.BusyTimeout == 0 {
+		o.BusyTimeout = DefaultBusyTimeout
+	}
+	if o.JournalMode == "" {
+		o.JournalMode = DefaultJournalMode
+	}
+	o.JournalMode = strings.ToUpper(o.JournalMode)
+	o.Synchronous = strings.ToUpper(o.Synchronous)
+	if o.MaxOpenConns == 0 {
+		o.MaxOpenConns = DefaultMaxOpenConns
+	}
+	if o.MaxIdleConns == 0 {
+		o.MaxIdleConns = o.MaxOpenConns
+	}
+	return o
+}
+
+// validate expects defaults to be filled in already.
+func (o SQLiteOptions) validate() error {
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("ftsengine: negative busy timeout %v", o.BusyTimeout)
+	}
+	if !slices.Contains(journalModes, o.JournalMode) {
+		return fmt.Errorf("ftsengine: unknown journal mode %q", o.JournalMode)
+	}
+	if o.Synchronous != "" && !slices.Contains(syncModes, o.Synchronous) {
+		return fmt.Errorf("ftsengine: unknown synchronous mode %q", o.Synchronous)
+	}
+	if o.MmapSize < 0 {
+		return fmt.Errorf("ftsengine: negative mmap size %d", o.MmapSize)
+	}
+	if o.MaxOpenConns < 0 || o.MaxIdleConns < 0 {
+		return fmt.Errorf("ftsengine: negative connection limit %d/%d", o.MaxOpenConns, o.MaxIdleConns)
+	}
+	if o.MaxIdleConns > o.MaxOpenConns {
+		return fmt.Errorf("ftsengine: MaxIdleConns %d above MaxOpenConns %d", o.MaxIdleConns, o.MaxOpenConns)
+	}
+	return nil
+}
+
+// pragmas returns the PRAGMA statements, without the keyword, to run on every new connection.
+func (o SQLiteOptions) pragmas() []string {
+	p := []string{
+		"busy_timeout(" + strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10) + ")",
+		"journal_mode(" + o.JournalMode + ")",
+	}
+	if o.Synchronous != "" {
+		p = append(p, "synchronous("+o.Synchronous+")")
+	}
+	if o.CacheSize != 0 {
+		p = append(p, "cache_size("+strconv.Itoa(o.CacheSize)+")")
+	}
+	if o.MmapSize != 0 {
+		p = append(p, "mmap_size("+strconv.FormatInt(o.MmapSize, 10)+")")
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package ftsengine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSQLiteOptionsValidation(t *testing.T) {
+	cols := []Column{{Name: "title"}}
+	bad := []struct {
+		name string
+		opts SQLiteOptions
+	}{
+		{"unknown journal mode", SQLiteOptions{JournalMode: "fast"}},
+		{"unknown synchronous", SQLiteOptions{Synchronous: "sometimes"}},
+		{"negative busy timeout", SQLiteOptions{BusyTimeout: -time.Second}},
+		{"negative mmap", SQLiteOptions{MmapSize: -1}},
+		{"idle above open", SQLiteOptions{MaxOpenConns: 1, MaxIdleConns: 2}},
+		{"negative conns", SQLiteOptions{MaxOpenConns: -1}},
+	}
+	for _, tc := range bad {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(Config{BaseDir: MemoryDBBaseDir, Table: "t", Columns: cols, SQLite: tc.opts})
+			if err == nil {
+				t.Fatalf("expected validation error for %+v", tc.opts)
+			}
+		})
+	}
+
+	base := Config{BaseDir: MemoryDBBaseDir, Table: "t", Columns: cols}
+	tuned := base
+	tuned.SQLite = SQLiteOptions{JournalMode: "delete", Synchronous: "full", CacheSize: -4096}
+	if schemaChecksum(base, tokenizerOptions) != schemaChecksum(tuned, tokenizerOptions) {
+		t.Fatal("connection options must not change the schema checksum")
+	}
+}
+
+func TestSQLiteOptionsApplied(t *testing.T) {
+	ctx := t.Context()
+	e, err := NewEngine(Config{
+		BaseDir:    t.TempDir(),
+		DBFileName: "fts.sqlite",
+		Table:      "docs",
+		Columns:    []Column{{Name: "title"}},
+		SQLite: SQLiteOptions{
+			BusyTimeout:  1500 * time.Millisecond,
+			JournalMode:  "truncate",
+			Synchronous:  "normal",
+			CacheSize:    -2048,
+			MmapSize:     1 << 20,
+			MaxOpenConns: 1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	t.Cleanup(func() { _ = e.Close() })
+
+	tests := []struct {
+		pragma string
+		want   string
+	}{
+		{"busy_timeout", "1500"},
+		{"journal_mode", "truncate"},
+		// NORMAL.
+		{"synchronous", "1"},
+		{"cache_size", "-2048"},
+		{"mmap_size", "1048576"},
+	}
+	for _, tc := range tests {
+		var got string
+		if err := e.db.QueryRowContext(ctx, "PRAGMA "+tc.pragma).Scan(&got); err != nil {
+			t.Fatalf("PRAGMA %s: %v", tc.pragma, err)
+		}
+		if got != tc.want {
+			t.Errorf("PRAGMA %s = %q, want %q", tc.pragma, got, tc.want)
+		}
+	}
+	if got := e.db.Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("MaxOpenConnections = %d, want 1", got)
+	}
+
+	// The engine still works with a single connection.
+	if err := e.Upsert(ctx, "a", map[string]string{"title": "hello world"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if hits, _, err := e.Search(ctx, "hello", "", 10); err != nil || len(hits) != 1 {
+		t.Fatalf("Search: %v %v", hits, err)
+	}
+}
@@ -0,0 +1,146 @@
+package ftsengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StructTagKey is the struct tag read by ColumnsFromStruct, e.g. `fts:"title,weight=2"` or `fts:"raw,unindexed"`.
+// Fields without the tag are skipped, "-" skips explicitly.
+const StructTagKey = "fts"
+
+// ColumnsFromStruct derives Columns from the fts tags of T, which must be a struct or a pointer to one.
+// A tag with an empty name uses the lower cased field name.
+func ColumnsFromStruct[T any]() ([]Column, error) {
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]Column, 0, len(fields))
+	for _, f := range fields {
+		cols = append(cols, f.col)
+	}
+	return cols, nil
+}
+
+// UpsertStruct upserts v using the columns derived from its fts tags.
+// Field values are converted with StructValues.
+func UpsertStruct[T any](ctx context.Context, e *Engine, id string, v T) error {
+	vals, err := StructValues(v)
+	if err != nil {
+		return err
+	}
+	return e.Upsert(ctx, id, vals)
+}
+
+// StructValues returns the column values of v keyed by column name.
+// Strings are used as is, []string is space joined, fmt.Stringer and other scalars are formatted with fmt.
+func StructValues[T any](v T) (map[string]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errors.New("ftsengine: nil struct pointer")
+		}
+		rv = rv.Elem()
+	}
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string, len(fields))
+	for _, f := range fields {
+		vals[f.col.Name] = fieldString(rv.FieldByIndex(f.index))
+	}
+	return vals, nil
+}
+
+type structField struct {
+	col   Column
+	index []int
+}
+
+func structFields(t reflect.Type) ([]structField, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ftsengine: %s is not a struct", t)
+	}
+	var (
+		out  []structField
+		seen = map[string]bool{}
+	)
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup(StructTagKey)
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		col, err := parseColumnTag(sf.Name, tag)
+		if err != nil {
+			return nil, fmt.Errorf("ftsengine: field %s: %w", sf.Name, err)
+		}
+		if seen[col.Name] {
+			return nil, fmt.Errorf("ftsengine: duplicate column %q", col.Name)
+		}
+		seen[col.Name] = true
+		out = append(out, structField{col: col, index: sf.Index})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("ftsengine: %s has no fields tagged %q", t, StructTagKey)
+	}
+	return out, nil
+}
+
+func parseColumnTag(fieldName, tag string) (Column, error) {
+	parts := strings.Split(tag, ",")
+	col := Column{Name: strings.TrimSpace(parts[0])}
+	if col.Name == "" {
+		col.Name = strings.ToLower(fieldName)
+	}
+	for _, opt := range parts[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "unindexed":
+			col.Unindexed = true
+		case strings.HasPrefix(opt, "weight="):
+			w, err := strconv.ParseFloat(strings.TrimPrefix(opt, "weight="), 64)
+			if err != nil || w < 0 {
+				return Column{}, fmt.Errorf("invalid weight %q", opt)
+			}
+			col.Weight = w
+		case opt == "":
+		default:
+			return Column{}, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return col, nil
+}
+
+func fieldString(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			parts := make([]string, v.Len())
+			for i := range parts {
+				parts[i] = v.Index(i).String()
+			}
+			return strings.Join(parts, " ")
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
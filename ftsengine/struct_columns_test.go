@@ -0,0 +1,92 @@
+package ftsengine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type testArticle struct {
+	Title    string   `fts:"title"`
+	Body     string   `fts:"body,weight=5"`
+	Tags     []string `fts:",weight=2"`
+	Path     string   `fts:"path,unindexed"`
+	Internal string   `fts:"-"`
+	Untagged string
+}
+
+func TestColumnsFromStruct(t *testing.T) {
+	cols, err := ColumnsFromStruct[testArticle]()
+	if err != nil {
+		t.Fatalf("ColumnsFromStruct: %v", err)
+	}
+	want := []Column{
+		{Name: "title"},
+		{Name: "body", Weight: 5},
+		{Name: "tags", Weight: 2},
+		{Name: "path", Unindexed: true},
+	}
+	if !reflect.DeepEqual(cols, want) {
+		t.Fatalf("got %+v, want %+v", cols, want)
+	}
+
+	if _, err := ColumnsFromStruct[*testArticle](); err != nil {
+		t.Fatalf("pointer type: %v", err)
+	}
+	type badWeight struct {
+		A string `fts:"a,weight=x"`
+	}
+	type dup struct {
+		A string `fts:"a"`
+		B string `fts:"a"`
+	}
+	type none struct{ A string }
+	for name, fn := range map[string]func() error{
+		"bad weight": func() error { _, err := ColumnsFromStruct[badWeight](); return err },
+		"duplicate":  func() error { _, err := ColumnsFromStruct[dup](); return err },
+		"no tags":    func() error { _, err := ColumnsFromStruct[none](); return err },
+		"not struct": func() error { _, err := ColumnsFromStruct[string](); return err },
+	} {
+		if fn() == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestUpsertStruct(t *testing.T) {
+	cols, err := ColumnsFromStruct[testArticle]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEngine(Config{BaseDir: t.TempDir(), DBFileName: "fts.sqlite", Table: "articles", Columns: cols})
+	if err != nil {
+		t.Fatalf("engine init: %v", err)
+	}
+	defer e.Close()
+
+	ctx := context.Background()
+	doc := testArticle{
+		Title: "golang generics",
+		Body:  "type parameters explained",
+		Tags:  []string{"tutorial", "advanced"},
+		Path:  "/docs/generics.md",
+	}
+	if err := UpsertStruct(ctx, e, "a1", &doc); err != nil {
+		t.Fatalf("UpsertStruct: %v", err)
+	}
+	hits, _, err := e.Search(ctx, "advanced", "", 10)
+	if err != nil || len(hits) != 1 || hits[0].ID != "a1" {
+		t.Fatalf("search by tag: hits %v err %v", hits, err)
+	}
+
+	rows, _, err := e.BatchList(ctx, "", nil, "", 10)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("BatchList: rows %v err %v", rows, err)
+	}
+	if got := rows[0].Values["tags"]; got != "tutorial advanced" {
+		t.Fatalf("tags column: got %q", got)
+	}
+	if got := rows[0].Values["path"]; got != doc.Path {
+		t.Fatalf("path column: got %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+package ftsengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SyncOption configures SyncDirToFTS and SyncIterToFTS.
+type SyncOption func(*syncOptions)
+
+type syncOptions struct {
+	newestFirst bool
+	extraCmp    []string
+	errPolicy   ErrorPolicy
+	include     []string
+	exclude     []string
+	walk        walkOptions
+}
+
+// WithCompareColumns adds compare columns next to the compareColumn argument, e.g. "size" next to "mtime", or a
+// content hash, for changes that keep the mtime (rsync -a). Each value is stored in its own column.
+// Producers set SyncDecision.CmpValues, and getPrev returns CompareKey of the stored values, so a document is
+// unchanged if getPrev(id) == CompareKey(newValues).
+func WithCompareColumns(cols ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.extraCmp = append(o.extraCmp, cols...)
+	}
+}
+
+// CompareKey encodes compare values keyed by column into one canonical string, independent of map order.
+func CompareKey(values map[string]string) string {
+	// Map keys are marshaled sorted.
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// WithNewestFirst makes SyncDirToFTS process files by modification time, newest first, instead of in lexical
+// walk order. Recently modified files then become searchable with the first batches of a long backfill.
+// The directory is walked once up front to collect the modification times.
+func WithNewestFirst() SyncOption {
+	return func(o *syncOptions) {
+		o.newestFirst = true
+	}
+}
+
+// ErrorPolicy decides what SyncDirToFTS does when processFile fails for a file, or a directory cannot be read.
+// The zero value is FailFast.
+type ErrorPolicy struct {
+	// Retries is the number of extra processFile attempts before a file counts as failed.
+	// Context errors are never retried.
+	Retries int
+	// Collect skips failed files and directories instead of aborting the sync. Their errors are reported in
+	// SyncReport.Errors and their existing rows are kept.
+	Collect bool
+}
+
+// FailFast aborts the sync on the first error. This is the default.
+func FailFast() ErrorPolicy { return ErrorPolicy{} }
+
+// SkipAndCollect skips failed files and reports them in SyncReport.Errors.
+func SkipAndCollect() ErrorPolicy { return ErrorPolicy{Collect: true} }
+
+// RetryN retries a failing file n times before aborting. Set Collect on the result to skip it instead.
+func RetryN(n int) ErrorPolicy { return ErrorPolicy{Retries: n} }
+
+// WithErrorPolicy sets how SyncDirToFTS handles per file errors, so that one unreadable file does not stop the
+// indexing of all others.
+func WithErrorPolicy(p ErrorPolicy) SyncOption {
+	return func(o *syncOptions) {
+		o.errPolicy = p
+	}
+}
+
+// WithExclude leaves files and directories matching any of the glob patterns out of SyncDirToFTS, e.g. ".git",
+// "node_modules" or "*.tmp-*". Excluded directories are not descended into. Rows of excluded files are deleted
+// like those of removed files.
+// A pattern without a slash is matched against the base name, one with a slash against the slash separated path
+// relative to baseDir (path.Match syntax, no "**").
+func WithExclude(patterns ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithInclude restricts SyncDirToFTS to files matching any of the glob patterns, e.g. "*.md". Directories are
+// always descended into unless excluded. Patterns are matched like in WithExclude.
+func WithInclude(patterns ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithFollowSymlinks makes SyncDirToFTS follow symlinks to files and directories. Files are indexed under the
+// link path. Links back into an ancestor directory are not followed, so link cycles cannot loop the walk.
+// A directory reachable through several links is walked once per path.
+func WithFollowSymlinks() SyncOption {
+	return func(o *syncOptions) {
+		o.walk.followSymlinks = true
+	}
+}
+
+// WithSameFilesystem keeps SyncDirToFTS on the filesystem of baseDir, like find -xdev: directories of other
+// mounts are not descended into. It has no effect on platforms without device ids.
+func WithSameFilesystem() SyncOption {
+	return func(o *syncOptions) {
+		o.walk.sameDevice = true
+	}
+}
+
+// WithMaxDepth limits SyncDirToFTS to files at most depth directories below baseDir; 1 means the direct children
+// only. Zero or less means no limit.
+func WithMaxDepth(depth int) SyncOption {
+	return func(o *syncOptions) {
+		o.walk.maxDepth = depth
+	}
+}
+
+func (o *syncOptions) validatePatterns() error {
+	for _, pattern := range slices.Concat(o.include, o.exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("ftsengine: invalid sync pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// filteredOut reports whether the walk skips p below baseDir.
+func (o *syncOptions) filteredOut(baseDir, p string, isDir bool) bool {
+	if len(o.include) == 0 && len(o.exclude) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(baseDir, p)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if matchAnyPattern(o.exclude, rel) {
+		return true
+	}
+	return !isDir && len(o.include) != 0 && !matchAnyPattern(o.include, rel)
+}
+
+func matchAnyPattern(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			name = rel
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
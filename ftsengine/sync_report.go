@@ -0,0 +1,51 @@
+package ftsengine
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SyncReport summarizes one SyncDirToFTS or SyncIterToFTS run.
+// On error it holds the counts up to the failure.
+type SyncReport struct {
+	// Documents emitted by the producer, changed or not. Skipped ones are not counted.
+	Processed int
+	// Documents written to the index.
+	Upserted int
+	// Documents whose compare value was up to date.
+	Unchanged int
+	// Decisions with Skip set or without an ID.
+	Skipped int
+	// Rows removed because the producer no longer emitted them.
+	Deleted int
+	// Files that failed without aborting the run, see WithErrorPolicy and SyncDecision.Err.
+	Errors []FileError
+	// Wall time of the run.
+	Duration time.Duration
+}
+
+// FileError is the error of a single file (or producer id) during a sync.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+func (r SyncReport) logAttrs() []any {
+	return []any{
+		slog.Duration("took", r.Duration),
+		slog.Int("processed", r.Processed),
+		slog.Int("upserted", r.Upserted),
+		slog.Int("unchanged", r.Unchanged),
+		slog.Int("skipped", r.Skipped),
+		slog.Int("deleted", r.Deleted),
+		slog.Int("errors", len(r.Errors)),
+	}
+}
@@ -16,6 +16,10 @@ type SearchResult struct {
 	ID string
 	// Bm25.
 	Score float64
+	// Number of hits collapsed into this one by WithGroupBy, 0 without grouping.
+	GroupSize int
+	// Per column scores, one per indexed column, only set with WithExplain.
+	Explain []ColumnScore
 }
 
 // ListResult is returned by BatchList().
@@ -25,6 +29,13 @@ type ListResult struct {
 	Values map[string]string
 }
 
+// OrderBy is one ORDER BY term of BatchListOrdered.
+type OrderBy struct {
+	// Column name or ColNameRowID.
+	Column string
+	Desc   bool
+}
+
 // Column declares one FTS5 column.
 type Column struct {
 	// SQL identifier.
@@ -40,6 +51,20 @@ type Config struct {
 	DBFileName string   `json:"dbFileName"`
 	Table      string   `json:"table"`
 	Columns    []Column `json:"columns"`
+	// Normalization of external ids. It changes stored ids, so it is part of the schema checksum.
+	NormalizeID IDNormalization `json:"normalizeID,omitempty"`
+	// Page and statement size bounds, zero values take the defaults.
+	Limits Limits `json:"-"`
+	// Connection pragmas and pool settings, zero values take the defaults.
+	SQLite SQLiteOptions `json:"-"`
+	// Optional hook run on every upsert, the result is stored in the managed ColNameLang column.
+	DetectLanguage LanguageDetector `json:"-"`
+	// Number of search result pages kept in an LRU cache, cleared on every write. 0 disables the cache.
+	SearchCacheSize int `json:"-"`
+	// Upserts fail with ErrIndexFull once the database uses this many bytes, 0 means no limit.
+	MaxDBSizeBytes int64 `json:"-"`
+	// Optional pruning hook, run before a write would fail with ErrIndexFull.
+	OnIndexFull IndexFullFunc `json:"-"`
 }
 
 type sqlExec interface {
@@ -0,0 +1,130 @@
+package ftsengine
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// walkOptions tune the directory walk of SyncDirToFTS, see WithFollowSymlinks, WithSameFilesystem and WithMaxDepth.
+type walkOptions struct {
+	followSymlinks bool
+	sameDevice     bool
+	maxDepth       int
+}
+
+// walkTree is filepath.WalkDir with the walkOptions applied. It calls fn like WalkDir does, in lexical order,
+// and honors filepath.SkipDir and filepath.SkipAll.
+// Followed symlinks are reported with the entry of their target under the link path. Broken links, links back
+// into an ancestor directory and vanished entries are left out.
+func walkTree(root string, o walkOptions, fn fs.WalkDirFunc) error {
+	info, err := os.Lstat(root)
+	if err == nil && o.followSymlinks && info.Mode()&fs.ModeSymlink != 0 {
+		info, err = os.Stat(root)
+	}
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		w := treeWalker{opts: o, fn: fn, root: info}
+		err = w.walk(root, fs.FileInfoToDirEntry(info), info, 0, nil)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+type treeWalker struct {
+	opts walkOptions
+	fn   fs.WalkDirFunc
+	root fs.FileInfo
+}
+
+func (w *treeWalker) walk(p string, d fs.DirEntry, info fs.FileInfo, depth int, ancestors []fs.FileInfo) error {
+	if err := w.fn(p, d, nil); err != nil || !d.IsDir() {
+		if d.IsDir() && errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+	if w.opts.maxDepth > 0 && depth >= w.opts.maxDepth {
+		return nil
+	}
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		if err := w.fn(p, d, err); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				return nil
+			}
+			return err
+		}
+		// As in WalkDir, the entries read before the error are still visited.
+	}
+	ancestors = append(ancestors, info)
+	for _, e := range entries {
+		cp := filepath.Join(p, e.Name())
+		cd, cinfo, ok, err := w.resolve(cp, e, ancestors)
+		if err != nil {
+			if err := w.fn(cp, e, err); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					continue
+				}
+				return err
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := w.walk(cp, cd, cinfo, depth+1, ancestors); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				// Returned for a file: skip the rest of this directory.
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the entry to visit for e, and its info for directories. Ok is false for entries that are
+// left out.
+func (w *treeWalker) resolve(p string, e fs.DirEntry, ancestors []fs.FileInfo) (fs.DirEntry, fs.FileInfo, bool, error) {
+	isLink := e.Type()&fs.ModeSymlink != 0
+	if isLink && !w.opts.followSymlinks {
+		return e, nil, true, nil
+	}
+	if !isLink && !e.IsDir() {
+		return e, nil, true, nil
+	}
+	var (
+		info fs.FileInfo
+		err  error
+	)
+	if isLink {
+		info, err = os.Stat(p)
+	} else {
+		info, err = e.Info()
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !info.IsDir() {
+		return fs.FileInfoToDirEntry(info), nil, true, nil
+	}
+	if w.opts.sameDevice && !sameDevice(w.root, info) {
+		return nil, nil, false, nil
+	}
+	if isLink {
+		for _, a := range ancestors {
+			if os.SameFile(a, info) {
+				return nil, nil, false, nil
+			}
+		}
+	}
+	return fs.FileInfoToDirEntry(info), info, true, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package ftsengine
+
+import "io/fs"
+
+// sameDevice always reports true, device ids are not available on this platform.
+func sameDevice(_, _ fs.FileInfo) bool {
+	return true
+}
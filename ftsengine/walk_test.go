@@ -0,0 +1,98 @@
+package ftsengine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalkTree(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	for _, name := range []string{"docs/a.md", "docs/sub/b.md", "docs/sub/deep/c.md", "outside/o.md"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"loop":      docs,
+		"sub/up":    filepath.Join(docs, "sub"),
+		"ext":       filepath.Join(dir, "outside"),
+		"link.md":   filepath.Join(dir, "outside", "o.md"),
+		"broken.md": filepath.Join(dir, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(docs, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	files := func(o walkOptions, skip string) []string {
+		t.Helper()
+		var got []string
+		err := walkTree(docs, o, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(docs, p)
+			rel = filepath.ToSlash(rel)
+			if d.IsDir() {
+				if rel == skip {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			got = append(got, rel)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts walkOptions
+		skip string
+		want []string
+	}{
+		{
+			name: "defaults report links as files",
+			want: []string{"a.md", "broken.md", "ext", "link.md", "loop", "sub/b.md", "sub/deep/c.md", "sub/up"},
+		},
+		{
+			name: "follow skips cycles and broken links",
+			opts: walkOptions{followSymlinks: true},
+			want: []string{"a.md", "ext/o.md", "link.md", "sub/b.md", "sub/deep/c.md"},
+		},
+		{
+			name: "max depth",
+			opts: walkOptions{maxDepth: 2},
+			want: []string{"a.md", "broken.md", "ext", "link.md", "loop", "sub/b.md", "sub/up"},
+		},
+		{
+			name: "same filesystem",
+			opts: walkOptions{followSymlinks: true, sameDevice: true, maxDepth: 1},
+			want: []string{"a.md", "link.md"},
+		},
+		{
+			name: "skip dir",
+			opts: walkOptions{followSymlinks: true},
+			skip: "sub",
+			want: []string{"a.md", "ext/o.md", "link.md"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := files(tc.opts, tc.skip); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package ftsengine
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// sameDevice reports whether a and b are on the same filesystem.
+func sameDevice(a, b fs.FileInfo) bool {
+	sa, okA := a.Sys().(*syscall.Stat_t)
+	sb, okB := b.Sys().(*syscall.Stat_t)
+	if !okA || !okB {
+		return true
+	}
+	return sa.Dev == sb.Dev
+}
@@ -0,0 +1,50 @@
+package gzipencdec
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+// GzipEncoderDecoder compresses the output of another encoder, e.g. JSON for ".json.gz" files.
+type GzipEncoderDecoder struct {
+	Inner mapstore.IOEncoderDecoder
+}
+
+// Encode encodes value with the inner encoder and writes it gzip compressed.
+func (d GzipEncoderDecoder) Encode(w io.Writer, value any) error {
+	if w == nil {
+		return errors.New("writer cannot be nil")
+	}
+	if d.Inner == nil {
+		return errors.New("inner encoder cannot be nil")
+	}
+	zw := gzip.NewWriter(w)
+	if err := d.Inner.Encode(zw, value); err != nil {
+		_ = zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress value: %w", err)
+	}
+	return nil
+}
+
+// Decode decompresses the reader and decodes it with the inner decoder.
+func (d GzipEncoderDecoder) Decode(r io.Reader, value any) error {
+	if r == nil {
+		return errors.New("reader cannot be nil")
+	}
+	if d.Inner == nil {
+		return errors.New("inner decoder cannot be nil")
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer zr.Close()
+	return d.Inner.Decode(zr, value)
+}
@@ -0,0 +1,40 @@
+package gzipencdec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestGzipEncoderDecoder_RoundTrip(t *testing.T) {
+	codec := GzipEncoderDecoder{Inner: jsonencdec.JSONEncoderDecoder{}}
+	in := map[string]any{"k": "v", "n": float64(1)}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, in); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0x1f, 0x8b}) {
+		t.Fatalf("output is not gzip: %x", buf.Bytes()[:2])
+	}
+	var out map[string]any
+	if err := codec.Decode(&buf, &out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch: got %v, want %v", out, in)
+	}
+}
+
+func TestGzipEncoderDecoder_Errors(t *testing.T) {
+	codec := GzipEncoderDecoder{Inner: jsonencdec.JSONEncoderDecoder{}}
+	var out map[string]any
+	if err := codec.Decode(bytes.NewReader([]byte(`{"plain":"json"}`)), &out); err == nil {
+		t.Fatal("want error for non gzip input")
+	}
+	if err := (GzipEncoderDecoder{}).Encode(&bytes.Buffer{}, 1); err == nil {
+		t.Fatal("want error for missing inner encoder")
+	}
+}
@@ -0,0 +1,203 @@
+// Package faultfs is a mapstore.FileSystem for tests that injects faults into the operating system file
+// system: I/O errors, short writes, failed renames and slow stats, each with a configurable probability.
+// Faults are drawn from a seeded source, so a failing run can be repeated with its seed.
+package faultfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+// ErrInjected is matched by every injected error.
+var ErrInjected = errors.New("injected fault")
+
+// Config sets the probability, from 0 to 1, of each fault per call.
+type Config struct {
+	// EIO fails Open, Create, OpenFile, Write, Stat, Chmod, Remove, RemoveAll and MkdirAll with syscall.EIO.
+	EIO float64
+	// ShortWrite makes a Write store only part of its buffer and fail with io.ErrShortWrite.
+	ShortWrite float64
+	// RenameFailure fails Rename, leaving both paths as they were.
+	RenameFailure float64
+	// SlowStat delays Stat by StatDelay.
+	SlowStat  float64
+	StatDelay time.Duration
+	// Seed seeds the fault source.
+	Seed uint64
+}
+
+// Stats counts the injected faults. Removes counts the EIO faults of Remove and RemoveAll, each of which may
+// leave a file behind, e.g. a temporary file of a failed flush.
+type Stats struct {
+	EIO, ShortWrites, RenameFailures, SlowStats, Removes uint64
+}
+
+var _ mapstore.FileSystem = (*FS)(nil)
+
+// FS injects faults into the operating system file system. It is safe for concurrent use.
+type FS struct {
+	cfg      Config
+	disabled atomic.Bool
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+
+	eio, shortWrites, renameFailures, slowStats, removes atomic.Uint64
+}
+
+// New returns a file system injecting the faults of cfg.
+func New(cfg Config) *FS {
+	return &FS{cfg: cfg, rnd: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))}
+}
+
+// SetEnabled turns fault injection on or off, e.g. to check the state on disk after a run.
+func (f *FS) SetEnabled(enabled bool) {
+	f.disabled.Store(!enabled)
+}
+
+// Stats returns how many faults were injected.
+func (f *FS) Stats() Stats {
+	return Stats{
+		EIO:            f.eio.Load(),
+		ShortWrites:    f.shortWrites.Load(),
+		RenameFailures: f.renameFailures.Load(),
+		SlowStats:      f.slowStats.Load(),
+		Removes:        f.removes.Load(),
+	}
+}
+
+// hit reports whether a fault of probability p is injected.
+func (f *FS) hit(p float64) bool {
+	if p <= 0 || f.disabled.Load() {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < p
+}
+
+// injectEIO returns an injected EIO error for op on name, or nil.
+func (f *FS) injectEIO(op, name string) error {
+	if !f.hit(f.cfg.EIO) {
+		return nil
+	}
+	f.eio.Add(1)
+	return &os.PathError{Op: op, Path: name, Err: errors.Join(syscall.EIO, ErrInjected)}
+}
+
+// Open implements mapstore.FileSystem.
+func (f *FS) Open(name string) (io.ReadCloser, error) {
+	if err := f.injectEIO("open", name); err != nil {
+		return nil, err
+	}
+	return os.Open(name)
+}
+
+// Create implements mapstore.FileSystem.
+func (f *FS) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	if err := f.injectEIO("open", name); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+// OpenFile implements mapstore.FileSystem.
+func (f *FS) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	if err := f.injectEIO("open", name); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+// Rename implements mapstore.FileSystem.
+func (f *FS) Rename(oldpath, newpath string) error {
+	if f.hit(f.cfg.RenameFailure) {
+		f.renameFailures.Add(1)
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrInjected}
+	}
+	return os.Rename(oldpath, newpath)
+}
+
+// Stat implements mapstore.FileSystem.
+func (f *FS) Stat(name string) (os.FileInfo, error) {
+	if f.hit(f.cfg.SlowStat) {
+		f.slowStats.Add(1)
+		time.Sleep(f.cfg.StatDelay)
+	}
+	if err := f.injectEIO("stat", name); err != nil {
+		return nil, err
+	}
+	return os.Stat(name)
+}
+
+// Chmod implements mapstore.FileSystem.
+func (f *FS) Chmod(name string, mode os.FileMode) error {
+	if err := f.injectEIO("chmod", name); err != nil {
+		return err
+	}
+	return os.Chmod(name, mode)
+}
+
+// Remove implements mapstore.FileSystem.
+func (f *FS) Remove(name string) error {
+	if err := f.injectEIO("remove", name); err != nil {
+		f.removes.Add(1)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// RemoveAll implements mapstore.FileSystem.
+func (f *FS) RemoveAll(path string) error {
+	if err := f.injectEIO("removeall", path); err != nil {
+		f.removes.Add(1)
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// MkdirAll implements mapstore.FileSystem.
+func (f *FS) MkdirAll(path string, perm os.FileMode) error {
+	if err := f.injectEIO("mkdir", path); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// faultFile injects faults into the writes of a created file.
+type faultFile struct {
+	*os.File
+	fs *FS
+}
+
+func (w *faultFile) Write(p []byte) (int, error) {
+	if err := w.fs.injectEIO("write", w.Name()); err != nil {
+		return 0, err
+	}
+	if len(p) > 1 && w.fs.hit(w.fs.cfg.ShortWrite) {
+		w.fs.shortWrites.Add(1)
+		n, err := w.File.Write(p[:len(p)/2])
+		if err != nil {
+			return n, err
+		}
+		return n, fmt.Errorf("%w: %w", io.ErrShortWrite, ErrInjected)
+	}
+	return w.File.Write(p)
+}
@@ -0,0 +1,64 @@
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFaults(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "f")
+	fs := New(Config{EIO: 1})
+	if _, err := fs.Create(p, 0o600); !errors.Is(err, syscall.EIO) || !errors.Is(err, ErrInjected) {
+		t.Fatalf("Create = %v, want injected EIO", err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Fatalf("failed Create created the file: %v", err)
+	}
+
+	fs = New(Config{ShortWrite: 1, RenameFailure: 1})
+	w, err := fs.Create(p, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := w.Write([]byte("abcd"))
+	if n != 2 || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Write = %d, %v; want a short write", n, err)
+	}
+	w.Close()
+	if err := fs.Rename(p, p+".new"); !errors.Is(err, ErrInjected) {
+		t.Fatalf("Rename = %v, want injected failure", err)
+	}
+	if got := fs.Stats(); got.ShortWrites != 1 || got.RenameFailures != 1 || got.EIO != 0 {
+		t.Fatalf("stats = %+v", got)
+	}
+
+	fs.SetEnabled(false)
+	if err := fs.Rename(p, p+".new"); err != nil {
+		t.Fatalf("Rename with faults disabled: %v", err)
+	}
+	if _, err := fs.Stat(p + ".new"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeedRepeatsFaults(t *testing.T) {
+	draw := func() []bool {
+		fs := New(Config{RenameFailure: 0.5, Seed: 42})
+		out := make([]bool, 32)
+		for i := range out {
+			out[i] = fs.hit(fs.cfg.RenameFailure)
+		}
+		return out
+	}
+	a, b := draw(), draw()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("draw %d differs between runs with the same seed", i)
+		}
+	}
+}
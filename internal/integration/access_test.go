@@ -0,0 +1,217 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+type accessCall struct {
+	op   mapstore.Operation
+	keys []string
+}
+
+// accessRecorder denies all writes except to keys below "public" and records every call.
+type accessRecorder struct {
+	mu    sync.Mutex
+	calls []accessCall
+}
+
+func (r *accessRecorder) check(_ context.Context, op mapstore.Operation, _ string, keys []string) error {
+	r.mu.Lock()
+	r.calls = append(r.calls, accessCall{op: op, keys: keys})
+	r.mu.Unlock()
+	switch op {
+	case mapstore.OpGetFile, mapstore.OpGetKey, mapstore.OpListFiles:
+		return nil
+	case mapstore.OpSetKey, mapstore.OpDeleteKey:
+		if len(keys) > 0 && keys[0] == "public" {
+			return nil
+		}
+	}
+	return mapstore.ErrAccessDenied
+}
+
+func (r *accessRecorder) ops() []mapstore.Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]mapstore.Operation, 0, len(r.calls))
+	for _, c := range r.calls {
+		out = append(out, c.op)
+	}
+	return out
+}
+
+func TestMapFileStore_AccessControl(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "acl.json")
+	rec := &accessRecorder{}
+	// Creating a file is a write, so start from an existing one.
+	if err := os.WriteFile(p, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st := openStore(p, mapstore.WithAccessControl(rec.check))
+
+	if err := st.SetKey([]string{"public", "name"}, "x"); err != nil {
+		t.Fatalf("allowed SetKey: %v", err)
+	}
+	if v, err := st.GetKey([]string{"public", "name"}); err != nil || v != "x" {
+		t.Fatalf("GetKey: got %v err %v", v, err)
+	}
+
+	denied := map[string]func() error{
+		"SetKey":     func() error { return st.SetKey([]string{"secret"}, "x") },
+		"DeleteKey":  func() error { return st.DeleteKey([]string{"secret"}) },
+		"SetAll":     func() error { return st.SetAll(map[string]any{}) },
+		"Reset":      st.Reset,
+		"DeleteFile": st.DeleteFile,
+		"Increment": func() error {
+			_, err := st.Increment([]string{"counter"}, 1)
+			return err
+		},
+	}
+	for name, fn := range denied {
+		if err := fn(); !errors.Is(err, mapstore.ErrAccessDenied) {
+			t.Errorf("%s: want ErrAccessDenied, got %v", name, err)
+		}
+	}
+
+	// Denied writes never reach the disk.
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("file removed despite denied delete: %v", err)
+	}
+	all, err := st.GetAll(false)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if _, ok := all["secret"]; ok {
+		t.Fatalf("denied key written: %v", all)
+	}
+	if got := rec.ops(); !slices.Contains(got, mapstore.OpGetKey) || !slices.Contains(got, mapstore.OpGetFile) {
+		t.Fatalf("read operations not checked: %v", got)
+	}
+}
+
+func TestMapDirectoryStore_AccessControl(t *testing.T) {
+	base := t.TempDir()
+	deny := errors.New("tenant denied")
+	checker := func(_ context.Context, op mapstore.Operation, file string, _ []string) error {
+		if op == mapstore.OpListFiles || filepath.Base(file) == "other.json" {
+			return deny
+		}
+		return nil
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+	)
+	if err != nil {
+		t.Fatalf("dir store: %v", err)
+	}
+
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "mine.json"}, map[string]any{"a": 1}); err != nil {
+		t.Fatalf("allowed SetFileData: %v", err)
+	}
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "other.json"}, map[string]any{"a": 1}); !errors.Is(err, deny) {
+		t.Fatalf("SetFileData: want deny, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "other.json")); !os.IsNotExist(err) {
+		t.Fatalf("denied file was created: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "other.json"), []byte(`{"a":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.GetFileData(mapstore.FileKey{FileName: "other.json"}, false); !errors.Is(err, deny) {
+		t.Fatalf("GetFileData: want deny, got %v", err)
+	}
+	if _, _, err := mds.ListFiles(mapstore.ListingConfig{}, ""); !errors.Is(err, deny) {
+		t.Fatalf("ListFiles: want deny, got %v", err)
+	}
+}
+
+func TestAccessControl_ReadsSeeContext(t *testing.T) {
+	base := t.TempDir()
+	// Only alice may read, anyone may write.
+	checker := func(ctx context.Context, op mapstore.Operation, _ string, _ []string) error {
+		switch op {
+		case mapstore.OpGetFile, mapstore.OpGetKey, mapstore.OpListFiles:
+			if mapstore.ActorFromContext(ctx) != "alice" {
+				return mapstore.ErrAccessDenied
+			}
+		}
+		return nil
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "doc.json"}
+	if err := mds.SetFileData(key, map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := mds.OpenFile(key, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reads := map[string]func(ctx context.Context) error{
+		"GetAllContext": func(ctx context.Context) error {
+			_, err := st.GetAllContext(ctx, false)
+			return err
+		},
+		"GetKeyContext": func(ctx context.Context) error {
+			_, err := st.GetKeyContext(ctx, []string{"a"})
+			return err
+		},
+		"QueryContext": func(ctx context.Context) error {
+			_, err := st.QueryContext(ctx, []string{"*"})
+			return err
+		},
+		"WalkContext": func(ctx context.Context) error {
+			return st.WalkContext(ctx, func([]string, any) error { return nil })
+		},
+		"SnapshotContext": func(ctx context.Context) error {
+			_, err := st.SnapshotContext(ctx)
+			return err
+		},
+		"ExportContext": func(ctx context.Context) error { return st.ExportContext(ctx, io.Discard) },
+		"GetFileDataContext": func(ctx context.Context) error {
+			_, err := mds.GetFileDataContext(ctx, key, false)
+			return err
+		},
+		"ListFilesContext": func(ctx context.Context) error {
+			_, _, err := mds.ListFilesContext(ctx, mapstore.ListingConfig{}, "")
+			return err
+		},
+		"ListPartitionsContext": func(ctx context.Context) error {
+			_, _, err := mds.ListPartitionsContext(ctx, base, "", "", 0)
+			return err
+		},
+		"ListDecodedContext": func(ctx context.Context) error {
+			_, _, err := mapstore.ListDecodedContext[map[string]any](ctx, mds, mapstore.ListDecodedConfig{}, "")
+			return err
+		},
+	}
+	alice := mapstore.ContextWithActor(t.Context(), "alice")
+	bob := mapstore.ContextWithActor(t.Context(), "bob")
+	for name, read := range reads {
+		if err := read(alice); err != nil {
+			t.Errorf("%s as alice: %v", name, err)
+		}
+		if err := read(bob); !errors.Is(err, mapstore.ErrAccessDenied) {
+			t.Errorf("%s as bob: want ErrAccessDenied, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_AddRemoveListener(t *testing.T) {
+	st := openStore(filepath.Join(t.TempDir(), "l.json"))
+	defer st.Close()
+
+	var got atomic.Int64
+	id := st.AddListener(func(mapstore.FileEvent) { got.Add(1) })
+	if err := st.SetKey([]string{"a"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !st.RemoveListener(id) || st.RemoveListener(id) {
+		t.Fatal("RemoveListener should succeed exactly once")
+	}
+	if err := st.SetKey([]string{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := got.Load(); n != 1 {
+		t.Fatalf("events = %d, want 1", n)
+	}
+
+	// Registration races with mutations, run with -race.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for j := range 20 {
+				id := st.AddListener(func(mapstore.FileEvent) {})
+				if err := st.SetKey([]string{fmt.Sprint(i)}, j); err != nil {
+					t.Error(err)
+				}
+				st.RemoveListener(id)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestMapDirectoryStore_AddRemoveListener(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	open := mapstore.FileKey{FileName: "open.json"}
+	if err := mds.SetFileData(open, map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var files []string
+	id := mds.AddListener(func(e mapstore.FileEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		files = append(files, filepath.Base(e.File))
+	})
+	later := mapstore.FileKey{FileName: "later.json"}
+	for _, key := range []mapstore.FileKey{open, later} {
+		if err := mds.SetFileData(key, map[string]any{"a": 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !mds.RemoveListener(id) {
+		t.Fatal("RemoveListener = false")
+	}
+	for _, key := range []mapstore.FileKey{open, later} {
+		if err := mds.SetFileData(key, map[string]any{"a": 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(files) != 2 || files[0] != "open.json" || files[1] != "later.json" {
+		t.Fatalf("events for %v", files)
+	}
+}
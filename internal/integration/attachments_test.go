@@ -0,0 +1,192 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/blobstore"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_Attachments(t *testing.T) {
+	t.Parallel()
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "chat.json"}
+
+	if err := mds.PutAttachment(key, "a.png", strings.NewReader("x")); err == nil {
+		t.Fatal("want error for an attachment of a missing file")
+	}
+	if err := mds.SetFileData(key, map[string]any{"title": "chat"}); err != nil {
+		t.Fatal(err)
+	}
+	png := bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0}, 1000)
+	if err := mds.PutAttachment(key, "a.png", bytes.NewReader(png)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PutAttachment(key, "b.txt", strings.NewReader("old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PutAttachment(key, "b.txt", strings.NewReader("new")); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"", "../x", "sub/x", ".hidden"} {
+		if err := mds.PutAttachment(key, bad, strings.NewReader("x")); err == nil {
+			t.Errorf("want error for attachment name %q", bad)
+		}
+	}
+
+	rc, err := mds.GetAttachment(key, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, png) {
+		t.Fatalf("read back %d bytes, err %v", len(got), err)
+	}
+
+	infos, err := mds.ListAttachments(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != "a.png" || infos[0].Size != int64(len(png)) ||
+		infos[1].Name != "b.txt" || infos[1].Size != 3 {
+		t.Fatalf("list: %+v", infos)
+	}
+
+	// The sidecar directory does not show up as a file.
+	entries, _, err := mds.ListFiles(mapstore.ListingConfig{PageSize: 10}, "")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("files %v, err %v", entries, err)
+	}
+
+	if err := mds.DeleteAttachment(key, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.GetAttachment(key, "b.txt"); !errors.Is(err, mapstore.ErrAttachmentNotFound) {
+		t.Fatalf("get deleted: %v", err)
+	}
+
+	if err := mds.DeleteFile(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "chat.json.attachments")); !os.IsNotExist(err) {
+		t.Fatalf("attachments left behind: %v", err)
+	}
+}
+
+func TestMapDirectoryStore_AttachmentAccess(t *testing.T) {
+	t.Parallel()
+	var seen []mapstore.Operation
+	checker := func(_ context.Context, op mapstore.Operation, _ string, keys []string) error {
+		seen = append(seen, op)
+		if op == mapstore.OpGetAttachment && len(keys) == 1 && keys[0] == "secret.bin" {
+			return mapstore.ErrAccessDenied
+		}
+		return nil
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "doc.json"}
+	if err := mds.SetFileData(key, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PutAttachment(key, "secret.bin", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.GetAttachment(key, "secret.bin"); !errors.Is(err, mapstore.ErrAccessDenied) {
+		t.Fatalf("want access denied, got %v", err)
+	}
+	if !slices.Contains(seen, mapstore.OpPutAttachment) {
+		t.Fatalf("put not checked: %v", seen)
+	}
+}
+
+func TestMapDirectoryStore_AttachmentBlobStore(t *testing.T) {
+	t.Parallel()
+	baseDir := t.TempDir()
+	blobs, err := blobstore.New(filepath.Join(baseDir, "blobs"), blobstore.WithGCGracePeriod(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blobs.Close()
+	mds, err := mapstore.NewMapDirectoryStore(
+		filepath.Join(baseDir, "docs"), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAttachmentBlobStore(blobs),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+
+	img := bytes.Repeat([]byte("image"), 500)
+	keys := []mapstore.FileKey{{FileName: "a.json"}, {FileName: "b.json"}}
+	for _, key := range keys {
+		if err := mds.SetFileData(key, map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := mds.PutAttachment(key, "img.png", bytes.NewReader(img)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	infos, err := mds.ListAttachments(keys[1])
+	if err != nil || len(infos) != 1 || infos[0].Size != int64(len(img)) {
+		t.Fatalf("list: %+v, %v", infos, err)
+	}
+	rc, err := mds.GetAttachment(keys[1], "img.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, img) {
+		t.Fatalf("read back %d bytes, err %v", len(got), err)
+	}
+
+	sum := sha256.Sum256(img)
+	digest := hex.EncodeToString(sum[:])
+	if n, err := blobs.RefCount(digest); err != nil || n != 2 {
+		t.Fatalf("refcount %d, err %v", n, err)
+	}
+
+	// Replacing and deleting release references; GC only collects once nothing refers to the blob.
+	if err := mds.PutAttachment(keys[0], "img.png", strings.NewReader("other")); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := blobs.GC(); n != 0 {
+		t.Fatalf("gc removed %d blobs still in use", n)
+	}
+	if err := mds.DeleteFile(keys[1]); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := blobs.RefCount(digest); err != nil || n != 0 {
+		t.Fatalf("refcount after delete %d, err %v", n, err)
+	}
+	if n, err := blobs.GC(); err != nil || n != 1 {
+		t.Fatalf("gc removed %d, err %v", n, err)
+	}
+}
@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_CompareAndSwapKey(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "cas.json")
+	s := openStore(p)
+	defer s.Close()
+
+	// A nil old value creates the key.
+	if err := s.CompareAndSwapKey([]string{"state"}, nil, "draft"); err != nil {
+		t.Fatal(err)
+	}
+	err := s.CompareAndSwapKey([]string{"state"}, "published", "archived")
+	var mismatch *mapstore.CASMismatchError
+	if !errors.Is(err, mapstore.ErrCASMismatch) || !errors.As(err, &mismatch) || mismatch.Current != "draft" {
+		t.Fatalf("CompareAndSwapKey with a stale value = %v", err)
+	}
+	if err := s.CompareAndSwapKey([]string{"state"}, "draft", "published"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.GetKey([]string{"state"}); v != "published" {
+		t.Fatalf("state = %v", v)
+	}
+
+	// Numbers compare by value, also after the file was read back.
+	if err := s.SetKey([]string{"n"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	reopened := openStore(p)
+	defer reopened.Close()
+	if err := reopened.CompareAndSwapKey([]string{"n"}, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapFileStore_CompareAndSwapKeyConcurrent(t *testing.T) {
+	s := openStore(filepath.Join(t.TempDir(), "counter.json"))
+	defer s.Close()
+	const workers, perWorker = 8, 20
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for range perWorker {
+				for {
+					cur, err := s.GetKey([]string{"n"})
+					if err != nil {
+						cur = nil
+					}
+					next := 1.0
+					if f, ok := cur.(float64); ok {
+						next = f + 1
+					}
+					err = s.CompareAndSwapKey([]string{"n"}, cur, next)
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, mapstore.ErrCASMismatch) {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if v, _ := s.GetKey([]string{"n"}); v != float64(workers*perWorker) {
+		t.Fatalf("n = %v, want %d", v, workers*perWorker)
+	}
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_Close(t *testing.T) {
+	st := openStore(filepath.Join(t.TempDir(), "s.json"))
+	if err := st.SetKey([]string{"a"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	calls := map[string]error{
+		"SetKey":    st.SetKey([]string{"a"}, 2),
+		"DeleteKey": st.DeleteKey([]string{"a"}),
+		"SetAll":    st.SetAll(map[string]any{}),
+		"Reset":     st.Reset(),
+		"Flush":     st.Flush(),
+		"Export":    st.Export(&bytes.Buffer{}),
+	}
+	_, calls["GetKey"] = st.GetKey([]string{"a"})
+	_, calls["GetAll"] = st.GetAll(false)
+	_, calls["GetAll(force)"] = st.GetAll(true)
+	_, calls["Increment"] = st.Increment([]string{"n"}, 1)
+	calls["DeleteFile"] = st.DeleteFile()
+	for name, err := range calls {
+		if !errors.Is(err, mapstore.ErrClosed) {
+			t.Errorf("%s after Close: %v, want ErrClosed", name, err)
+		}
+	}
+}
+
+func TestMapFileStore_ConcurrentClose(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "s.json")
+	st := openStore(p)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := map[string]bool{}
+	for w := range 4 {
+		wg.Go(func() {
+			for i := 0; ; i++ {
+				key := fmt.Sprintf("w%d-%d", w, i)
+				err := st.SetKey([]string{key}, i)
+				if errors.Is(err, mapstore.ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("SetKey: %v", err)
+					return
+				}
+				mu.Lock()
+				written[key] = true
+				mu.Unlock()
+			}
+		})
+	}
+	for {
+		mu.Lock()
+		n := len(written)
+		mu.Unlock()
+		if n >= 20 {
+			break
+		}
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	// Every acknowledged write is on disk, nothing was written after Close.
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened := openStore(p)
+	defer reopened.Close()
+	data, err := reopened.GetAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(written) {
+		t.Fatalf("file has %d keys, %d writes were acknowledged: %s", len(data), len(written), raw)
+	}
+	for k := range written {
+		if _, ok := data[k]; !ok {
+			t.Errorf("acknowledged write %s missing", k)
+		}
+	}
+}
+
+func TestMapDirectoryStore_CloseFileWhileInUse(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "a.json"}
+	st, err := mds.OpenFile(key, true, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.CloseFile(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"x"}, 1); !errors.Is(err, mapstore.ErrClosed) {
+		t.Fatalf("write through a closed handle: %v", err)
+	}
+	// Opening again returns a fresh store.
+	if err := mds.SetFileData(key, map[string]any{"x": 2.0}); err != nil {
+		t.Fatal(err)
+	}
+}
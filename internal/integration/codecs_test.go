@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/gzipencdec"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// lineCodec stores a flat map as sorted "key=value" lines, standing in for a YAML style codec.
+type lineCodec struct{}
+
+func (lineCodec) Encode(w io.Writer, value any) error {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("lineCodec: unsupported %T", value)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s=%v\n", k, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lineCodec) Decode(r io.Reader, value any) error {
+	out, ok := value.(*map[string]any)
+	if !ok {
+		return fmt.Errorf("lineCodec: unsupported %T", value)
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		k, v, found := strings.Cut(sc.Text(), "=")
+		if found {
+			(*out)[k] = v
+		}
+	}
+	return sc.Err()
+}
+
+func TestMapDirectoryStore_CodecByExtension(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "hand.conf"), []byte("name=alice\nrole=admin\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirCodecForExtension(".conf", lineCodec{}),
+		mapstore.WithDirCodecForExtension(
+			".json.gz",
+			gzipencdec.GzipEncoderDecoder{Inner: jsonencdec.JSONEncoderDecoder{}},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := mds.GetFileData(mapstore.FileKey{FileName: "hand.conf"}, false)
+	if err != nil || conf["name"] != "alice" {
+		t.Fatalf("hand.conf: got %v err %v", conf, err)
+	}
+
+	for _, name := range []string{"plain.json", "packed.JSON.GZ"} {
+		if err := mds.SetFileData(mapstore.FileKey{FileName: name}, map[string]any{"k": "v"}); err != nil {
+			t.Fatalf("SetFileData %s: %v", name, err)
+		}
+	}
+	raw, err := os.ReadFile(filepath.Join(base, "packed.JSON.GZ"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		t.Fatalf("packed.JSON.GZ is not gzip: %q", raw)
+	}
+	raw, err = os.ReadFile(filepath.Join(base, "plain.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(raw, []byte(`"k": "v"`)) {
+		t.Fatalf("plain.json is not JSON: %q", raw)
+	}
+
+	// Reopen through a fresh store to read back the compressed file.
+	if err := mds.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	packed, err := mds.GetFileData(mapstore.FileKey{FileName: "packed.JSON.GZ"}, false)
+	if err != nil || packed["k"] != "v" {
+		t.Fatalf("packed.JSON.GZ: got %v err %v", packed, err)
+	}
+}
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_Compression(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "compressed.json")
+	data := map[string]any{}
+	for i := range 200 {
+		data[fmt.Sprintf("item%03d", i)] = map[string]any{"name": "repetitive value", "enabled": true}
+	}
+
+	plain := openStore(p)
+	if err := plain.SetAll(data); err != nil {
+		t.Fatal(err)
+	}
+	plain.Close()
+	plainInfo, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		compression mapstore.Compression
+		magic       []byte
+	}{
+		{"gzip", mapstore.CompressionGzip, []byte{0x1f, 0x8b}},
+		{"zstd", mapstore.CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	} {
+		// The file written with the previous compression, or none, still loads.
+		s := openStore(p, mapstore.WithCompression(tc.compression))
+		if got, err := s.GetAll(false); err != nil || !deepEqual(got, data) {
+			t.Fatalf("%s: GetAll = %v, %v", tc.name, len(got), err)
+		}
+		if err := s.SetKey([]string{"item000", "name"}, tc.name); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		data["item000"] = map[string]any{"name": tc.name, "enabled": true}
+
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(raw, tc.magic) || int64(len(raw)) >= plainInfo.Size()/4 {
+			t.Fatalf("%s: %d bytes starting %x, plain file %d bytes", tc.name, len(raw), raw[:4], plainInfo.Size())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestNewMapFileStoreFromConfig(t *testing.T) {
+	var events []mapstore.FileEvent
+	st, err := mapstore.NewMapFileStoreFromConfig(mapstore.MapFileStoreConfig{
+		Filename:           filepath.Join(t.TempDir(), "cfg.json"),
+		DefaultData:        map[string]any{"a": 1.0},
+		FileEncoderDecoder: jsonencdec.JSONEncoderDecoder{},
+		CreateIfNotExists:  true,
+		Listeners:          []mapstore.FileListener{func(e mapstore.FileEvent) { events = append(events, e) }},
+		AccessChecker: func(_ context.Context, op mapstore.Operation, _ string, _ []string) error {
+			if op == mapstore.OpDeleteFile {
+				return mapstore.ErrAccessDenied
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if v, err := st.GetKey([]string{"a"}); err != nil || v != 1.0 {
+		t.Fatalf("GetKey = %v, %v", v, err)
+	}
+	if err := st.SetKey([]string{"b"}, 2); err != nil || len(events) != 1 {
+		t.Fatalf("SetKey: %v, %d events", err, len(events))
+	}
+	if err := st.DeleteFile(); !errors.Is(err, mapstore.ErrAccessDenied) {
+		t.Fatalf("DeleteFile = %v, want ErrAccessDenied", err)
+	}
+
+	if err := (mapstore.MapFileStoreConfig{Filename: "x.json"}).Validate(); err == nil {
+		t.Fatal("want error without an encoder")
+	}
+}
+
+func TestNewMapDirectoryStoreFromConfig(t *testing.T) {
+	cfg := mapstore.MapDirectoryStoreConfig{
+		BaseDir:               t.TempDir(),
+		PartitionProvider:     &dirpartition.NoPartitionProvider{},
+		FileEncoderDecoder:    jsonencdec.JSONEncoderDecoder{},
+		PageSize:              2,
+		FilenameNormalization: mapstore.NormalizeFilenameLowercase,
+		FileOptions:           []mapstore.FileOption{mapstore.WithFileAutoFlush(true)},
+	}
+	mds, err := mapstore.NewMapDirectoryStoreFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	for _, name := range []string{"A.json", "b.json", "c.json"} {
+		if err := mds.SetFileData(mapstore.FileKey{FileName: name}, map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, next, err := mds.ListFiles(mapstore.ListingConfig{SortOrder: mapstore.SortOrderAscending}, "")
+	if err != nil || len(entries) != 2 || next == "" || entries[0].FileInfo.Name() != "a.json" {
+		t.Fatalf("ListFiles = %v, %q, %v", entries, next, err)
+	}
+
+	for name, bad := range map[string]mapstore.MapDirectoryStoreConfig{
+		"no provider": {FileEncoderDecoder: jsonencdec.JSONEncoderDecoder{}},
+		"negative page": {
+			PartitionProvider: cfg.PartitionProvider, FileEncoderDecoder: cfg.FileEncoderDecoder,
+			PageSize: -1,
+		},
+		"bad policy": {
+			PartitionProvider: cfg.PartitionProvider, FileEncoderDecoder: cfg.FileEncoderDecoder,
+			PartitionCreatePolicy: 7,
+		},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%s: want validation error", name)
+		}
+		if _, err := mapstore.NewMapDirectoryStoreFromConfig(bad); err == nil {
+			t.Errorf("%s: want constructor error", name)
+		}
+	}
+	if _, err := mapstore.NewMapDirectoryStore(t.TempDir(), true, nil, jsonencdec.JSONEncoderDecoder{}); err == nil {
+		t.Fatal("want error for a nil partition provider")
+	}
+}
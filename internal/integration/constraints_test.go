@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_RequiredPaths(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "required.json")
+	if err := os.WriteFile(p, []byte(`{"db": {"host": "x"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := openStore(p, mapstore.WithRequiredPaths([]string{"db", "host"}, []string{"db", "port"}))
+	defer s.Close()
+
+	_, err := s.GetKey([]string{"db", "host"})
+	if !errors.Is(err, mapstore.ErrRequiredPathMissing) || !strings.Contains(err.Error(), "db.port") {
+		t.Fatalf("GetKey err = %v, want ErrRequiredPathMissing for db.port", err)
+	}
+	if _, err := s.GetAll(false); !errors.Is(err, mapstore.ErrRequiredPathMissing) {
+		t.Fatalf("GetAll err = %v", err)
+	}
+
+	// Writes are allowed, so the data can be fixed.
+	if err := s.SetKey([]string{"db", "port"}, 5432.0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.GetKey([]string{"db", "port"}); err != nil || v != 5432.0 {
+		t.Fatalf("GetKey = %v, %v", v, err)
+	}
+}
+
+func TestMapFileStore_ImmutablePaths(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "immutable.json")
+	s := openStore(p, mapstore.WithImmutablePaths([]string{"app", "id"}, []string{"count"}))
+	defer s.Close()
+
+	// A path without a value can be set once.
+	if err := s.SetKey([]string{"app"}, map[string]any{"id": "a1", "name": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Increment([]string{"count"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	for name, fn := range map[string]func() error{
+		"set":           func() error { return s.SetKey([]string{"app", "id"}, "a2") },
+		"set below":     func() error { return s.SetKey([]string{"app", "id", "x"}, 1.0) },
+		"set parent":    func() error { return s.SetKey([]string{"app"}, map[string]any{"name": "y"}) },
+		"delete":        func() error { return s.DeleteKey([]string{"app", "id"}) },
+		"delete parent": func() error { return s.DeleteKey([]string{"app"}) },
+		"set all":       func() error { return s.SetAll(map[string]any{}) },
+		"reset":         func() error { return s.Reset() },
+		"cas":           func() error { return s.CompareAndSwapKey([]string{"app", "id"}, "a1", "a2") },
+		"increment":     func() error { _, err := s.Increment([]string{"count"}, 1); return err },
+		"merge key":     func() error { return s.MergeKey([]string{"app"}, map[string]any{"id": "a2"}) },
+		"merge all": func() error {
+			return s.MergeAll(map[string]any{"app": map[string]any{"id": "a2"}})
+		},
+		"patch": func() error {
+			return s.ApplyPatch([]byte(`[{"op": "replace", "path": "/app/id", "value": "a2"}]`))
+		},
+		"patch remove": func() error { return s.ApplyPatch([]byte(`[{"op": "remove", "path": "/app"}]`)) },
+		"rename from": func() error {
+			_, err := s.RenamePath([]string{"app"}, []string{"moved"}, mapstore.RenameOverwrite)
+			return err
+		},
+		"rename onto": func() error {
+			_, err := s.RenamePath([]string{"app", "name"}, []string{"app", "id"}, mapstore.RenameOverwrite)
+			return err
+		},
+		"transaction": func() error {
+			tx, err := s.Begin()
+			if err != nil {
+				return err
+			}
+			if err := tx.DeleteKey([]string{"app", "id"}); err != nil {
+				return err
+			}
+			return tx.Commit()
+		},
+	} {
+		if err := fn(); !errors.Is(err, mapstore.ErrImmutable) {
+			t.Errorf("%s: err = %v, want ErrImmutable", name, err)
+		}
+	}
+
+	// Writes that keep the value, and writes next to it, are allowed.
+	if err := s.SetKey([]string{"app"}, map[string]any{"id": "a1", "name": "y"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"app", "name"}, "z"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"app": map[string]any{"id": "a1", "name": "z"}, "count": 1.0}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
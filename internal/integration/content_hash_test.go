@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func sha256File(t *testing.T, p string) string {
+	t.Helper()
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMapFileStore_ContentHash(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "h.json")
+	var last mapstore.FileEvent
+	s := openStore(p, mapstore.WithContentHash(true), mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		last = e
+	}))
+	if err := s.SetKey([]string{"a"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := sha256File(t, p)
+	if got := s.ContentHash(); got != want {
+		t.Fatalf("ContentHash = %q, want %q", got, want)
+	}
+	if last.ContentHash != want {
+		t.Fatalf("event ContentHash = %q, want %q", last.ContentHash, want)
+	}
+	s.Close()
+
+	reopened := openStore(p, mapstore.WithContentHash(true))
+	defer reopened.Close()
+	if got := reopened.ContentHash(); got != want {
+		t.Fatalf("ContentHash after reload = %q, want %q", got, want)
+	}
+
+	plain := openStore(filepath.Join(t.TempDir(), "p.json"))
+	defer plain.Close()
+	if err := plain.SetKey([]string{"a"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := plain.ContentHash(); got != "" {
+		t.Fatalf("ContentHash without WithContentHash = %q", got)
+	}
+}
+
+func TestMapFileStore_ContentHashSegmented(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "seg.json")
+	s := openStore(p, mapstore.WithContentHash(true), mapstore.WithSegmentedStorage(true))
+	defer s.Close()
+	if err := s.SetAll(map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatal(err)
+	}
+	before := s.ContentHash()
+	if err := s.SetKey([]string{"b"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	after := s.ContentHash()
+	if before == "" || before == after {
+		t.Fatalf("manifest hash did not change with a segment: %q, %q", before, after)
+	}
+	if got := sha256File(t, p); got != after {
+		t.Fatalf("ContentHash = %q, want the hash of the manifest %q", after, got)
+	}
+}
+
+func TestMapDirectoryStore_ListFilesContentHash(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirContentHash(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "open.json"}, map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "closed.json"), []byte(`{"b":2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, err := mds.ListFiles(mapstore.ListingConfig{PageSize: 10}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if want := sha256File(t, filepath.Join(baseDir, e.BaseRelativePath)); e.ContentHash != want {
+			t.Errorf("%s: ContentHash = %q, want %q", e.BaseRelativePath, e.ContentHash, want)
+		}
+	}
+}
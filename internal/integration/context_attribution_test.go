@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_ContextAttribution(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []mapstore.FileEvent
+		actors []string
+	)
+	checker := func(ctx context.Context, op mapstore.Operation, file string, keys []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		actors = append(actors, mapstore.ActorFromContext(ctx))
+		return nil
+	}
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+		mapstore.WithDirFileListeners(func(e mapstore.FileEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+
+	ctx := mapstore.ContextWithRequestID(mapstore.ContextWithActor(context.Background(), "alice"), "req-1")
+	key := mapstore.FileKey{FileName: "a.json"}
+	if err := mds.SetFileDataContext(ctx, key, map[string]any{"x": 1}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := mds.OpenFile(key, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKeyContext(ctx, []string{"y"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DeleteKeyContext(ctx, []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"z"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.DeleteFileContext(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, e := range events {
+		if e.File != filepath.Join(baseDir, "a.json") {
+			continue
+		}
+		got = append(got, string(e.Op)+":"+e.Actor+":"+e.RequestID)
+	}
+	want := []string{
+		"setFile:alice:req-1",
+		"setKey:alice:req-1",
+		"deleteKey:alice:req-1",
+		"setKey::",
+		"deleteFile:alice:req-1",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if actors[len(actors)-1] != "alice" {
+		t.Fatalf("access checker saw actors %v", actors)
+	}
+}
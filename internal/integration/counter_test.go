@@ -0,0 +1,105 @@
+package integration
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_Increment(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "counter.json")
+	st := openStore(f)
+
+	got, err := st.Increment([]string{"stats", "hits"}, 5)
+	if err != nil || got != 5 {
+		t.Fatalf("first increment: got %d err %v", got, err)
+	}
+	got, err = st.Increment([]string{"stats", "hits"}, -2)
+	if err != nil || got != 3 {
+		t.Fatalf("second increment: got %d err %v", got, err)
+	}
+
+	v, err := openStore(f).GetKey([]string{"stats", "hits"})
+	if err != nil || v != float64(3) {
+		t.Fatalf("reloaded value: got %v err %v", v, err)
+	}
+
+	// Persisted even with autoFlush off.
+	noFlushFile := filepath.Join(t.TempDir(), "noflush.json")
+	noFlush := openStore(noFlushFile, mapstore.WithFileAutoFlush(false))
+	if _, err := noFlush.Increment([]string{"n"}, 1); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	v, err = openStore(noFlushFile).GetKey([]string{"n"})
+	if err != nil || v != float64(1) {
+		t.Fatalf("increment with autoFlush off not persisted: got %v err %v", v, err)
+	}
+
+	if err := st.SetKey([]string{"name"}, "x"); err != nil {
+		t.Fatalf("SetKey: %v", err)
+	}
+	if _, err := st.Increment([]string{"name"}, 1); err == nil {
+		t.Fatal("want error incrementing a string")
+	}
+	if _, err := st.Increment(nil, 1); err == nil {
+		t.Fatal("want error incrementing root")
+	}
+}
+
+// Two store instances over the same file behave like two processes.
+func TestMapFileStore_Increment_ConcurrentInstances(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "shared.json")
+	a := openStore(f)
+	b := openStore(f)
+
+	const perWorker = 25
+	var wg sync.WaitGroup
+	for _, st := range []*mapstore.MapFileStore{a, b, a, b} {
+		wg.Go(func() {
+			for range perWorker {
+				if _, err := st.Increment([]string{"n"}, 1); err != nil {
+					t.Errorf("increment: %v", err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	got, err := openStore(f).GetKey([]string{"n"})
+	if err != nil {
+		t.Fatalf("GetKey: %v", err)
+	}
+	if got != float64(4*perWorker) {
+		t.Fatalf("lost updates: got %v, want %d", got, 4*perWorker)
+	}
+}
+
+func TestMapFileStore_Sequence(t *testing.T) {
+	st := openStore(filepath.Join(t.TempDir(), "seq.json"))
+	orders := st.Sequence("orders")
+
+	cur, err := orders.Current()
+	if err != nil || cur != 0 {
+		t.Fatalf("unused sequence: got %d err %v", cur, err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		got, err := orders.Next()
+		if err != nil || got != want {
+			t.Fatalf("Next: got %d err %v, want %d", got, err, want)
+		}
+	}
+	if other, _ := st.Sequence("invoices").Next(); other != 1 {
+		t.Fatalf("sequences must be independent, got %d", other)
+	}
+	cur, err = orders.Current()
+	if err != nil || cur != 3 {
+		t.Fatalf("Current: got %d err %v", cur, err)
+	}
+	v, err := st.GetKey([]string{mapstore.SequencesKey, "orders"})
+	if err != nil || v != int64(3) {
+		t.Fatalf("stored value: got %v (%T) err %v", v, v, err)
+	}
+}
@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_Defaults(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "defaults.json")
+	if err := os.WriteFile(p, []byte(`{"server": {"port": 9000}, "name": "x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defaults := map[string]any{
+		"server":  map[string]any{"port": 8080.0, "host": "localhost"},
+		"timeout": 30.0,
+	}
+	s, err := mapstore.NewMapFileStore(p, defaults, jsonencdec.JSONEncoderDecoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		keys []string
+		want any
+	}{
+		{[]string{"name"}, "x"},
+		{[]string{"timeout"}, 30.0},
+		{[]string{"server", "host"}, "localhost"},
+		{[]string{"server"}, map[string]any{"port": 9000.0, "host": "localhost"}},
+		{[]string{"missing"}, "fallback"},
+		{[]string{"server", "missing"}, "fallback"},
+	}
+	for _, tc := range tests {
+		got, err := s.GetKeyOr(tc.keys, "fallback")
+		if err != nil || !deepEqual(got, tc.want) {
+			t.Errorf("GetKeyOr(%v) = %v, %v, want %v", tc.keys, got, err, tc.want)
+		}
+	}
+	if _, err := s.GetKeyOr([]string{"name", "below"}, "fallback"); err == nil {
+		t.Error("GetKeyOr below a string: want error")
+	}
+
+	all, err := s.GetAllWithDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"server":  map[string]any{"port": 9000.0, "host": "localhost"},
+		"name":    "x",
+		"timeout": 30.0,
+	}
+	if !deepEqual(all, want) {
+		t.Fatalf("GetAllWithDefaults = %v, want %v", all, want)
+	}
+	onDisk := map[string]any{"server": map[string]any{"port": 9000.0}, "name": "x"}
+	if got := readJSONFile(t, p); !deepEqual(got, onDisk) {
+		t.Fatalf("file changed: %v", got)
+	}
+
+	s.Close()
+	if _, err := s.GetKeyOr([]string{"name"}, "fallback"); !errors.Is(err, mapstore.ErrClosed) {
+		t.Fatalf("GetKeyOr on closed store: err = %v", err)
+	}
+}
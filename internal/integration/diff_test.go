@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestDiffStores(t *testing.T) {
+	dir := t.TempDir()
+	a := openStore(filepath.Join(dir, "a.json"))
+	b := openStore(filepath.Join(dir, "b.json"),
+		mapstore.WithRedactor(mapstore.RedactPaths([]string{"auth", "token"})))
+	if err := a.SetAll(map[string]any{
+		"name": "old",
+		"auth": map[string]any{"token": "t0", "user": "u"},
+		"gone": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetAll(map[string]any{
+		"name": "new",
+		"auth": map[string]any{"token": "t1", "user": "u"},
+		"tags": []any{"x"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := mapstore.DiffStores(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "~ auth.token: \"t0\" -> \"[REDACTED]\"\n" +
+		"- gone: true\n" +
+		"~ name: \"old\" -> \"new\"\n" +
+		"+ tags: [\"x\"]\n"
+	if got := changes.String(); got != want {
+		t.Fatalf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 4 || decoded[1]["kind"] != "removed" || decoded[3]["kind"] != "added" {
+		t.Fatalf("unexpected JSON rendering: %s", raw)
+	}
+
+	same, err := mapstore.DiffStores(a, a)
+	if err != nil || same != nil {
+		t.Fatalf("diff with itself: %v, %v", same, err)
+	}
+
+	// An int set in memory equals the float64 it decodes to.
+	if err := a.SetAll(map[string]any{"n": 2, "l": []any{1}}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := openStore(filepath.Join(dir, "a.json"))
+	defer reloaded.Close()
+	if same, err := mapstore.DiffStores(a, reloaded); err != nil || same != nil {
+		t.Fatalf("diff with the reloaded file: %v, %v", same, err)
+	}
+	coerce := mapstore.EqualOptions{NumericCoercion: true}
+	if !mapstore.Equal(2, 2.0, coerce) || mapstore.Equal(2, 2.0, mapstore.EqualOptions{}) {
+		t.Fatal("Equal ignores NumericCoercion")
+	}
+}
+
+func TestMapDirectoryStore_DiffFiles(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	ka, kb := mapstore.FileKey{FileName: "a.json"}, mapstore.FileKey{FileName: "b.json"}
+	if err := mds.SetFileData(ka, map[string]any{"cfg": map[string]any{"n": 1.0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.DiffFiles(ka, kb); err == nil {
+		t.Fatal("want error for a missing file")
+	}
+	if err := mds.SetFileData(kb, map[string]any{"cfg": map[string]any{"n": 2.0}}); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := mds.DiffFiles(ka, kb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Kind != mapstore.ChangeChanged || changes[0].New != 2.0 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	// Files that were not open are closed again, so a later diff sees a change made on disk.
+	if err := mds.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.DiffFiles(ka, kb); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mds.BaseDir(), "b.json"), []byte(`{"cfg":{"n":1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := mds.DiffFiles(ka, kb); err != nil || len(changes) != 0 {
+		t.Fatalf("diff after a change on disk = %+v, %v", changes, err)
+	}
+}
@@ -1066,7 +1066,7 @@ func TestMapDirectoryStore_ListFiles_ErrorsAndEdgeCases(t *testing.T) {
 		files, nextPageToken, err := mds.ListFiles(
 			mapstore.ListingConfig{
 				SortOrder:        mapstore.SortOrderAscending,
-				FilterPartitions: []string{"doesnotexist"},
+				FilterPartitions: []string{"209912"},
 			},
 			"",
 		)
@@ -1197,7 +1197,7 @@ func TestMapDirectoryStore_ListFiles_ErrorsAndEdgeCases(t *testing.T) {
 		_, _, err = mds.ListFiles(
 			mapstore.ListingConfig{
 				SortOrder:        mapstore.SortOrderAscending,
-				FilterPartitions: []string{"202301", "doesnotexist"},
+				FilterPartitions: []string{"202301", "209912"},
 			},
 			"",
 		)
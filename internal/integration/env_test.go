@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestApplyEnvOverrides(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.json")
+	content := `{"server":{"port":80,"host":"localhost","debug":false},"logLevel":"info"}`
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP__SERVER__PORT", "8080")
+	t.Setenv("APP__SERVER__DEBUG", "true")
+	t.Setenv("APP__LOGLEVEL", "debug")
+	t.Setenv("APP__FEATURES__BETA", "1")
+	t.Setenv("OTHER__SERVER__HOST", "ignored")
+
+	st := openStore(p, mapstore.WithEnvOverrides("APP"))
+
+	tests := []struct {
+		keys []string
+		want any
+	}{
+		{[]string{"server", "port"}, float64(8080)},
+		{[]string{"server", "debug"}, true},
+		{[]string{"server", "host"}, "localhost"},
+		{[]string{"logLevel"}, "debug"},
+		{[]string{"features", "beta"}, float64(1)},
+	}
+	for _, tc := range tests {
+		got, err := st.GetKey(tc.keys)
+		if err != nil || got != tc.want {
+			t.Errorf("GetKey(%v): got %v (%T) err %v, want %v", tc.keys, got, got, err, tc.want)
+		}
+	}
+
+	server, err := st.GetKey([]string{"server"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantServer := map[string]any{"port": float64(8080), "host": "localhost", "debug": true}
+	if !deepEqual(server, wantServer) {
+		t.Fatalf("merged subtree: got %v", server)
+	}
+	all, err := st.GetAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getValueAtPath(all, []string{"server", "port"}) != float64(8080) {
+		t.Fatalf("GetAll misses override: %v", all)
+	}
+
+	// Overrides are never written back.
+	if err := st.SetKey([]string{"server", "host"}, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "8080") || strings.Contains(string(raw), "features") {
+		t.Fatalf("override flushed to disk: %s", raw)
+	}
+}
+
+func TestApplyEnvOverrides_InvalidValue(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(p, []byte(`{"port":80}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP__PORT", "eighty")
+	st := openStore(p)
+	if err := mapstore.ApplyEnvOverrides(st, "APP"); err == nil {
+		t.Fatal("want coercion error")
+	}
+}
@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestFileEvent_Get(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "events.json")
+	var seen []any
+	s := openStore(p,
+		mapstore.WithFileListeners(
+			func(e mapstore.FileEvent) {
+				v, err := e.Get([]string{"cfg", "n"})
+				if err != nil {
+					v = "missing"
+				}
+				seen = append(seen, v)
+				// Values are copies, a listener changing them does not affect the next one.
+				if m, err := e.Get([]string{"cfg"}); err == nil {
+					m.(map[string]any)["n"] = "changed"
+				}
+			},
+			func(e mapstore.FileEvent) {
+				if v, err := e.Get([]string{"cfg", "n"}); err == nil && v == "changed" {
+					t.Error("value changed by an earlier listener")
+				}
+			},
+		),
+	)
+	defer s.Close()
+
+	if err := s.SetKey([]string{"cfg", "n"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"cfg", "n"}, 2.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteKey([]string{"cfg", "n"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{1.0, 2.0, "missing"}; !deepEqual(seen, want) {
+		t.Fatalf("seen %v, want %v", seen, want)
+	}
+	if _, err := (mapstore.FileEvent{Op: mapstore.OpDeleteFile}).Get([]string{"cfg"}); err == nil {
+		t.Fatal("Get on an event without data: want error")
+	}
+}
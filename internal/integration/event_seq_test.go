@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_EventSequence(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []mapstore.FileEvent
+	)
+	st := openStore(filepath.Join(t.TempDir(), "seq.json"),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}))
+	defer st.Close()
+
+	const writers, writes = 8, 25
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Go(func() {
+			for i := range writes {
+				if err := st.SetKey([]string{"last"}, w*writes+i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if err := st.DeleteKey([]string{"last"}); err != nil {
+		t.Fatal(err)
+	}
+
+	total := writers*writes + 1
+	if len(events) != total {
+		t.Fatalf("got %d events, want %d", len(events), total)
+	}
+	slices.SortFunc(events, func(a, b mapstore.FileEvent) int { return int(a.Seq) - int(b.Seq) })
+	for i, e := range events {
+		if e.Seq != uint64(i+1) {
+			t.Fatalf("event %d has Seq %d, want %d without gaps", i, e.Seq, i+1)
+		}
+		// Ordered by Seq, every change starts from the value the previous one wrote.
+		if i > 0 && e.OldValue != events[i-1].NewValue {
+			t.Fatalf("Seq %d: old value %v, previous new value %v", e.Seq, e.OldValue, events[i-1].NewValue)
+		}
+	}
+	if last := events[total-1]; last.Op != mapstore.OpDeleteKey {
+		t.Fatalf("last event %s, want deleteKey", last.Op)
+	}
+}
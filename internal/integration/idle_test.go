@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func readJSONFile(t *testing.T, p string) map[string]any {
+	t.Helper()
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMapDirectoryStore_IdlePolicy(t *testing.T) {
+	t.Parallel()
+	open := func(t *testing.T, flushAfter, closeAfter time.Duration) (*mapstore.MapDirectoryStore, string) {
+		t.Helper()
+		baseDir := t.TempDir()
+		mds, err := mapstore.NewMapDirectoryStore(
+			baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+			mapstore.WithDirFileOptions(mapstore.WithFileAutoFlush(false)),
+			mapstore.WithIdlePolicy(flushAfter, closeAfter),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = mds.Close() })
+		return mds, baseDir
+	}
+	key := mapstore.FileKey{FileName: "a.json"}
+
+	t.Run("Flush", func(t *testing.T) {
+		t.Parallel()
+		mds, baseDir := open(t, 30*time.Millisecond, 0)
+		st, err := mds.OpenFile(key, true, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.SetKey([]string{"k"}, "v"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := readJSONFile(t, filepath.Join(baseDir, "a.json"))["k"]; ok {
+			t.Fatal("written although auto flush is off")
+		}
+		waitFor(t, "idle flush", func() bool {
+			return readJSONFile(t, filepath.Join(baseDir, "a.json"))["k"] == "v"
+		})
+		// Flushed, not closed.
+		if err := st.SetKey([]string{"k2"}, "v2"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		t.Parallel()
+		mds, baseDir := open(t, 0, 50*time.Millisecond)
+		st, err := mds.OpenFile(key, true, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.SetKey([]string{"k"}, "v"); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "idle close", func() bool { return errors.Is(st.Flush(), mapstore.ErrClosed) })
+		if got := readJSONFile(t, filepath.Join(baseDir, "a.json"))["k"]; got != "v" {
+			t.Fatalf("unflushed change lost on idle close: %v", got)
+		}
+		data, err := mds.GetFileData(key, false)
+		if err != nil || data["k"] != "v" {
+			t.Fatalf("reopen after idle close: %v, %v", data, err)
+		}
+	})
+
+	t.Run("CloseFlushes", func(t *testing.T) {
+		t.Parallel()
+		mds, baseDir := open(t, time.Hour, time.Hour)
+		st, err := mds.OpenFile(key, true, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.SetKey([]string{"k"}, "v"); err != nil {
+			t.Fatal(err)
+		}
+		if err := mds.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := readJSONFile(t, filepath.Join(baseDir, "a.json"))["k"]; got != "v" {
+			t.Fatalf("Close did not flush: %v", got)
+		}
+		if err := mds.Close(); err != nil {
+			t.Fatalf("second Close: %v", err)
+		}
+	})
+}
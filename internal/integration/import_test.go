@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func importSource(n, failAt int) iter.Seq2[mapstore.FileKey, map[string]any] {
+	return func(yield func(mapstore.FileKey, map[string]any) bool) {
+		for i := range n {
+			data := map[string]any{"i": i}
+			if i == failAt {
+				data = nil
+			}
+			if !yield(mapstore.FileKey{FileName: fmt.Sprintf("f%03d.json", i)}, data) {
+				return
+			}
+		}
+	}
+}
+
+func TestMapDirectoryStore_Import(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	var calls []mapstore.ImportProgress
+	opts := mapstore.ImportOptions{
+		Concurrency:    3,
+		BatchSize:      10,
+		CheckpointFile: checkpoint,
+		Progress:       func(p mapstore.ImportProgress) { calls = append(calls, p) },
+	}
+
+	// The item at 25 is invalid, so the import stops after the second batch.
+	p, err := mds.Import(context.Background(), importSource(40, 25), opts)
+	if err == nil || p.Position != 20 || p.Imported != 20 || len(calls) != 2 {
+		t.Fatalf("failed import: %+v, %d progress calls, err %v", p, len(calls), err)
+	}
+
+	// Resuming skips the checkpointed items, whose files exist, and imports the rest.
+	calls = nil
+	p, err = mds.Import(context.Background(), importSource(40, -1), opts)
+	if err != nil || p != (mapstore.ImportProgress{Position: 40, Imported: 20, Skipped: 20}) {
+		t.Fatalf("resumed import: %+v, err %v", p, err)
+	}
+	if len(calls) != 2 || calls[1] != p {
+		t.Fatalf("progress calls: %+v", calls)
+	}
+	files, _, err := mds.ListFiles(mapstore.ListingConfig{PageSize: 100}, "")
+	if err != nil || len(files) != 40 {
+		t.Fatalf("listed %d files, err %v", len(files), err)
+	}
+	data := readJSONFile(t, filepath.Join(baseDir, "f039.json"))
+	if data["i"] != 39.0 {
+		t.Fatalf("f039 = %v", data)
+	}
+
+	// A finished checkpoint imports nothing, a canceled context stops early.
+	if p, err := mds.Import(context.Background(), importSource(40, -1), opts); err != nil || p.Imported != 0 {
+		t.Fatalf("repeat: %+v, err %v", p, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := mds.Import(ctx, importSource(5, -1), mapstore.ImportOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// pathCache is a minimal read cache of values by file and dotted path.
+type pathCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]bool
+}
+
+func (c *pathCache) put(file, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[file] == nil {
+		c.entries[file] = map[string]bool{}
+	}
+	c.entries[file][path] = true
+}
+
+func (c *pathCache) paths(file string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []string
+	for p := range c.entries[file] {
+		out = append(out, p)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func (c *pathCache) InvalidateCache(file string, keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.entries[file] {
+		if mapstore.KeysOverlap(keys, strings.Split(p, ".")) {
+			delete(c.entries[file], p)
+		}
+	}
+}
+
+func TestMapDirectoryStore_CacheInvalidators(t *testing.T) {
+	cache := &pathCache{entries: map[string]map[string]bool{}}
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirCacheInvalidators(cache),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "c.json"}
+	if err := mds.SetFileData(key, map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := mds.OpenFile(key, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(baseDir, "c.json")
+	fill := func() {
+		for _, p := range []string{"a", "a.b", "a.b.c", "a.x", "z"} {
+			cache.put(file, p)
+		}
+	}
+
+	fill()
+	if err := st.SetKey([]string{"a", "b"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.paths(file); !slices.Equal(got, []string{"a.x", "z"}) {
+		t.Fatalf("after SetKey a.b: %v", got)
+	}
+	fill()
+	if err := mds.SetFileData(key, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.paths(file); len(got) != 0 {
+		t.Fatalf("after SetFileData: %v", got)
+	}
+	fill()
+	if err := mds.DeleteFile(key); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.paths(file); len(got) != 0 {
+		t.Fatalf("after DeleteFile: %v", got)
+	}
+}
+
+func TestKeysOverlap(t *testing.T) {
+	tests := []struct {
+		changed, path []string
+		want          bool
+	}{
+		{nil, []string{"a"}, true},
+		{[]string{"a"}, []string{"a", "b"}, true},
+		{[]string{"a", "b"}, []string{"a"}, true},
+		{[]string{"a", "b"}, []string{"a", "c"}, false},
+		{[]string{"b"}, []string{"a"}, false},
+	}
+	for _, tt := range tests {
+		if got := mapstore.KeysOverlap(tt.changed, tt.path); got != tt.want {
+			t.Errorf("KeysOverlap(%v, %v) = %v", tt.changed, tt.path, got)
+		}
+	}
+}
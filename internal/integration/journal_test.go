@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_JournalReplay(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "journal.json")
+	opts := []mapstore.FileOption{mapstore.WithFileAutoFlush(false), mapstore.WithJournal(true)}
+	s := openStore(p, opts...)
+	if err := s.SetAll(map[string]any{"old": true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"cfg", "a"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKeyWithTTL([]string{"token"}, "t", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"gone"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteKey([]string{"gone"}); err != nil {
+		t.Fatal(err)
+	}
+	// Close does not flush, like a crash.
+	s.Close()
+	if got := readJSONFile(t, p); len(got) != 0 {
+		t.Fatalf("file flushed before the crash: %v", got)
+	}
+
+	// An entry cut short by the crash is ignored.
+	f, err := os.OpenFile(p+".wal", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"set":{"partial"`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s = openStore(p, opts...)
+	defer s.Close()
+	want := map[string]any{"old": true, "cfg": map[string]any{"a": 1.0}, "token": "t"}
+	if got, _ := s.GetAll(false); !deepEqual(got, want) {
+		t.Fatalf("replayed = %v, want %v", got, want)
+	}
+	if _, ok := s.ExpiresAt([]string{"token"}); !ok {
+		t.Fatal("expiry not replayed")
+	}
+
+	// A flush writes the replayed data and empties the journal.
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readJSONFile(t, p); !deepEqual(got["cfg"], want["cfg"]) {
+		t.Fatalf("file = %v", got)
+	}
+	if info, err := os.Stat(p + ".wal"); err != nil || info.Size() != 0 {
+		t.Fatalf("journal after flush: %v, %v", info, err)
+	}
+}
+
+func TestMapFileStore_JournalAutoFlushOnReplay(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "journal.json")
+	s := openStore(p, mapstore.WithFileAutoFlush(false), mapstore.WithJournal(true))
+	if err := s.SetKey([]string{"k"}, "v"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Opened with auto flush, the replayed changes are written right away.
+	s = openStore(p, mapstore.WithJournal(true))
+	defer s.Close()
+	if got := readJSONFile(t, p); got["k"] != "v" {
+		t.Fatalf("file = %v", got)
+	}
+	if err := s.DeleteFile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p + ".wal"); !os.IsNotExist(err) {
+		t.Fatalf("journal kept after DeleteFile: %v", err)
+	}
+}
+
+func TestMapFileStore_JournalEncodesValues(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "journal.json")
+	opts := []mapstore.FileOption{
+		mapstore.WithFileAutoFlush(false),
+		mapstore.WithJournal(true),
+		mapstore.WithValueEncDecGetter(func(pathSoFar []string) mapstore.IOEncoderDecoder {
+			if len(pathSoFar) == 1 && pathSoFar[0] == "secret" {
+				return reverseStringEncoderDecoder{}
+			}
+			return nil
+		}),
+	}
+	s := openStore(p, opts...)
+	if err := s.SetKey([]string{"secret"}, "plaintext"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	raw, err := os.ReadFile(p + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) == 0 || strings.Contains(string(raw), "plaintext") {
+		t.Fatalf("journal not encoded: %s", raw)
+	}
+	s = openStore(p, opts...)
+	defer s.Close()
+	if v, err := s.GetKey([]string{"secret"}); err != nil || v != "plaintext" {
+		t.Fatalf("GetKey = %v, %v", v, err)
+	}
+}
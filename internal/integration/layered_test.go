@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestLayeredStore(t *testing.T) {
+	dir := t.TempDir()
+	layer := func(name, content string) *mapstore.MapFileStore {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return openStore(p)
+	}
+	system := layer("system.json", `{"ui":{"theme":"light","font":"mono"},"limit":10,"name":"sys"}`)
+	user := layer("user.json", `{"ui":{"theme":"dark"},"limit":"unlimited"}`)
+	runtime := layer("runtime.json", `{"name":"rt"}`)
+
+	ls, err := mapstore.NewLayeredStore(system, user, runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keys []string
+		want any
+	}{
+		{[]string{"ui", "theme"}, "dark"},
+		{[]string{"ui", "font"}, "mono"},
+		{[]string{"ui"}, map[string]any{"theme": "dark", "font": "mono"}},
+		{[]string{"limit"}, "unlimited"},
+		{[]string{"name"}, "rt"},
+	}
+	for _, tc := range tests {
+		got, err := ls.GetKey(tc.keys)
+		if err != nil || !deepEqual(got, tc.want) {
+			t.Errorf("GetKey(%v): got %v err %v, want %v", tc.keys, got, err, tc.want)
+		}
+	}
+	if _, err := ls.GetKey([]string{"missing"}); err == nil {
+		t.Error("want error for missing key")
+	}
+
+	all, err := ls.GetAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"ui":    map[string]any{"theme": "dark", "font": "mono"},
+		"limit": "unlimited",
+		"name":  "rt",
+	}
+	if !deepEqual(all, want) {
+		t.Fatalf("GetAll: got %v", all)
+	}
+
+	// Writes land in the top layer only, deletes uncover lower layers.
+	if err := ls.SetKey([]string{"ui", "theme"}, "solarized"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := runtime.GetKey([]string{"ui", "theme"}); v != "solarized" {
+		t.Fatalf("top layer not written: %v", v)
+	}
+	if v, _ := user.GetKey([]string{"ui", "theme"}); v != "dark" {
+		t.Fatalf("lower layer modified: %v", v)
+	}
+	if err := ls.DeleteKey([]string{"name"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ls.GetKey([]string{"name"}); err != nil || v != "sys" {
+		t.Fatalf("after delete: got %v err %v", v, err)
+	}
+
+	if _, err := mapstore.NewLayeredStore(); err == nil {
+		t.Fatal("want error for no layers")
+	}
+}
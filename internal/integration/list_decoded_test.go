@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+type conversation struct {
+	Title string `json:"title"`
+	Turns int    `json:"turns"`
+}
+
+func TestListDecoded(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	for i := range 5 {
+		// Keys without a field in conversation are ignored.
+		data := map[string]any{"title": fmt.Sprintf("c%d", i), "turns": i, "model": "m"}
+		if err := mds.SetFileData(mapstore.FileKey{FileName: fmt.Sprintf("c%d.json", i)}, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := mapstore.ListDecodedConfig{ListingConfig: mapstore.ListingConfig{PageSize: 3}, Concurrency: 2}
+	items, next, err := mapstore.ListDecoded[conversation](mds, cfg, "")
+	if err != nil || next == "" || len(items) != 3 {
+		t.Fatalf("first page: %d items, next %q, err %v", len(items), next, err)
+	}
+	for i, item := range items {
+		want := conversation{Title: fmt.Sprintf("c%d", i), Turns: i}
+		if item.Value != want || item.Entry.BaseRelativePath != fmt.Sprintf("c%d.json", i) || item.Err != nil {
+			t.Errorf("item %d = %+v", i, item)
+		}
+	}
+	items, next, err = mapstore.ListDecoded[conversation](mds, cfg, next)
+	if err != nil || next != "" || len(items) != 2 || items[1].Value.Title != "c4" {
+		t.Fatalf("second page: %+v, next %q, err %v", items, next, err)
+	}
+
+	// Listed files that were not open are closed again, so a later read sees a change made on disk.
+	key := mapstore.FileKey{FileName: "c0.json"}
+	if err := mds.CloseFile(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mapstore.ListDecoded[conversation](mds, cfg, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mds.BaseDir(), "c0.json"), []byte(`{"title":"disk"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := mds.GetFileData(key, false); err != nil || data["title"] != "disk" {
+		t.Fatalf("GetFileData after ListDecoded = %v, %v", data, err)
+	}
+
+	bad := map[string]any{"title": "bad", "turns": "many"}
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "c1.json"}, bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mapstore.ListDecoded[conversation](mds, cfg, ""); err == nil {
+		t.Fatal("want error with DecodeFailFast")
+	}
+	cfg.ErrorPolicy = mapstore.DecodeCollectErrors
+	items, _, err = mapstore.ListDecoded[conversation](mds, cfg, "")
+	if err != nil || len(items) != 3 {
+		t.Fatalf("collect: %d items, err %v", len(items), err)
+	}
+	if items[1].Err == nil || items[0].Err != nil || items[2].Value.Turns != 2 {
+		t.Fatalf("collect: %+v", items)
+	}
+}
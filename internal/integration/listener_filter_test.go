@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// eventLog records events as "op:key.path" strings.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) listener(e mapstore.FileEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, string(e.Op)+":"+strings.Join(e.Keys, "."))
+}
+
+func (l *eventLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := l.events
+	l.events = nil
+	return out
+}
+
+func TestMapFileStore_FilteredListener(t *testing.T) {
+	var settings, deletes, live eventLog
+	s := openStore(filepath.Join(t.TempDir(), "f.json"),
+		mapstore.WithFilteredListener(mapstore.ListenerFilter{Prefix: []string{"settings"}}, settings.listener),
+		mapstore.WithFilteredListener(mapstore.ListenerFilter{Ops: []mapstore.Operation{mapstore.OpDeleteKey}},
+			deletes.listener),
+	)
+	defer s.Close()
+	id := s.AddFilteredListener(mapstore.ListenerFilter{
+		Ops:    []mapstore.Operation{mapstore.OpSetKey, mapstore.OpTransaction},
+		Prefix: []string{"settings", "theme"},
+	}, live.listener)
+
+	steps := []func() error{
+		func() error { return s.SetKey([]string{"settings", "theme"}, "dark") },
+		func() error { return s.SetKey([]string{"settings", "lang"}, "en") },
+		func() error { return s.SetKey([]string{"other"}, 1) },
+		func() error { return s.SetKey([]string{"settingsX"}, 1) },
+		func() error { return s.DeleteKey([]string{"other"}) },
+		// A parent replaces the values below it.
+		func() error { return s.SetKey([]string{"settings"}, map[string]any{"theme": "light"}) },
+		func() error { return s.SetAll(map[string]any{"settings": map[string]any{}}) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SetKey([]string{"other"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SetKey([]string{"settings", "theme"}, "blue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"setKey:settings.theme", "setKey:settings.lang", "setKey:settings", "setFile:", "transaction:",
+	}
+	if got := settings.take(); !slices.Equal(got, want) {
+		t.Fatalf("settings events = %v, want %v", got, want)
+	}
+	if got := deletes.take(); !slices.Equal(got, []string{"deleteKey:other"}) {
+		t.Fatalf("delete events = %v", got)
+	}
+	want = []string{"setKey:settings.theme", "setKey:settings", "transaction:"}
+	if got := live.take(); !slices.Equal(got, want) {
+		t.Fatalf("live events = %v, want %v", got, want)
+	}
+
+	// A transaction without matching changes is skipped, and a removed listener gets nothing.
+	if err := s.SetKey([]string{"settings", "theme"}, "red"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RemoveListener(id) {
+		t.Fatal("RemoveListener = false")
+	}
+	tx, _ = s.Begin()
+	if err := tx.SetKey([]string{"other"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := settings.take(); !slices.Equal(got, []string{"setKey:settings.theme"}) {
+		t.Fatalf("settings events = %v", got)
+	}
+	if got := live.take(); !slices.Equal(got, []string{"setKey:settings.theme"}) {
+		t.Fatalf("live events = %v", got)
+	}
+}
+
+func TestMapDirectoryStore_FilteredListener(t *testing.T) {
+	var deleted, live eventLog
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFilteredListener(
+			mapstore.ListenerFilter{Ops: []mapstore.Operation{mapstore.OpDeleteFile}}, deleted.listener,
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "a.json"}
+	if err := mds.SetFileData(key, map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	mds.AddFilteredListener(mapstore.ListenerFilter{Prefix: []string{"a"}}, live.listener)
+	store, err := mds.OpenFile(key, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetKey([]string{"b"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetKey([]string{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.DeleteFile(key); err != nil {
+		t.Fatal(err)
+	}
+	if got := live.take(); !slices.Equal(got, []string{"setKey:a", "deleteFile:"}) {
+		t.Fatalf("live events = %v", got)
+	}
+	if got := deleted.take(); !slices.Equal(got, []string{"deleteFile:"}) {
+		t.Fatalf("delete events = %v", got)
+	}
+}
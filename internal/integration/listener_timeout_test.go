@@ -0,0 +1,123 @@
+package integration
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_ListenerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var seqs []uint64
+	slow := func(e mapstore.FileEvent) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		seqs = append(seqs, e.Seq)
+	}
+	var fast []uint64
+	st := openStore(filepath.Join(t.TempDir(), "t.json"),
+		mapstore.WithFileAutoFlush(false),
+		mapstore.WithListenerTimeout(20*time.Millisecond, 2),
+		mapstore.WithFileListeners(slow, func(e mapstore.FileEvent) { fast = append(fast, e.Seq) }),
+	)
+	defer st.Close()
+
+	for i := range 2 {
+		if err := st.SetKey([]string{"k"}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := st.ListenerStats(); s.Slow != 2 || s.Async != 1 || s.Dropped != 0 {
+		t.Fatalf("stats after two timeouts = %+v", s)
+	}
+
+	// The slow listener is async now, writes no longer wait for it and overflow its queue.
+	start := time.Now()
+	for i := range 300 {
+		if err := st.SetKey([]string{"k"}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("writes took %v with an async listener", d)
+	}
+	s := st.ListenerStats()
+	if s.Slow != 2 || s.Dropped == 0 {
+		t.Fatalf("stats after async writes = %+v", s)
+	}
+	if len(fast) != 302 {
+		t.Fatalf("fast listener got %d events", len(fast))
+	}
+
+	close(release)
+	want := 302 - int(s.Dropped)
+	waitFor(t, "slow listener", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seqs) == want
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	// Queued events arrive in order; the two calls that timed out finish concurrently with them.
+	queued := slices.DeleteFunc(slices.Clone(seqs), func(seq uint64) bool { return seq <= 2 })
+	if len(queued) != want-2 || !slices.IsSorted(queued) || queued[0] != 3 {
+		t.Fatalf("async events out of order: %v", queued[:5])
+	}
+}
+
+func TestMapFileStore_AsyncListeners(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var seqs []uint64
+	st := openStore(filepath.Join(t.TempDir(), "a.json"),
+		mapstore.WithFileAutoFlush(false),
+		mapstore.WithAsyncListeners(3),
+	)
+	defer st.Close()
+	started := make(chan struct{}, 1)
+	id := st.AddListener(func(e mapstore.FileEvent) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		seqs = append(seqs, e.Seq)
+	})
+
+	// Writes do not wait for the blocked listener. The first event is taken by the listener, three are queued
+	// and the last is dropped.
+	for i := range 5 {
+		if err := st.SetKey([]string{"k"}, i); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			<-started
+		}
+	}
+	if d := st.ListenerStats().Dropped; d != 1 {
+		t.Fatalf("dropped = %d, want 1", d)
+	}
+	close(release)
+	waitFor(t, "queued events", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seqs) == 4
+	})
+	mu.Lock()
+	got := slices.Clone(seqs)
+	mu.Unlock()
+	if !slices.IsSorted(got) {
+		t.Fatalf("events out of order: %v", got)
+	}
+	if !st.RemoveListener(id) {
+		t.Fatal("listener not registered")
+	}
+}
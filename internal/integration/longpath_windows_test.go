@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// deepDir returns a directory path well beyond MAX_PATH (260 characters) below base.
+func deepDir(base string) string {
+	segment := strings.Repeat("d", 60)
+	parts := []string{base}
+	for range 6 {
+		parts = append(parts, segment)
+	}
+	return filepath.Join(parts...)
+}
+
+func TestLongPath_DirectoryStore(t *testing.T) {
+	baseDir := deepDir(t.TempDir())
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: strings.Repeat("f", 120) + ".json"}
+	if err := mds.SetFileData(key, map[string]any{"deep": true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PutAttachment(key, strings.Repeat("a", 100)+".bin", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := mds.GetFileData(key, true)
+	if err != nil || data["deep"] != true {
+		t.Fatalf("GetFileData = %v, %v", data, err)
+	}
+	entries, _, err := mds.ListFiles(mapstore.ListingConfig{SortOrder: mapstore.SortOrderAscending}, "")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListFiles = %v, %v", entries, err)
+	}
+	if err := mds.DeleteFile(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLongPath_RelativeFileStore(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := deepDir(".")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	st, err := mapstore.NewMapFileStore(
+		filepath.Join(dir, "store.json"), map[string]any{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.SetKey([]string{"k"}, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := st.GetKey([]string{"k"}); err != nil || v != "v" {
+		t.Fatalf("GetKey = %v, %v", v, err)
+	}
+}
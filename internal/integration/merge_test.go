@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_MergeAll(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "merge.json")
+	s := openStore(p)
+	defer s.Close()
+	if err := s.SetAll(map[string]any{
+		"app":  map[string]any{"name": "x", "opts": map[string]any{"a": 1.0, "b": 2.0}},
+		"tags": []any{"one"},
+		"keep": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.MergeAll(map[string]any{
+		"app":  map[string]any{"opts": map[string]any{"b": 3.0, "c": 4.0}},
+		"tags": []any{"two"},
+		"new":  "v",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"app":  map[string]any{"name": "x", "opts": map[string]any{"a": 1.0, "b": 3.0, "c": 4.0}},
+		"tags": []any{"two"},
+		"keep": true,
+		"new":  "v",
+	}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
+
+func TestMapFileStore_MergeKey(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "merge.json")
+	var events []mapstore.FileEvent
+	s := openStore(p,
+		mapstore.WithSliceMergeStrategy(mapstore.SliceMergeAppend),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }),
+	)
+	defer s.Close()
+	if err := s.SetKey([]string{"cfg"}, map[string]any{"tags": []any{"one"}, "n": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKeyWithTTL([]string{"cfg", "n"}, 1.0, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	events = nil
+
+	if err := s.MergeKey([]string{"cfg"}, map[string]any{"tags": []any{"two"}, "n": 2.0}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"tags": []any{"one", "two"}, "n": 2.0}
+	if got, _ := s.GetKey([]string{"cfg"}); !deepEqual(got, want) {
+		t.Fatalf("cfg = %v, want %v", got, want)
+	}
+	if at, ok := s.ExpiresAt([]string{"cfg", "n"}); ok {
+		t.Fatalf("expiry of a merged value kept: %v", at)
+	}
+	if len(events) != 1 || events[0].Op != mapstore.OpSetKey || !deepEqual(events[0].NewValue, want) {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// Values that are not maps are replaced, missing parents are created.
+	if err := s.MergeKey([]string{"a", "b"}, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MergeKey([]string{"cfg", "tags"}, "flat"); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]any{"cfg": map[string]any{"tags": "flat", "n": 2.0}, "a": map[string]any{"b": "x"}}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
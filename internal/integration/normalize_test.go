@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_FilenameNormalization(t *testing.T) {
+	const (
+		nfc = "café.json"
+		nfd = "cafe\u0301.json"
+	)
+	baseDir := t.TempDir()
+	// A file written on macOS, which stores decomposed names.
+	if err := os.WriteFile(filepath.Join(baseDir, nfd), []byte(`{"from":"mac"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mds, err := mapstore.NewMapDirectoryStore(baseDir, true, &dirpartition.NoPartitionProvider{},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFilenameNormalization(mapstore.NormalizeFilenameNFC|mapstore.NormalizeFilenameLowercase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+
+	for _, name := range []string{nfc, nfd, "CAFÉ.JSON"} {
+		data, err := mds.GetFileData(mapstore.FileKey{FileName: name}, false)
+		if err != nil || data["from"] != "mac" {
+			t.Fatalf("GetFileData(%q) = %v, %v", name, data, err)
+		}
+	}
+
+	// New files are created under the normalized name.
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "Notes.JSON"}, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "notes.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two files that normalize to the same name are reported.
+	for _, name := range []string{"Dup.json", "DUP.json"} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte(`{}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mds.GetFileData(mapstore.FileKey{FileName: "dup.json"}, false); !errors.Is(
+		err, mapstore.ErrFilenameCollision,
+	) {
+		t.Fatalf("want ErrFilenameCollision, got %v", err)
+	}
+}
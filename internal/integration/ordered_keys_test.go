@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_OrderedKeys(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ordered.json")
+	src := `{"server": {"port": 8080, "host": "local"}, "name": "app", "debug": false}`
+	if err := os.WriteFile(p, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := openStore(p, mapstore.WithOrderedKeys(true))
+	defer s.Close()
+	if err := s.SetKey([]string{"server", "tls"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"added"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteKey([]string{"debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Existing keys keep their place, new ones follow them.
+	last := -1
+	for _, k := range []string{`"server"`, `"port"`, `"host"`, `"tls"`, `"name"`, `"added"`} {
+		i := strings.Index(string(raw), k)
+		if i < last {
+			t.Fatalf("%s out of order in %s", k, raw)
+		}
+		last = i
+	}
+	if strings.Contains(string(raw), `"debug"`) {
+		t.Fatalf("deleted key written: %s", raw)
+	}
+	want := map[string]any{
+		"server": map[string]any{"port": 8080.0, "host": "local", "tls": true},
+		"name":   "app",
+		"added":  1.0,
+	}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package integration
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_PartitionStats(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(baseDir, true, &dirpartition.MonthPartitionProvider{
+		TimeFn: func(mapstore.FileKey) (time.Time, error) {
+			return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil
+		},
+	}, jsonencdec.JSONEncoderDecoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := mds.SetFileData(mapstore.FileKey{FileName: name}, map[string]any{"n": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mds.PutAttachment(mapstore.FileKey{FileName: "a.json"}, "x.bin", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	partDir := filepath.Join(baseDir, "202403")
+	var wantBytes int64
+	var newest time.Time
+	for _, name := range []string{"a.json", "b.json"} {
+		fi, err := os.Stat(filepath.Join(partDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantBytes += fi.Size()
+		if fi.ModTime().After(newest) {
+			newest = fi.ModTime()
+		}
+	}
+
+	stats, err := mds.PartitionStats("202403")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 || stats.TotalBytes != wantBytes || !stats.NewestModTime.Equal(newest) {
+		t.Fatalf("stats = %+v, want 2 files, %d bytes, newest %v", stats, wantBytes, newest)
+	}
+
+	// With an unchanged, settled directory the cached stats are served.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(partDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.PartitionStats("202403"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(partDir, "b.json"), make([]byte, 1000), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(partDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := mds.PartitionStats("202403"); err != nil || cached.TotalBytes != wantBytes {
+		t.Fatalf("cached stats = %+v, %v", cached, err)
+	}
+
+	// Writes through the store replace files and refresh the stats.
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "c.json"}, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err = mds.PartitionStats("202403"); err != nil || stats.Files != 3 {
+		t.Fatalf("stats after write = %+v, %v", stats, err)
+	}
+
+	if _, err := mds.PartitionStats("209901"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing partition: %v", err)
+	}
+	if _, err := mds.PartitionStats("../x"); err == nil {
+		t.Fatal("want error for a partition outside the base directory")
+	}
+}
+
+func TestMapDirectoryStore_PartitionCreatePolicy(t *testing.T) {
+	provider := &dirpartition.MonthPartitionProvider{
+		TimeFn: func(mapstore.FileKey) (time.Time, error) {
+			return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), nil
+		},
+	}
+	key := mapstore.FileKey{FileName: "a.json"}
+	open := func(t *testing.T, p mapstore.PartitionCreatePolicy) (*mapstore.MapDirectoryStore, string) {
+		t.Helper()
+		baseDir := t.TempDir()
+		mds, err := mapstore.NewMapDirectoryStore(baseDir, true, provider, jsonencdec.JSONEncoderDecoder{},
+			mapstore.WithDirPartitionCreatePolicy(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = mds.CloseAll() })
+		return mds, filepath.Join(baseDir, "202405")
+	}
+	exists := func(dir string) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	}
+
+	t.Run("OnWrite", func(t *testing.T) {
+		mds, dir := open(t, mapstore.PartitionCreateOnWrite)
+		if _, err := mds.GetFileData(key, false); err == nil || exists(dir) {
+			t.Fatalf("read of a missing file: err %v, dir created %v", err, exists(dir))
+		}
+		if err := mds.SetFileData(key, map[string]any{}); err != nil || !exists(dir) {
+			t.Fatalf("write: err %v, dir created %v", err, exists(dir))
+		}
+	})
+
+	t.Run("Always", func(t *testing.T) {
+		mds, dir := open(t, mapstore.PartitionCreateAlways)
+		if _, err := mds.GetFileData(key, false); err == nil || !exists(dir) {
+			t.Fatalf("read of a missing file: err %v, dir created %v", err, exists(dir))
+		}
+	})
+
+	t.Run("Never", func(t *testing.T) {
+		mds, dir := open(t, mapstore.PartitionCreateNever)
+		err := mds.SetFileData(key, map[string]any{})
+		if !errors.Is(err, mapstore.ErrPartitionNotFound) || exists(dir) {
+			t.Fatalf("write: err %v, dir created %v", err, exists(dir))
+		}
+		if err := mds.EnsurePartition("202405"); err != nil {
+			t.Fatal(err)
+		}
+		if err := mds.SetFileData(key, map[string]any{"ok": true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := mds.EnsurePartition("../outside"); err == nil {
+			t.Fatal("want error for a partition outside the base directory")
+		}
+	})
+}
+
+func TestMapDirectoryStore_FilterPartitionsValidation(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(baseDir, true, &dirpartition.MonthPartitionProvider{
+		TimeFn: func(mapstore.FileKey) (time.Time, error) {
+			return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil
+		},
+	}, jsonencdec.JSONEncoderDecoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	if err := mds.SetFileData(mapstore.FileKey{FileName: "a.json"}, map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"..", ".", "/etc", "202403/../..", `..\x`, "", "2024-03", "202413"} {
+		_, _, err := mds.ListFiles(mapstore.ListingConfig{FilterPartitions: []string{"202403", name}}, "")
+		var ipe *mapstore.InvalidPartitionNameError
+		if !errors.Is(err, mapstore.ErrInvalidPartitionName) || !errors.As(err, &ipe) || ipe.Name != name {
+			t.Errorf("FilterPartitions %q: got %v", name, err)
+		}
+	}
+	files, _, err := mds.ListFiles(mapstore.ListingConfig{FilterPartitions: []string{"202403", "202401"}}, "")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("valid filter: %v, %v", files, err)
+	}
+
+	// A page token carrying a tampered filter is rejected as well.
+	tok := base64.StdEncoding.EncodeToString(
+		[]byte(`{"pageSize":1,"partitionFilterPageToken":{"filterPartitions":["../x"]}}`),
+	)
+	if _, _, err := mds.ListFiles(mapstore.ListingConfig{}, tok); !errors.Is(err, mapstore.ErrInvalidPartitionName) {
+		t.Fatalf("tampered token: got %v", err)
+	}
+
+	flat, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = flat.ListFiles(mapstore.ListingConfig{FilterPartitions: []string{"sub"}}, "")
+	if !errors.Is(err, mapstore.ErrInvalidPartitionName) {
+		t.Fatalf("unpartitioned store: got %v", err)
+	}
+	if _, _, err := flat.ListFiles(mapstore.ListingConfig{FilterPartitions: []string{""}}, ""); err != nil {
+		t.Fatalf("unpartitioned store, base partition: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_ApplyPatch(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "patch.json")
+	var (
+		mu     sync.Mutex
+		events []mapstore.FileEvent
+	)
+	s := openStore(p, mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}))
+	defer s.Close()
+	if err := s.SetAll(map[string]any{
+		"a":    map[string]any{"b": "c", "x/y": 1.0},
+		"tags": []any{"one", "three"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	events = nil
+	mu.Unlock()
+
+	err := s.ApplyPatch([]byte(`[
+		{"op": "test", "path": "/a/b", "value": "c"},
+		{"op": "add", "path": "/tags/1", "value": "two"},
+		{"op": "add", "path": "/tags/-", "value": "four"},
+		{"op": "replace", "path": "/a/x~1y", "value": 2},
+		{"op": "copy", "from": "/a/b", "path": "/d"},
+		{"op": "move", "from": "/d", "path": "/e"},
+		{"op": "remove", "path": "/tags/0"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"a":    map[string]any{"b": "c", "x/y": 2.0},
+		"tags": []any{"two", "three", "four"},
+		"e":    "c",
+	}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+
+	mu.Lock()
+	got := events
+	mu.Unlock()
+	wantOps := []struct {
+		op   mapstore.Operation
+		keys []string
+	}{
+		{mapstore.OpSetKey, []string{"tags", "1"}},
+		{mapstore.OpSetKey, []string{"tags", "3"}},
+		{mapstore.OpSetKey, []string{"a", "x/y"}},
+		{mapstore.OpSetKey, []string{"d"}},
+		{mapstore.OpDeleteKey, []string{"d"}},
+		{mapstore.OpSetKey, []string{"e"}},
+		{mapstore.OpDeleteKey, []string{"tags", "0"}},
+	}
+	if len(got) != len(wantOps) {
+		t.Fatalf("got %d events, want %d", len(got), len(wantOps))
+	}
+	for i, w := range wantOps {
+		if got[i].Op != w.op || !slices.Equal(got[i].Keys, w.keys) {
+			t.Errorf("event %d = %s %v, want %s %v", i, got[i].Op, got[i].Keys, w.op, w.keys)
+		}
+		if i > 0 && got[i].Seq != got[i-1].Seq+1 {
+			t.Errorf("event %d seq = %d after %d", i, got[i].Seq, got[i-1].Seq)
+		}
+	}
+	if got[2].OldValue != 1.0 || got[2].NewValue != 2.0 {
+		t.Errorf("replace event values = %v -> %v", got[2].OldValue, got[2].NewValue)
+	}
+}
+
+func TestMapFileStore_ApplyPatchAtomic(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "patch.json")
+	s := openStore(p)
+	defer s.Close()
+	if err := s.SetAll(map[string]any{"n": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.ApplyPatch([]byte(`[
+		{"op": "replace", "path": "/n", "value": 2},
+		{"op": "test", "path": "/n", "value": 3}
+	]`))
+	if !errors.Is(err, mapstore.ErrPatchTestFailed) {
+		t.Fatalf("failing test op = %v", err)
+	}
+	for _, patch := range []string{
+		`[{"op": "remove", "path": "/missing"}]`,
+		`[{"op": "replace", "path": "/missing", "value": 1}]`,
+		`[{"op": "add", "path": "/n/x", "value": 1}]`,
+		`[{"op": "add", "path": "", "value": {}}]`,
+		`[{"op": "add", "path": "/x"}]`,
+		`[{"op": "frobnicate", "path": "/n"}]`,
+		`{"op": "add"}`,
+	} {
+		if err := s.ApplyPatch([]byte(patch)); err == nil {
+			t.Errorf("ApplyPatch(%s) succeeded", patch)
+		}
+	}
+	if got := readJSONFile(t, p); !deepEqual(got, map[string]any{"n": 1.0}) {
+		t.Fatalf("file after failed patches = %v", got)
+	}
+}
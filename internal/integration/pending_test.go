@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_DiscardPending(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "settings.json")
+	var events []mapstore.FileEvent
+	s := openStore(p,
+		mapstore.WithFileAutoFlush(false),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }),
+	)
+	defer s.Close()
+	if s.HasPending() {
+		t.Fatal("pending changes after open")
+	}
+	if err := s.DiscardPending(); err != nil || len(events) != 0 {
+		t.Fatalf("DiscardPending without changes: %v, events %+v", err, events)
+	}
+
+	// The edit is visible at once but not written.
+	if err := s.SetKey([]string{"theme"}, "dark"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetKey([]string{"theme"}); got != "dark" || !s.HasPending() {
+		t.Fatalf("theme = %v, pending = %v", got, s.HasPending())
+	}
+	if got := readJSONFile(t, p); len(got) != 0 {
+		t.Fatalf("file written before flush: %v", got)
+	}
+
+	// Another writer wins, the flush conflicts and the edit is rolled back to the file.
+	other := openStore(p)
+	if err := other.SetAll(map[string]any{"theme": "light", "font": "mono"}); err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+	if err := s.Flush(); !errors.Is(err, mapstore.ErrFileConflict) {
+		t.Fatalf("Flush = %v, want ErrFileConflict", err)
+	}
+	if !s.HasPending() {
+		t.Fatal("failed flush cleared the pending changes")
+	}
+	events = nil
+	if err := s.DiscardPending(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"theme": "light", "font": "mono"}
+	if got, _ := s.GetAll(false); !deepEqual(got, want) || s.HasPending() {
+		t.Fatalf("data = %v, pending = %v; want %v", got, s.HasPending(), want)
+	}
+	if len(events) != 1 || events[0].Op != mapstore.OpDiscardPending || !deepEqual(events[0].Data, want) {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// Later edits flush normally.
+	if err := s.SetKey([]string{"theme"}, "dark"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want["theme"] = "dark"
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_FileAndDirMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on windows")
+	}
+	p := filepath.Join(t.TempDir(), "secrets.json")
+	s := openStore(p, mapstore.WithFileMode(0o600), mapstore.WithDirMode(0o700))
+	defer s.Close()
+	assertMode := func(path string, want os.FileMode) {
+		t.Helper()
+		st, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := st.Mode().Perm(); got != want {
+			t.Fatalf("mode of %s = %o, want %o", path, got, want)
+		}
+	}
+	assertMode(p, 0o600)
+	if err := s.SetKey([]string{"token"}, "t"); err != nil {
+		t.Fatal(err)
+	}
+	assertMode(p, 0o600)
+
+	// An existing file keeps its mode.
+	if err := os.Chmod(p, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	reopened := openStore(p, mapstore.WithFileMode(0o600))
+	defer reopened.Close()
+	if err := reopened.SetKey([]string{"token"}, "u"); err != nil {
+		t.Fatal(err)
+	}
+	assertMode(p, 0o640)
+
+	seg := filepath.Join(t.TempDir(), "seg.json")
+	segmented := openStore(seg, mapstore.WithSegmentedStorage(true),
+		mapstore.WithFileMode(0o600), mapstore.WithDirMode(0o700))
+	defer segmented.Close()
+	if err := segmented.SetKey([]string{"a"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	assertMode(seg+".segments", 0o700)
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_Query(t *testing.T) {
+	s := openStore(filepath.Join(t.TempDir(), "query.json"))
+	defer s.Close()
+	if err := s.SetAll(map[string]any{
+		"providers": map[string]any{
+			"a": map[string]any{"apiKey": "ka", "url": "ua"},
+			"b": map[string]any{"apiKey": "kb"},
+			"c": "not a map",
+		},
+		"apiKey": "root",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(matches []mapstore.PathMatch) [][]string {
+		out := make([][]string, 0, len(matches))
+		for _, m := range matches {
+			out = append(out, m.Keys)
+		}
+		return out
+	}
+	tests := []struct {
+		pattern []string
+		want    [][]string
+	}{
+		{[]string{"providers", "*", "apiKey"}, [][]string{{"providers", "a", "apiKey"}, {"providers", "b", "apiKey"}}},
+		{[]string{"**", "apiKey"}, [][]string{
+			{"apiKey"}, {"providers", "a", "apiKey"}, {"providers", "b", "apiKey"},
+		}},
+		{[]string{"providers", "*"}, [][]string{{"providers", "a"}, {"providers", "b"}, {"providers", "c"}}},
+		{[]string{"**", "**", "url"}, [][]string{{"providers", "a", "url"}}},
+		{[]string{"providers", "c", "*"}, [][]string{}},
+		{[]string{"missing", "**"}, [][]string{}},
+	}
+	for _, tt := range tests {
+		got, err := s.Query(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.EqualFunc(paths(got), tt.want, slices.Equal) {
+			t.Errorf("Query(%v) = %v, want %v", tt.pattern, paths(got), tt.want)
+		}
+	}
+
+	got, err := s.Query([]string{"providers", "*", "apiKey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Value != "ka" || got[1].Value != "kb" {
+		t.Fatalf("values = %v, %v", got[0].Value, got[1].Value)
+	}
+	if _, err := s.Query(nil); err == nil {
+		t.Fatal("empty pattern succeeded")
+	}
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "shared.json")
+	if err := os.WriteFile(p, []byte(`{"region": "eu", "n": 1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := openStore(p, mapstore.WithReadOnly(true), mapstore.WithJournal(true))
+	defer s.Close()
+	if got, err := s.GetKey([]string{"region"}); err != nil || got != "eu" {
+		t.Fatalf("GetKey = %v, %v", got, err)
+	}
+
+	mutations := map[string]func() error{
+		"SetKey":     func() error { return s.SetKey([]string{"region"}, "us") },
+		"DeleteKey":  func() error { return s.DeleteKey([]string{"n"}) },
+		"SetAll":     func() error { return s.SetAll(map[string]any{}) },
+		"Reset":      func() error { return s.Reset() },
+		"MergeAll":   func() error { return s.MergeAll(map[string]any{"x": 1.0}) },
+		"Increment":  func() error { _, err := s.Increment([]string{"n"}, 1); return err },
+		"Flush":      s.Flush,
+		"DeleteFile": s.DeleteFile,
+	}
+	for name, mutate := range mutations {
+		err := mutate()
+		var roe *mapstore.ReadOnlyError
+		if !errors.Is(err, mapstore.ErrReadOnly) || !errors.As(err, &roe) || roe.File == "" {
+			t.Errorf("%s = %v, want a *ReadOnlyError", name, err)
+		}
+	}
+
+	after, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		t.Fatal("read-only store wrote the file")
+	}
+	if _, err := os.Stat(p + ".wal"); !os.IsNotExist(err) {
+		t.Fatalf("read-only store created a journal: %v", err)
+	}
+
+	// A missing file is not created.
+	missing := filepath.Join(dir, "missing.json")
+	if _, err := mapstore.NewMapFileStore(missing, map[string]any{"a": 1.0}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true), mapstore.WithReadOnly(true)); !errors.Is(err, mapstore.ErrReadOnly) {
+		t.Fatalf("open missing file = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("missing file was created: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_Redactor(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "redact.json")
+	var events []mapstore.FileEvent
+	st := openStore(p,
+		mapstore.WithRedactor(mapstore.RedactPaths(
+			[]string{"auth", "token"},
+			[]string{"providers", "*", "apiKey"},
+		)),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }),
+	)
+
+	if err := st.SetKey([]string{"auth", "token"}, "t0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"auth", "token"}, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"providers"}, map[string]any{
+		"openai": map[string]any{"apiKey": "sk-1", "model": "m"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("want 3 events, got %d", len(events))
+	}
+	if e := events[1]; e.OldValue != mapstore.RedactedValue || e.NewValue != mapstore.RedactedValue {
+		t.Fatalf("token change not redacted: %+v", e)
+	}
+	want := map[string]any{
+		"auth": map[string]any{"token": mapstore.RedactedValue},
+		"providers": map[string]any{
+			"openai": map[string]any{"apiKey": mapstore.RedactedValue, "model": "m"},
+		},
+	}
+	last := events[2]
+	if !deepEqual(last.NewValue, want["providers"]) {
+		t.Fatalf("NewValue not redacted: %v", last.NewValue)
+	}
+	if !deepEqual(last.Data, want) {
+		t.Fatalf("Data not redacted: %v", last.Data)
+	}
+
+	// Reads are unaffected.
+	if v, err := st.GetKey([]string{"auth", "token"}); err != nil || v != "t1" {
+		t.Fatalf("GetKey: got %v err %v", v, err)
+	}
+
+	var buf bytes.Buffer
+	if err := st.Export(&buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "t1") || strings.Contains(out, "sk-1") || !strings.Contains(out, `"model"`) {
+		t.Fatalf("unexpected export output: %s", out)
+	}
+}
+
+func TestMapFileStore_RedactorSlices(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "redact.json")
+	var events []mapstore.FileEvent
+	st := openStore(p,
+		mapstore.WithRedactor(mapstore.RedactPaths(
+			[]string{"users", "*", "password"},
+			[]string{"keys", "1"},
+		)),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }),
+	)
+	defer st.Close()
+
+	users := []any{
+		map[string]any{"name": "a", "password": "pw-a"},
+		map[string]any{"name": "b", "password": "pw-b"},
+	}
+	if err := st.SetAll(map[string]any{"users": users, "keys": []any{"k0", "k1"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"users": []any{
+			map[string]any{"name": "a", "password": mapstore.RedactedValue},
+			map[string]any{"name": "b", "password": mapstore.RedactedValue},
+		},
+		"keys": []any{"k0", mapstore.RedactedValue},
+	}
+	if len(events) != 1 || !deepEqual(events[0].Data, want) {
+		t.Fatalf("events = %+v", events)
+	}
+	if err := st.SetKey([]string{"users"}, users[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !deepEqual(events[1].NewValue, want["users"].([]any)[:1]) {
+		t.Fatalf("NewValue not redacted: %v", events[1].NewValue)
+	}
+	if !deepEqual(events[1].OldValue, want["users"]) {
+		t.Fatalf("OldValue not redacted: %v", events[1].OldValue)
+	}
+
+	var buf bytes.Buffer
+	if err := st.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "pw-a") || strings.Contains(out, "k1") ||
+		!strings.Contains(out, `"k0"`) {
+		t.Fatalf("unexpected export output: %s", out)
+	}
+
+	// Reads are unaffected.
+	if v, err := st.GetKey([]string{"users"}); err != nil || !deepEqual(v, users[:1]) {
+		t.Fatalf("GetKey: got %v err %v", v, err)
+	}
+}
@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_Refresh(t *testing.T) {
+	base := t.TempDir()
+	var (
+		mu     sync.Mutex
+		events []mapstore.FileEvent
+	)
+	mds, err := mapstore.NewMapDirectoryStore(
+		base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileListeners(func(e mapstore.FileEvent) {
+			if e.Op == mapstore.OpExternalChange {
+				mu.Lock()
+				events = append(events, e)
+				mu.Unlock()
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		if err := mds.SetFileData(mapstore.FileKey{FileName: name}, map[string]any{"v": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := mds.Refresh(context.Background()); err != nil || n != 0 {
+		t.Fatalf("Refresh without changes: n=%d err=%v", n, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(base, "a.json"), []byte(`{"v":2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(base, "b.json")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := mds.Refresh(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("Refresh: n=%d err=%v", n, err)
+	}
+
+	byFile := map[string]mapstore.FileEvent{}
+	for _, e := range events {
+		byFile[filepath.Base(e.File)] = e
+	}
+	if len(events) != 2 || byFile["a.json"].Data["v"] != float64(2) || byFile["b.json"].Data != nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	data, err := mds.GetFileData(mapstore.FileKey{FileName: "a.json"}, false)
+	if err != nil || data["v"] != float64(2) {
+		t.Fatalf("a.json not reloaded: %v err %v", data, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := mds.Refresh(ctx); err == nil {
+		t.Fatal("want context error")
+	}
+}
@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_RefResolution(t *testing.T) {
+	base := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("shared.json", `{"models":["a","b"],"prompts":{"greet":"hello","alias":{"$ref":"#/prompts/greet"}}}`)
+	write("app.json", `{"models":{"$ref":"shared.json#/models"},"hi":{"$ref":"shared.json#/prompts/alias"},"n":1}`)
+	write("loop1.json", `{"x":{"$ref":"loop2.json#/y"}}`)
+	write("loop2.json", `{"y":{"$ref":"loop1.json#/x"}}`)
+	write("broken.json", `{"x":{"$ref":"shared.json#/nope"}}`)
+
+	open := func(enabled bool) *mapstore.MapDirectoryStore {
+		mds, err := mapstore.NewMapDirectoryStore(
+			base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+			mapstore.WithDirRefResolution(enabled),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mds
+	}
+
+	mds := open(true)
+	got, err := mds.GetFileData(mapstore.FileKey{FileName: "app.json"}, false)
+	if err != nil {
+		t.Fatalf("GetFileData: %v", err)
+	}
+	want := map[string]any{"models": []any{"a", "b"}, "hi": "hello", "n": float64(1)}
+	if !deepEqual(got, want) {
+		t.Fatalf("resolved data: got %v, want %v", got, want)
+	}
+
+	_, err = mds.GetFileData(mapstore.FileKey{FileName: "loop1.json"}, false)
+	if !errors.Is(err, mapstore.ErrRefCycle) {
+		t.Fatalf("want ErrRefCycle, got %v", err)
+	}
+	_, err = mds.GetFileData(mapstore.FileKey{FileName: "broken.json"}, false)
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("want missing key error, got %v", err)
+	}
+
+	// Disabled resolution returns the references as stored.
+	raw, err := open(false).GetFileData(mapstore.FileKey{FileName: "app.json"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deepEqual(raw["models"], map[string]any{"$ref": "shared.json#/models"}) {
+		t.Fatalf("unresolved data: got %v", raw)
+	}
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestMapFileStore_GetAllForceFetch_Concurrent(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "reload.json")
+	st := openStore(p)
+	if err := st.SetKey([]string{"nested", "n"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Go(func() {
+			for i := range 50 {
+				data, err := st.GetAll(true)
+				if err != nil {
+					t.Errorf("GetAll: %v", err)
+					return
+				}
+				// The snapshot is ours, mutating it must not race with the store.
+				if nested, ok := data["nested"].(map[string]any); ok {
+					nested[fmt.Sprintf("reader%d", w)] = i
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		for i := range 50 {
+			if err := st.SetKey([]string{"nested", "n"}, i); err != nil {
+				t.Errorf("SetKey: %v", err)
+				return
+			}
+		}
+	})
+	wg.Wait()
+
+	if _, ok := getValueAtPath(mustGetAll(t, st.GetAll), []string{"nested", "reader0"}).(int); ok {
+		t.Fatal("snapshot mutation leaked into the store")
+	}
+
+	// External edits are picked up.
+	if err := os.WriteFile(p, []byte(`{"external":true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data := mustGetAll(t, st.GetAll)
+	if data["external"] != true {
+		t.Fatalf("external change not loaded: %v", data)
+	}
+}
+
+func mustGetAll(t *testing.T, getAll func(bool) (map[string]any, error)) map[string]any {
+	t.Helper()
+	data, err := getAll(true)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	return data
+}
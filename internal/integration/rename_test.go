@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_RenamePath(t *testing.T) {
+	var events []mapstore.FileEvent
+	st := openStore(filepath.Join(t.TempDir(), "r.json"),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }))
+	defer st.Close()
+	if err := st.SetAll(map[string]any{
+		"settings": map[string]any{"ai": map[string]any{"model": "m1"}, "theme": "dark"},
+		"ai":       map[string]any{"settings": "old"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	events = nil
+
+	from, to := []string{"settings", "ai"}, []string{"ai", "settings"}
+	if _, err := st.RenamePath(from, to, mapstore.RenameFail); !errors.Is(err, mapstore.ErrRenameCollision) {
+		t.Fatalf("want ErrRenameCollision, got %v", err)
+	}
+	if ok, err := st.RenamePath(from, to, mapstore.RenameSkip); ok || err != nil {
+		t.Fatalf("skip: %v, %v", ok, err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("events for an unchanged store: %v", events)
+	}
+	ok, err := st.RenamePath(from, to, mapstore.RenameOverwrite)
+	if !ok || err != nil {
+		t.Fatalf("overwrite: %v, %v", ok, err)
+	}
+	want := map[string]any{
+		"settings": map[string]any{"theme": "dark"},
+		"ai":       map[string]any{"settings": map[string]any{"model": "m1"}},
+	}
+	if got, _ := st.GetAll(true); !deepEqual(got, want) {
+		t.Fatalf("data after rename = %v", got)
+	}
+	if len(events) != 2 || events[0].Op != mapstore.OpDeleteKey || events[1].Op != mapstore.OpSetKey ||
+		events[1].OldValue != "old" || events[1].Seq != events[0].Seq+1 {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// Repeating is a no-op, moving into itself is refused.
+	if ok, err := st.RenamePath(from, to, mapstore.RenameFail); ok || err != nil {
+		t.Fatalf("repeat: %v, %v", ok, err)
+	}
+	if _, err := st.RenamePath([]string{"ai"}, []string{"ai", "x"}, mapstore.RenameOverwrite); err == nil {
+		t.Fatal("want error for a rename into itself")
+	}
+	// A destination below a scalar fails without changing data.
+	if _, err := st.RenamePath([]string{"ai"}, []string{"settings", "theme", "x"}, mapstore.RenameFail); err == nil {
+		t.Fatal("want error for a destination below a scalar")
+	}
+	if got, _ := st.GetAll(false); !deepEqual(got, want) {
+		t.Fatalf("failed rename changed data: %v", got)
+	}
+}
+
+func TestMapDirectoryStore_RenamePathInAll(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	for i := range 5 {
+		data := map[string]any{"settings": map[string]any{"ai": i}}
+		if i == 4 {
+			data = map[string]any{"other": true}
+		}
+		if err := mds.SetFileData(mapstore.FileKey{FileName: fmt.Sprintf("u%d.json", i)}, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := mapstore.ListingConfig{PageSize: 2}
+	n, err := mds.RenamePathInAll(cfg, []string{"settings", "ai"}, []string{"ai", "settings"}, mapstore.RenameFail)
+	if err != nil || n != 4 {
+		t.Fatalf("RenamePathInAll = %d, %v", n, err)
+	}
+	data, err := mds.GetFileData(mapstore.FileKey{FileName: "u2.json"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := getValueAtPath(data, []string{"ai", "settings"}); fmt.Sprint(v) != "2" {
+		t.Fatalf("u2 ai.settings = %v", v)
+	}
+	if n, err := mds.RenamePathInAll(cfg, []string{"settings", "ai"}, []string{"ai", "settings"},
+		mapstore.RenameFail); err != nil || n != 0 {
+		t.Fatalf("repeat = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func segmentFiles(t *testing.T, file string) map[string]os.FileInfo {
+	t.Helper()
+	entries, err := os.ReadDir(file + ".segments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]os.FileInfo, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[e.Name()] = info
+	}
+	return out
+}
+
+func TestMapFileStore_SegmentedStorage(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "big.json")
+	st := openStore(p, mapstore.WithSegmentedStorage(true))
+	big := strings.Repeat("x", 1<<20)
+	if err := st.SetAll(map[string]any{"blob": big, "prefs": map[string]any{"theme": "dark"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readJSONFile(t, p); len(got) != 1 || got["mapstore.segments"] == nil {
+		t.Fatalf("main file = %v", got)
+	}
+	before := segmentFiles(t, p)
+	if len(before) != 2 {
+		t.Fatalf("segments = %v", before)
+	}
+
+	// A change below "prefs" leaves the segment of "blob" untouched.
+	if err := st.SetKey([]string{"prefs", "theme"}, "light"); err != nil {
+		t.Fatal(err)
+	}
+	after := segmentFiles(t, p)
+	for name, info := range before {
+		unchanged := os.SameFile(info, after[name]) && info.ModTime().Equal(after[name].ModTime())
+		if isBlob := info.Size() > 1<<20; unchanged != isBlob {
+			t.Errorf("segment %s (blob %v) unchanged = %v", name, isBlob, unchanged)
+		}
+	}
+
+	if err := st.SetKey([]string{"tmp"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DeleteKey([]string{"tmp"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(segmentFiles(t, p)); n != 2 {
+		t.Fatalf("segments after delete = %d", n)
+	}
+	st.Close()
+
+	re := openStore(p, mapstore.WithSegmentedStorage(true))
+	want := map[string]any{"blob": big, "prefs": map[string]any{"theme": "light"}}
+	if got, _ := re.GetAll(false); !deepEqual(got, want) {
+		t.Fatal("reopened data differs")
+	}
+	// SetAll drops segments of keys that are gone.
+	if err := re.SetAll(map[string]any{"prefs": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(segmentFiles(t, p)); n != 1 {
+		t.Fatalf("segments after SetAll = %d", n)
+	}
+	if err := re.DeleteFile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p + ".segments"); !os.IsNotExist(err) {
+		t.Fatalf("segment directory after DeleteFile: %v", err)
+	}
+}
+
+func TestMapFileStore_SegmentedStorageConvertsPlainFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "plain.json")
+	if err := os.WriteFile(p, []byte(`{"a":{"b":1},"c":"d"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st := openStore(p, mapstore.WithSegmentedStorage(true))
+	defer st.Close()
+	want := map[string]any{"a": map[string]any{"b": 1.0}, "c": "d"}
+	if got, _ := st.GetAll(false); !deepEqual(got, want) {
+		t.Fatalf("plain data = %v", got)
+	}
+	if err := st.SetKey([]string{"c"}, "e"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(segmentFiles(t, p)); n != 2 {
+		t.Fatalf("converted segments = %d", n)
+	}
+	if _, err := st.GetAll(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"mapstore.segments"}, 1); err == nil {
+		t.Fatal("want error for the reserved key")
+	}
+}
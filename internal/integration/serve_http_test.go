@@ -0,0 +1,248 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/internal/faultfs"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_ServeHTTP(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileOptions(mapstore.WithRedactor(mapstore.RedactPaths([]string{"secret"}))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "doc.json"}
+	if err := mds.SetFileData(key, map[string]any{"title": "hello", "secret": "s3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PutAttachment(key, "blob.bin", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"doc.json.lock", "doc.json.wal"} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte("1"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(mds)
+	defer srv.Close()
+	do := func(method, p, ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), method, srv.URL+p, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/doc.json", "")
+	var got map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !deepEqual(got, map[string]any{"title": "hello", "secret": mapstore.RedactedValue}) {
+		t.Fatalf("body = %v", got)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	resp = do(http.MethodGet, "/doc.json", etag)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("revalidation status = %d", resp.StatusCode)
+	}
+
+	for _, p := range []string{
+		"/missing.json", "/doc.json.lock", "/doc.json.wal", "/doc.json.attachments/blob.bin", "/../x", "/",
+	} {
+		resp := do(http.MethodGet, p, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", p, resp.StatusCode)
+		}
+	}
+	resp = do(http.MethodPost, "/doc.json", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d", resp.StatusCode)
+	}
+}
+
+func TestMapDirectoryStore_ServeHTTPConcurrent(t *testing.T) {
+	// Slow stats on strict reads keep requests busy with an open store for a while.
+	slow := faultfs.New(faultfs.Config{SlowStat: 1, StatDelay: time.Millisecond})
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileOptions(mapstore.WithFileSystem(slow), mapstore.WithStrictReads(true)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "doc.json"}
+	if err := mds.SetFileData(key, map[string]any{"n": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.CloseFile(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests open and release the file concurrently, while the application opens, writes and closes it. A
+	// request must not close the store the application got.
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			<-start
+			for range 20 {
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/doc.json", http.NoBody)
+				mds.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Errorf("status %d: %s", rec.Code, rec.Body)
+					return
+				}
+			}
+		})
+	}
+	close(start)
+	for i := range 50 {
+		store, err := mds.OpenFile(key, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runtime.Gosched()
+		if err := store.SetKey([]string{"n"}, i); err != nil {
+			t.Errorf("SetKey: %v", err)
+			break
+		}
+		if err := mds.CloseFile(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+// addVersion is a data migrator that sets "version" to 2.
+type addVersion struct{}
+
+func (addVersion) Migrate(_ string, data map[string]any) (map[string]any, bool, error) {
+	if data["version"] == 2 {
+		return data, false, nil
+	}
+	data["version"] = 2
+	return data, true, nil
+}
+
+func TestMapDirectoryStore_ServeHTTPReadOnly(t *testing.T) {
+	baseDir := t.TempDir()
+	checker := func(ctx context.Context, op mapstore.Operation, _ string, _ []string) error {
+		if op == mapstore.OpGetFile && mapstore.ActorFromContext(ctx) != "alice" {
+			return mapstore.ErrAccessDenied
+		}
+		return nil
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+		mapstore.WithDirFileOptions(
+			mapstore.WithFileAutoFlush(false), mapstore.WithJournal(true), mapstore.WithDataMigrator(addVersion{}),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	docPath := filepath.Join(baseDir, "doc.json")
+	raw := []byte(`{"n":1}`)
+	if err := os.WriteFile(docPath, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	body := func(rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("status %d, body %s: %v", rec.Code, rec.Body, err)
+		}
+		return got
+	}
+	get := func(actor, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		ctx := mapstore.ContextWithActor(t.Context(), actor)
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/doc.json", http.NoBody)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mds.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The access checker sees the request context.
+	if rec := get("bob", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("bob: status %d", rec.Code)
+	}
+
+	// A file that is not open is migrated in memory only, and neither written nor journaled.
+	rec := get("alice", "")
+	if got := body(rec); !deepEqual(got, map[string]any{"n": 1.0, "version": 2.0}) {
+		t.Fatalf("alice: body %v", got)
+	}
+	if got, err := os.ReadFile(docPath); err != nil || string(got) != string(raw) {
+		t.Fatalf("file changed by GET: %q, %v", got, err)
+	}
+	if _, err := os.Stat(docPath + ".wal"); !os.IsNotExist(err) {
+		t.Fatalf("journal created by GET: %v", err)
+	}
+	etag := rec.Header().Get("ETag")
+	if rec := get("alice", etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("revalidation status = %d", rec.Code)
+	}
+
+	// Changes that are not flushed yet are served, with an ETag of their own.
+	store, err := mds.OpenFile(mapstore.FileKey{FileName: "doc.json"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetKey([]string{"n"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	rec = get("alice", etag)
+	if got := body(rec); rec.Code != http.StatusOK || !deepEqual(got, map[string]any{"n": 2.0, "version": 2.0}) {
+		t.Fatalf("pending: status %d, body %v", rec.Code, got)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("ETag did not change with the served data")
+	}
+}
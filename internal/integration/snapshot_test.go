@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_SnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "snap.json")
+	var events []mapstore.FileEvent
+	s := openStore(p, mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }))
+	defer s.Close()
+	if err := s.SetKey([]string{"cfg"}, map[string]any{"a": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKeyWithTTL([]string{"token"}, "t", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"cfg", "a"}, 2.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteKey([]string{"token"}); err != nil {
+		t.Fatal(err)
+	}
+	events = nil
+
+	if err := s.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"cfg": map[string]any{"a": 1.0}, "token": "t"}
+	if got, _ := s.GetAll(false); !deepEqual(got, want) {
+		t.Fatalf("GetAll = %v, want %v", got, want)
+	}
+	if _, ok := s.ExpiresAt([]string{"token"}); !ok {
+		t.Fatal("expiry of token not restored")
+	}
+	if len(events) != 1 || events[0].Op != mapstore.OpSetFile {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// The snapshot is unaffected by the restored store changing, and can be restored again.
+	if err := s.SetKey([]string{"cfg", "a"}, 3.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	reopened := openStore(p)
+	defer reopened.Close()
+	if got, _ := reopened.GetAll(false); !deepEqual(got, want) {
+		t.Fatalf("file after restore = %v, want %v", got, want)
+	}
+
+	other := openStore(filepath.Join(dir, "other.json"))
+	defer other.Close()
+	if err := other.Restore(snap); err == nil {
+		t.Fatal("restore of another store's snapshot: want error")
+	}
+}
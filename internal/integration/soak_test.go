@@ -0,0 +1,234 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/internal/faultfs"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+// soakDuration is how long soak tests run: MAPSTORE_SOAK, e.g. "10m", for long runs, else a short smoke run.
+func soakDuration(t *testing.T) time.Duration {
+	t.Helper()
+	v := os.Getenv("MAPSTORE_SOAK")
+	if v == "" {
+		return 300 * time.Millisecond
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		t.Fatalf("invalid MAPSTORE_SOAK: %v", err)
+	}
+	return d
+}
+
+// soakFaults returns a fault injecting file system seeded from the clock, logging the seed to repeat a run.
+func soakFaults(t *testing.T) *faultfs.FS {
+	t.Helper()
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("fault seed %d", seed)
+	return faultfs.New(faultfs.Config{
+		EIO:           0.05,
+		ShortWrite:    0.05,
+		RenameFailure: 0.05,
+		SlowStat:      0.05,
+		StatDelay:     time.Millisecond,
+		Seed:          seed,
+	})
+}
+
+// checkCounterFile reads the counter n of the JSON file p from disk. The file must always be a complete
+// document whose n is at least the last acknowledged write and at most the last attempted one.
+func checkCounterFile(p string, acked, attempted int) error {
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("torn file %s: %w: %q", p, err, raw)
+	}
+	n, _ := data["n"].(float64)
+	if int(n) < acked || int(n) > attempted {
+		return fmt.Errorf("file %s has n = %v, want between acknowledged %d and attempted %d", p, n, acked, attempted)
+	}
+	return nil
+}
+
+// checkTempFiles fails if dir holds more temp files than removals failed by injected faults.
+func checkTempFiles(t *testing.T, dir string, fs *faultfs.FS) {
+	t.Helper()
+	tmps, err := filepath.Glob(filepath.Join(dir, "*.tmp-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(tmps)) > fs.Stats().Removes {
+		t.Fatalf("temp files left behind: %v, removals failed %d", tmps, fs.Stats().Removes)
+	}
+}
+
+func TestSoak_MapFileStoreFaults(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "soak.json")
+	fs := soakFaults(t)
+	fs.SetEnabled(false)
+	s := openStore(p, mapstore.WithFileSystem(fs))
+	defer s.Close()
+	if err := s.SetKey([]string{"n"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	fs.SetEnabled(true)
+
+	// A reader reloads concurrently, so reloads race with failing flushes.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_, _ = s.GetAll(true)
+			}
+		}
+	})
+
+	acked, attempt, failed := 0, 0, 0
+	for deadline := time.Now().Add(soakDuration(t)); time.Now().Before(deadline); {
+		attempt++
+		err := s.SetKey([]string{"n"}, attempt)
+		switch {
+		case err == nil:
+			acked = attempt
+		case errors.Is(err, mapstore.ErrFileConflict):
+			// A failed stat after a rename leaves the store behind the file, reload it.
+			failed++
+			_, _ = s.GetAll(true)
+		default:
+			failed++
+		}
+		if err := checkCounterFile(p, acked, attempt); err != nil {
+			close(stop)
+			wg.Wait()
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	t.Logf("%d writes, %d failed, faults %+v", attempt, failed, fs.Stats())
+
+	// Without faults the store recovers and the last write is durable.
+	fs.SetEnabled(false)
+	if _, err := s.GetAll(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKey([]string{"n"}, attempt+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCounterFile(p, attempt+1, attempt+1); err != nil {
+		t.Fatal(err)
+	}
+	checkTempFiles(t, dir, fs)
+}
+
+func TestSoak_MapDirectoryStoreFaults(t *testing.T) {
+	dir := t.TempDir()
+	fs := soakFaults(t)
+	mds, err := mapstore.NewMapDirectoryStore(
+		dir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileOptions(mapstore.WithFileSystem(fs)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+
+	const writers = 4
+	deadline := time.Now().Add(soakDuration(t))
+	acked := make([]int, writers)
+	attempts := make([]int, writers)
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Go(func() {
+			key := mapstore.FileKey{FileName: fmt.Sprintf("w%d.json", w)}
+			for time.Now().Before(deadline) {
+				attempts[w]++
+				err := mds.SetFileData(key, map[string]any{"n": attempts[w]})
+				if err == nil {
+					acked[w] = attempts[w]
+				} else if errors.Is(err, mapstore.ErrFileConflict) {
+					_, _ = mds.GetFileData(key, true)
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		for time.Now().Before(deadline) {
+			if _, _, err := mds.ListFiles(mapstore.ListingConfig{}, ""); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	})
+	wg.Wait()
+	t.Logf("attempts %v, acknowledged %v, faults %+v", attempts, acked, fs.Stats())
+
+	fs.SetEnabled(false)
+	for w := range writers {
+		p := filepath.Join(dir, fmt.Sprintf("w%d.json", w))
+		if acked[w] == 0 {
+			if _, err := os.Stat(p); err != nil {
+				continue
+			}
+		}
+		if err := checkCounterFile(p, acked[w], attempts[w]); err != nil {
+			t.Error(err)
+		}
+	}
+	checkTempFiles(t, dir, fs)
+}
+
+// TestFaults_ReachFileOperations checks that creating, deleting and refreshing a file go through the injected
+// file system.
+func TestFaults_ReachFileOperations(t *testing.T) {
+	dir := t.TempDir()
+	fs := faultfs.New(faultfs.Config{EIO: 1})
+	p := filepath.Join(dir, "f.json")
+	_, err := mapstore.NewMapFileStore(p, map[string]any{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true), mapstore.WithFileSystem(fs))
+	if !errors.Is(err, faultfs.ErrInjected) {
+		t.Fatalf("create: want an injected fault, got %v", err)
+	}
+
+	mds, err := mapstore.NewMapDirectoryStore(
+		dir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileOptions(mapstore.WithFileSystem(fs)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	fs.SetEnabled(false)
+	key := mapstore.FileKey{FileName: "f.json"}
+	if err := mds.SetFileData(key, map[string]any{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	fs.SetEnabled(true)
+	if _, err := mds.Refresh(t.Context()); !errors.Is(err, faultfs.ErrInjected) {
+		t.Fatalf("Refresh: want an injected fault, got %v", err)
+	}
+	if err := mds.DeleteFile(key); !errors.Is(err, faultfs.ErrInjected) {
+		t.Fatalf("DeleteFile: want an injected fault, got %v", err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("file removed despite the fault: %v", err)
+	}
+}
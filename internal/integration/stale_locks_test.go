@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapDirectoryStore_BreakStaleLocks(t *testing.T) {
+	baseDir := t.TempDir()
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir, true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "counter.json"}
+	if err := mds.SetFileData(key, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	write := func(name string, mtime time.Time) {
+		p := filepath.Join(baseDir, name)
+		if err := os.WriteFile(p, []byte("1"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Left behind by a crashed process.
+	write("counter.json.lock", old)
+	write(".counter.json.tmp-1700000000000000000", old)
+	// In use, or not owned by the store.
+	write("fresh.json", time.Now())
+	write("fresh.json.lock", time.Now())
+	write("notes.tmp", old)
+	write("app.lock", old)
+	write("report.json.tmp-1700000000000000000", old)
+
+	removed, err := mds.BreakStaleLocks(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(removed)
+	if want := []string{".counter.json.tmp-1700000000000000000", "counter.json.lock"}; !slices.Equal(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	for _, name := range []string{
+		"fresh.json.lock", "notes.tmp", "app.lock", "report.json.tmp-1700000000000000000", "counter.json",
+	} {
+		if _, err := os.Stat(filepath.Join(baseDir, name)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	st, err := mds.OpenFile(key, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := st.Increment([]string{"n"}, 1); err != nil || n != 1 {
+		t.Fatalf("Increment after breaking the lock = %d, %v", n, err)
+	}
+	if _, err := mds.BreakStaleLocks(0); err == nil {
+		t.Fatal("expected an error for a zero age")
+	}
+}
@@ -0,0 +1,45 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_StrictReads(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "shared.json")
+	writer := openStore(p)
+	defer writer.Close()
+	if err := writer.SetKey([]string{"v"}, "one"); err != nil {
+		t.Fatal(err)
+	}
+	strict := openStore(p, mapstore.WithStrictReads(true))
+	defer strict.Close()
+	lax := openStore(p)
+	defer lax.Close()
+
+	if err := writer.SetKey([]string{"v"}, "two"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := strict.GetKey([]string{"v"}); err != nil || v != "two" {
+		t.Fatalf("strict GetKey = %v, %v", v, err)
+	}
+	if v, _ := lax.GetKey([]string{"v"}); v != "one" {
+		t.Fatalf("lax GetKey = %v, want the cached value", v)
+	}
+
+	if err := writer.SetKey([]string{"v"}, "three"); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := strict.GetAll(false); err != nil || all["v"] != "three" {
+		t.Fatalf("strict GetAll = %v, %v", all, err)
+	}
+	// Writes of the strict store build on what it reloaded.
+	if err := strict.SetKey([]string{"w"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := writer.GetAll(true); all["v"] != "three" || all["w"] == nil {
+		t.Fatalf("writer sees %v", all)
+	}
+}
@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_ExpandTemplates(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tmpl.json")
+	content := `{
+		"server": {"host": "localhost", "port": 8080},
+		"url": "http://${key:server.host}:${key:server.port}/api",
+		"token": "Bearer ${env:TMPL_TEST_TOKEN}",
+		"port": "${key:server.port}",
+		"nested": {"list": ["${key:server.host}", 1]},
+		"self": "${key:self}",
+		"api.version": "v1",
+		"version": "${key:api\\.version}",
+		"missing": "${env:TMPL_TEST_UNDEFINED}"
+	}`
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMPL_TEST_TOKEN", "s3cret")
+	st := openStore(p, mapstore.WithReadProcessor(mapstore.ExpandTemplates))
+
+	tests := []struct {
+		keys []string
+		want any
+	}{
+		{[]string{"url"}, "http://localhost:8080/api"},
+		{[]string{"token"}, "Bearer s3cret"},
+		{[]string{"port"}, float64(8080)},
+		{[]string{"version"}, "v1"},
+		{[]string{"nested"}, map[string]any{"list": []any{"localhost", float64(1)}}},
+	}
+	for _, tc := range tests {
+		got, err := st.GetKey(tc.keys)
+		if err != nil || !deepEqual(got, tc.want) {
+			t.Errorf("GetKey(%v): got %v err %v, want %v", tc.keys, got, err, tc.want)
+		}
+	}
+	for _, k := range []string{"self", "missing"} {
+		if _, err := st.GetKey([]string{k}); err == nil {
+			t.Errorf("GetKey(%s): want error", k)
+		}
+	}
+
+	// Expanded values are never persisted.
+	if err := st.SetKey([]string{"self"}, "fixed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetKey([]string{"missing"}, "fixed"); err != nil {
+		t.Fatal(err)
+	}
+	all, err := st.GetAll(false)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if all["url"] != "http://localhost:8080/api" {
+		t.Fatalf("GetAll not expanded: %v", all["url"])
+	}
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "s3cret") || !strings.Contains(string(raw), "${key:server.host}") {
+		t.Fatalf("expanded value persisted: %s", raw)
+	}
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_SetTimeGetTime(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "time.json")
+	s := openStore(p)
+	defer s.Close()
+	at := time.Date(2024, 1, 31, 9, 30, 0, 500, time.FixedZone("CET", 3600))
+	if err := s.SetTime([]string{"job", "lastRun"}, at); err != nil {
+		t.Fatal(err)
+	}
+	file := readJSONFile(t, p)
+	if got := file["job"].(map[string]any)["lastRun"]; got != "2024-01-31T09:30:00.0000005+01:00" {
+		t.Fatalf("stored %v", got)
+	}
+	got, err := s.GetTime([]string{"job", "lastRun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(at) {
+		t.Fatalf("GetTime = %v, want %v", got, at)
+	}
+	if _, offset := got.Zone(); offset != 3600 {
+		t.Fatalf("offset = %d, want 3600", offset)
+	}
+
+	if err := s.SetKey([]string{"job", "n"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTime([]string{"job", "n"}); err == nil {
+		t.Fatal("want error for a value that is not a time")
+	}
+	if _, err := s.GetTime([]string{"job", "missing"}); err == nil {
+		t.Fatal("want error for a missing key")
+	}
+}
+
+func TestMapDirectoryStore_ListFilesContentFilter(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		data := map[string]any{"updated": base.Add(time.Duration(i) * time.Hour).Format(time.RFC3339Nano)}
+		if i == 5 {
+			data = map[string]any{"updated": "not a time"}
+		}
+		if err := mds.SetFileData(mapstore.FileKey{FileName: fmt.Sprintf("f%d.json", i)}, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := []string{"updated"}
+	list := func(filter mapstore.ContentFilter) []string {
+		t.Helper()
+		var names []string
+		token := ""
+		for {
+			entries, next, err := mds.ListFiles(
+				mapstore.ListingConfig{SortOrder: "asc", PageSize: 2, ContentFilter: filter}, token,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				names = append(names, e.FileInfo.Name())
+			}
+			if next == "" {
+				return names
+			}
+			token = next
+		}
+	}
+
+	if got := list(mapstore.TimeAfter(keys, base.Add(time.Hour))); fmt.Sprint(got) != "[f2.json f3.json f4.json]" {
+		t.Fatalf("TimeAfter = %v", got)
+	}
+	if got := list(mapstore.TimeBefore(keys, base.Add(2*time.Hour))); fmt.Sprint(got) != "[f0.json f1.json]" {
+		t.Fatalf("TimeBefore = %v", got)
+	}
+	from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+	if got := list(mapstore.TimeBetween(keys, from, to)); fmt.Sprint(got) != "[f1.json f2.json]" {
+		t.Fatalf("TimeBetween = %v", got)
+	}
+	if got := list(nil); len(got) != 6 {
+		t.Fatalf("unfiltered = %v", got)
+	}
+
+	// Candidates that were not open are closed again, so a later read sees a change made on disk.
+	key := mapstore.FileKey{FileName: "f0.json"}
+	if err := mds.CloseFile(key); err != nil {
+		t.Fatal(err)
+	}
+	list(mapstore.TimeAfter(keys, base))
+	if err := os.WriteFile(filepath.Join(mds.BaseDir(), "f0.json"), []byte(`{"updated":"x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := mds.GetFileData(key, false); err != nil || data["updated"] != "x" {
+		t.Fatalf("GetFileData after filtered listing = %v, %v", data, err)
+	}
+}
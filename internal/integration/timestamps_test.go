@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMapFileStore_Timestamps(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "stamped.json")
+	created, updated := []string{"meta", "createdAt"}, []string{"meta", "updatedAt"}
+	var events []mapstore.FileEvent
+	start := time.Now()
+	s := openStore(p,
+		mapstore.WithTimestamps(created, updated),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) { events = append(events, e) }),
+	)
+	defer s.Close()
+
+	// Creating the file stamps both.
+	createdAt, err := s.GetTime(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if createdAt.Before(start.Truncate(time.Second)) {
+		t.Fatalf("createdAt %v before open at %v", createdAt, start)
+	}
+
+	if err := s.SetKey([]string{"name"}, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %+v", events)
+	}
+	stamp := events[0].Data["meta"].(map[string]any)
+	if stamp["createdAt"] != createdAt.Format(time.RFC3339Nano) || stamp["updatedAt"] == nil {
+		t.Fatalf("event data meta = %v", stamp)
+	}
+	firstUpdate, err := s.GetTime(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SetAll replaces the data but keeps the creation time.
+	time.Sleep(2 * time.Millisecond)
+	if err := s.SetAll(map[string]any{"name": "y"}); err != nil {
+		t.Fatal(err)
+	}
+	file := readJSONFile(t, p)
+	meta, _ := file["meta"].(map[string]any)
+	if meta["createdAt"] != createdAt.Format(time.RFC3339Nano) {
+		t.Fatalf("createdAt after SetAll = %v, want %v", meta["createdAt"], createdAt)
+	}
+	if got, err := s.GetTime(updated); err != nil || !got.After(firstUpdate) {
+		t.Fatalf("updatedAt after SetAll = %v, %v; want after %v", got, err, firstUpdate)
+	}
+
+	// Reads do not stamp.
+	before := readJSONFile(t, p)
+	if _, err := s.GetAll(false); err != nil {
+		t.Fatal(err)
+	}
+	if after := readJSONFile(t, p); !deepEqual(after, before) {
+		t.Fatalf("read changed the file: %v, want %v", after, before)
+	}
+}
+
+func TestMapDirectoryStore_Timestamps(t *testing.T) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirTimestamps(nil, []string{"updatedAt"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	key := mapstore.FileKey{FileName: "a.json"}
+	if err := mds.SetFileData(key, map[string]any{"n": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := mds.GetFileData(key, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data["updatedAt"].(string); !ok || len(data) != 2 {
+		t.Fatalf("data = %v", data)
+	}
+}
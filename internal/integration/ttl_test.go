@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_SetKeyWithTTL(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ttl.json")
+	var events []mapstore.FileEvent
+	s := openStore(p, mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		events = append(events, e)
+	}))
+	if err := s.SetKeyWithTTL([]string{"tokens", "a"}, "x", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKeyWithTTL([]string{"tokens", "b"}, "y", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// Setting a key again clears its expiry.
+	if err := s.SetKey([]string{"tokens", "b"}, "kept"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.ExpiresAt([]string{"tokens", "b"}); ok {
+		t.Fatal("SetKey kept the expiry")
+	}
+	if _, ok := readJSONFile(t, p)["mapstore.expiry"]; !ok {
+		t.Fatal("expiry not persisted")
+	}
+	if all, _ := s.GetAll(false); all["mapstore.expiry"] != nil {
+		t.Fatalf("expiry visible to reads: %v", all)
+	}
+	s.Close()
+
+	s = openStore(p, mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		events = append(events, e)
+	}))
+	defer s.Close()
+	if _, ok := s.ExpiresAt([]string{"tokens", "a"}); !ok {
+		t.Fatal("expiry lost on reopen")
+	}
+	if n, err := s.ExpireKeys(); err != nil || n != 0 {
+		t.Fatalf("ExpireKeys before the ttl = %d, %v", n, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	events = nil
+	if n, err := s.ExpireKeys(); err != nil || n != 1 {
+		t.Fatalf("ExpireKeys = %d, %v", n, err)
+	}
+	if len(events) != 1 || events[0].Op != mapstore.OpDeleteKey || events[0].OldValue != "x" {
+		t.Fatalf("events = %+v", events)
+	}
+	want := map[string]any{"tokens": map[string]any{"b": "kept"}}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+}
+
+func TestMapFileStore_ExpirySweep(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "sweep.json")
+	var (
+		mu      sync.Mutex
+		deleted bool
+	)
+	s := openStore(p,
+		mapstore.WithExpirySweep(10*time.Millisecond),
+		mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = deleted || e.Op == mapstore.OpDeleteKey
+		}),
+	)
+	defer s.Close()
+	if err := s.SetKeyWithTTL([]string{"session"}, "s", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the sweeper to expire the key", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return deleted
+	})
+	if _, err := s.GetKey([]string{"session"}); err == nil {
+		t.Fatal("expired key still readable")
+	}
+}
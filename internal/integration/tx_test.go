@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_TransactionCommit(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tx.json")
+	var events []mapstore.FileEvent
+	s := openStore(p, mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		events = append(events, e)
+	}))
+	defer s.Close()
+	if err := s.SetAll(map[string]any{"a": 1.0, "old": true}); err != nil {
+		t.Fatal(err)
+	}
+	events = nil
+
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := tx.SetKey([]string{"a"}, 2.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SetKey([]string{"b", "c"}, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteKey([]string{"old"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.GetKey([]string{"a"}); v != 1.0 {
+		t.Fatalf("uncommitted change visible: %v", v)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"a": 2.0, "b": map[string]any{"c": "x"}}
+	if got := readJSONFile(t, p); !deepEqual(got, want) {
+		t.Fatalf("file = %v, want %v", got, want)
+	}
+	if len(events) != 1 || events[0].Op != mapstore.OpTransaction {
+		t.Fatalf("events = %v, want one transaction event", events)
+	}
+	changes := events[0].Changes
+	if len(changes) != 3 || changes[0].OldValue != 1.0 || changes[0].NewValue != 2.0 ||
+		changes[2].Op != mapstore.OpDeleteKey || changes[2].OldValue != true {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if err := tx.SetKey([]string{"a"}, 3.0); !errors.Is(err, mapstore.ErrTxDone) {
+		t.Fatalf("SetKey after Commit = %v, want ErrTxDone", err)
+	}
+}
+
+func TestMapFileStore_TransactionAtomic(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tx.json")
+	s := openStore(p)
+	defer s.Close()
+	if err := s.SetAll(map[string]any{"leaf": "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SetKey([]string{"a"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	// Setting below a string value fails at commit, so the first change must not be applied either.
+	if err := tx.SetKey([]string{"leaf", "x"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected Commit to fail")
+	}
+	if got := readJSONFile(t, p); !deepEqual(got, map[string]any{"leaf": "v"}) {
+		t.Fatalf("file after failed Commit = %v", got)
+	}
+	if all, _ := s.GetAll(false); !deepEqual(all, map[string]any{"leaf": "v"}) {
+		t.Fatalf("data after failed Commit = %v", all)
+	}
+
+	tx, err = s.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SetKey([]string{"a"}, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); !errors.Is(err, mapstore.ErrTxDone) {
+		t.Fatalf("Commit after Rollback = %v, want ErrTxDone", err)
+	}
+	if v, err := s.GetKey([]string{"a"}); err == nil {
+		t.Fatalf("rolled back change visible: %v", v)
+	}
+}
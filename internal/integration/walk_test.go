@@ -0,0 +1,52 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_Walk(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "walk.json")
+	s := openStore(p)
+	defer s.Close()
+	if err := s.SetAll(map[string]any{
+		"b": map[string]any{"y": 1.0, "x": map[string]any{"z": true}},
+		"a": []any{map[string]any{"skipped": 1.0}},
+		"c": "s",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	err := s.Walk(func(path []string, _ any) error {
+		visited = append(visited, strings.Join(path, "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(visited, " "), "a b b/x b/x/z b/y c"; got != want {
+		t.Fatalf("visited %q, want %q", got, want)
+	}
+
+	// ErrStopWalk stops early without error, other errors are returned.
+	visited = nil
+	err = s.Walk(func(path []string, _ any) error {
+		visited = append(visited, strings.Join(path, "/"))
+		if len(visited) == 2 {
+			return mapstore.ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil || len(visited) != 2 {
+		t.Fatalf("stop: visited %v, err %v", visited, err)
+	}
+	errBoom := errors.New("boom")
+	if err := s.Walk(func([]string, any) error { return errBoom }); !errors.Is(err, errBoom) {
+		t.Fatalf("err = %v, want %v", err, errBoom)
+	}
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+func TestMapFileStore_WatchFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "watched.json")
+	var (
+		mu       sync.Mutex
+		external []mapstore.FileEvent
+	)
+	s := openStore(p, mapstore.WithWatchFile(true), mapstore.WithFileListeners(func(e mapstore.FileEvent) {
+		if e.Op == mapstore.OpExternalReload || e.Op == mapstore.OpExternalChange {
+			mu.Lock()
+			external = append(external, e)
+			mu.Unlock()
+		}
+	}))
+	defer s.Close()
+	externalCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(external)
+	}
+
+	// Writes of the store itself are not reported.
+	if err := s.SetKey([]string{"own"}, "write"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := externalCount(); n != 0 {
+		t.Fatalf("%d external events for an own write", n)
+	}
+
+	// Another process replaces the file.
+	tmp := filepath.Join(dir, "watched.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"own": "write", "other": "process"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "external reload", func() bool {
+		v, err := s.GetKey([]string{"other"})
+		return err == nil && v == "process" && externalCount() > 0
+	})
+	mu.Lock()
+	e := external[0]
+	mu.Unlock()
+	if e.Op != mapstore.OpExternalReload || e.File != p || e.Data["other"] != "process" {
+		t.Fatalf("event = %+v", e)
+	}
+}
@@ -0,0 +1,64 @@
+package maputil
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+)
+
+// EqualOptions configures Equal.
+type EqualOptions struct {
+	// NumericCoercion compares numbers of any Go numeric type, and json.Number, by value, so that the int 2 set
+	// by a caller equals the float64 2 read back from a JSON file.
+	NumericCoercion bool
+}
+
+// Equal reports whether a and b are deeply equal. Maps and slices are compared element by element with the same
+// options, every other value with reflect.DeepEqual unless opts say otherwise.
+func Equal(a, b any, opts EqualOptions) bool {
+	if opts.NumericCoercion {
+		if af, ok := numberValue(a); ok {
+			bf, ok := numberValue(b)
+			return ok && af == bf
+		}
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) || (av == nil) != (bv == nil) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !Equal(v, w, opts) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		return ok && (av == nil) == (bv == nil) && slices.EqualFunc(av, bv, func(x, y any) bool {
+			return Equal(x, y, opts)
+		})
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func numberValue(v any) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
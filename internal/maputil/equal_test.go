@@ -0,0 +1,59 @@
+package maputil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	coerce := EqualOptions{NumericCoercion: true}
+	tests := []struct {
+		name       string
+		a, b       any
+		want       bool
+		wantCoerce bool
+	}{
+		{"same strings", "x", "x", true, true},
+		{"int and float", 2, 2.0, false, true},
+		{"int and different float", 2, 2.5, false, false},
+		{"uint8 and int64", uint8(7), int64(7), false, true},
+		{"json number", json.Number("3"), 3.0, false, true},
+		{"number and string", 2, "2", false, false},
+		{"nil and nil", nil, nil, true, true},
+		{"nil and zero", nil, 0, false, false},
+		{
+			"nested maps",
+			map[string]any{"a": map[string]any{"n": 1}, "l": []any{1, "x"}},
+			map[string]any{"a": map[string]any{"n": 1.0}, "l": []any{1.0, "x"}},
+			false, true,
+		},
+		{"extra key", map[string]any{"a": 1}, map[string]any{"a": 1, "b": 2}, false, false},
+		{"slice length", []any{1}, []any{1, 1}, false, false},
+		{"nil and empty map", map[string]any(nil), map[string]any{}, false, false},
+		{"map and slice", map[string]any{}, []any{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b, EqualOptions{}); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+			if got := Equal(tt.a, tt.b, coerce); got != tt.wantCoerce {
+				t.Errorf("Equal() with NumericCoercion = %v, want %v", got, tt.wantCoerce)
+			}
+			if got := Equal(tt.b, tt.a, coerce); got != tt.wantCoerce {
+				t.Errorf("Equal() with NumericCoercion, swapped = %v, want %v", got, tt.wantCoerce)
+			}
+		})
+	}
+}
+
+func TestDiffNumericCoercion(t *testing.T) {
+	old := map[string]any{"n": 2, "l": []any{1}}
+	cur := map[string]any{"n": 2.0, "l": []any{1.0}}
+	if got := Diff(old, cur, EqualOptions{}); len(got) != 2 {
+		t.Fatalf("Diff() = %v, want 2 differences", got)
+	}
+	if got := Diff(old, cur, EqualOptions{NumericCoercion: true}); len(got) != 0 {
+		t.Fatalf("Diff() with NumericCoercion = %v, want none", got)
+	}
+}
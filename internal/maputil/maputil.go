@@ -3,7 +3,7 @@ package maputil
 import (
 	"errors"
 	"fmt"
-	"strings"
+	"slices"
 )
 
 // KeyNotFoundError is a custom error type for missing keys.
@@ -28,7 +28,7 @@ func GetValueAtPath(data any, keys []string) (any, error) {
 	}
 	val, ok := parentMap[lastKey]
 	if !ok {
-		path := strings.Join(keys[:len(keys)-1], ".")
+		path := Path(keys[:len(keys)-1]).String()
 		return nil, &KeyNotFoundError{Key: lastKey, Path: path}
 	}
 	return val, nil
@@ -41,7 +41,7 @@ func SetValueAtPath(data any, keys []string, value any) error {
 		return err
 	}
 	if lastKey == "" {
-		return &KeyNotFoundError{Key: lastKey, Path: strings.Join(keys, ".")}
+		return &KeyNotFoundError{Key: lastKey, Path: Path(keys).String()}
 	}
 	parentMap[lastKey] = DeepCopyValue(value)
 	return nil
@@ -103,7 +103,7 @@ func NavigateToParentMap(
 		key := keys[i]
 		m, ok := current.(map[string]any)
 		if !ok {
-			path := strings.Join(keys[:i], ".")
+			path := Path(keys[:i]).String()
 			return nil, "", fmt.Errorf("path '%s' is not a map", path)
 		}
 		next, ok := m[key]
@@ -114,7 +114,7 @@ func NavigateToParentMap(
 				m[key] = newMap
 				current = newMap
 			} else {
-				path := strings.Join(keys[:i], ".")
+				path := Path(keys[:i]).String()
 				return nil, "", &KeyNotFoundError{Key: key, Path: path}
 			}
 		} else {
@@ -124,9 +124,55 @@ func NavigateToParentMap(
 
 	parentMap, ok := current.(map[string]any)
 	if !ok {
-		path := strings.Join(keys[:len(keys)-1], ".")
+		path := Path(keys[:len(keys)-1]).String()
 		return nil, "", fmt.Errorf("path '%s' is not a map", path)
 	}
 	lastKey = keys[len(keys)-1]
 	return parentMap, lastKey, nil
 }
+
+// Difference is one path at which two maps differ.
+// InOld and InNew report whether the path exists on either side, so nil values are told apart from missing ones.
+type Difference struct {
+	Path  []string
+	Old   any
+	New   any
+	InOld bool
+	InNew bool
+}
+
+// Diff returns the paths at which oldData and newData differ, sorted by path.
+// Nested maps are compared key by key; any other value, including slices, is compared as a whole with Equal.
+func Diff(oldData, newData map[string]any, opts EqualOptions) []Difference {
+	var out []Difference
+	diffMaps(nil, oldData, newData, opts, &out)
+	return out
+}
+
+func diffMaps(prefix []string, oldData, newData map[string]any, opts EqualOptions, out *[]Difference) {
+	keys := make([]string, 0, len(oldData)+len(newData))
+	for k := range oldData {
+		keys = append(keys, k)
+	}
+	for k := range newData {
+		if _, ok := oldData[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		path := append(slices.Clone(prefix), k)
+		ov, inOld := oldData[k]
+		nv, inNew := newData[k]
+		om, oldIsMap := ov.(map[string]any)
+		nm, newIsMap := nv.(map[string]any)
+		switch {
+		case inOld && inNew && oldIsMap && newIsMap:
+			diffMaps(path, om, nm, opts, out)
+		case inOld && inNew && Equal(ov, nv, opts):
+		default:
+			*out = append(*out, Difference{Path: path, Old: ov, New: nv, InOld: inOld, InNew: inNew})
+		}
+	}
+}
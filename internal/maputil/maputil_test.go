@@ -1015,3 +1015,61 @@ func TestNavigateToParentMap(t *testing.T) {
 		})
 	}
 }
+
+// TestDiff tests the Diff function.
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  map[string]any
+		new  map[string]any
+		want []Difference
+	}{
+		{
+			name: "Equal maps",
+			old:  map[string]any{"a": 1, "b": map[string]any{"c": []any{1, 2}}},
+			new:  map[string]any{"a": 1, "b": map[string]any{"c": []any{1, 2}}},
+			want: nil,
+		},
+		{
+			name: "Added, removed and changed keys are sorted by path",
+			old:  map[string]any{"b": 1, "c": "x"},
+			new:  map[string]any{"a": true, "b": 2},
+			want: []Difference{
+				{Path: []string{"a"}, New: true, InNew: true},
+				{Path: []string{"b"}, Old: 1, New: 2, InOld: true, InNew: true},
+				{Path: []string{"c"}, Old: "x", InOld: true},
+			},
+		},
+		{
+			name: "Nested maps are compared key by key",
+			old:  map[string]any{"a": map[string]any{"b": 1, "c": 2}},
+			new:  map[string]any{"a": map[string]any{"b": 1, "c": 3}},
+			want: []Difference{
+				{Path: []string{"a", "c"}, Old: 2, New: 3, InOld: true, InNew: true},
+			},
+		},
+		{
+			name: "Map replaced by a scalar",
+			old:  map[string]any{"a": map[string]any{"b": 1}},
+			new:  map[string]any{"a": "flat"},
+			want: []Difference{
+				{Path: []string{"a"}, Old: map[string]any{"b": 1}, New: "flat", InOld: true, InNew: true},
+			},
+		},
+		{
+			name: "Nil value differs from a missing key",
+			old:  map[string]any{},
+			new:  map[string]any{"a": nil},
+			want: []Difference{{Path: []string{"a"}, InNew: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.old, tt.new, EqualOptions{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package maputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// KeyOrder is the order of the keys of a map and, recursively, of the maps nested in it. Elements of slices
+// are children by their decimal index.
+type KeyOrder struct {
+	Keys     []string
+	Children map[string]*KeyOrder
+}
+
+// ReadKeyOrder reads the order of the object keys of the JSON document in r.
+func ReadKeyOrder(r io.Reader) (*KeyOrder, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("document is not a JSON object")
+	}
+	o := &KeyOrder{}
+	if err := readObjectOrder(dec, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// readObjectOrder reads the members of an object whose opening brace was consumed.
+func readObjectOrder(dec *json.Decoder, o *KeyOrder) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return errors.New("invalid object key")
+		}
+		o.Keys = append(o.Keys, key)
+		child, err := readValueOrder(dec)
+		if err != nil {
+			return err
+		}
+		o.setChild(key, child)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// readValueOrder reads one value and returns the order inside it, empty for scalars.
+func readValueOrder(dec *json.Decoder) (*KeyOrder, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := &KeyOrder{}
+		return o, readObjectOrder(dec, o)
+	case json.Delim('['):
+		o := &KeyOrder{}
+		for i := 0; dec.More(); i++ {
+			child, err := readValueOrder(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.setChild(strconv.Itoa(i), child)
+		}
+		_, err := dec.Token()
+		return o, err
+	default:
+		return &KeyOrder{}, nil
+	}
+}
+
+// setChild records the order inside the value at key, if it has any.
+func (o *KeyOrder) setChild(key string, child *KeyOrder) {
+	if len(child.Keys) == 0 && len(child.Children) == 0 {
+		return
+	}
+	if o.Children == nil {
+		o.Children = make(map[string]*KeyOrder)
+	}
+	o.Children[key] = child
+}
+
+// Reconcile updates o to the keys of data: keys no longer present are dropped, new keys are appended in sorted
+// order, recursively for nested maps and slices.
+func (o *KeyOrder) Reconcile(data map[string]any) {
+	o.Keys = slices.DeleteFunc(o.Keys, func(k string) bool {
+		_, ok := data[k]
+		return !ok
+	})
+	known := make(map[string]struct{}, len(o.Keys))
+	for _, k := range o.Keys {
+		known[k] = struct{}{}
+	}
+	var added []string
+	for k := range data {
+		if _, ok := known[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	slices.Sort(added)
+	o.Keys = append(o.Keys, added...)
+
+	children := o.Children
+	o.Children = nil
+	for _, k := range o.Keys {
+		o.reconcileChild(k, data[k], children[k])
+	}
+}
+
+func (o *KeyOrder) reconcileChild(key string, v any, child *KeyOrder) {
+	switch tv := v.(type) {
+	case map[string]any:
+		if child == nil {
+			child = &KeyOrder{}
+		}
+		child.Reconcile(tv)
+		o.setChild(key, child)
+	case []any:
+		if child == nil {
+			child = &KeyOrder{}
+		}
+		old := child.Children
+		child.Keys, child.Children = nil, nil
+		for i, e := range tv {
+			child.reconcileChild(strconv.Itoa(i), e, old[strconv.Itoa(i)])
+		}
+		o.setChild(key, child)
+	}
+}
+
+// OrderedMap is a map that marshals to JSON with its keys in the order of Order, and its nested maps likewise.
+// Keys missing from Order are written after the others, sorted.
+type OrderedMap struct {
+	Map   map[string]any
+	Order *KeyOrder
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeOrdered(&buf, m.Map, m.Order); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeOrdered(buf *bytes.Buffer, v any, o *KeyOrder) error {
+	if o == nil {
+		o = &KeyOrder{}
+	}
+	switch tv := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(tv))
+		listed := make(map[string]struct{}, len(tv))
+		for _, k := range o.Keys {
+			if _, ok := tv[k]; ok {
+				keys = append(keys, k)
+				listed[k] = struct{}{}
+			}
+		}
+		if len(keys) != len(tv) {
+			var rest []string
+			for k := range tv {
+				if _, ok := listed[k]; !ok {
+					rest = append(rest, k)
+				}
+			}
+			slices.Sort(rest)
+			keys = append(keys, rest...)
+		}
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			kb, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			buf.Write(kb)
+			buf.WriteByte(':')
+			if err := writeOrdered(buf, tv[k], o.Children[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, e := range tv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrdered(buf, e, o.Children[strconv.Itoa(i)]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
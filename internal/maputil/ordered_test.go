@@ -0,0 +1,59 @@
+package maputil
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyOrderRoundTrip(t *testing.T) {
+	src := `{"z":1,"a":{"y":true,"b":null},"m":[{"k2":1,"k1":2},"s"]}`
+	o, err := ReadKeyOrder(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(o.Keys, []string{"z", "a", "m"}) {
+		t.Fatalf("keys = %v", o.Keys)
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(src), &m); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(OrderedMap{Map: m, Order: o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Fatalf("marshaled %s, want %s", out, src)
+	}
+
+	if _, err := ReadKeyOrder(strings.NewReader(`[1]`)); err == nil {
+		t.Fatal("array document accepted")
+	}
+}
+
+func TestKeyOrderReconcile(t *testing.T) {
+	o, err := ReadKeyOrder(strings.NewReader(`{"z":1,"gone":2,"a":{"y":1,"b":2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]any{
+		"z":   1.0,
+		"a":   map[string]any{"y": 1.0, "b": 2.0, "c": 3.0},
+		"new": map[string]any{"q": 1.0, "p": 2.0},
+		"d":   []any{map[string]any{"x": 1.0, "w": 2.0}},
+	}
+	o.Reconcile(m)
+	if !reflect.DeepEqual(o.Keys, []string{"z", "a", "d", "new"}) {
+		t.Fatalf("keys = %v", o.Keys)
+	}
+	out, err := json.Marshal(OrderedMap{Map: m, Order: o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"z":1,"a":{"y":1,"b":2,"c":3},"d":[{"w":2,"x":1}],"new":{"p":2,"q":1}}`
+	if string(out) != want {
+		t.Fatalf("marshaled %s, want %s", out, want)
+	}
+}
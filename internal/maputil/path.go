@@ -0,0 +1,68 @@
+package maputil
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Path is a key path into nested maps. It is a []string, so it can be passed wherever keys are, and has a
+// dotted text form in which dots and backslashes inside keys are escaped with a backslash: the keys
+// {"a", "b.c"} are written `a.b\.c`.
+type Path []string
+
+// ParsePath parses the dotted form of a path. The empty string is the empty path. Empty keys, a trailing
+// backslash and escapes of other characters than '.' and '\' are errors.
+func ParsePath(s string) (Path, error) {
+	if s == "" {
+		return Path{}, nil
+	}
+	var (
+		p   Path
+		key strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) || (s[i+1] != '.' && s[i+1] != '\\') {
+				return nil, errors.New("invalid escape in path " + s)
+			}
+			i++
+			key.WriteByte(s[i])
+		case '.':
+			if key.Len() == 0 {
+				return nil, errors.New("empty key in path " + s)
+			}
+			p = append(p, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(c)
+		}
+	}
+	if key.Len() == 0 {
+		return nil, errors.New("empty key in path " + s)
+	}
+	return append(p, key.String()), nil
+}
+
+// String returns the dotted form of p, which ParsePath parses back to p.
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, k := range p {
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		for j := range len(k) {
+			if k[j] == '.' || k[j] == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(k[j])
+		}
+	}
+	return sb.String()
+}
+
+// Append returns a new path of p followed by keys. It never shares the backing array of p.
+func (p Path) Append(keys ...string) Path {
+	return append(slices.Clip(p), keys...)
+}
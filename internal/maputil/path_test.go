@@ -0,0 +1,44 @@
+package maputil
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    Path
+		wantErr bool
+	}{
+		{src: "", want: Path{}},
+		{src: "a", want: Path{"a"}},
+		{src: `a.b\.c.d`, want: Path{"a", "b.c", "d"}},
+		{src: `a\\.b`, want: Path{`a\`, "b"}},
+		{src: `\.\\`, want: Path{`.\`}},
+		{src: "a..b", wantErr: true},
+		{src: ".a", wantErr: true},
+		{src: "a.", wantErr: true},
+		{src: `a\`, wantErr: true},
+		{src: `a\b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePath(tt.src)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("ParsePath(%q) = %q, %v", tt.src, got, err)
+			continue
+		}
+		if err == nil && got.String() != tt.src {
+			t.Errorf("ParsePath(%q).String() = %q", tt.src, got.String())
+		}
+	}
+}
+
+func TestPathAppend(t *testing.T) {
+	p := make(Path, 1, 4)
+	p[0] = "a"
+	b, c := p.Append("b"), p.Append("c")
+	if !slices.Equal(b, Path{"a", "b"}) || !slices.Equal(c, Path{"a", "c"}) {
+		t.Fatalf("Append = %q, %q", b, c)
+	}
+}
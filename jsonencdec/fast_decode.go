@@ -0,0 +1,342 @@
+package jsonencdec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// maxNestingDepth matches the nesting limit of encoding/json.
+	maxNestingDepth = 10000
+	// maxPooledBuffer is the largest read buffer kept for reuse. Pooled buffers are dropped by the garbage
+	// collector when they are not used.
+	maxPooledBuffer = 256 << 20
+	// maxInternedLen and maxInterned bound the interning of string values; object keys are always interned.
+	maxInternedLen = 16
+	maxInterned    = 1 << 16
+)
+
+// decodeState is the reusable state of a fast decode.
+type decodeState struct {
+	buf    bytes.Buffer
+	intern map[string]string
+	data   []byte
+	pos    int
+	depth  int
+}
+
+var decodeStatePool = sync.Pool{
+	New: func() any { return &decodeState{intern: map[string]string{}} },
+}
+
+// decodeFast decodes the first JSON value of r into value if it is a *map[string]any or *any, with the same
+// result as encoding/json. It reports false, reading nothing, for other targets.
+func decodeFast(r io.Reader, value any) (bool, error) {
+	switch value.(type) {
+	case *map[string]any, *any:
+	default:
+		return false, nil
+	}
+
+	d, _ := decodeStatePool.Get().(*decodeState)
+	defer func() {
+		d.data = nil
+		clear(d.intern)
+		if d.buf.Cap() <= maxPooledBuffer {
+			d.buf.Reset()
+			decodeStatePool.Put(d)
+		}
+	}()
+	if _, err := d.buf.ReadFrom(r); err != nil {
+		return true, err
+	}
+	d.data, d.pos, d.depth = d.buf.Bytes(), 0, 0
+
+	d.skipSpace()
+	if d.pos == len(d.data) {
+		return true, io.EOF
+	}
+	v, err := d.value()
+	if err != nil {
+		return true, err
+	}
+
+	switch out := value.(type) {
+	case *map[string]any:
+		switch m := v.(type) {
+		case nil:
+			*out = nil
+		case map[string]any:
+			// Like encoding/json, keep an existing map and add the decoded keys to it.
+			if *out == nil {
+				*out = m
+			} else {
+				for k, e := range m {
+					(*out)[k] = e
+				}
+			}
+		default:
+			return true, fmt.Errorf("json: cannot unmarshal %s into Go value of type map[string]interface {}",
+				jsonKind(v))
+		}
+	case *any:
+		*out = v
+	}
+	return true, nil
+}
+
+func (d *decodeState) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *decodeState) syntaxError(what string) error {
+	if d.pos >= len(d.data) {
+		return errors.New("unexpected end of JSON input")
+	}
+	return fmt.Errorf("invalid character %q %s at offset %d", d.data[d.pos], what, d.pos)
+}
+
+// value decodes the value at pos, which is not whitespace.
+func (d *decodeState) value() (any, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.syntaxError("")
+	}
+	switch c := d.data[d.pos]; {
+	case c == '{':
+		return d.object()
+	case c == '[':
+		return d.array()
+	case c == '"':
+		s, err := d.str(false)
+		return s, err
+	case c == 't':
+		return true, d.literal("true")
+	case c == 'f':
+		return false, d.literal("false")
+	case c == 'n':
+		return nil, d.literal("null")
+	case c == '-' || ('0' <= c && c <= '9'):
+		return d.number()
+	default:
+		return nil, d.syntaxError("looking for beginning of value")
+	}
+}
+
+func (d *decodeState) literal(lit string) error {
+	for i := range len(lit) {
+		if d.pos >= len(d.data) || d.data[d.pos] != lit[i] {
+			return d.syntaxError("in literal " + lit)
+		}
+		d.pos++
+	}
+	return nil
+}
+
+func (d *decodeState) object() (any, error) {
+	d.depth++
+	if d.depth > maxNestingDepth {
+		return nil, errors.New("exceeded max depth")
+	}
+	d.pos++
+	m := make(map[string]any)
+	d.skipSpace()
+	if d.pos < len(d.data) && d.data[d.pos] == '}' {
+		d.pos++
+		d.depth--
+		return m, nil
+	}
+	for {
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return nil, d.syntaxError("looking for beginning of object key string")
+		}
+		key, err := d.str(true)
+		if err != nil {
+			return nil, err
+		}
+		d.skipSpace()
+		if d.pos >= len(d.data) || d.data[d.pos] != ':' {
+			return nil, d.syntaxError("after object key")
+		}
+		d.pos++
+		d.skipSpace()
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("")
+		}
+		switch d.data[d.pos] {
+		case ',':
+			d.pos++
+			d.skipSpace()
+		case '}':
+			d.pos++
+			d.depth--
+			return m, nil
+		default:
+			return nil, d.syntaxError("after object key:value pair")
+		}
+	}
+}
+
+func (d *decodeState) array() (any, error) {
+	d.depth++
+	if d.depth > maxNestingDepth {
+		return nil, errors.New("exceeded max depth")
+	}
+	d.pos++
+	// Like encoding/json, an empty array decodes to an empty, non-nil slice.
+	a := make([]any, 0)
+	d.skipSpace()
+	if d.pos < len(d.data) && d.data[d.pos] == ']' {
+		d.pos++
+		d.depth--
+		return a, nil
+	}
+	for {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("")
+		}
+		switch d.data[d.pos] {
+		case ',':
+			d.pos++
+			d.skipSpace()
+		case ']':
+			d.pos++
+			d.depth--
+			return a, nil
+		default:
+			return nil, d.syntaxError("after array element")
+		}
+	}
+}
+
+// str decodes the string at pos. Strings with escapes or invalid UTF-8 are left to encoding/json, so they
+// decode exactly as it would.
+func (d *decodeState) str(isKey bool) (string, error) {
+	start := d.pos
+	d.pos++
+	plain := true
+	for {
+		if d.pos >= len(d.data) {
+			return "", d.syntaxError("")
+		}
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			raw := d.data[start+1 : d.pos-1]
+			if plain && utf8.Valid(raw) {
+				return d.makeString(raw, isKey), nil
+			}
+			var s string
+			if err := json.Unmarshal(d.data[start:d.pos], &s); err != nil {
+				return "", err
+			}
+			return s, nil
+		case c == '\\':
+			plain = false
+			d.pos += 2
+		case c < 0x20:
+			return "", d.syntaxError("in string literal")
+		default:
+			d.pos++
+		}
+	}
+}
+
+// makeString returns raw as a string, shared with earlier equal keys and short values of this decode.
+func (d *decodeState) makeString(raw []byte, isKey bool) string {
+	if !isKey && (len(raw) > maxInternedLen || len(d.intern) >= maxInterned) {
+		return string(raw)
+	}
+	if s, ok := d.intern[string(raw)]; ok {
+		return s
+	}
+	s := string(raw)
+	d.intern[s] = s
+	return s
+}
+
+// number decodes the number at pos as a float64, checking the JSON number grammar.
+func (d *decodeState) number() (any, error) {
+	start := d.pos
+	if d.data[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos < len(d.data) && d.data[d.pos] == '0':
+		d.pos++
+	case d.pos < len(d.data) && '1' <= d.data[d.pos] && d.data[d.pos] <= '9':
+		d.digits()
+	default:
+		return nil, d.syntaxError("in numeric literal")
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		if d.digits() == 0 {
+			return nil, d.syntaxError("after decimal point in numeric literal")
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if d.digits() == 0 {
+			return nil, d.syntaxError("in exponent of numeric literal")
+		}
+	}
+	f, err := strconv.ParseFloat(string(d.data[start:d.pos]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("json: cannot unmarshal number %s into Go value of type float64",
+			d.data[start:d.pos])
+	}
+	return f, nil
+}
+
+func (d *decodeState) digits() int {
+	n := 0
+	for d.pos < len(d.data) && '0' <= d.data[d.pos] && d.data[d.pos] <= '9' {
+		d.pos++
+		n++
+	}
+	return n
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	default:
+		return "value"
+	}
+}
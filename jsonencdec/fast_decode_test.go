@@ -0,0 +1,102 @@
+package jsonencdec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var fastDecodeInputs = []string{
+	`{}`,
+	`  {"a": 1, "b": [true, false, null], "c": {"d": "e"}}  `,
+	`{"n": [0, -0, 1.5, -2e10, 3E-2, 1e308, 12345678901234567890]}`,
+	`{"s": "plain", "u": "こんにちは", "esc": "a\"b\\c\/d\n\té😀", "bad": "\ud800"}`,
+	"{\"invalid utf8\": \"\xff\xfe\"}",
+	`{"dup": 1, "dup": 2}`,
+	`{"empty": [], "nested": [[], {}, [{}]]}`,
+	`null`,
+	`[1, "two"]`,
+	`"str"`,
+	`{"trailing": 1} {"ignored": 2}`,
+	``,
+	`   `,
+	`{"a": 1,}`,
+	`{"a" 1}`,
+	`{"a": 01}`,
+	`{"a": 1.}`,
+	`{"a": 1e}`,
+	`{"a": -}`,
+	`{"a": tru}`,
+	`{"a": "unterminated}`,
+	"{\"a\": \"ctrl\x01\"}",
+	`{"a": "\x"}`,
+	`{"a": 1e400}`,
+	`{"a": [1, 2}`,
+	`{1: 2}`,
+	`[`,
+	strings.Repeat("[", 10001) + strings.Repeat("]", 10001),
+}
+
+// decodeBoth decodes src into a fresh target of the given kind with encoding/json and with FastDecode.
+func decodeBoth(t testing.TB, src string, intoAny bool) (std, fast any, stdErr, fastErr error) {
+	t.Helper()
+	if intoAny {
+		var a, b any
+		stdErr = JSONEncoderDecoder{}.Decode(strings.NewReader(src), &a)
+		fastErr = JSONEncoderDecoder{FastDecode: true}.Decode(strings.NewReader(src), &b)
+		return a, b, stdErr, fastErr
+	}
+	a, b := map[string]any{}, map[string]any{}
+	stdErr = JSONEncoderDecoder{}.Decode(strings.NewReader(src), &a)
+	fastErr = JSONEncoderDecoder{FastDecode: true}.Decode(strings.NewReader(src), &b)
+	return a, b, stdErr, fastErr
+}
+
+func TestFastDecodeMatchesEncodingJSON(t *testing.T) {
+	for _, src := range fastDecodeInputs {
+		for _, intoAny := range []bool{false, true} {
+			std, fast, stdErr, fastErr := decodeBoth(t, src, intoAny)
+			if (stdErr != nil) != (fastErr != nil) {
+				t.Errorf("%.40q (any=%v): encoding/json error %v, fast error %v", src, intoAny, stdErr, fastErr)
+				continue
+			}
+			if stdErr == nil && !reflect.DeepEqual(std, fast) {
+				t.Errorf("%.40q (any=%v): encoding/json %#v, fast %#v", src, intoAny, std, fast)
+			}
+		}
+	}
+}
+
+func TestFastDecodeKeepsExistingMap(t *testing.T) {
+	m := map[string]any{"old": 1.0}
+	if err := (JSONEncoderDecoder{FastDecode: true}).Decode(strings.NewReader(`{"new": 2}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]any{"old": 1.0, "new": 2.0}) {
+		t.Fatalf("map = %v", m)
+	}
+
+	// Other targets use encoding/json.
+	var s struct{ A int }
+	if err := (JSONEncoderDecoder{FastDecode: true}).Decode(strings.NewReader(`{"A": 3}`), &s); err != nil || s.A != 3 {
+		t.Fatalf("struct = %+v, %v", s, err)
+	}
+}
+
+func FuzzFastDecode(f *testing.F) {
+	for _, src := range fastDecodeInputs {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		// Only complete documents are compared: encoding/json reports errors in trailing data that the fast
+		// path, like a streaming decoder reading one value, does not look at.
+		if !json.Valid([]byte(src)) {
+			return
+		}
+		std, fast, stdErr, fastErr := decodeBoth(t, src, true)
+		if (stdErr != nil) != (fastErr != nil) || (stdErr == nil && !reflect.DeepEqual(std, fast)) {
+			t.Fatalf("%q: encoding/json %#v, %v; fast %#v, %v", src, std, stdErr, fast, fastErr)
+		}
+	})
+}
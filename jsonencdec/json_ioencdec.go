@@ -9,7 +9,13 @@ import (
 	"github.com/ppipada/mapstore-go/internal/encdecutil"
 )
 
-type JSONEncoderDecoder struct{}
+// JSONEncoderDecoder encodes and decodes values as indented JSON with encoding/json.
+type JSONEncoderDecoder struct {
+	// FastDecode decodes into *map[string]any and *any with a pooled parser that shares object keys and short
+	// strings within a document, which allocates much less for large files. The result is the same as with
+	// encoding/json.
+	FastDecode bool
+}
 
 // Encode encodes the given value into JSON format and writes it to the writer.
 func (d JSONEncoderDecoder) Encode(w io.Writer, value any) error {
@@ -38,6 +44,15 @@ func (d JSONEncoderDecoder) Decode(r io.Reader, value any) error {
 		return err
 	}
 
+	if d.FastDecode {
+		if ok, err := decodeFast(r, value); ok {
+			if err != nil {
+				return fmt.Errorf("failed to decode JSON: %w", err)
+			}
+			return nil
+		}
+	}
+
 	decoder := json.NewDecoder(r)
 
 	decoder.DisallowUnknownFields()
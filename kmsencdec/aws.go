@@ -0,0 +1,190 @@
+//go:build kmsencdec_aws
+
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the credentials requests to AWS KMS are signed with.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials.
+	SessionToken string
+}
+
+// AWSKMS wraps data keys with an AWS KMS key through the Encrypt and Decrypt actions of the KMS API, signed with
+// Signature Version 4. The wrapped key is the KMS ciphertext blob, which names the key version it was encrypted
+// with, so keys rotated in KMS keep unwrapping older data keys.
+type AWSKMS struct {
+	// Region is the AWS region of the key, e.g. "eu-west-1".
+	Region string
+	// KeyID is the key ID, key ARN, alias name or alias ARN used to wrap data keys.
+	KeyID string
+	// Credentials returns the credentials for each request, e.g. from the environment or an instance role.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// Endpoint overrides "https://kms.<Region>.amazonaws.com", e.g. for FIPS or VPC endpoints.
+	Endpoint string
+	// Client is used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// WrapKey implements KeyWrapper with the Encrypt action.
+func (a *AWSKMS) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var out struct {
+		CiphertextBlob []byte
+	}
+	in := map[string]any{"KeyId": a.KeyID, "Plaintext": dataKey}
+	if err := a.call(ctx, "Encrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return out.CiphertextBlob, nil
+}
+
+// UnwrapKey implements KeyWrapper with the Decrypt action. The key ID is passed along, so a ciphertext of
+// another key is rejected.
+func (a *AWSKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var out struct {
+		Plaintext []byte
+	}
+	in := map[string]any{"KeyId": a.KeyID, "CiphertextBlob": wrapped}
+	if err := a.call(ctx, "Decrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// call posts body to the KMS action and decodes the response into out. Byte slices are base64 encoded in JSON,
+// as the KMS API expects.
+func (a *AWSKMS) call(ctx context.Context, action string, body, out any) error {
+	if a.Credentials == nil {
+		return fmt.Errorf("aws kms %s: no credentials", action)
+	}
+	creds, err := a.Credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("aws kms %s: credentials: %w", action, err)
+	}
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = "https://kms." + a.Region + ".amazonaws.com"
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid aws kms endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	signAWSV4(req, payload, creds, a.Region, "kms", time.Now())
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("aws kms %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("aws kms %s: %s: %s", action, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("aws kms %s: invalid response: %w", action, err)
+	}
+	return nil
+}
+
+// signAWSV4 signs req with Signature Version 4. Every header set on req is signed, together with Host and
+// X-Amz-Date, which it sets.
+func signAWSV4(req *http.Request, payload []byte, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery returns the query sorted by key and value, encoded as Signature Version 4 requires.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for k, vs := range query {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters.
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,106 @@
+//go:build kmsencdec_aws
+
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWSV4(t *testing.T) {
+	// The example of the AWS Signature Version 4 documentation.
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSV4(req, nil, creds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("Authorization = %q\nwant %q", got, want)
+	}
+}
+
+func TestAWSKMS(t *testing.T) {
+	// The fake KMS "encrypts" by prefixing the key ID.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed := strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/")
+		if !signed || r.Header.Get("X-Amz-Security-Token") != "st" {
+			http.Error(w, `{"__type":"UnrecognizedClientException"}`, http.StatusBadRequest)
+			return
+		}
+		var in struct {
+			KeyID          string `json:"KeyId"`
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			blob := append([]byte(in.KeyID+":"), in.Plaintext...)
+			_ = json.NewEncoder(w).Encode(map[string]any{"CiphertextBlob": blob})
+		case "TrentService.Decrypt":
+			plain, ok := bytes.CutPrefix(in.CiphertextBlob, []byte(in.KeyID+":"))
+			if !ok {
+				http.Error(w, `{"__type":"IncorrectKeyException"}`, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"Plaintext": plain})
+		default:
+			http.Error(w, "unknown target", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	kms := func(keyID, accessKey string) *AWSKMS {
+		return &AWSKMS{
+			Region:   "eu-west-1",
+			KeyID:    keyID,
+			Endpoint: srv.URL,
+			Credentials: func(context.Context) (AWSCredentials, error) {
+				return AWSCredentials{AccessKeyID: accessKey, SecretAccessKey: "secret", SessionToken: "st"}, nil
+			},
+		}
+	}
+	e, err := NewEncryptedStringValueEncoderDecoder(kms("alias/app", "AKID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.String()
+	other, err := NewEncryptedStringValueEncoderDecoder(kms("alias/app", "AKID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out string
+	if err := other.Decode(strings.NewReader(encoded), &out); err != nil || out != "secret" {
+		t.Fatalf("Decode = %q, %v", out, err)
+	}
+
+	wrongKey, err := NewEncryptedStringValueEncoderDecoder(kms("alias/other", "AKID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrongKey.Decode(strings.NewReader(encoded), &out); err == nil {
+		t.Fatal("Decode with another key succeeded")
+	}
+	denied, err := NewEncryptedStringValueEncoderDecoder(kms("alias/app", "OTHER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := denied.Encode(&bytes.Buffer{}, "x"); err == nil {
+		t.Fatal("Encode with bad credentials succeeded")
+	}
+}
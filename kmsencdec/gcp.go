@@ -0,0 +1,96 @@
+//go:build kmsencdec_gcp
+
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GCPKMS wraps data keys with a Google Cloud KMS symmetric key through the encrypt and decrypt methods of the
+// Cloud KMS REST API. The wrapped key is the Cloud KMS ciphertext, which names the key version it was encrypted
+// with, so keys rotated in Cloud KMS keep unwrapping older data keys.
+type GCPKMS struct {
+	// KeyName is the resource name of the crypto key, e.g.
+	// "projects/p/locations/global/keyRings/r/cryptoKeys/k".
+	KeyName string
+	// Token returns the OAuth2 access token for each request, e.g. from an oauth2.TokenSource or the metadata
+	// server.
+	Token func(ctx context.Context) (string, error)
+	// Endpoint overrides "https://cloudkms.googleapis.com", e.g. for regional or private endpoints.
+	Endpoint string
+	// Client is used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// WrapKey implements KeyWrapper with the encrypt method.
+func (g *GCPKMS) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var out struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := g.call(ctx, "encrypt", map[string][]byte{"plaintext": dataKey}, &out); err != nil {
+		return nil, err
+	}
+	return out.Ciphertext, nil
+}
+
+// UnwrapKey implements KeyWrapper with the decrypt method.
+func (g *GCPKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var out struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := g.call(ctx, "decrypt", map[string][]byte{"ciphertext": wrapped}, &out); err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// call posts body to the key's method and decodes the response into out. Byte slices are base64 encoded in
+// JSON, as the Cloud KMS API expects.
+func (g *GCPKMS) call(ctx context.Context, method string, body, out any) error {
+	if g.Token == nil {
+		return fmt.Errorf("gcp kms %s: no token", method)
+	}
+	token, err := g.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("gcp kms %s: token: %w", method, err)
+	}
+	endpoint := strings.TrimSuffix(g.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = "https://cloudkms.googleapis.com"
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := endpoint + "/v1/" + strings.Trim(g.KeyName, "/") + ":" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid gcp kms endpoint: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gcp kms %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("gcp kms %s: %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("gcp kms %s: invalid response: %w", method, err)
+	}
+	return nil
+}
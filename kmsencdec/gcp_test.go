@@ -0,0 +1,84 @@
+//go:build kmsencdec_gcp
+
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGCPKMS(t *testing.T) {
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	// The fake Cloud KMS "encrypts" by prefixing the key name.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, `{"error":{"code":401}}`, http.StatusUnauthorized)
+			return
+		}
+		name, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), ":")
+		var in struct {
+			Plaintext  []byte `json:"plaintext"`
+			Ciphertext []byte `json:"ciphertext"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		switch method {
+		case "encrypt":
+			_ = json.NewEncoder(w).Encode(map[string]any{"ciphertext": append([]byte(name+":"), in.Plaintext...)})
+		case "decrypt":
+			plain, ok := bytes.CutPrefix(in.Ciphertext, []byte(name+":"))
+			if !ok {
+				http.Error(w, `{"error":{"code":400}}`, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"plaintext": plain})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	kms := func(name, token string) *GCPKMS {
+		return &GCPKMS{
+			KeyName:  name,
+			Endpoint: srv.URL,
+			Token:    func(context.Context) (string, error) { return token, nil },
+		}
+	}
+	e, err := NewEncryptedStringValueEncoderDecoder(kms(keyName, "tok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.String()
+	other, err := NewEncryptedStringValueEncoderDecoder(kms(keyName, "tok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out string
+	if err := other.Decode(strings.NewReader(encoded), &out); err != nil || out != "secret" {
+		t.Fatalf("Decode = %q, %v", out, err)
+	}
+
+	wrongKey, err := NewEncryptedStringValueEncoderDecoder(kms(keyName+"2", "tok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrongKey.Decode(strings.NewReader(encoded), &out); err == nil {
+		t.Fatal("Decode with another key succeeded")
+	}
+	denied, err := NewEncryptedStringValueEncoderDecoder(kms(keyName, "bad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := denied.Encode(&bytes.Buffer{}, "x"); err == nil {
+		t.Fatal("Encode with a bad token succeeded")
+	}
+}
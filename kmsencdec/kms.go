@@ -0,0 +1,275 @@
+// Package kmsencdec encrypts string values with AES-256-GCM under a data key that is wrapped by an external key
+// management service, so stores can meet key management requirements without keeping key material locally.
+//
+// An encoder generates one random data key, has the KeyWrapper wrap it, and stores the wrapped key with every
+// value it encodes; use one encoder per file for per-file data keys. Decoding unwraps the stored key, once per
+// distinct wrapped key, so values stay readable after the encoder moves to a new data key or the service rotates
+// its master key.
+//
+// The Vault transit, AWS KMS and Google Cloud KMS wrappers are compiled in with the kmsencdec_vault,
+// kmsencdec_aws and kmsencdec_gcp build tags. For other services wrap the client's encrypt and decrypt calls in
+// a FuncWrapper.
+package kmsencdec
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	formatV1    = 1
+	dataKeySize = 32
+
+	// DefaultTimeout bounds each call to the key management service.
+	DefaultTimeout = 30 * time.Second
+)
+
+// KeyWrapper wraps and unwraps data keys with a master key held by a key management service.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// FuncWrapper adapts a pair of functions, e.g. around the Encrypt and Decrypt calls of a cloud KMS client, to
+// KeyWrapper.
+type FuncWrapper struct {
+	Wrap   func(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap func(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// WrapKey implements KeyWrapper.
+func (f FuncWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return f.Wrap(ctx, dataKey)
+}
+
+// UnwrapKey implements KeyWrapper.
+func (f FuncWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return f.Unwrap(ctx, wrapped)
+}
+
+// EncryptedStringValueEncoderDecoder uses AES-256-GCM + base64 for encoding/decoding, with a data key wrapped by
+// a KeyWrapper.
+type EncryptedStringValueEncoderDecoder struct {
+	wrapper KeyWrapper
+	timeout time.Duration
+
+	mu sync.Mutex
+	// header is the value header for the current data key.
+	header   []byte
+	dataKeys map[string]cipher.AEAD
+}
+
+// Option is a functional option for configuring EncryptedStringValueEncoderDecoder.
+type Option func(*EncryptedStringValueEncoderDecoder)
+
+// WithTimeout bounds each call to the key management service. The default is DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(e *EncryptedStringValueEncoderDecoder) {
+		e.timeout = d
+	}
+}
+
+// NewEncryptedStringValueEncoderDecoder constructs a new instance wrapping its data key with wrapper.
+func NewEncryptedStringValueEncoderDecoder(
+	wrapper KeyWrapper,
+	opts ...Option,
+) (*EncryptedStringValueEncoderDecoder, error) {
+	if wrapper == nil {
+		return nil, errors.New("nil key wrapper")
+	}
+	e := &EncryptedStringValueEncoderDecoder{
+		wrapper:  wrapper,
+		timeout:  DefaultTimeout,
+		dataKeys: map[string]cipher.AEAD{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(e)
+		}
+	}
+	return e, nil
+}
+
+// RotateDataKey makes the encoder generate and wrap a new data key for the next encoded value. Values encoded
+// before stay readable.
+func (e *EncryptedStringValueEncoderDecoder) RotateDataKey() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.header = nil
+}
+
+func (e *EncryptedStringValueEncoderDecoder) Encode(w io.Writer, value any) error {
+	v, ok := value.(string)
+	if !ok {
+		return errors.New("got non string encode input")
+	}
+	encryptedData, err := e.encryptString(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(encryptedData))
+	return err
+}
+
+func (e *EncryptedStringValueEncoderDecoder) Decode(r io.Reader, value any) error {
+	encryptedData, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	decryptedData, err := e.decryptString(string(encryptedData))
+	if err != nil {
+		return err
+	}
+
+	valuePtr := reflect.ValueOf(value)
+	if valuePtr.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be a pointer. Kind: %v", valuePtr.Kind())
+	}
+	valueElem := valuePtr.Elem()
+	switch valueElem.Kind() {
+	case reflect.Interface:
+		valueElem.Set(reflect.ValueOf(decryptedData))
+	case reflect.String:
+		valueElem.SetString(decryptedData)
+	default:
+		return fmt.Errorf("value must be a pointer to a string or interface. Kind: %v", valueElem.Kind())
+	}
+	return nil
+}
+
+// encryptString encrypts plaintext and returns base64 of header, nonce and ciphertext. The header is the format
+// version and the length prefixed wrapped data key; it is authenticated as additional data.
+func (e *EncryptedStringValueEncoderDecoder) encryptString(plaintext string) (string, error) {
+	header, aesGCM, err := e.currentKey()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aesGCM.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	out = aesGCM.Seal(out, nonce, []byte(plaintext), header)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// decryptString decrypts a value produced by encryptString.
+func (e *EncryptedStringValueEncoderDecoder) decryptString(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 ciphertext: %w", err)
+	}
+	if len(data) < 3 {
+		return "", errors.New("ciphertext too short")
+	}
+	if data[0] != formatV1 {
+		return "", fmt.Errorf("unsupported format version %d", data[0])
+	}
+	headerLen := 3 + int(binary.BigEndian.Uint16(data[1:3]))
+	if len(data) < headerLen {
+		return "", errors.New("ciphertext too short")
+	}
+	header := data[:headerLen]
+	aesGCM, err := e.keyFor(header[3:])
+	if err != nil {
+		return "", err
+	}
+	rest := data[headerLen:]
+	if len(rest) < aesGCM.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := rest[:aesGCM.NonceSize()], rest[aesGCM.NonceSize():]
+	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt ciphertext: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// currentKey returns the value header for encoding and the cipher of its data key, generating and wrapping a
+// new data key on first use.
+func (e *EncryptedStringValueEncoderDecoder) currentKey() ([]byte, cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.header != nil {
+		return e.header, e.dataKeys[string(e.header[3:])], nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	wrapped, err := e.wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	n := len(wrapped)
+	if n == 0 || n > math.MaxUint16 {
+		return nil, nil, fmt.Errorf("unexpected wrapped data key length %d", n)
+	}
+	aesGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make([]byte, 0, 3+n)
+	header = append(header, formatV1)
+	header = binary.BigEndian.AppendUint16(header, uint16(n))
+	header = append(header, wrapped...)
+	e.header = header
+	e.dataKeys[string(wrapped)] = aesGCM
+	return header, aesGCM, nil
+}
+
+// keyFor returns the cipher for a wrapped data key, unwrapping it on first use.
+func (e *EncryptedStringValueEncoderDecoder) keyFor(wrapped []byte) (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if aesGCM, ok := e.dataKeys[string(wrapped)]; ok {
+		return aesGCM, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	dataKey, err := e.wrapper.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	if len(dataKey) != dataKeySize {
+		return nil, fmt.Errorf("unexpected key length: got %d, want %d", len(dataKey), dataKeySize)
+	}
+	aesGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	e.dataKeys[string(wrapped)] = aesGCM
+	return aesGCM, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM block cipher mode: %w", err)
+	}
+	return aesGCM, nil
+}
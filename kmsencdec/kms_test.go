@@ -0,0 +1,130 @@
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeKMS wraps keys by prefixing a master key version and reversing the bytes, counting calls.
+type fakeKMS struct {
+	version        string
+	wraps, unwraps atomic.Int32
+}
+
+func (f *fakeKMS) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	f.wraps.Add(1)
+	out := []byte(f.version + ":")
+	for i := len(dataKey) - 1; i >= 0; i-- {
+		out = append(out, dataKey[i])
+	}
+	return out, nil
+}
+
+func (f *fakeKMS) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	f.unwraps.Add(1)
+	_, rest, ok := bytes.Cut(wrapped, []byte(":"))
+	if !ok {
+		return nil, errors.New("not wrapped by this kms")
+	}
+	out := make([]byte, 0, len(rest))
+	for i := len(rest) - 1; i >= 0; i-- {
+		out = append(out, rest[i])
+	}
+	return out, nil
+}
+
+func TestEncodeDecode(t *testing.T) {
+	kms := &fakeKMS{version: "v1"}
+	e, err := NewEncryptedStringValueEncoderDecoder(kms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded []string
+	for _, in := range []string{"", "secret", strings.Repeat("x", 1000)} {
+		var buf bytes.Buffer
+		if err := e.Encode(&buf, in); err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, buf.String())
+		var out string
+		if err := e.Decode(&buf, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out != in {
+			t.Fatalf("round trip = %q, want %q", out, in)
+		}
+	}
+	if kms.wraps.Load() != 1 || kms.unwraps.Load() != 0 {
+		t.Fatalf("wraps %d, unwraps %d; want one wrap and cached keys", kms.wraps.Load(), kms.unwraps.Load())
+	}
+
+	// A fresh decoder unwraps the stored key once.
+	other, err := NewEncryptedStringValueEncoderDecoder(kms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, enc := range encoded[1:] {
+		var out any
+		if err := other.Decode(strings.NewReader(enc), &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if kms.unwraps.Load() != 1 {
+		t.Fatalf("unwraps = %d, want 1", kms.unwraps.Load())
+	}
+
+	// After rotating the data key, old values stay readable.
+	e.RotateDataKey()
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if kms.wraps.Load() != 2 {
+		t.Fatalf("wraps after rotation = %d, want 2", kms.wraps.Load())
+	}
+	var out string
+	if err := e.Decode(strings.NewReader(encoded[1]), &out); err != nil || out != "secret" {
+		t.Fatalf("Decode after rotation = %q, %v", out, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	failing := FuncWrapper{
+		Wrap: func(context.Context, []byte) ([]byte, error) { return nil, errors.New("kms down") },
+		Unwrap: func(context.Context, []byte) ([]byte, error) {
+			return nil, errors.New("kms down")
+		},
+	}
+	e, err := NewEncryptedStringValueEncoderDecoder(failing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode(&bytes.Buffer{}, "x"); err == nil || !strings.Contains(err.Error(), "kms down") {
+		t.Fatalf("Encode with a failing wrapper = %v", err)
+	}
+
+	good, err := NewEncryptedStringValueEncoderDecoder(&fakeKMS{version: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := good.Encode(&buf, "x"); err != nil {
+		t.Fatal(err)
+	}
+	var out string
+	if err := e.Decode(bytes.NewReader(buf.Bytes()), &out); err == nil {
+		t.Fatal("Decode with a failing wrapper succeeded")
+	}
+	for _, bad := range []string{"not base64!", "", "AgAA"} {
+		if err := good.Decode(strings.NewReader(bad), &out); err == nil {
+			t.Errorf("Decode(%q) succeeded", bad)
+		}
+	}
+	if _, err := NewEncryptedStringValueEncoderDecoder(nil); err == nil {
+		t.Fatal("nil wrapper succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+//go:build kmsencdec_vault
+
+package kmsencdec
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VaultTransit wraps data keys with a HashiCorp Vault transit secrets engine key. The wrapped key is the Vault
+// ciphertext, e.g. "vault:v1:...", so keys rotated in Vault keep unwrapping older data keys.
+type VaultTransit struct {
+	// Address is the Vault server, e.g. "https://vault.example.com:8200".
+	Address string
+	// Token is sent as X-Vault-Token.
+	Token string
+	// Mount is the transit mount path, "transit" if empty.
+	Mount string
+	// KeyName names the transit key.
+	KeyName string
+	// Client is used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// WrapKey implements KeyWrapper with the transit encrypt endpoint.
+func (v *VaultTransit) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out.Ciphertext), nil
+}
+
+// UnwrapKey implements KeyWrapper with the transit decrypt endpoint.
+func (v *VaultTransit) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &out); err != nil {
+		return nil, err
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(out.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault plaintext: %w", err)
+	}
+	return dataKey, nil
+}
+
+// call posts body to the transit endpoint op and decodes the data of the response into out.
+func (v *VaultTransit) call(ctx context.Context, op string, body, out any) error {
+	mount := strings.Trim(v.Mount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+	endpoint, err := url.JoinPath(v.Address, "v1", mount, op, v.KeyName)
+	if err != nil {
+		return fmt.Errorf("invalid vault address: %w", err)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault transit %s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault transit %s: %s: %s", op, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("vault transit %s: invalid response: %w", op, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("vault transit %s: invalid response data: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build kmsencdec_vault
+
+package kmsencdec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultTransit(t *testing.T) {
+	// The fake transit engine "encrypts" by tagging the base64 plaintext.
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/transit/encrypt/app", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "tok" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		var in struct{ Plaintext string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		data := map[string]string{"ciphertext": "vault:v1:" + in.Plaintext}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	mux.HandleFunc("POST /v1/transit/decrypt/app", func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Ciphertext string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": in.Ciphertext[9:]}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	e, err := NewEncryptedStringValueEncoderDecoder(&VaultTransit{Address: srv.URL, Token: "tok", KeyName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewEncryptedStringValueEncoderDecoder(&VaultTransit{Address: srv.URL, Token: "tok", KeyName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out string
+	if err := other.Decode(&buf, &out); err != nil || out != "secret" {
+		t.Fatalf("Decode = %q, %v", out, err)
+	}
+
+	denied, err := NewEncryptedStringValueEncoderDecoder(&VaultTransit{Address: srv.URL, Token: "bad", KeyName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := denied.Encode(&bytes.Buffer{}, "x"); err == nil {
+		t.Fatal("Encode with a bad token succeeded")
+	}
+}
@@ -0,0 +1,49 @@
+// Package kafka publishes mapstore FileEvents to a Kafka topic.
+//
+// The package does not import a Kafka client. Writer is satisfied by a small adapter, for example around
+// github.com/segmentio/kafka-go:
+//
+//	type writer struct{ w *kafka.Writer }
+//
+//	func (a writer) WriteMessages(ctx context.Context, msgs ...kafkalistener.Record) error {
+//		out := make([]kafka.Message, len(msgs))
+//		for i, m := range msgs {
+//			out[i] = kafka.Message{Topic: m.Topic, Key: m.Key, Value: m.Value}
+//		}
+//		return a.w.WriteMessages(ctx, out...)
+//	}
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ppipada/mapstore-go/listeners"
+)
+
+// Record is one message for Kafka. Key is the file path, so all events of a file land in one partition and stay
+// ordered.
+type Record struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Writer writes a batch of records synchronously, returning once they are acknowledged.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...Record) error
+}
+
+// New returns a sink writing every batch of events to topic in a single call.
+func New(w Writer, topic string, opts ...listeners.Option) (*listeners.Sink, error) {
+	if w == nil || topic == "" {
+		return nil, errors.New("kafka: writer and topic are required")
+	}
+	return listeners.NewSink(listeners.PublisherFunc(func(ctx context.Context, batch []listeners.Message) error {
+		recs := make([]Record, len(batch))
+		for i, m := range batch {
+			recs[i] = Record{Topic: topic, Key: []byte(m.Key), Value: m.Value}
+		}
+		return w.WriteMessages(ctx, recs...)
+	}), opts...)
+}
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/listeners"
+)
+
+type fakeWriter struct {
+	calls [][]Record
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...Record) error {
+	w.calls = append(w.calls, msgs)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&fakeWriter{}, ""); err == nil {
+		t.Error("empty topic accepted")
+	}
+	w := &fakeWriter{}
+	sink, err := New(w, "events", listeners.WithBatchSize(10), listeners.WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a", "b", "a"} {
+		sink.Listener()(mapstore.FileEvent{Op: mapstore.OpSetKey, File: f, Timestamp: time.Now()})
+	}
+	if err := sink.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.calls) != 1 || len(w.calls[0]) != 3 {
+		t.Fatalf("calls = %v, want one batch of 3", w.calls)
+	}
+	for i, want := range []string{"a", "b", "a"} {
+		r := w.calls[0][i]
+		if r.Topic != "events" || string(r.Key) != want {
+			t.Errorf("record %d = %s/%s", i, r.Topic, r.Key)
+		}
+	}
+}
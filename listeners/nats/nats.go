@@ -0,0 +1,37 @@
+// Package nats publishes mapstore FileEvents to a NATS subject.
+//
+// The package does not import the NATS client; *nats.Conn from github.com/nats-io/nats.go satisfies Conn:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	sink, _ := natslistener.New(nc, "mapstore.events")
+//	store, _ := mapstore.NewMapFileStore(path, nil, encdec, mapstore.WithFileListeners(sink.Listener()))
+package nats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ppipada/mapstore-go/listeners"
+)
+
+// Conn is the subset of *nats.Conn used to publish.
+type Conn interface {
+	Publish(subject string, data []byte) error
+	FlushWithContext(ctx context.Context) error
+}
+
+// New returns a sink publishing every event as one message on subject. A batch is flushed to the server before
+// it counts as delivered, so a lost connection makes the sink retry it.
+func New(conn Conn, subject string, opts ...listeners.Option) (*listeners.Sink, error) {
+	if conn == nil || subject == "" {
+		return nil, errors.New("nats: connection and subject are required")
+	}
+	return listeners.NewSink(listeners.PublisherFunc(func(ctx context.Context, batch []listeners.Message) error {
+		for _, m := range batch {
+			if err := conn.Publish(subject, m.Value); err != nil {
+				return err
+			}
+		}
+		return conn.FlushWithContext(ctx)
+	}), opts...)
+}
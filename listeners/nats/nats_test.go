@@ -0,0 +1,50 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/listeners"
+)
+
+type fakeConn struct {
+	subjects []string
+	flushes  int
+	flushErr int
+}
+
+func (c *fakeConn) Publish(subject string, _ []byte) error {
+	c.subjects = append(c.subjects, subject)
+	return nil
+}
+
+func (c *fakeConn) FlushWithContext(context.Context) error {
+	c.flushes++
+	if c.flushErr > 0 {
+		c.flushErr--
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, "s"); err == nil {
+		t.Error("nil connection accepted")
+	}
+	conn := &fakeConn{flushErr: 1}
+	sink, err := New(conn, "mapstore.events", listeners.WithRetry(1, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Listener()(mapstore.FileEvent{Op: mapstore.OpSetKey, File: "f", Timestamp: time.Now()})
+	if err := sink.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	// The first flush fails, so the message is published again.
+	if len(conn.subjects) != 2 || conn.subjects[0] != "mapstore.events" || conn.flushes != 2 {
+		t.Errorf("subjects = %v, flushes = %d", conn.subjects, conn.flushes)
+	}
+}
@@ -0,0 +1,276 @@
+// Package listeners exports FileEvents to message brokers.
+//
+// A Sink is a mapstore.FileListener that encodes events into versioned Envelopes and hands them, in batches and
+// with retries, to a Publisher. The nats and kafka subpackages adapt the clients of those brokers; they take
+// small interfaces instead of importing the client libraries, so applications that do not use a broker do not
+// pull in its dependencies.
+package listeners
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+)
+
+// EnvelopeVersion is the version of the Envelope format, bumped on incompatible changes.
+const EnvelopeVersion = 1
+
+// ErrClosed is returned when a closed Sink is used.
+var ErrClosed = errors.New("listeners: sink closed")
+
+// Envelope is the wire form of a FileEvent.
+type Envelope struct {
+	Version int `json:"v"`
+	// Seq numbers the events of one Sink, so consumers can detect gaps and reordering.
+	Seq       uint64             `json:"seq"`
+	Op        mapstore.Operation `json:"op"`
+	File      string             `json:"file"`
+	Keys      []string           `json:"keys,omitempty"`
+	OldValue  any                `json:"old,omitempty"`
+	NewValue  any                `json:"new,omitempty"`
+	Data      map[string]any     `json:"data,omitempty"`
+	Timestamp time.Time          `json:"ts"`
+	// Changes lists the mutations of a mapstore.OpTransaction.
+	Changes []Change `json:"changes,omitempty"`
+	// Actor and RequestID attribute the change, see mapstore.ContextWithActor.
+	Actor     string `json:"actor,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Change is the wire form of a mapstore.KeyChange.
+type Change struct {
+	Op       mapstore.Operation `json:"op"`
+	Keys     []string           `json:"keys"`
+	OldValue any                `json:"old,omitempty"`
+	NewValue any                `json:"new,omitempty"`
+}
+
+// Message is one encoded envelope. Key is the file, so brokers that partition by key keep the events of a file
+// in order.
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+// Publisher delivers a batch of messages. A returned error makes the Sink retry the whole batch.
+type Publisher interface {
+	Publish(ctx context.Context, batch []Message) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, batch []Message) error
+
+// Publish calls f.
+func (f PublisherFunc) Publish(ctx context.Context, batch []Message) error {
+	return f(ctx, batch)
+}
+
+// Sink batches events and publishes them from a background goroutine.
+type Sink struct {
+	pub           Publisher
+	batchSize     int
+	flushInterval time.Duration
+	bufferSize    int
+	maxRetries    int
+	retryBackoff  time.Duration
+	fullData      bool
+	onDrop        func(batch []Message, err error)
+
+	seq    atomic.Uint64
+	ch     chan Message
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// WithBatchSize sets the maximum number of messages per publish, 100 by default.
+func WithBatchSize(n int) Option {
+	return func(s *Sink) { s.batchSize = n }
+}
+
+// WithFlushInterval sets how long a partial batch waits for more events, 100ms by default.
+func WithFlushInterval(d time.Duration) Option {
+	return func(s *Sink) { s.flushInterval = d }
+}
+
+// WithBufferSize sets how many events may wait for publishing, 10000 by default. When the buffer is full the
+// listener blocks the writing store, so that no event is lost.
+func WithBufferSize(n int) Option {
+	return func(s *Sink) { s.bufferSize = n }
+}
+
+// WithRetry sets how often a failed batch is retried, and the backoff before the first retry, which doubles
+// with every further retry. The default is 5 retries starting at 100ms.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.retryBackoff = backoff
+	}
+}
+
+// WithFullData includes the complete file data after the change in every envelope. It is left out by default,
+// as it can be large.
+func WithFullData() Option {
+	return func(s *Sink) { s.fullData = true }
+}
+
+// WithDropHook is called with batches that could not be published after all retries.
+// Without a hook they are logged.
+func WithDropHook(fn func(batch []Message, err error)) Option {
+	return func(s *Sink) { s.onDrop = fn }
+}
+
+// NewSink starts a sink publishing to pub. Close it to flush pending events.
+func NewSink(pub Publisher, opts ...Option) (*Sink, error) {
+	if pub == nil {
+		return nil, errors.New("listeners: nil publisher")
+	}
+	s := &Sink{
+		pub:           pub,
+		batchSize:     100,
+		flushInterval: 100 * time.Millisecond,
+		bufferSize:    10000,
+		maxRetries:    5,
+		retryBackoff:  100 * time.Millisecond,
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.batchSize <= 0 || s.bufferSize <= 0 || s.flushInterval <= 0 || s.maxRetries < 0 || s.retryBackoff < 0 {
+		return nil, errors.New("listeners: invalid sink options")
+	}
+	s.ch = make(chan Message, s.bufferSize)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+// Listener returns the FileListener to register with a store.
+func (s *Sink) Listener() mapstore.FileListener {
+	return func(e mapstore.FileEvent) {
+		if err := s.send(e); err != nil {
+			slog.Warn("listeners: event not exported", "file", e.File, "op", e.Op, "error", err)
+		}
+	}
+}
+
+// Close publishes the pending events and stops the sink. If ctx ends first, pending events are dropped.
+func (s *Sink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	close(s.ch)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-s.done
+		return ctx.Err()
+	}
+}
+
+func (s *Sink) send(e mapstore.FileEvent) error {
+	env := Envelope{
+		Version:   EnvelopeVersion,
+		Op:        e.Op,
+		File:      e.File,
+		Keys:      e.Keys,
+		OldValue:  e.OldValue,
+		NewValue:  e.NewValue,
+		Timestamp: e.Timestamp,
+		Actor:     e.Actor,
+		RequestID: e.RequestID,
+	}
+	for _, c := range e.Changes {
+		env.Changes = append(env.Changes, Change{Op: c.Op, Keys: c.Keys, OldValue: c.OldValue, NewValue: c.NewValue})
+	}
+	if s.fullData {
+		env.Data = e.Data
+	}
+
+	// Hold the read lock while sending so that Close cannot close the channel under us.
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	env.Seq = s.seq.Add(1)
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	s.ch <- Message{Key: e.File, Value: b}
+	return nil
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	batch := make([]Message, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.publish(batch)
+		batch = make([]Message, 0, s.batchSize)
+	}
+	for {
+		select {
+		case m, ok := <-s.ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, m)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// publish delivers batch with retries and reports it to the drop hook if all attempts fail.
+func (s *Sink) publish(batch []Message) {
+	backoff := s.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = s.pub.Publish(s.ctx, batch); err == nil {
+			return
+		}
+		if attempt >= s.maxRetries || s.ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+		}
+		backoff *= 2
+	}
+	if s.onDrop != nil {
+		s.onDrop(batch, err)
+		return
+	}
+	slog.Error("listeners: dropped events after retries", "events", len(batch), "error", err)
+}
@@ -0,0 +1,125 @@
+package listeners
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]Message
+	fails   int
+}
+
+func (r *recorder) Publish(_ context.Context, batch []Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fails > 0 {
+		r.fails--
+		return errors.New("broker down")
+	}
+	r.batches = append(r.batches, append([]Message(nil), batch...))
+	return nil
+}
+
+func (r *recorder) messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Message
+	for _, b := range r.batches {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func TestSink_PublishesStoreEvents(t *testing.T) {
+	rec := &recorder{fails: 2}
+	sink, err := NewSink(rec, WithBatchSize(2), WithRetry(3, time.Millisecond), WithFullData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(t.TempDir(), "s.json")
+	st, err := mapstore.NewMapFileStore(f, map[string]any{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true), mapstore.WithFileListeners(sink.Listener()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := st.SetKey([]string{k}, k+"-value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	var envs []Envelope
+	for _, m := range rec.messages() {
+		if m.Key != f {
+			t.Errorf("message key = %q, want %q", m.Key, f)
+		}
+		var e Envelope
+		if err := json.Unmarshal(m.Value, &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Op == mapstore.OpSetKey {
+			envs = append(envs, e)
+		}
+	}
+	if len(envs) != 3 {
+		t.Fatalf("got %d setKey envelopes, want 3", len(envs))
+	}
+	for i, e := range envs {
+		if e.Version != EnvelopeVersion || e.NewValue == nil || e.Data == nil {
+			t.Errorf("envelope %d incomplete: %+v", i, e)
+		}
+		if i > 0 && e.Seq <= envs[i-1].Seq {
+			t.Errorf("sequence not increasing: %d after %d", e.Seq, envs[i-1].Seq)
+		}
+	}
+	if err := sink.Close(t.Context()); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestSink_DropsAfterRetries(t *testing.T) {
+	rec := &recorder{fails: 100}
+	dropped := make(chan int, 1)
+	sink, err := NewSink(rec, WithRetry(2, time.Millisecond),
+		WithDropHook(func(batch []Message, err error) { dropped <- len(batch) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Listener()(mapstore.FileEvent{Op: mapstore.OpSetKey, File: "x", Timestamp: time.Now()})
+	if err := sink.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-dropped:
+		if n != 1 {
+			t.Errorf("dropped batch of %d, want 1", n)
+		}
+	default:
+		t.Fatal("drop hook not called")
+	}
+	if rec.fails != 97 {
+		t.Errorf("publish attempts = %d, want 3", 100-rec.fails)
+	}
+}
+
+func TestNewSink_InvalidOptions(t *testing.T) {
+	if _, err := NewSink(nil); err == nil {
+		t.Error("nil publisher accepted")
+	}
+	if _, err := NewSink(&recorder{}, WithBatchSize(0)); err == nil {
+		t.Error("zero batch size accepted")
+	}
+}
@@ -0,0 +1,439 @@
+package mapqueue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+	"github.com/ppipada/mapstore-go/uuidv7filename"
+)
+
+const (
+	// PartitionReady holds messages that are waiting to be leased or are currently leased.
+	PartitionReady = "ready"
+	// PartitionDead holds messages that exceeded the maximum number of delivery attempts.
+	PartitionDead = "dead"
+
+	fileSuffix    = "msg"
+	fileExtension = "json"
+
+	keyBody       = "body"
+	keyEnqueuedAt = "enqueuedAt"
+	keyLease      = "lease"
+	keyAttempts   = "attempts"
+	keyUntil      = "until"
+
+	listPageSize = 100
+)
+
+var (
+	// ErrEmpty is returned by Lease when no message is currently available.
+	ErrEmpty = errors.New("mapqueue: no message available")
+	// ErrLeaseLost is returned by Ack/Nack when the message lease is no longer held by the caller.
+	ErrLeaseLost = errors.New("mapqueue: lease lost")
+	// ErrClosed is returned by the methods of a closed Queue.
+	ErrClosed = errors.New("mapqueue: queue is closed")
+)
+
+// Message is a leased queue entry.
+type Message struct {
+	ID         string
+	Body       map[string]any
+	Attempts   int
+	EnqueuedAt time.Time
+	LeaseUntil time.Time
+
+	fileName string
+}
+
+// Queue is an at-least-once durable queue backed by a MapDirectoryStore.
+// Every message is one file named by a UUIDv7, so listing a partition in ascending order yields FIFO order.
+// A leased message stays invisible until its visibility timeout expires, after which it is delivered again.
+// Messages leased more than maxAttempts times are moved to the dead-letter partition.
+type Queue struct {
+	mds               *mapstore.MapDirectoryStore
+	visibilityTimeout time.Duration
+	maxAttempts       int
+	now               func() time.Time
+
+	// Serializes leasing, Ack and Nack inside one process, so that a lease check and the write that follows it
+	// see the same file. Cross process safety comes from the file store CAS.
+	mu     sync.Mutex
+	closed bool
+}
+
+// Option configures a Queue.
+type Option func(*Queue)
+
+// WithVisibilityTimeout sets how long a leased message stays invisible to other consumers.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(q *Queue) {
+		q.visibilityTimeout = d
+	}
+}
+
+// WithMaxAttempts sets how many times a message is delivered before it is dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) {
+		q.maxAttempts = n
+	}
+}
+
+// New opens (or creates) a queue rooted at baseDir. Close releases the files it keeps open.
+func New(baseDir string, opts ...Option) (*Queue, error) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir,
+		true,
+		&queuePartitionProvider{},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirPageSize(listPageSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		mds:               mds,
+		visibilityTimeout: 30 * time.Second,
+		maxAttempts:       5,
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.visibilityTimeout <= 0 {
+		return nil, errors.New("mapqueue: visibility timeout must be positive")
+	}
+	if q.maxAttempts <= 0 {
+		return nil, errors.New("mapqueue: max attempts must be positive")
+	}
+	return q, nil
+}
+
+// Close closes the files the queue holds open. Messages stay on disk for the next New of the same directory;
+// the methods of a closed queue return ErrClosed. Closing twice is a no-op.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	return q.mds.CloseAll()
+}
+
+// checkOpen returns ErrClosed if the queue is closed.
+func (q *Queue) checkOpen() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Enqueue durably stores body as a new message and returns its id.
+func (q *Queue) Enqueue(body map[string]any) (string, error) {
+	if err := q.checkOpen(); err != nil {
+		return "", err
+	}
+	if body == nil {
+		return "", errors.New("mapqueue: nil body")
+	}
+	id, err := uuidv7filename.NewUUIDv7String()
+	if err != nil {
+		return "", err
+	}
+	info, err := uuidv7filename.Build(id, fileSuffix, fileExtension)
+	if err != nil {
+		return "", err
+	}
+	key := mapstore.FileKey{FileName: info.FileName, XAttr: PartitionReady}
+	data := map[string]any{
+		keyBody:       body,
+		keyEnqueuedAt: q.now().UTC().Format(time.RFC3339Nano),
+		keyLease:      map[string]any{keyAttempts: 0, keyUntil: ""},
+	}
+	if err := q.mds.SetFileData(key, data); err != nil {
+		return "", err
+	}
+	return id, q.mds.CloseFile(key)
+}
+
+// Lease returns the oldest visible message and hides it for the visibility timeout.
+// It returns ErrEmpty if no message is available.
+func (q *Queue) Lease() (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+
+	cfg := mapstore.ListingConfig{
+		SortOrder:        mapstore.SortOrderAscending,
+		FilterPartitions: []string{PartitionReady},
+		FilenamePrefix:   "",
+		PageSize:         listPageSize,
+	}
+	token := ""
+	for {
+		entries, next, err := q.mds.ListFiles(cfg, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			msg, ok, err := q.tryLease(entry.FileInfo.Name())
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return msg, nil
+			}
+		}
+		if next == "" {
+			return nil, ErrEmpty
+		}
+		token = next
+	}
+}
+
+// Ack removes a leased message from the queue.
+func (q *Queue) Ack(msg *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	key, err := q.checkLease(msg)
+	if err != nil {
+		return err
+	}
+	// The delete fails with ErrFileConflict if another process changed the file since checkLease read it.
+	return leaseErr(q.mds.DeleteFile(key))
+}
+
+// Nack releases the lease of msg so that it becomes visible again immediately.
+func (q *Queue) Nack(msg *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	key, err := q.checkLease(msg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = q.mds.CloseFile(key) }()
+	store, err := q.mds.OpenFile(key, false, map[string]any{})
+	if err != nil {
+		return err
+	}
+	// The store is the one checkLease read, so the write fails with ErrFileConflict if the file changed since.
+	return leaseErr(store.SetKey([]string{keyLease}, map[string]any{
+		keyAttempts: msg.Attempts,
+		keyUntil:    "",
+	}))
+}
+
+// leaseErr reports a write that lost the race for the message file as ErrLeaseLost.
+func leaseErr(err error) error {
+	if errors.Is(err, mapstore.ErrFileConflict) {
+		return fmt.Errorf("%w: %w", ErrLeaseLost, err)
+	}
+	return err
+}
+
+// ListDeadLetters returns one page of dead-lettered message ids.
+func (q *Queue) ListDeadLetters(pageToken string) (ids []string, nextPageToken string, err error) {
+	if err := q.checkOpen(); err != nil {
+		return nil, "", err
+	}
+	entries, next, err := q.mds.ListFiles(mapstore.ListingConfig{
+		SortOrder:        mapstore.SortOrderAscending,
+		FilterPartitions: []string{PartitionDead},
+		PageSize:         listPageSize,
+	}, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, entry := range entries {
+		info, err := uuidv7filename.Parse(entry.FileInfo.Name())
+		if err != nil {
+			continue
+		}
+		ids = append(ids, info.ID)
+	}
+	return ids, next, nil
+}
+
+// tryLease leases the named message if it is visible. Ok is false if the file could not be leased.
+func (q *Queue) tryLease(fileName string) (msg *Message, ok bool, err error) {
+	key := mapstore.FileKey{FileName: fileName, XAttr: PartitionReady}
+	defer func() { _ = q.mds.CloseFile(key) }()
+
+	// A failure to open or read means the message was acked or dead-lettered in the meantime.
+	store, openErr := q.mds.OpenFile(key, false, map[string]any{})
+	if openErr != nil {
+		return nil, false, nil
+	}
+	data, readErr := store.GetAll(true)
+	if readErr != nil {
+		return nil, false, nil
+	}
+	msg, err = messageFromData(fileName, data)
+	if err != nil {
+		return nil, false, err
+	}
+	now := q.now()
+	if !msg.LeaseUntil.IsZero() && now.Before(msg.LeaseUntil) {
+		return nil, false, nil
+	}
+	if msg.Attempts >= q.maxAttempts {
+		return nil, false, q.deadLetter(key, data)
+	}
+
+	msg.Attempts++
+	msg.LeaseUntil = now.Add(q.visibilityTimeout).UTC()
+	err = store.SetKey([]string{keyLease}, map[string]any{
+		keyAttempts: msg.Attempts,
+		keyUntil:    msg.LeaseUntil.Format(time.RFC3339Nano),
+	})
+	if errors.Is(err, mapstore.ErrFileConflict) {
+		// Another process won the race for this message.
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, true, nil
+}
+
+// deadLetter moves the message to the dead-letter partition.
+func (q *Queue) deadLetter(key mapstore.FileKey, data map[string]any) error {
+	deadKey := mapstore.FileKey{FileName: key.FileName, XAttr: PartitionDead}
+	if err := q.mds.SetFileData(deadKey, data); err != nil {
+		return err
+	}
+	if err := q.mds.CloseFile(deadKey); err != nil {
+		return err
+	}
+	if err := q.mds.DeleteFile(key); err != nil {
+		// The message stays live, drop the copy so that it is not in both partitions.
+		if delErr := q.mds.DeleteFile(deadKey); delErr != nil {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkLease verifies that msg is still leased with the same attempt and returns its key.
+func (q *Queue) checkLease(msg *Message) (mapstore.FileKey, error) {
+	if msg == nil || msg.fileName == "" {
+		return mapstore.FileKey{}, errors.New("mapqueue: invalid message")
+	}
+	key := mapstore.FileKey{FileName: msg.fileName, XAttr: PartitionReady}
+	data, err := q.mds.GetFileData(key, true)
+	if err != nil {
+		_ = q.mds.CloseFile(key)
+		return key, fmt.Errorf("%w: %w", ErrLeaseLost, err)
+	}
+	cur, err := messageFromData(msg.fileName, data)
+	if err != nil {
+		_ = q.mds.CloseFile(key)
+		return key, err
+	}
+	if cur.Attempts != msg.Attempts || !cur.LeaseUntil.Equal(msg.LeaseUntil) {
+		_ = q.mds.CloseFile(key)
+		return key, ErrLeaseLost
+	}
+	return key, nil
+}
+
+func messageFromData(fileName string, data map[string]any) (*Message, error) {
+	info, err := uuidv7filename.Parse(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("mapqueue: invalid message file %s: %w", fileName, err)
+	}
+	msg := &Message{ID: info.ID, fileName: fileName}
+	if body, ok := data[keyBody].(map[string]any); ok {
+		msg.Body = body
+	}
+	if s, ok := data[keyEnqueuedAt].(string); ok && s != "" {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			msg.EnqueuedAt = t
+		}
+	}
+	if lease, ok := data[keyLease].(map[string]any); ok {
+		switch n := lease[keyAttempts].(type) {
+		case float64:
+			msg.Attempts = int(n)
+		case int:
+			msg.Attempts = n
+		}
+		if s, ok := lease[keyUntil].(string); ok && s != "" {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("mapqueue: invalid lease in %s: %w", fileName, err)
+			}
+			msg.LeaseUntil = t
+		}
+	}
+	return msg, nil
+}
+
+// queuePartitionProvider places messages in the partition named by FileKey.XAttr.
+type queuePartitionProvider struct{}
+
+// GetPartitionDir implements the PartitionProvider interface.
+func (p *queuePartitionProvider) GetPartitionDir(key mapstore.FileKey) (string, error) {
+	partition, _ := key.XAttr.(string)
+	switch partition {
+	case "":
+		return PartitionReady, nil
+	case PartitionReady, PartitionDead:
+		return partition, nil
+	default:
+		return "", fmt.Errorf("mapqueue: unknown partition %q", partition)
+	}
+}
+
+// ValidatePartitionName implements the PartitionNameValidator interface.
+func (p *queuePartitionProvider) ValidatePartitionName(name string) error {
+	if name != PartitionReady && name != PartitionDead {
+		return &mapstore.InvalidPartitionNameError{Name: name, Reason: "not a queue partition"}
+	}
+	return nil
+}
+
+// ListPartitions implements the PartitionProvider interface over the fixed set of queue partitions.
+func (p *queuePartitionProvider) ListPartitions(
+	baseDir, sortOrder, pageToken string,
+	pageSize int,
+) (partitions []string, nextPageToken string, err error) {
+	all := []string{PartitionDead, PartitionReady}
+	if strings.EqualFold(sortOrder, mapstore.SortOrderDescending) {
+		all = []string{PartitionReady, PartitionDead}
+	}
+	start := 0
+	if pageToken != "" {
+		start, err = strconv.Atoi(pageToken)
+		if err != nil || start < 0 || start > len(all) {
+			return nil, "", fmt.Errorf("invalid page token: %s", pageToken)
+		}
+	}
+	if pageSize <= 0 {
+		pageSize = len(all)
+	}
+	end := min(start+pageSize, len(all))
+	if end < len(all) {
+		nextPageToken = strconv.Itoa(end)
+	}
+	return all[start:end], nextPageToken, nil
+}
@@ -0,0 +1,312 @@
+package mapqueue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestQueue_FIFOAndAck(t *testing.T) {
+	q := newTestQueue(t)
+
+	var ids []string
+	for i := range 3 {
+		id, err := q.Enqueue(map[string]any{"n": i})
+		if err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	for i, want := range ids {
+		msg, err := q.Lease()
+		if err != nil {
+			t.Fatalf("lease %d: %v", i, err)
+		}
+		if msg.ID != want {
+			t.Fatalf("lease %d: got id %s, want %s", i, msg.ID, want)
+		}
+		if got := msg.Body["n"]; got != float64(i) {
+			t.Fatalf("lease %d: body mismatch %v", i, msg.Body)
+		}
+		if msg.Attempts != 1 {
+			t.Fatalf("lease %d: attempts %d, want 1", i, msg.Attempts)
+		}
+		if err := q.Ack(msg); err != nil {
+			t.Fatalf("ack %d: %v", i, err)
+		}
+	}
+
+	if _, err := q.Lease(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("want ErrEmpty, got %v", err)
+	}
+}
+
+func TestQueue_VisibilityTimeoutAndNack(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	q := newTestQueue(t, WithVisibilityTimeout(time.Minute))
+	q.now = clock.Now
+
+	if _, err := q.Enqueue(map[string]any{"k": "v"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	first, err := q.Lease()
+	if err != nil {
+		t.Fatalf("lease: %v", err)
+	}
+
+	// Leased message is invisible.
+	if _, err := q.Lease(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("want ErrEmpty while leased, got %v", err)
+	}
+
+	// After the timeout it is delivered again, and the first lease is lost.
+	clock.Advance(2 * time.Minute)
+	second, err := q.Lease()
+	if err != nil {
+		t.Fatalf("re-lease: %v", err)
+	}
+	if second.ID != first.ID || second.Attempts != 2 {
+		t.Fatalf("unexpected redelivery %+v", second)
+	}
+	if err := q.Ack(first); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("stale ack: want ErrLeaseLost, got %v", err)
+	}
+
+	// Nack makes it visible immediately.
+	if err := q.Nack(second); err != nil {
+		t.Fatalf("nack: %v", err)
+	}
+	third, err := q.Lease()
+	if err != nil {
+		t.Fatalf("lease after nack: %v", err)
+	}
+	if third.Attempts != 3 {
+		t.Fatalf("attempts after nack: got %d, want 3", third.Attempts)
+	}
+}
+
+func TestQueue_DeadLetter(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	q := newTestQueue(t, WithVisibilityTimeout(time.Second), WithMaxAttempts(2))
+	q.now = clock.Now
+
+	id, err := q.Enqueue(map[string]any{"poison": true})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for range 2 {
+		if _, err := q.Lease(); err != nil {
+			t.Fatalf("lease: %v", err)
+		}
+		clock.Advance(2 * time.Second)
+	}
+
+	if _, err := q.Lease(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("want ErrEmpty after dead-lettering, got %v", err)
+	}
+	dead, next, err := q.ListDeadLetters("")
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	if next != "" || len(dead) != 1 || dead[0] != id {
+		t.Fatalf("unexpected dead letters %v (next=%q)", dead, next)
+	}
+}
+
+func TestQueue_ConcurrentConsumersDeliverOnce(t *testing.T) {
+	q := newTestQueue(t)
+	const n = 20
+	for i := range n {
+		if _, err := q.Enqueue(map[string]any{"n": i}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+		wg   sync.WaitGroup
+	)
+	for range 4 {
+		wg.Go(func() {
+			for {
+				msg, err := q.Lease()
+				if errors.Is(err, ErrEmpty) {
+					return
+				}
+				if err != nil {
+					t.Errorf("lease: %v", err)
+					return
+				}
+				mu.Lock()
+				seen[msg.ID]++
+				mu.Unlock()
+				if err := q.Ack(msg); err != nil {
+					t.Errorf("ack: %v", err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(seen) != n {
+		t.Fatalf("want %d distinct messages, got %d", n, len(seen))
+	}
+	for id, c := range seen {
+		if c != 1 {
+			t.Fatalf("message %s delivered %d times", id, c)
+		}
+	}
+}
+
+func TestQueue_ExpiringLeasesAckOnce(t *testing.T) {
+	// Leases expire while consumers still work, so Ack and Nack race with other consumers leasing the same
+	// message. A message must be acked once and never come back after its ack.
+	q := newTestQueue(t, WithVisibilityTimeout(time.Millisecond), WithMaxAttempts(1000))
+	const n = 10
+	for i := range n {
+		if _, err := q.Enqueue(map[string]any{"n": i}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		acked = map[string]int{}
+		wg    sync.WaitGroup
+	)
+	deadline := time.Now().Add(10 * time.Second)
+	for c := range 4 {
+		wg.Go(func() {
+			for i := 0; time.Now().Before(deadline); i++ {
+				mu.Lock()
+				done := len(acked) == n
+				mu.Unlock()
+				if done {
+					return
+				}
+				msg, err := q.Lease()
+				if errors.Is(err, ErrEmpty) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("lease: %v", err)
+					return
+				}
+				if (c+i)%3 == 0 {
+					if err := q.Nack(msg); err != nil && !errors.Is(err, ErrLeaseLost) {
+						t.Errorf("nack: %v", err)
+					}
+					continue
+				}
+				err = q.Ack(msg)
+				if errors.Is(err, ErrLeaseLost) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("ack: %v", err)
+					return
+				}
+				mu.Lock()
+				acked[msg.ID]++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(acked) != n {
+		t.Fatalf("acked %d of %d messages", len(acked), n)
+	}
+	for id, c := range acked {
+		if c != 1 {
+			t.Fatalf("message %s acked %d times", id, c)
+		}
+	}
+	time.Sleep(2 * time.Millisecond)
+	if msg, err := q.Lease(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("acked message came back: %+v, %v", msg, err)
+	}
+}
+
+func TestQueue_Close(t *testing.T) {
+	dir := t.TempDir()
+	q, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := q.Enqueue(map[string]any{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leased, err := q.Lease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if _, err := q.Lease(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("lease after close: %v, want ErrClosed", err)
+	}
+	if _, err := q.Enqueue(map[string]any{"n": 2}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("enqueue after close: %v, want ErrClosed", err)
+	}
+	if err := q.Ack(leased); !errors.Is(err, ErrClosed) {
+		t.Fatalf("ack after close: %v, want ErrClosed", err)
+	}
+
+	// The leased message is kept on disk and delivered again once its lease expires.
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	clock := &fakeClock{now: time.Now().Add(time.Hour)}
+	reopened.now = clock.Now
+	msg, err := reopened.Lease()
+	if err != nil || msg.ID != id || msg.Attempts != 2 {
+		t.Fatalf("lease after reopen = %+v, %v", msg, err)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	if _, err := New(t.TempDir(), WithMaxAttempts(0)); err == nil {
+		t.Fatal("want error for zero max attempts")
+	}
+	if _, err := New(t.TempDir(), WithVisibilityTimeout(0)); err == nil {
+		t.Fatal("want error for zero visibility timeout")
+	}
+}
+
+func newTestQueue(t *testing.T, opts ...Option) *Queue {
+	t.Helper()
+	q, err := New(t.TempDir(), opts...)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	return q
+}
@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// DefaultVersionKey is the top level key that records the schema version of a file.
+const DefaultVersionKey = "schemaVersion"
+
+// Func upgrades data from the previous version to the version it was registered with.
+// It may mutate and return data, or return a new map.
+type Func func(data map[string]any) (map[string]any, error)
+
+// Result describes one file that was migrated.
+type Result struct {
+	File        string
+	FromVersion int
+	ToVersion   int
+}
+
+type step struct {
+	version int
+	fn      Func
+}
+
+// Migrator holds an ordered list of migrations and implements mapstore.DataMigrator.
+// Files without a version key are treated as version 0, so default data for new files should
+// carry the latest version to skip migrations.
+type Migrator struct {
+	versionKey []string
+	onMigrated func(Result)
+
+	mu    sync.RWMutex
+	steps []step
+}
+
+// Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithVersionKey stores the schema version at the given path instead of DefaultVersionKey.
+func WithVersionKey(keys ...string) Option {
+	return func(m *Migrator) {
+		m.versionKey = slices.Clone(keys)
+	}
+}
+
+// WithResultHook registers a callback invoked after every successfully migrated file.
+func WithResultHook(fn func(Result)) Option {
+	return func(m *Migrator) {
+		m.onMigrated = fn
+	}
+}
+
+// New creates an empty Migrator.
+func New(opts ...Option) *Migrator {
+	m := &Migrator{versionKey: []string{DefaultVersionKey}}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register adds a migration that upgrades data to version.
+// Versions must be positive and registered in strictly increasing order.
+func (m *Migrator) Register(version int, fn Func) error {
+	if fn == nil {
+		return errors.New("migrations: nil migration func")
+	}
+	if version <= 0 {
+		return fmt.Errorf("migrations: invalid version %d", version)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(m.steps); n > 0 && m.steps[n-1].version >= version {
+		return fmt.Errorf(
+			"migrations: version %d must be greater than %d",
+			version,
+			m.steps[n-1].version,
+		)
+	}
+	m.steps = append(m.steps, step{version: version, fn: fn})
+	return nil
+}
+
+// LatestVersion returns the highest registered version, or 0 if none.
+func (m *Migrator) LatestVersion() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.steps) == 0 {
+		return 0
+	}
+	return m.steps[len(m.steps)-1].version
+}
+
+// Migrate implements mapstore.DataMigrator. It runs all pending migrations in order and
+// records the reached version in the data.
+func (m *Migrator) Migrate(file string, data map[string]any) (map[string]any, bool, error) {
+	m.mu.RLock()
+	steps := slices.Clone(m.steps)
+	m.mu.RUnlock()
+
+	from, err := m.version(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("migrations: %w", err)
+	}
+	if len(steps) == 0 {
+		return data, false, nil
+	}
+	if latest := steps[len(steps)-1].version; from > latest {
+		return nil, false, fmt.Errorf(
+			"migrations: file version %d is newer than latest known version %d",
+			from,
+			latest,
+		)
+	}
+
+	cur := from
+	for _, st := range steps {
+		if st.version <= cur {
+			continue
+		}
+		next, err := st.fn(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("migrations: to version %d: %w", st.version, err)
+		}
+		if next == nil {
+			return nil, false, fmt.Errorf("migrations: to version %d returned nil data", st.version)
+		}
+		data = next
+		cur = st.version
+	}
+	if cur == from {
+		return data, false, nil
+	}
+	if err := maputil.SetValueAtPath(data, m.versionKey, cur); err != nil {
+		return nil, false, fmt.Errorf("migrations: cannot record version: %w", err)
+	}
+	if m.onMigrated != nil {
+		m.onMigrated(Result{File: file, FromVersion: from, ToVersion: cur})
+	}
+	return data, true, nil
+}
+
+// version reads the stored schema version. A missing key means version 0.
+func (m *Migrator) version(data map[string]any) (int, error) {
+	v, err := maputil.GetValueAtPath(data, m.versionKey)
+	if err != nil {
+		var kne *maputil.KeyNotFoundError
+		if errors.As(err, &kne) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n != math.Trunc(n) || n < 0 {
+			return 0, fmt.Errorf("invalid schema version %v", n)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("invalid schema version of type %T", v)
+	}
+}
+
+// MigrateAll eagerly migrates every file matched by cfg.
+// The directory store must be created with mapstore.WithDirFileOptions(mapstore.WithDataMigrator(m)),
+// opening a file is what runs its pending migrations. It returns the number of files visited.
+// Visited files are removed from the open-store cache afterwards, so run it before handing out stores.
+func MigrateAll(mds *mapstore.MapDirectoryStore, cfg mapstore.ListingConfig) (int, error) {
+	visited := 0
+	token := ""
+	for {
+		entries, next, err := mds.ListFiles(cfg, token)
+		if err != nil {
+			return visited, err
+		}
+		for _, entry := range entries {
+			if _, err := mds.OpenFileEntry(entry); err != nil {
+				return visited, err
+			}
+			if err := mds.CloseFileEntry(entry); err != nil {
+				return visited, err
+			}
+			visited++
+		}
+		if next == "" {
+			return visited, nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,160 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestMigrator_Register(t *testing.T) {
+	m := New()
+	noop := func(d map[string]any) (map[string]any, error) { return d, nil }
+	if err := m.Register(1, noop); err != nil {
+		t.Fatalf("register 1: %v", err)
+	}
+	if err := m.Register(1, noop); err == nil {
+		t.Fatal("want error for duplicate version")
+	}
+	if err := m.Register(0, noop); err == nil {
+		t.Fatal("want error for version 0")
+	}
+	if err := m.Register(2, nil); err == nil {
+		t.Fatal("want error for nil func")
+	}
+	if got := m.LatestVersion(); got != 1 {
+		t.Fatalf("LatestVersion: got %d, want 1", got)
+	}
+}
+
+func TestMigrator_FileStoreOnOpen(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, f, `{"theme":"dark"}`)
+
+	var results []Result
+	m := newTestMigrator(t, WithResultHook(func(r Result) { results = append(results, r) }))
+
+	st, err := mapstore.NewMapFileStore(f, nil, jsonencdec.JSONEncoderDecoder{}, mapstore.WithDataMigrator(m))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	got, err := st.GetKey([]string{"ui", "theme"})
+	if err != nil || got != "dark" {
+		t.Fatalf("migrated value: got %v err %v", got, err)
+	}
+	if _, err := st.GetKey([]string{"theme"}); err == nil {
+		t.Fatal("old key should be gone after migration")
+	}
+	if len(results) != 1 || results[0].FromVersion != 0 || results[0].ToVersion != 2 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	// Persisted, so reopening does not migrate again.
+	raw, _ := os.ReadFile(f)
+	if !strings.Contains(string(raw), `"schemaVersion": 2`) {
+		t.Fatalf("version not persisted: %s", raw)
+	}
+	_, err = mapstore.NewMapFileStore(f, nil, jsonencdec.JSONEncoderDecoder{}, mapstore.WithDataMigrator(m))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("file migrated twice: %+v", results)
+	}
+}
+
+func TestMigrator_Errors(t *testing.T) {
+	t.Run("newer file version", func(t *testing.T) {
+		f := filepath.Join(t.TempDir(), "new.json")
+		writeFile(t, f, `{"schemaVersion":9}`)
+		_, err := mapstore.NewMapFileStore(
+			f, nil, jsonencdec.JSONEncoderDecoder{}, mapstore.WithDataMigrator(newTestMigrator(t)),
+		)
+		if err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Fatalf("want newer version error, got %v", err)
+		}
+	})
+
+	t.Run("failing migration leaves file untouched", func(t *testing.T) {
+		f := filepath.Join(t.TempDir(), "fail.json")
+		writeFile(t, f, `{"a":1}`)
+		boom := errors.New("boom")
+		m := New()
+		_ = m.Register(1, func(map[string]any) (map[string]any, error) { return nil, boom })
+		_, err := mapstore.NewMapFileStore(f, nil, jsonencdec.JSONEncoderDecoder{}, mapstore.WithDataMigrator(m))
+		if !errors.Is(err, boom) {
+			t.Fatalf("want boom, got %v", err)
+		}
+		raw, _ := os.ReadFile(f)
+		if string(raw) != `{"a":1}` {
+			t.Fatalf("file modified: %s", raw)
+		}
+	})
+}
+
+func TestMigrateAll(t *testing.T) {
+	base := t.TempDir()
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		writeFile(t, filepath.Join(base, name), `{"theme":"light"}`)
+	}
+
+	var results []Result
+	m := newTestMigrator(t, WithResultHook(func(r Result) { results = append(results, r) }))
+	mds, err := mapstore.NewMapDirectoryStore(
+		base, false, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirFileOptions(mapstore.WithDataMigrator(m)),
+	)
+	if err != nil {
+		t.Fatalf("dir store: %v", err)
+	}
+
+	n, err := MigrateAll(mds, mapstore.ListingConfig{})
+	if err != nil || n != 3 {
+		t.Fatalf("MigrateAll: visited %d err %v", n, err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("want 3 migrated files, got %+v", results)
+	}
+	data, err := mds.GetFileData(mapstore.FileKey{FileName: "b.json"}, false)
+	if err != nil {
+		t.Fatalf("GetFileData: %v", err)
+	}
+	if ui, _ := data["ui"].(map[string]any); ui["theme"] != "light" {
+		t.Fatalf("unexpected data %v", data)
+	}
+}
+
+// newTestMigrator moves "theme" to "ui.theme" in two steps.
+func newTestMigrator(t *testing.T, opts ...Option) *Migrator {
+	t.Helper()
+	m := New(opts...)
+	if err := m.Register(1, func(d map[string]any) (map[string]any, error) {
+		d["ui"] = map[string]any{}
+		return d, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register(2, func(d map[string]any) (map[string]any, error) {
+		if v, ok := d["theme"]; ok {
+			ui, _ := d["ui"].(map[string]any)
+			ui["theme"] = v
+			delete(d, "theme")
+		}
+		return d, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,278 @@
+// Package passphraseencdec encrypts string values with AES-256-GCM under a key derived from a passphrase with
+// argon2id, for environments where neither the OS keyring nor a KMS is available.
+//
+// Every encoded value carries the format version, the argon2id parameters and the salt its key was derived
+// with, so values stay readable after the default parameters change. The key is derived once per salt and
+// cached; values encoded by one encoder share a salt but never a nonce.
+package passphraseencdec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+const (
+	formatV1  = 1
+	saltSize  = 16
+	keySize   = 32
+	headerLen = 1 + 4 + 4 + 1 + saltSize
+
+	// maxMemoryKiB bounds the memory a value read from disk may ask the KDF for.
+	maxMemoryKiB = 4 << 20
+)
+
+// Params are the argon2id cost parameters.
+type Params struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// DefaultParams are the second recommended argon2id parameters of RFC 9106: 3 passes over 64 MiB.
+var DefaultParams = Params{Time: 3, MemoryKiB: 64 << 10, Threads: 4}
+
+func (p Params) validate() error {
+	if p.Time == 0 || p.MemoryKiB < 8*uint32(p.Threads) || p.Threads == 0 || p.MemoryKiB > maxMemoryKiB {
+		return fmt.Errorf("invalid argon2id parameters: %+v", p)
+	}
+	return nil
+}
+
+// PassphraseSource returns the passphrase. It is called once, when the first value is encoded or decoded.
+type PassphraseSource func() ([]byte, error)
+
+// EnvPassphrase reads the passphrase from the environment variable name.
+func EnvPassphrase(name string) PassphraseSource {
+	return func() ([]byte, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			return nil, fmt.Errorf("passphrase environment variable %s is not set", name)
+		}
+		return []byte(v), nil
+	}
+}
+
+// InteractivePassphrase prompts for the passphrase on the terminal, without echo. The prompt goes to stderr.
+// It fails if stdin is not a terminal.
+func InteractivePassphrase(prompt string) PassphraseSource {
+	return func() ([]byte, error) {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return nil, errors.New("cannot prompt for passphrase: stdin is not a terminal")
+		}
+		fmt.Fprint(os.Stderr, prompt)
+		pass, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return pass, nil
+	}
+}
+
+// EncryptedStringValueEncoderDecoder uses AES-256-GCM + base64 for encoding/decoding, with the key derived
+// from a passphrase.
+type EncryptedStringValueEncoderDecoder struct {
+	source PassphraseSource
+	params Params
+
+	mu         sync.Mutex
+	passphrase []byte
+	salt       []byte
+	keys       map[string][]byte
+}
+
+// Option is a functional option for configuring EncryptedStringValueEncoderDecoder.
+type Option func(*EncryptedStringValueEncoderDecoder)
+
+// WithParams sets the argon2id parameters for newly encoded values. Decoding uses the parameters stored with
+// each value.
+func WithParams(p Params) Option {
+	return func(e *EncryptedStringValueEncoderDecoder) {
+		e.params = p
+	}
+}
+
+// NewEncryptedStringValueEncoderDecoder constructs a new instance reading the passphrase from source.
+func NewEncryptedStringValueEncoderDecoder(
+	source PassphraseSource,
+	opts ...Option,
+) (*EncryptedStringValueEncoderDecoder, error) {
+	if source == nil {
+		return nil, errors.New("nil passphrase source")
+	}
+	e := &EncryptedStringValueEncoderDecoder{
+		source: source,
+		params: DefaultParams,
+		keys:   map[string][]byte{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(e)
+		}
+	}
+	if err := e.params.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *EncryptedStringValueEncoderDecoder) Encode(w io.Writer, value any) error {
+	v, ok := value.(string)
+	if !ok {
+		return errors.New("got non string encode input")
+	}
+	encryptedData, err := e.encryptString(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(encryptedData))
+	return err
+}
+
+func (e *EncryptedStringValueEncoderDecoder) Decode(r io.Reader, value any) error {
+	encryptedData, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	decryptedData, err := e.decryptString(string(encryptedData))
+	if err != nil {
+		return err
+	}
+
+	valuePtr := reflect.ValueOf(value)
+	if valuePtr.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be a pointer. Kind: %v", valuePtr.Kind())
+	}
+	valueElem := valuePtr.Elem()
+	switch valueElem.Kind() {
+	case reflect.Interface:
+		valueElem.Set(reflect.ValueOf(decryptedData))
+	case reflect.String:
+		valueElem.SetString(decryptedData)
+	default:
+		return fmt.Errorf("value must be a pointer to a string or interface. Kind: %v", valueElem.Kind())
+	}
+	return nil
+}
+
+// encryptString encrypts plaintext and returns base64 of header, nonce and ciphertext. The header is the format
+// version, the argon2id parameters and the salt; it is authenticated as additional data.
+func (e *EncryptedStringValueEncoderDecoder) encryptString(plaintext string) (string, error) {
+	e.mu.Lock()
+	if e.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			e.mu.Unlock()
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		e.salt = salt
+	}
+	salt := e.salt
+	e.mu.Unlock()
+
+	header := make([]byte, 0, headerLen)
+	header = append(header, formatV1)
+	header = binary.BigEndian.AppendUint32(header, e.params.Time)
+	header = binary.BigEndian.AppendUint32(header, e.params.MemoryKiB)
+	header = append(header, e.params.Threads)
+	header = append(header, salt...)
+
+	aesGCM, err := e.cipherFor(e.params, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aesGCM.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	out = aesGCM.Seal(out, nonce, []byte(plaintext), header)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// decryptString decrypts a value produced by encryptString.
+func (e *EncryptedStringValueEncoderDecoder) decryptString(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 ciphertext: %w", err)
+	}
+	if len(data) < headerLen {
+		return "", errors.New("ciphertext too short")
+	}
+	if data[0] != formatV1 {
+		return "", fmt.Errorf("unsupported format version %d", data[0])
+	}
+	header := data[:headerLen]
+	p := Params{
+		Time:      binary.BigEndian.Uint32(header[1:5]),
+		MemoryKiB: binary.BigEndian.Uint32(header[5:9]),
+		Threads:   header[9],
+	}
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	aesGCM, err := e.cipherFor(p, header[10:])
+	if err != nil {
+		return "", err
+	}
+	rest := data[headerLen:]
+	if len(rest) < aesGCM.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := rest[:aesGCM.NonceSize()], rest[aesGCM.NonceSize():]
+	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt ciphertext: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// cipherFor returns the AES-GCM cipher for the key derived with p and salt, deriving it on first use.
+func (e *EncryptedStringValueEncoderDecoder) cipherFor(p Params, salt []byte) (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.passphrase == nil {
+		pass, err := e.source()
+		if err != nil {
+			return nil, err
+		}
+		if len(pass) == 0 {
+			return nil, errors.New("empty passphrase")
+		}
+		e.passphrase = pass
+	}
+	id := fmt.Sprintf("%d/%d/%d/%x", p.Time, p.MemoryKiB, p.Threads, salt)
+	key, ok := e.keys[id]
+	if !ok {
+		key = argon2.IDKey(e.passphrase, salt, p.Time, p.MemoryKiB, p.Threads, keySize)
+		e.keys[id] = key
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM block cipher mode: %w", err)
+	}
+	return aesGCM, nil
+}
@@ -0,0 +1,98 @@
+package passphraseencdec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// cheap keeps the KDF fast in tests.
+var cheap = Params{Time: 1, MemoryKiB: 64, Threads: 1}
+
+func staticPassphrase(p string) PassphraseSource {
+	return func() ([]byte, error) { return []byte(p), nil }
+}
+
+func TestEncodeDecode(t *testing.T) {
+	e, err := NewEncryptedStringValueEncoderDecoder(staticPassphrase("correct horse"), WithParams(cheap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []string{"", "a", "こんにちは世界", strings.Repeat("x", 1000)} {
+		var buf bytes.Buffer
+		if err := e.Encode(&buf, in); err != nil {
+			t.Fatal(err)
+		}
+		if len(in) > 8 && strings.Contains(buf.String(), in) {
+			t.Fatalf("plaintext %q visible in %q", in, buf.String())
+		}
+		var out string
+		if err := e.Decode(&buf, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out != in {
+			t.Fatalf("round trip = %q, want %q", out, in)
+		}
+	}
+	if err := e.Encode(&bytes.Buffer{}, 1); err == nil {
+		t.Fatal("encoding a non string succeeded")
+	}
+}
+
+func TestDecodeUsesStoredParams(t *testing.T) {
+	enc, err := NewEncryptedStringValueEncoderDecoder(staticPassphrase("pw"), WithParams(cheap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.String()
+
+	// A decoder with other defaults reads the parameters from the value.
+	dec, err := NewEncryptedStringValueEncoderDecoder(
+		staticPassphrase("pw"), WithParams(Params{Time: 2, MemoryKiB: 128, Threads: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := dec.Decode(strings.NewReader(encoded), &out); err != nil || out != "secret" {
+		t.Fatalf("Decode = %v, %v", out, err)
+	}
+
+	wrong, err := NewEncryptedStringValueEncoderDecoder(staticPassphrase("not pw"), WithParams(cheap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrong.Decode(strings.NewReader(encoded), &out); err == nil {
+		t.Fatal("decoding with a wrong passphrase succeeded")
+	}
+
+	// The header is authenticated: changing the stored cost breaks decryption.
+	raw, _ := base64.StdEncoding.DecodeString(encoded)
+	raw[4]++
+	tampered := base64.StdEncoding.EncodeToString(raw)
+	if err := dec.Decode(strings.NewReader(tampered), &out); err == nil {
+		t.Fatal("decoding a tampered header succeeded")
+	}
+}
+
+func TestEnvPassphrase(t *testing.T) {
+	t.Setenv("MAPSTORE_TEST_PASSPHRASE", "from env")
+	pass, err := EnvPassphrase("MAPSTORE_TEST_PASSPHRASE")()
+	if err != nil || string(pass) != "from env" {
+		t.Fatalf("EnvPassphrase = %q, %v", pass, err)
+	}
+	if _, err := EnvPassphrase("MAPSTORE_TEST_PASSPHRASE_UNSET")(); err == nil {
+		t.Fatal("unset variable succeeded")
+	}
+	if _, err := NewEncryptedStringValueEncoderDecoder(nil); err == nil {
+		t.Fatal("nil source succeeded")
+	}
+	if _, err := NewEncryptedStringValueEncoderDecoder(staticPassphrase("x"), WithParams(Params{})); err == nil {
+		t.Fatal("zero params succeeded")
+	}
+}
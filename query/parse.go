@@ -0,0 +1,332 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits a query into tokens. Identifiers are kept as written, keywords are matched case-insensitively by
+// the parser.
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, fmt.Errorf("unterminated string at %d", i)
+				}
+				if src[j] == '\'' {
+					if j+1 < len(src) && src[j+1] == '\'' {
+						sb.WriteByte('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(src[j])
+				j++
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), pos: i})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i + 1
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:j], pos: i})
+			i = j
+		case isIdentRune(c):
+			j := i
+			for j < len(src) && (isIdentRune(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '.' ||
+				src[j] == '\\') {
+				if src[j] == '\\' && j+1 < len(src) {
+					// An escaped character of a data key path, see mapstore.Path.
+					j++
+				}
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		case strings.HasPrefix(src[i:], "<=") || strings.HasPrefix(src[i:], ">=") ||
+			strings.HasPrefix(src[i:], "!=") || strings.HasPrefix(src[i:], "<>"):
+			toks = append(toks, token{kind: tokOp, text: src[i : i+2], pos: i})
+			i += 2
+		case c == '=' || c == '<' || c == '>':
+			toks = append(toks, token{kind: tokOp, text: string(c), pos: i})
+			i++
+		case c == '(' || c == ')' || c == ',' || c == '*':
+			toks = append(toks, token{kind: tokPunct, text: string(c), pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+// keyword consumes the next token if it is the keyword kw.
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.keyword(kw) {
+		return p.errorf("expected %s", kw)
+	}
+	return nil
+}
+
+func (p *parser) punct(s string) bool {
+	t := p.peek()
+	if t.kind == tokPunct && t.text == s {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	t := p.peek()
+	found := t.text
+	if t.kind == tokEOF {
+		found = "end of query"
+	}
+	return fmt.Errorf("%s at %d, found %q", fmt.Sprintf(format, args...), t.pos, found)
+}
+
+// Parse parses a query of the form
+//
+//	SELECT <field>, ... | * [WHERE <condition>] [ORDER BY <field> [ASC|DESC]] [LIMIT <n>]
+//
+// Keywords are case-insensitive. See the package documentation for fields and conditions.
+func Parse(src string) (*Query, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	p := &parser{toks: toks}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return q, nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	if p.punct("*") {
+		q.Fields = []Field{FieldPath, FieldData}
+	} else {
+		for {
+			f, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			q.Fields = append(q.Fields, f)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("WHERE") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		q.Where = cond
+	}
+	if p.keyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		q.OrderBy = &f
+		if p.keyword("DESC") {
+			q.Desc = true
+		} else {
+			p.keyword("ASC")
+		}
+	}
+	if p.keyword("LIMIT") {
+		t := p.peek()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tokNumber || err != nil || n < 0 {
+			return nil, p.errorf("expected a limit")
+		}
+		p.i++
+		q.Limit = n
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return q, nil
+}
+
+func (p *parser) parseField() (Field, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return "", p.errorf("expected a field")
+	}
+	f := Field(t.text)
+	if !f.valid() {
+		return "", p.errorf("unknown field")
+	}
+	p.i++
+	return f, nil
+}
+
+func (p *parser) parseOr() (Cond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Cond, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Cond, error) {
+	if p.keyword("NOT") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{c}, nil
+	}
+	if p.punct("(") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, p.errorf("expected )")
+		}
+		return c, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (Cond, error) {
+	f, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp {
+		return nil, p.errorf("expected a comparison operator")
+	}
+	p.i++
+	op := t.text
+	if op == "<>" {
+		op = "!="
+	}
+	if p.keyword("NULL") {
+		if op != "=" && op != "!=" {
+			return nil, errors.New("null can only be compared with = and !=")
+		}
+		return Compare{Field: f, Op: op}, nil
+	}
+	v, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return Compare{Field: f, Op: op, Value: v}, nil
+}
+
+func (p *parser) parseLiteral() (any, error) {
+	t := p.peek()
+	var v any
+	switch t.kind {
+	case tokString:
+		v = t.text
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number")
+		}
+		v = n
+	case tokIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		default:
+			return nil, p.errorf("expected a value")
+		}
+	default:
+		return nil, p.errorf("expected a value")
+	}
+	p.i++
+	return v, nil
+}
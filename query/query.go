@@ -0,0 +1,460 @@
+// Package query runs small SQL like queries over the files of a MapDirectoryStore, as power user tooling for
+// command line and HTTP layers:
+//
+//	SELECT data.title, mtime WHERE data.archived = false AND partition >= '202401' ORDER BY mtime DESC LIMIT 20
+//
+// Fields are the file metadata path, name, partition, mtime and size, the whole document data, and values inside
+// it as data.<key>.<key>..., in the dotted form of mapstore.Path, so data.a\.b is the key "a.b". Conditions
+// compare a field with a string, number, true, false or null using =, != (or <>), <, <=, > and >=, and combine
+// with AND, OR, NOT and parentheses. mtime compares with RFC 3339 timestamps or dates, e.g. '2024-01-31'. A
+// comparison of a missing field or of different types is false, except for !=.
+//
+// Comparisons of partition that all conditions must satisfy select the partitions to read, everything else is
+// evaluated on a scan. File data is only read when the query uses it.
+package query
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// scanPageSize is the number of files listed and read per page of a scan.
+const scanPageSize = 100
+
+// Field names a value of a file, see the package documentation.
+type Field string
+
+const (
+	FieldPath      Field = "path"
+	FieldName      Field = "name"
+	FieldPartition Field = "partition"
+	FieldModTime   Field = "mtime"
+	FieldSize      Field = "size"
+	FieldData      Field = "data"
+)
+
+func (f Field) valid() bool {
+	switch f {
+	case FieldPath, FieldName, FieldPartition, FieldModTime, FieldSize, FieldData:
+		return true
+	default:
+		rest, ok := strings.CutPrefix(string(f), string(FieldData)+".")
+		keys, err := mapstore.ParsePath(rest)
+		return ok && err == nil && len(keys) > 0
+	}
+}
+
+func (f Field) usesData() bool {
+	return f == FieldData || strings.HasPrefix(string(f), string(FieldData)+".")
+}
+
+// Cond is a condition of a WHERE clause: Compare, And, Or or Not.
+type Cond interface {
+	eval(r *record) bool
+	walk(fn func(Cond))
+}
+
+// Compare compares a field with a value. A nil Value is null.
+type Compare struct {
+	Field Field
+	// Op is one of =, !=, <, <=, > and >=.
+	Op    string
+	Value any
+}
+
+// And is true if both conditions are.
+type And [2]Cond
+
+// Or is true if either condition is.
+type Or [2]Cond
+
+// Not negates a condition.
+type Not [1]Cond
+
+// Query is a parsed query.
+type Query struct {
+	Fields  []Field
+	Where   Cond
+	OrderBy *Field
+	Desc    bool
+	// Limit is the maximum number of rows, zero for all.
+	Limit int
+}
+
+// Result holds the rows of a query, with one value per column in Columns.
+type Result struct {
+	Columns []string
+	Rows    []Row
+}
+
+// Row is a matching file and its selected values. Missing values are nil.
+type Row struct {
+	Entry  mapstore.FileEntry
+	Values []any
+}
+
+// Run parses and runs src on mds.
+func Run(ctx context.Context, mds *mapstore.MapDirectoryStore, src string) (*Result, error) {
+	q, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(ctx, mds)
+}
+
+// Run runs the query on mds. Ctx is passed to the access checker of every listing and read, and a canceled ctx
+// stops the scan between pages. Without ORDER BY the scan stops once LIMIT rows matched. Only the selected values
+// of matching files are kept, and with ORDER BY and LIMIT only the first LIMIT of them.
+func (q *Query) Run(ctx context.Context, mds *mapstore.MapDirectoryStore) (*Result, error) {
+	res := &Result{}
+	for _, f := range q.Fields {
+		res.Columns = append(res.Columns, string(f))
+	}
+	partitions, err := q.partitions(ctx, mds)
+	if err != nil {
+		return nil, err
+	}
+	if partitions != nil && len(partitions) == 0 {
+		return res, nil
+	}
+
+	matches, err := q.scan(ctx, mds, partitions)
+	if err != nil {
+		return nil, err
+	}
+	if q.OrderBy != nil && q.Limit == 0 {
+		slices.SortStableFunc(matches, q.order)
+	}
+	for _, m := range matches {
+		res.Rows = append(res.Rows, m.row)
+	}
+	return res, nil
+}
+
+// match is a matching file with its selected values and, with ORDER BY, its sort value, nil if it has none.
+type match struct {
+	row     Row
+	sortKey any
+}
+
+func (q *Query) newMatch(r *record) *match {
+	m := &match{row: Row{Entry: r.entry, Values: make([]any, len(q.Fields))}}
+	for i, f := range q.Fields {
+		m.row.Values[i], _ = r.get(f)
+	}
+	if q.OrderBy != nil {
+		m.sortKey, _ = r.get(*q.OrderBy)
+	}
+	return m
+}
+
+// add adds m to the sorted matches with ORDER BY and LIMIT, keeping the first LIMIT. A match sorts after the
+// matches it is equal to, as with a stable sort.
+func (q *Query) add(matches []*match, m *match) []*match {
+	i := sort.Search(len(matches), func(i int) bool { return q.order(m, matches[i]) < 0 })
+	if i == q.Limit {
+		return matches
+	}
+	matches = slices.Insert(matches, i, m)
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches
+}
+
+func (q *Query) scan(
+	ctx context.Context,
+	mds *mapstore.MapDirectoryStore,
+	partitions []string,
+) ([]*match, error) {
+	cfg := mapstore.ListingConfig{PageSize: scanPageSize, FilterPartitions: partitions}
+	needData := q.usesData()
+	var matches []*match
+	token := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var (
+			page []*record
+			next string
+		)
+		if needData {
+			items, nextToken, err := mapstore.ListDecodedContext[map[string]any](
+				ctx, mds, mapstore.ListDecodedConfig{ListingConfig: cfg}, token,
+			)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				page = append(page, &record{entry: item.Entry, data: item.Value})
+			}
+			next = nextToken
+		} else {
+			entries, nextToken, err := mds.ListFilesContext(ctx, cfg, token)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				page = append(page, &record{entry: e})
+			}
+			next = nextToken
+		}
+
+		for _, r := range page {
+			if q.Where != nil && !q.Where.eval(r) {
+				continue
+			}
+			if q.OrderBy != nil && q.Limit > 0 {
+				matches = q.add(matches, q.newMatch(r))
+				continue
+			}
+			matches = append(matches, q.newMatch(r))
+			if q.OrderBy == nil && q.Limit > 0 && len(matches) == q.Limit {
+				return matches, nil
+			}
+		}
+		if next == "" {
+			return matches, nil
+		}
+		token = next
+	}
+}
+
+func (q *Query) usesData() bool {
+	uses := slices.ContainsFunc(q.Fields, Field.usesData) || (q.OrderBy != nil && q.OrderBy.usesData())
+	if q.Where != nil {
+		q.Where.walk(func(c Cond) {
+			if cc, ok := c.(Compare); ok && cc.Field.usesData() {
+				uses = true
+			}
+		})
+	}
+	return uses
+}
+
+// partitions returns the partitions that satisfy the partition comparisons every match must satisfy, or nil if
+// there are none and all partitions are scanned.
+func (q *Query) partitions(ctx context.Context, mds *mapstore.MapDirectoryStore) ([]string, error) {
+	var conds []Compare
+	var collect func(c Cond)
+	collect = func(c Cond) {
+		switch c := c.(type) {
+		case And:
+			collect(c[0])
+			collect(c[1])
+		case Compare:
+			if c.Field == FieldPartition {
+				conds = append(conds, c)
+			}
+		default:
+		}
+	}
+	if q.Where != nil {
+		collect(q.Where)
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	partitions := []string{}
+	token := ""
+	for {
+		names, next, err := mds.ListPartitionsContext(
+			ctx, mds.BaseDir(), mapstore.SortOrderAscending, token, scanPageSize,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partitions: %w", err)
+		}
+		for _, name := range names {
+			r := &record{entry: mapstore.FileEntry{PartitionName: name}}
+			if !slices.ContainsFunc(conds, func(c Compare) bool { return !c.eval(r) }) {
+				partitions = append(partitions, name)
+			}
+		}
+		if next == "" {
+			return partitions, nil
+		}
+		token = next
+	}
+}
+
+// record is a listed file, with its data if the query uses it.
+type record struct {
+	entry mapstore.FileEntry
+	data  map[string]any
+}
+
+func (r *record) get(f Field) (any, bool) {
+	switch f {
+	case FieldPath:
+		return r.entry.BaseRelativePath, true
+	case FieldName:
+		return filepath.Base(r.entry.BaseRelativePath), true
+	case FieldPartition:
+		return r.entry.PartitionName, true
+	case FieldModTime:
+		if r.entry.FileInfo == nil {
+			return nil, false
+		}
+		return r.entry.FileInfo.ModTime(), true
+	case FieldSize:
+		if r.entry.FileInfo == nil {
+			return nil, false
+		}
+		return float64(r.entry.FileInfo.Size()), true
+	case FieldData:
+		return r.data, r.data != nil
+	default:
+		keys, err := mapstore.ParsePath(strings.TrimPrefix(string(f), string(FieldData)+"."))
+		if err != nil {
+			return nil, false
+		}
+		v, err := maputil.GetValueAtPath(r.data, keys)
+		return v, err == nil
+	}
+}
+
+func (c Compare) eval(r *record) bool {
+	v, found := r.get(c.Field)
+	if c.Value == nil {
+		isNull := !found || v == nil
+		return isNull == (c.Op == "=")
+	}
+	if !found {
+		return c.Op == "!="
+	}
+	n, ok := compareValues(v, c.Value)
+	if !ok {
+		return c.Op == "!="
+	}
+	switch c.Op {
+	case "=":
+		return n == 0
+	case "!=":
+		return n != 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	default:
+		return false
+	}
+}
+
+func (c Compare) walk(fn func(Cond)) { fn(c) }
+
+func (c And) eval(r *record) bool { return c[0].eval(r) && c[1].eval(r) }
+
+func (c And) walk(fn func(Cond)) {
+	fn(c)
+	c[0].walk(fn)
+	c[1].walk(fn)
+}
+
+func (c Or) eval(r *record) bool { return c[0].eval(r) || c[1].eval(r) }
+
+func (c Or) walk(fn func(Cond)) {
+	fn(c)
+	c[0].walk(fn)
+	c[1].walk(fn)
+}
+
+func (c Not) eval(r *record) bool { return !c[0].eval(r) }
+
+func (c Not) walk(fn func(Cond)) {
+	fn(c)
+	c[0].walk(fn)
+}
+
+// compareValues orders a against b. It reports false if they cannot be compared.
+func compareValues(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			}
+			if y {
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Compare(y), true
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return x.Compare(t), true
+			}
+			if t, err := time.ParseInLocation(time.DateOnly, y, time.Local); err == nil {
+				return x.Compare(t), true
+			}
+		}
+	default:
+		if xf, ok := toFloat(a); ok {
+			if yf, ok := toFloat(b); ok {
+				return cmp.Compare(xf, yf), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// order orders a and b by their sort values. Matches without a value sort last in either direction.
+func (q *Query) order(a, b *match) int {
+	av, bv := a.sortKey, b.sortKey
+	if av == nil || bv == nil {
+		return cmp.Compare(boolRank(av == nil), boolRank(bv == nil))
+	}
+	n, ok := compareValues(av, bv)
+	if !ok {
+		return 0
+	}
+	if q.Desc {
+		return -n
+	}
+	return n
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{src: "SELECT *"},
+		{
+			src: "select data.title, mtime where data.archived = false and partition >= '202401' " +
+				"order by mtime desc limit 20",
+		},
+		{src: "SELECT path WHERE NOT (data.n > 1 OR data.s = 'it''s') AND data.x != null"},
+		{src: "SELECT path WHERE data.n <> -1.5"},
+		{src: "SELECT", wantErr: "expected a field"},
+		{src: "SELECT foo", wantErr: "unknown field"},
+		{src: "SELECT data..x", wantErr: "unknown field"},
+		{src: `SELECT data.a\.b, data.c\\d`},
+		{src: `SELECT data.a\x`, wantErr: "unknown field"},
+		{src: "SELECT path WHERE data.x > null", wantErr: "null can only"},
+		{src: "SELECT path WHERE data.x = 'open", wantErr: "unterminated string"},
+		{src: "SELECT path LIMIT x", wantErr: "expected a limit"},
+		{src: "SELECT path WHERE data.x", wantErr: "expected a comparison operator"},
+		{src: "SELECT path ORDER mtime", wantErr: "expected BY"},
+		{src: "SELECT path extra", wantErr: "unexpected token"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Parse(%q) = %v", tt.src, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func newMonthStore(t *testing.T) *mapstore.MapDirectoryStore {
+	t.Helper()
+	provider := &dirpartition.MonthPartitionProvider{
+		TimeFn: func(key mapstore.FileKey) (time.Time, error) {
+			ts, ok := key.XAttr.(time.Time)
+			if !ok {
+				return time.Time{}, fmt.Errorf("no time for %s", key.FileName)
+			}
+			return ts, nil
+		},
+	}
+	mds, err := mapstore.NewMapDirectoryStore(t.TempDir(), true, provider, jsonencdec.JSONEncoderDecoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mds.CloseAll() })
+	docs := []struct {
+		name     string
+		month    time.Month
+		title    string
+		archived bool
+		rank     float64
+	}{
+		{"a.json", time.December, "old", false, 1},
+		{"b.json", time.January, "kept", false, 3},
+		{"c.json", time.January, "gone", true, 2},
+		{"d.json", time.February, "new", false, 2},
+	}
+	for _, d := range docs {
+		year := 2024
+		if d.month == time.December {
+			year = 2023
+		}
+		key := mapstore.FileKey{FileName: d.name, XAttr: time.Date(year, d.month, 1, 0, 0, 0, 0, time.UTC)}
+		data := map[string]any{"title": d.title, "archived": d.archived, "rank": d.rank, "meta.title": d.title}
+		if err := mds.SetFileData(key, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mds
+}
+
+func TestRun(t *testing.T) {
+	mds := newMonthStore(t)
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			src:  "SELECT data.title WHERE data.archived = false AND partition >= '202401' ORDER BY data.rank DESC",
+			want: "[kept] [new]",
+		},
+		{src: "SELECT name, partition WHERE partition = '202312'", want: "[a.json 202312]"},
+		{src: "SELECT name WHERE partition > '202402'", want: ""},
+		{
+			src:  "SELECT data.title WHERE data.rank = 2 OR data.title = 'old' ORDER BY data.title",
+			want: "[gone] [new] [old]",
+		},
+		{src: "SELECT data.title WHERE NOT data.archived = false ORDER BY name", want: "[gone]"},
+		{src: "SELECT name WHERE data.missing = null ORDER BY name LIMIT 2", want: "[a.json] [b.json]"},
+		{src: "SELECT data.missing WHERE name = 'a.json'", want: "[<nil>]"},
+		{src: `SELECT data.meta\.title WHERE name = 'b.json'`, want: "[kept]"},
+		{src: "SELECT data.title ORDER BY data.rank DESC LIMIT 2", want: "[kept] [gone]"},
+		{src: "SELECT data.title ORDER BY data.rank LIMIT 3", want: "[old] [gone] [new]"},
+		{src: "SELECT name ORDER BY data.missing DESC LIMIT 1", want: "[a.json]"},
+	}
+	for _, tt := range tests {
+		res, err := Run(context.Background(), mds, tt.src)
+		if err != nil {
+			t.Errorf("Run(%q) = %v", tt.src, err)
+			continue
+		}
+		var rows []string
+		for _, r := range res.Rows {
+			rows = append(rows, fmt.Sprint(r.Values))
+		}
+		if got := strings.Join(rows, " "); got != tt.want {
+			t.Errorf("Run(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRun_LimitWithoutOrderStopsEarly(t *testing.T) {
+	mds := newMonthStore(t)
+	res, err := Run(context.Background(), mds, "SELECT path LIMIT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 1 || len(res.Columns) != 1 || res.Columns[0] != "path" {
+		t.Fatalf("got %+v", res)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, mds, "SELECT path"); err == nil {
+		t.Fatal("expected an error for a canceled context")
+	}
+}
+
+func TestRun_Context(t *testing.T) {
+	// Only alice may read, and the first read of a file cancels the context of the scan.
+	var cancelScan context.CancelFunc
+	checker := func(ctx context.Context, op mapstore.Operation, _ string, _ []string) error {
+		switch op {
+		case mapstore.OpGetFile, mapstore.OpListFiles:
+			if mapstore.ActorFromContext(ctx) != "alice" {
+				return mapstore.ErrAccessDenied
+			}
+		}
+		if op == mapstore.OpGetFile && cancelScan != nil {
+			cancelScan()
+		}
+		return nil
+	}
+	mds, err := mapstore.NewMapDirectoryStore(
+		t.TempDir(), true, &dirpartition.NoPartitionProvider{}, jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirAccessControl(checker),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mds.CloseAll()
+	for i := range scanPageSize + 1 {
+		key := mapstore.FileKey{FileName: fmt.Sprintf("f%03d.json", i)}
+		if err := mds.SetFileData(key, map[string]any{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice := mapstore.ContextWithActor(context.Background(), "alice")
+	res, err := Run(alice, mds, "SELECT data.n ORDER BY data.n DESC LIMIT 1")
+	if err != nil || len(res.Rows) != 1 || res.Rows[0].Values[0] != float64(scanPageSize) {
+		t.Fatalf("alice: %+v, %v", res, err)
+	}
+	bob := mapstore.ContextWithActor(context.Background(), "bob")
+	if _, err := Run(bob, mds, "SELECT data.n"); !errors.Is(err, mapstore.ErrAccessDenied) {
+		t.Fatalf("bob: want ErrAccessDenied, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(alice)
+	defer cancel()
+	cancelScan = cancel
+	if _, err := Run(ctx, mds, "SELECT data.n"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled scan: want context.Canceled, got %v", err)
+	}
+}
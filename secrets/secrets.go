@@ -0,0 +1,314 @@
+// Package secrets keeps named secrets encrypted in a MapFileStore file.
+//
+// Every secret value is encrypted with the configured encoder, by default the keyring backed AES-GCM encoder of
+// keyringencdec, so only ciphertext reaches the disk. Names, versions and update times stay readable, so secrets
+// can be listed and checked for age without decrypting them.
+package secrets
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/internal/maputil"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+	"github.com/ppipada/mapstore-go/keyringencdec"
+	"github.com/ppipada/mapstore-go/passphraseencdec"
+)
+
+// ErrNotFound is returned for names that are not stored.
+var ErrNotFound = errors.New("secrets: not found")
+
+const (
+	rootKey      = "secrets"
+	valueKey     = "value"
+	versionKey   = "version"
+	updatedAtKey = "updatedAt"
+)
+
+// Op is the kind of access reported to the audit hook.
+type Op string
+
+const (
+	OpGet       Op = "get"
+	OpSet       Op = "set"
+	OpDelete    Op = "delete"
+	OpList      Op = "list"
+	OpRotateKey Op = "rotateKey"
+)
+
+// AuditEvent describes one access to the store. Secret values are never included.
+type AuditEvent struct {
+	Op Op
+	// Empty for OpList and OpRotateKey.
+	Name string
+	// Version of the secret after OpSet, or the one read by OpGet.
+	Version int
+	Time    time.Time
+	// Error of the access, nil on success.
+	Err error
+}
+
+// Info is the metadata of a stored secret.
+type Info struct {
+	Name string
+	// Version starts at 1 and is incremented by every Set.
+	Version   int
+	UpdatedAt time.Time
+}
+
+// Store is a thread-safe store of named string secrets.
+type Store struct {
+	mu     sync.Mutex
+	file   *mapstore.MapFileStore
+	encdec mapstore.IOEncoderDecoder
+	audit  func(AuditEvent)
+	now    func() time.Time
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithAuditHook registers a callback invoked after every access, successful or not.
+func WithAuditHook(fn func(AuditEvent)) Option {
+	return func(s *Store) {
+		s.audit = fn
+	}
+}
+
+// New opens or creates the secrets file at filename, encrypting values with encdec.
+// A new file is created with 0600 permissions.
+func New(filename string, encdec mapstore.IOEncoderDecoder, opts ...Option) (*Store, error) {
+	if encdec == nil {
+		return nil, errors.New("secrets: nil encoder decoder")
+	}
+	s := &Store{encdec: encdec, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := createPrivateFile(filename); err != nil {
+		return nil, err
+	}
+	file, err := mapstore.NewMapFileStore(
+		filename,
+		map[string]any{rootKey: map[string]any{}},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithValueEncDecGetter(s.valueEncDec),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// NewWithKeyring is New with the keyring encoder, keeping the AES key under service and username in the OS keyring.
+func NewWithKeyring(filename, service, username string, opts ...Option) (*Store, error) {
+	encdec, err := keyringencdec.NewEncryptedStringValueEncoderDecoder(service, username)
+	if err != nil {
+		return nil, err
+	}
+	return New(filename, encdec, opts...)
+}
+
+// NewWithPassphrase is New with the passphrase encoder, deriving the AES key from the passphrase of source.
+func NewWithPassphrase(filename string, source passphraseencdec.PassphraseSource, opts ...Option) (*Store, error) {
+	encdec, err := passphraseencdec.NewEncryptedStringValueEncoderDecoder(source)
+	if err != nil {
+		return nil, err
+	}
+	return New(filename, encdec, opts...)
+}
+
+// Get returns the value of the named secret, or ErrNotFound.
+func (s *Store) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, version, err := s.get(name)
+	s.emit(AuditEvent{Op: OpGet, Name: name, Version: version, Err: err})
+	return value, err
+}
+
+// Set stores value under name and increments its version.
+func (s *Store) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version, err := s.set(name, value)
+	s.emit(AuditEvent{Op: OpSet, Name: name, Version: version, Err: err})
+	return err
+}
+
+// Delete removes the named secret, or returns ErrNotFound.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.delete(name)
+	s.emit(AuditEvent{Op: OpDelete, Name: name, Err: err})
+	return err
+}
+
+// List returns the metadata of all secrets sorted by name. Values are not decrypted.
+// Use UpdatedAt to find secrets that are due for rotation.
+func (s *Store) List() ([]Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos, err := s.list()
+	s.emit(AuditEvent{Op: OpList, Err: err})
+	return infos, err
+}
+
+// RotateKey re-encrypts all secrets with next and uses it from then on, e.g. an encoder with a fresh keyring
+// entry. The file is rewritten in one step; on error the store keeps the previous encoder.
+func (s *Store) RotateKey(next mapstore.IOEncoderDecoder) error {
+	if next == nil {
+		return errors.New("secrets: nil encoder decoder")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.rotateKey(next)
+	s.emit(AuditEvent{Op: OpRotateKey, Err: err})
+	return err
+}
+
+// Close releases the underlying store.
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+func (s *Store) get(name string) (value string, version int, err error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return "", 0, err
+	}
+	value, ok := entry[valueKey].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("secrets: malformed value of %q", name)
+	}
+	return value, toInt(entry[versionKey]), nil
+}
+
+func (s *Store) set(name, value string) (int, error) {
+	if name == "" {
+		return 0, errors.New("secrets: empty name")
+	}
+	version := 1
+	if entry, err := s.entry(name); err == nil {
+		version = toInt(entry[versionKey]) + 1
+	}
+	err := s.file.SetKey([]string{rootKey, name}, map[string]any{
+		valueKey:     value,
+		versionKey:   version,
+		updatedAtKey: s.now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *Store) delete(name string) error {
+	if _, err := s.entry(name); err != nil {
+		return err
+	}
+	return s.file.DeleteKey([]string{rootKey, name})
+}
+
+func (s *Store) list() ([]Info, error) {
+	all, err := s.file.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	entries, _ := all[rootKey].(map[string]any)
+	infos := make([]Info, 0, len(entries))
+	for name, v := range entries {
+		entry, _ := v.(map[string]any)
+		info := Info{Name: name, Version: toInt(entry[versionKey])}
+		if ts, ok := entry[updatedAtKey].(string); ok {
+			info.UpdatedAt, _ = time.Parse(time.RFC3339Nano, ts)
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b Info) int { return cmp.Compare(a.Name, b.Name) })
+	return infos, nil
+}
+
+func (s *Store) rotateKey(next mapstore.IOEncoderDecoder) error {
+	// In memory values are decrypted, so writing them back with next re-encrypts them.
+	all, err := s.file.GetAll(false)
+	if err != nil {
+		return err
+	}
+	prev := s.encdec
+	s.encdec = next
+	if err := s.file.SetAll(all); err != nil {
+		s.encdec = prev
+		return err
+	}
+	return nil
+}
+
+func (s *Store) entry(name string) (map[string]any, error) {
+	v, err := s.file.GetKey([]string{rootKey, name})
+	if notFound := (*maputil.KeyNotFoundError)(nil); errors.As(err, &notFound) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("secrets: malformed entry %q", name)
+	}
+	return entry, nil
+}
+
+// valueEncDec encrypts the value field of every secret.
+// The store calls it with s.mu held, or from New before any other use.
+func (s *Store) valueEncDec(pathSoFar []string) mapstore.IOEncoderDecoder {
+	if len(pathSoFar) == 3 && pathSoFar[0] == rootKey && pathSoFar[2] == valueKey {
+		return s.encdec
+	}
+	return nil
+}
+
+func (s *Store) emit(e AuditEvent) {
+	if s.audit == nil {
+		return
+	}
+	e.Time = s.now()
+	s.audit(e)
+}
+
+// createPrivateFile creates an empty secrets file readable by the owner only. MapFileStore keeps the
+// permissions of an existing file when it rewrites it.
+func createPrivateFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return jsonencdec.JSONEncoderDecoder{}.Encode(f, map[string]any{rootKey: map[string]any{}})
+}
+
+// toInt reads a version that is an int in memory and a float64 after a JSON round trip.
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
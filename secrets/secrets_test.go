@@ -0,0 +1,144 @@
+package secrets
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// prefixEncDec stands in for the keyring encoder: it "encrypts" by prefixing and refuses foreign ciphertext.
+type prefixEncDec struct{ prefix string }
+
+func (p prefixEncDec) Encode(w io.Writer, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("non string value")
+	}
+	_, err := io.WriteString(w, p.prefix+s)
+	return err
+}
+
+func (p prefixEncDec) Decode(r io.Reader, value any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	plain, ok := strings.CutPrefix(string(b), p.prefix)
+	if !ok {
+		return errors.New("wrong key")
+	}
+	ptr, ok := value.(*any)
+	if !ok {
+		return errors.New("want *any")
+	}
+	*ptr = plain
+	return nil
+}
+
+func TestStore_SetGetDeleteList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	var events []AuditEvent
+	s, err := New(path, prefixEncDec{"k1:"}, WithAuditHook(func(e AuditEvent) { events = append(events, e) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Get("api"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get missing: %v", err)
+	}
+	if err := s.Set("api", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("api", "hunter3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("db", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get("api"); err != nil || got != "hunter3" {
+		t.Fatalf("get: %q, %v", got, err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "hunter") {
+		t.Fatalf("plaintext on disk: %s", raw)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := st.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("file mode %v, want 0600", perm)
+	}
+
+	infos, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != "api" || infos[0].Version != 2 || infos[1].Version != 1 ||
+		infos[0].UpdatedAt.IsZero() {
+		t.Fatalf("list: %+v", infos)
+	}
+
+	if err := s.Delete("db"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("db"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete missing: %v", err)
+	}
+
+	var ops []Op
+	for _, e := range events {
+		ops = append(ops, e.Op)
+	}
+	want := []Op{OpGet, OpSet, OpSet, OpSet, OpGet, OpList, OpDelete, OpDelete}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("audit ops %v, want %v", ops, want)
+	}
+	if events[0].Err == nil || events[2].Version != 2 || events[4].Name != "api" {
+		t.Fatalf("audit events %+v", events)
+	}
+}
+
+func TestStore_RotateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	s, err := New(path, prefixEncDec{"k1:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("api", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RotateKey(prefixEncDec{"k2:"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get("api"); err != nil || got != "hunter2" {
+		t.Fatalf("get after rotation: %q, %v", got, err)
+	}
+	s.Close()
+
+	// The file only opens with the new key now.
+	if _, err := New(path, prefixEncDec{"k1:"}); err == nil {
+		t.Fatal("old key still decrypts")
+	}
+	s, err = New(path, prefixEncDec{"k2:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, err := s.Get("api"); err != nil || got != "hunter2" {
+		t.Fatalf("reopen: %q, %v", got, err)
+	}
+	infos, err := s.List()
+	if err != nil || len(infos) != 1 || infos[0].Version != 1 {
+		t.Fatalf("rotation changed versions: %+v, %v", infos, err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package sessions persists web sessions in a MapDirectoryStore, one JSON file per session.
+//
+// Files are named by the UUIDv7 part of the session id. The id handed to clients also carries a 256 bit random
+// token, of which only a SHA-256 hash is stored, so ids cannot be guessed from the file names or their time order.
+// The Get/Save/Destroy/GC contract maps directly onto common session manager interfaces.
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppipada/mapstore-go"
+	"github.com/ppipada/mapstore-go/dirpartition"
+	"github.com/ppipada/mapstore-go/jsonencdec"
+	"github.com/ppipada/mapstore-go/uuidv7filename"
+)
+
+const (
+	fileSuffix    = "session"
+	fileExtension = "json"
+
+	keyTokenHash = "tokenHash"
+	keyValues    = "values"
+	keyCreatedAt = "createdAt"
+	keyExpiresAt = "expiresAt"
+
+	tokenBytes   = 32
+	listPageSize = 100
+)
+
+// ErrNotFound is returned for unknown, expired or forged session ids.
+var ErrNotFound = errors.New("sessions: session not found")
+
+// Session is one stored session.
+type Session struct {
+	// ID is the opaque value to hand to the client, e.g. in a cookie.
+	ID        string
+	Values    map[string]any
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Store keeps sessions in a directory.
+type Store struct {
+	mds     *mapstore.MapDirectoryStore
+	baseDir string
+	ttl     time.Duration
+	now     func() time.Time
+
+	// Serializes file access inside one process.
+	mu sync.Mutex
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithTTL sets how long a session lives after it was last saved. The default is 24 hours.
+func WithTTL(d time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = d
+	}
+}
+
+// New opens (or creates) a session store rooted at baseDir.
+func New(baseDir string, opts ...Option) (*Store, error) {
+	mds, err := mapstore.NewMapDirectoryStore(
+		baseDir,
+		true,
+		&dirpartition.NoPartitionProvider{},
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithDirPageSize(listPageSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		mds:     mds,
+		baseDir: abs,
+		ttl:     24 * time.Hour,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.ttl <= 0 {
+		return nil, errors.New("sessions: ttl must be positive")
+	}
+	return s, nil
+}
+
+// Create stores a new session with values and returns it.
+func (s *Store) Create(values map[string]any) (*Session, error) {
+	id, err := uuidv7filename.NewUUIDv7String()
+	if err != nil {
+		return nil, err
+	}
+	token := make([]byte, tokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	now := s.now().UTC()
+	sess := &Session{
+		ID:        id + "." + base64.RawURLEncoding.EncodeToString(token),
+		Values:    values,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	key, tokenHash, err := parseID(sess.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(key, tokenHash, sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Get returns the session with id. Unknown, expired and forged ids give ErrNotFound.
+func (s *Store) Get(id string) (*Session, error) {
+	key, tokenHash, err := parseID(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read(key)
+	if err != nil {
+		return nil, err
+	}
+	stored, _ := data[keyTokenHash].(string)
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(tokenHash)) != 1 {
+		return nil, ErrNotFound
+	}
+	sess, err := sessionFromData(id, data)
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(sess.ExpiresAt) {
+		// Expired sessions are removed lazily, GC sweeps the rest.
+		_ = s.mds.DeleteFile(key)
+		return nil, ErrNotFound
+	}
+	return sess, nil
+}
+
+// Save writes the values of sess and extends its expiry by the ttl. Expired sessions give ErrNotFound.
+func (s *Store) Save(sess *Session) error {
+	if sess == nil {
+		return errors.New("sessions: nil session")
+	}
+	key, tokenHash, err := parseID(sess.ID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read(key)
+	if err != nil {
+		return err
+	}
+	stored, _ := data[keyTokenHash].(string)
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(tokenHash)) != 1 {
+		return ErrNotFound
+	}
+	if cur, err := sessionFromData(sess.ID, data); err != nil || !s.now().Before(cur.ExpiresAt) {
+		// Do not bring an expired session back to life.
+		return ErrNotFound
+	}
+	if sess.Values == nil {
+		sess.Values = map[string]any{}
+	}
+	sess.ExpiresAt = s.now().UTC().Add(s.ttl)
+	return s.write(key, tokenHash, sess)
+}
+
+// Destroy removes the session with id. Unknown ids are not an error.
+func (s *Store) Destroy(id string) error {
+	key, _, err := parseID(id)
+	if err == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.exists(key) {
+			return s.mds.DeleteFile(key)
+		}
+	}
+	return nil
+}
+
+// GC removes all expired sessions and returns how many were removed.
+func (s *Store) GC() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []mapstore.FileKey
+	cfg := mapstore.ListingConfig{SortOrder: mapstore.SortOrderAscending, PageSize: listPageSize}
+	token := ""
+	now := s.now()
+	for {
+		entries, next, err := s.mds.ListFiles(cfg, token)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			key := mapstore.FileKey{FileName: entry.FileInfo.Name()}
+			data, err := s.read(key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+			sess, err := sessionFromData("", data)
+			if err != nil || !now.Before(sess.ExpiresAt) {
+				expired = append(expired, key)
+			}
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	n := 0
+	for _, key := range expired {
+		err := s.mds.DeleteFile(key)
+		if errors.Is(err, mapstore.ErrFileConflict) {
+			// Saved by another process in the meantime.
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) write(key mapstore.FileKey, tokenHash string, sess *Session) error {
+	data := map[string]any{
+		keyTokenHash: tokenHash,
+		keyValues:    sess.Values,
+		keyCreatedAt: sess.CreatedAt.UTC().Format(time.RFC3339Nano),
+		keyExpiresAt: sess.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	}
+	if err := s.mds.SetFileData(key, data); err != nil {
+		return err
+	}
+	return s.mds.CloseFile(key)
+}
+
+// read returns the data of the session file, or ErrNotFound if it does not exist.
+func (s *Store) read(key mapstore.FileKey) (map[string]any, error) {
+	if !s.exists(key) {
+		return nil, ErrNotFound
+	}
+	defer func() { _ = s.mds.CloseFile(key) }()
+	data, err := s.mds.GetFileData(key, true)
+	if err != nil && !s.exists(key) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *Store) exists(key mapstore.FileKey) bool {
+	_, err := os.Stat(filepath.Join(s.baseDir, key.FileName))
+	return err == nil
+}
+
+// parseID splits a session id into the key of its file and the hash of its token.
+func parseID(id string) (key mapstore.FileKey, tokenHash string, err error) {
+	uuid, token, ok := strings.Cut(id, ".")
+	if !ok || token == "" {
+		return key, "", errors.New("sessions: malformed session id")
+	}
+	info, err := uuidv7filename.Build(uuid, fileSuffix, fileExtension)
+	if err != nil {
+		return key, "", fmt.Errorf("sessions: malformed session id: %w", err)
+	}
+	sum := sha256.Sum256([]byte(token))
+	return mapstore.FileKey{FileName: info.FileName}, hex.EncodeToString(sum[:]), nil
+}
+
+func sessionFromData(id string, data map[string]any) (*Session, error) {
+	sess := &Session{ID: id}
+	if values, ok := data[keyValues].(map[string]any); ok {
+		sess.Values = values
+	} else {
+		sess.Values = map[string]any{}
+	}
+	if v, ok := data[keyCreatedAt].(string); ok {
+		sess.CreatedAt, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	v, _ := data[keyExpiresAt].(string)
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, fmt.Errorf("sessions: invalid expiry %q: %w", v, err)
+	}
+	sess.ExpiresAt = t
+	return sess, nil
+}
@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestStore(t *testing.T, opts ...Option) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := New(dir, opts...)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return s, dir
+}
+
+func TestStore_CreateGetSaveDestroy(t *testing.T) {
+	s, dir := newTestStore(t)
+
+	sess, err := s.Create(map[string]any{"user": "ada"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	got, err := s.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Values["user"] != "ada" || !got.ExpiresAt.Equal(sess.ExpiresAt) {
+		t.Fatalf("got %+v, want %+v", got, sess)
+	}
+
+	got.Values["theme"] = "dark"
+	if err := s.Save(got); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	again, err := s.Get(sess.ID)
+	if err != nil || again.Values["theme"] != "dark" {
+		t.Fatalf("get after save: %+v, %v", again, err)
+	}
+
+	// The token never reaches the disk and a forged token does not match.
+	uuid, token, _ := strings.Cut(sess.ID, ".")
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries %v, err %v", entries, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), token) || strings.Contains(entries[0].Name(), token) {
+		t.Fatalf("token stored in plain text")
+	}
+	if _, err := s.Get(uuid + ".forged"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("forged id: %v", err)
+	}
+	if _, err := s.Get("garbage"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("malformed id: %v", err)
+	}
+
+	if err := s.Destroy(sess.ID); err != nil {
+		t.Fatalf("destroy: %v", err)
+	}
+	if err := s.Destroy(sess.ID); err != nil {
+		t.Fatalf("destroy twice: %v", err)
+	}
+	if _, err := s.Get(sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get destroyed: %v", err)
+	}
+}
+
+func TestStore_TTLAndGC(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	s, dir := newTestStore(t, WithTTL(time.Hour))
+	s.now = clock.Now
+
+	old, err := s.Create(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := s.Create(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(45 * time.Minute)
+	// Saving slides the expiry.
+	if err := s.Save(kept); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Minute)
+
+	if _, err := s.Get(kept.ID); err != nil {
+		t.Fatalf("saved session expired: %v", err)
+	}
+	if err := s.Save(old); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("save of expired session: %v", err)
+	}
+	n, err := s.GC()
+	if err != nil || n != 1 {
+		t.Fatalf("gc removed %d, err %v", n, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries after gc %v, err %v", entries, err)
+	}
+	if _, err := s.Get(old.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get expired: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrAccessDenied is a convenience sentinel for access checkers to return when an operation is not allowed.
+var ErrAccessDenied = errors.New("access denied")
+
+// AccessChecker decides whether op may be performed on file (and keys, for key level operations).
+// A non nil error aborts the operation and is returned to the caller unchanged.
+type AccessChecker func(ctx context.Context, op Operation, file string, keys []string) error
+
+// WithAccessControl registers a checker invoked before every read and write on the store.
+func WithAccessControl(checker AccessChecker) FileOption {
+	return func(store *MapFileStore) {
+		store.accessChecker = checker
+	}
+}
+
+// WithDirAccessControl registers a checker invoked before every listing in the directory store
+// and before every read and write on the file stores it opens.
+func WithDirAccessControl(checker AccessChecker) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.accessChecker = checker
+		mds.fileOptions = append(mds.fileOptions, WithAccessControl(checker))
+	}
+}
+
+// checkAccess runs the access checker, if any, for op on this store's file.
+// Every public operation starts with it, so it also records the use for idle tracking.
+func (store *MapFileStore) checkAccess(ctx context.Context, op Operation, keys []string) error {
+	store.lastUsed.Store(time.Now().UnixNano())
+	if op.mutates() {
+		if err := store.checkWritable(op); err != nil {
+			return err
+		}
+	}
+	if store.accessChecker == nil {
+		return nil
+	}
+	return store.accessChecker(ctx, op, store.filename, slices.Clone(keys))
+}
+
+// checkAccess runs the access checker, if any, for op on path inside the directory store.
+func (mds *MapDirectoryStore) checkAccess(ctx context.Context, op Operation, path string) error {
+	if mds.accessChecker == nil {
+		return nil
+	}
+	return mds.accessChecker(ctx, op, path, nil)
+}
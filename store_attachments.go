@@ -0,0 +1,276 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// attachmentsSuffix names the sidecar directory of a file, "<file>.attachments" next to the file.
+const attachmentsSuffix = ".attachments"
+
+// blobRefMagic starts the small reference files that stand in for attachments kept in a BlobStore.
+const blobRefMagic = "mapstore-blobref\n"
+
+// ErrAttachmentNotFound is returned for attachments that do not exist.
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// BlobStore keeps attachment content by digest, see the blobstore package.
+// Put adds a reference to the stored content and Release drops one.
+type BlobStore interface {
+	Put(r io.Reader) (digest string, size int64, err error)
+	Open(digest string) (io.ReadCloser, error)
+	Release(digest string) error
+}
+
+// WithDirAttachmentBlobStore keeps attachment content in bs, so identical attachments occupy disk once.
+// The sidecar directory then holds small reference files. Attachments written before stay readable.
+func WithDirAttachmentBlobStore(bs BlobStore) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.blobs = bs
+	}
+}
+
+// AttachmentInfo describes a stored attachment.
+type AttachmentInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// PutAttachment stores the content of r as attachment name of the file, replacing an attachment of the same name.
+// The file must exist. Attachments live in a sidecar directory next to the file, so binary blobs stay out of the
+// JSON, and are removed with the file by DeleteFile. The write is atomic, readers see the old or the new content.
+func (mds *MapDirectoryStore) PutAttachment(fileKey FileKey, name string, r io.Reader) error {
+	filePath, err := mds.attachmentPath(OpPutAttachment, fileKey, name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		return fmt.Errorf("attachment owner %s: %w", fileKey.FileName, err)
+	}
+	dir := attachmentsDir(filePath)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create attachments directory %s: %w", dir, err)
+	}
+	target := filepath.Join(dir, name)
+	if mds.blobs == nil {
+		return writeFileAtomic(dir, name, r)
+	}
+
+	prev, _, isRef, err := readBlobRef(target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	digest, size, err := mds.blobs.Put(r)
+	if err != nil {
+		return fmt.Errorf("failed to store attachment %s: %w", name, err)
+	}
+	ref := fmt.Sprintf("%s%s\n%d\n", blobRefMagic, digest, size)
+	if err := writeFileAtomic(dir, name, strings.NewReader(ref)); err != nil {
+		return errors.Join(err, mds.blobs.Release(digest))
+	}
+	if isRef {
+		return mds.blobs.Release(prev)
+	}
+	return nil
+}
+
+// writeFileAtomic writes the content of r to dir/name through a temporary file and a rename.
+func writeFileAtomic(dir, name string, r io.Reader) error {
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write attachment %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// GetAttachment opens attachment name of the file. The caller must close the reader.
+func (mds *MapDirectoryStore) GetAttachment(fileKey FileKey, name string) (io.ReadCloser, error) {
+	filePath, err := mds.attachmentPath(OpGetAttachment, fileKey, name)
+	if err != nil {
+		return nil, err
+	}
+	p := filepath.Join(attachmentsDir(filePath), name)
+	digest, _, isRef, err := readBlobRef(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s of %s", ErrAttachmentNotFound, name, fileKey.FileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isRef {
+		return os.Open(p)
+	}
+	if mds.blobs == nil {
+		return nil, fmt.Errorf(
+			"attachment %s of %s is kept in a blob store, none is configured",
+			name,
+			fileKey.FileName,
+		)
+	}
+	return mds.blobs.Open(digest)
+}
+
+// DeleteAttachment removes attachment name of the file.
+func (mds *MapDirectoryStore) DeleteAttachment(fileKey FileKey, name string) error {
+	filePath, err := mds.attachmentPath(OpDeleteAttachment, fileKey, name)
+	if err != nil {
+		return err
+	}
+	return mds.removeAttachment(filepath.Join(attachmentsDir(filePath), name), fileKey, name)
+}
+
+// removeAttachment removes the attachment file at p and releases its blob, if any.
+func (mds *MapDirectoryStore) removeAttachment(p string, fileKey FileKey, name string) error {
+	digest, _, isRef, err := readBlobRef(p)
+	if err == nil {
+		err = os.Remove(p)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s of %s", ErrAttachmentNotFound, name, fileKey.FileName)
+	}
+	if err != nil || !isRef || mds.blobs == nil {
+		return err
+	}
+	return mds.blobs.Release(digest)
+}
+
+// removeAttachments removes the sidecar directory of filePath, releasing the blobs of its attachments.
+func (mds *MapDirectoryStore) removeAttachments(fileKey FileKey, filePath string) error {
+	dir := attachmentsDir(filePath)
+	if mds.blobs != nil {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			err := mds.removeAttachment(filepath.Join(dir, e.Name()), fileKey, e.Name())
+			if err != nil && !errors.Is(err, ErrAttachmentNotFound) {
+				return err
+			}
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// ListAttachments returns the attachments of the file sorted by name.
+func (mds *MapDirectoryStore) ListAttachments(fileKey FileKey) ([]AttachmentInfo, error) {
+	filePath, err := mds.validateAndGetFilePath(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := mds.checkAttachmentAccess(OpListAttachments, filePath, ""); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(attachmentsDir(filePath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []AttachmentInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]AttachmentInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			// Temporary files of writes in progress.
+			continue
+		}
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		size := info.Size()
+		if size <= maxBlobRefSize {
+			_, refSize, isRef, err := readBlobRef(filepath.Join(attachmentsDir(filePath), e.Name()))
+			if err == nil && isRef {
+				size = refSize
+			}
+		}
+		infos = append(infos, AttachmentInfo{Name: e.Name(), Size: size, ModTime: info.ModTime()})
+	}
+	slices.SortFunc(infos, func(a, b AttachmentInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos, nil
+}
+
+// attachmentPath validates the attachment name, runs the access checker and returns the path of the owning file.
+func (mds *MapDirectoryStore) attachmentPath(op Operation, fileKey FileKey, name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid attachment name: %q", name)
+	}
+	filePath, err := mds.validateAndGetFilePath(fileKey)
+	if err != nil {
+		return "", err
+	}
+	if err := mds.checkAttachmentAccess(op, filePath, name); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+func (mds *MapDirectoryStore) checkAttachmentAccess(op Operation, filePath, name string) error {
+	if mds.accessChecker == nil {
+		return nil
+	}
+	var keys []string
+	if name != "" {
+		keys = []string{name}
+	}
+	return mds.accessChecker(context.Background(), op, filePath, keys)
+}
+
+// maxBlobRefSize bounds the size of a blob reference file: magic, a hex digest and a size.
+const maxBlobRefSize = 256
+
+// readBlobRef reads the blob reference in the file at p. IsRef is false for files holding content.
+func readBlobRef(p string) (digest string, size int64, isRef bool, err error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, false, err
+	}
+	defer f.Close()
+	buf := make([]byte, maxBlobRefSize+1)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", 0, false, err
+	}
+	rest, ok := strings.CutPrefix(string(buf[:n]), blobRefMagic)
+	if !ok || n > maxBlobRefSize {
+		return "", 0, false, nil
+	}
+	if _, err := fmt.Sscanf(rest, "%s\n%d\n", &digest, &size); err != nil {
+		return "", 0, false, fmt.Errorf("malformed blob reference %s: %w", p, err)
+	}
+	return digest, size, true, nil
+}
+
+// attachmentsDir returns the sidecar directory holding the attachments of filePath.
+func attachmentsDir(filePath string) string {
+	return filePath + attachmentsSuffix
+}
@@ -0,0 +1,97 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// ErrCASMismatch matches every CASMismatchError with errors.Is.
+var ErrCASMismatch = errors.New("compare and swap mismatch")
+
+// CASMismatchError is returned by CompareAndSwapKey when the current value is not the expected one.
+type CASMismatchError struct {
+	Keys []string
+	// Current is a copy of the value found, nil if the key does not exist.
+	Current any
+}
+
+// Error implements the error interface.
+func (e *CASMismatchError) Error() string {
+	return fmt.Sprintf("compare and swap mismatch at key %v: current value %v", e.Keys, e.Current)
+}
+
+// Is reports whether target is ErrCASMismatch.
+func (e *CASMismatchError) Is(target error) bool {
+	return target == ErrCASMismatch
+}
+
+// CompareAndSwapKey sets the value at keys to newValue only if the current value equals oldValue, atomically
+// with respect to other writers of this store. A nil oldValue expects the key to be missing or null. Values
+// are compared with Equal and NumericCoercion, so numbers compare by value whatever their Go type. On a mismatch
+// nothing changes and a *CASMismatchError carrying the current value is returned.
+//
+// Writers in other processes are detected by the optimistic file check of the flush, which fails with
+// ErrFileConflict; reload and retry in that case.
+func (store *MapFileStore) CompareAndSwapKey(keys []string, oldValue, newValue any) error {
+	return store.CompareAndSwapKeyContext(context.Background(), keys, oldValue, newValue)
+}
+
+// CompareAndSwapKeyContext is CompareAndSwapKey, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) CompareAndSwapKeyContext(ctx context.Context, keys []string, oldValue, newValue any) error {
+	if len(keys) == 0 {
+		return errors.New("cannot set value at root")
+	}
+	if err := store.checkAccess(ctx, OpSetKey, keys); err != nil {
+		return err
+	}
+	copyAfter, seq, err := store.compareAndSwapKey(keys, oldValue, newValue)
+	if err != nil {
+		return err
+	}
+	store.fireEvent(attributeEvent(ctx, FileEvent{
+		Op:        OpSetKey,
+		Seq:       seq,
+		File:      store.filename,
+		Keys:      slices.Clone(keys),
+		OldValue:  maputil.DeepCopyValue(oldValue),
+		NewValue:  maputil.DeepCopyValue(newValue),
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	}))
+	return nil
+}
+
+func (store *MapFileStore) compareAndSwapKey(
+	keys []string,
+	oldValue, newValue any,
+) (copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, ErrClosed
+	}
+
+	current, _ := maputil.GetValueAtPath(store.data, keys)
+	if !maputil.Equal(current, oldValue, maputil.EqualOptions{NumericCoercion: true}) {
+		return nil, 0, &CASMismatchError{Keys: slices.Clone(keys), Current: maputil.DeepCopyValue(current)}
+	}
+	if err := store.checkImmutableUnlocked(keys, newValue, false); err != nil {
+		return nil, 0, err
+	}
+	if err := maputil.SetValueAtPath(store.data, keys, newValue); err != nil {
+		return nil, 0, fmt.Errorf("failed to set value at key %v: %w", keys, err)
+	}
+	store.setExpiryUnlocked(keys, time.Time{})
+	store.touchUnlocked(store.data, keys[0])
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	if err := store.persistUnlocked(); err != nil {
+		return nil, 0, fmt.Errorf("failed to save data after CompareAndSwapKey for keys %v: %w", keys, err)
+	}
+	store.seq++
+	return copyAfter, store.seq, nil
+}
@@ -0,0 +1,102 @@
+package mapstore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression of the files of a store, see WithCompression.
+type Compression int
+
+const (
+	// CompressionNone writes files as the file encoder produces them.
+	CompressionNone Compression = iota
+	// CompressionGzip writes gzip compressed files.
+	CompressionGzip
+	// CompressionZstd writes zstd compressed files, smaller and faster than gzip.
+	CompressionZstd
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// WithCompression compresses the output of the file encoder when flushing, for large and repetitive documents.
+// Loads detect the compression from the file content, so files written with another compression, or none,
+// still load and are rewritten with c on the next flush. Segment files are compressed too; the journal of
+// WithJournal is not. The default is CompressionNone.
+func WithCompression(c Compression) FileOption {
+	return func(store *MapFileStore) {
+		store.compression = c
+	}
+}
+
+// compressedEncoderDecoder compresses the output of inner and decompresses input in any supported compression.
+type compressedEncoderDecoder struct {
+	inner       IOEncoderDecoder
+	compression Compression
+}
+
+// Encode encodes value with the inner encoder and writes it compressed.
+func (d compressedEncoderDecoder) Encode(w io.Writer, value any) error {
+	var zw io.WriteCloser
+	switch d.compression {
+	case CompressionGzip:
+		zw = gzip.NewWriter(w)
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			return fmt.Errorf("failed to compress value: %w", err)
+		}
+		zw = enc
+	default:
+		return d.inner.Encode(w, value)
+	}
+	if err := d.inner.Encode(zw, value); err != nil {
+		_ = zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress value: %w", err)
+	}
+	return nil
+}
+
+// Decode decompresses r, if it is compressed, and decodes it with the inner decoder.
+func (d compressedEncoderDecoder) Decode(r io.Reader, value any) error {
+	zr, err := decompressed(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return d.inner.Decode(zr, value)
+}
+
+// decompressed returns a reader of the content of r, decompressed if it starts with the magic bytes of gzip or
+// zstd.
+func decompressed(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+		return zr, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
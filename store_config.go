@@ -0,0 +1,284 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// MapFileStoreConfig holds every setting of a MapFileStore, as an alternative to positional arguments and
+// functional options. The zero value of a field keeps the default.
+type MapFileStoreConfig struct {
+	Filename           string
+	DefaultData        map[string]any
+	FileEncoderDecoder IOEncoderDecoder
+	// CreateIfNotExists creates the file with DefaultData if it does not exist.
+	CreateIfNotExists bool
+	// DisableAutoFlush keeps changes in memory until Flush is called.
+	DisableAutoFlush bool
+	// Segmented stores every top level key in its own file, see WithSegmentedStorage.
+	Segmented bool
+	// StrictReads reloads the file on reads when it changed on disk, see WithStrictReads.
+	StrictReads bool
+	// Compression compresses the files, see WithCompression.
+	Compression Compression
+	// Journal appends mutations to a journal replayed on open, see WithJournal.
+	Journal bool
+	// WatchFile reloads the file when another process changes it, see WithWatchFile.
+	WatchFile bool
+	// ContentHash keeps the hash of the file content, see WithContentHash.
+	ContentHash       bool
+	ValueEncDecGetter FileValueEncDecGetter
+	KeyEncDecGetter   FileKeyEncDecGetter
+	Listeners         []FileListener
+	// ListenerTimeout and ListenerAsyncAfter bound listener calls, see WithListenerTimeout.
+	ListenerTimeout    time.Duration
+	ListenerAsyncAfter int
+	// ListenerQueueSize dispatches events to listeners asynchronously if positive, see WithAsyncListeners.
+	ListenerQueueSize int
+	// ExpirySweep is the interval of the expiry sweeper, see WithExpirySweep.
+	ExpirySweep time.Duration
+	// SliceMerge is how MergeAll and MergeKey merge slices, see WithSliceMergeStrategy.
+	SliceMerge SliceMergeStrategy
+	// OrderedKeys keeps the key order of the file across writes, see WithOrderedKeys.
+	OrderedKeys bool
+	// ReadOnly rejects mutations and never writes the file, see WithReadOnly.
+	ReadOnly bool
+	// RequiredPaths and ImmutablePaths constrain the data, see WithRequiredPaths and WithImmutablePaths.
+	RequiredPaths  [][]string
+	ImmutablePaths [][]string
+	// CreatedPath and UpdatedPath are timestamps maintained in the data, see WithTimestamps.
+	CreatedPath []string
+	UpdatedPath []string
+	// FileMode and DirMode are the permissions of created files and directories, see WithFileMode and
+	// WithDirMode.
+	FileMode      os.FileMode
+	DirMode       os.FileMode
+	DataMigrator  DataMigrator
+	AccessChecker AccessChecker
+	Redactor      Redactor
+	ReadProcessor ReadProcessor
+	// EnvPrefix applies environment variable overrides, see ApplyEnvOverrides.
+	EnvPrefix string
+	// Options are applied after the fields above and win over them.
+	Options []FileOption
+}
+
+// Validate reports whether the config can open a store.
+func (c MapFileStoreConfig) Validate() error {
+	if c.Filename == "" {
+		return errors.New("invalid filename")
+	}
+	if c.FileEncoderDecoder == nil {
+		return errors.New("invalid file encoder decoder")
+	}
+	if c.ListenerTimeout < 0 || c.ListenerAsyncAfter < 0 {
+		return errors.New("invalid listener timeout: negative value")
+	}
+	if c.ListenerQueueSize < 0 {
+		return fmt.Errorf("invalid listener queue size: %d", c.ListenerQueueSize)
+	}
+	return nil
+}
+
+// fileOptions returns the config fields as options, followed by Options.
+func (c MapFileStoreConfig) fileOptions() []FileOption {
+	var opts []FileOption
+	if c.CreateIfNotExists {
+		opts = append(opts, WithCreateIfNotExists(true))
+	}
+	if c.DisableAutoFlush {
+		opts = append(opts, WithFileAutoFlush(false))
+	}
+	if c.Segmented {
+		opts = append(opts, WithSegmentedStorage(true))
+	}
+	if c.StrictReads {
+		opts = append(opts, WithStrictReads(true))
+	}
+	if c.Compression != CompressionNone {
+		opts = append(opts, WithCompression(c.Compression))
+	}
+	if c.Journal {
+		opts = append(opts, WithJournal(true))
+	}
+	if c.WatchFile {
+		opts = append(opts, WithWatchFile(true))
+	}
+	if c.ContentHash {
+		opts = append(opts, WithContentHash(true))
+	}
+	if c.ValueEncDecGetter != nil {
+		opts = append(opts, WithValueEncDecGetter(c.ValueEncDecGetter))
+	}
+	if c.KeyEncDecGetter != nil {
+		opts = append(opts, WithKeyEncDecGetter(c.KeyEncDecGetter))
+	}
+	if len(c.Listeners) > 0 {
+		opts = append(opts, WithFileListeners(c.Listeners...))
+	}
+	if c.ListenerTimeout > 0 {
+		opts = append(opts, WithListenerTimeout(c.ListenerTimeout, c.ListenerAsyncAfter))
+	}
+	if c.ListenerQueueSize > 0 {
+		opts = append(opts, WithAsyncListeners(c.ListenerQueueSize))
+	}
+	if c.ExpirySweep > 0 {
+		opts = append(opts, WithExpirySweep(c.ExpirySweep))
+	}
+	if c.SliceMerge != SliceMergeReplace {
+		opts = append(opts, WithSliceMergeStrategy(c.SliceMerge))
+	}
+	if c.OrderedKeys {
+		opts = append(opts, WithOrderedKeys(true))
+	}
+	if c.ReadOnly {
+		opts = append(opts, WithReadOnly(true))
+	}
+	if len(c.RequiredPaths) > 0 {
+		opts = append(opts, WithRequiredPaths(c.RequiredPaths...))
+	}
+	if len(c.ImmutablePaths) > 0 {
+		opts = append(opts, WithImmutablePaths(c.ImmutablePaths...))
+	}
+	if c.FileMode != 0 {
+		opts = append(opts, WithFileMode(c.FileMode))
+	}
+	if c.DirMode != 0 {
+		opts = append(opts, WithDirMode(c.DirMode))
+	}
+	if len(c.CreatedPath) > 0 || len(c.UpdatedPath) > 0 {
+		opts = append(opts, WithTimestamps(c.CreatedPath, c.UpdatedPath))
+	}
+	if c.DataMigrator != nil {
+		opts = append(opts, WithDataMigrator(c.DataMigrator))
+	}
+	if c.AccessChecker != nil {
+		opts = append(opts, WithAccessControl(c.AccessChecker))
+	}
+	if c.Redactor != nil {
+		opts = append(opts, WithRedactor(c.Redactor))
+	}
+	if c.ReadProcessor != nil {
+		opts = append(opts, WithReadProcessor(c.ReadProcessor))
+	}
+	if c.EnvPrefix != "" {
+		opts = append(opts, WithEnvOverrides(c.EnvPrefix))
+	}
+	return append(opts, c.Options...)
+}
+
+// NewMapFileStoreFromConfig validates cfg and opens the store it describes.
+func NewMapFileStoreFromConfig(cfg MapFileStoreConfig) (*MapFileStore, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return newMapFileStore(cfg.Filename, cfg.DefaultData, cfg.FileEncoderDecoder, cfg.fileOptions())
+}
+
+// MapDirectoryStoreConfig holds every setting of a MapDirectoryStore, as an alternative to positional
+// arguments and functional options. The zero value of a field keeps the default.
+type MapDirectoryStoreConfig struct {
+	BaseDir string
+	// CreateIfNotExists creates BaseDir if it does not exist.
+	CreateIfNotExists  bool
+	PartitionProvider  PartitionProvider
+	FileEncoderDecoder IOEncoderDecoder
+	// PageSize is the default page size of listings, 10 if zero.
+	PageSize              int
+	PartitionCreatePolicy PartitionCreatePolicy
+	FilenameNormalization FilenameNormalization
+	Listeners             []FileListener
+	// FileOptions are applied to every MapFileStore the directory store opens.
+	FileOptions []FileOption
+	// Codecs selects a codec by file extension, see WithDirCodecForExtension.
+	Codecs        map[string]IOEncoderDecoder
+	AccessChecker AccessChecker
+	ResolveRefs   bool
+	BlobStore     BlobStore
+	// ContentHash fills FileEntry.ContentHash and hashes every file, see WithDirContentHash.
+	ContentHash bool
+	// IdleFlushAfter and IdleCloseAfter configure the idle daemon, see WithIdlePolicy.
+	IdleFlushAfter time.Duration
+	IdleCloseAfter time.Duration
+	// Options are applied after the fields above and win over them.
+	Options []DirOption
+}
+
+// Validate reports whether the config can open a store.
+func (c MapDirectoryStoreConfig) Validate() error {
+	if c.PartitionProvider == nil {
+		return errors.New("invalid partition provider")
+	}
+	if c.FileEncoderDecoder == nil {
+		return errors.New("invalid file encoder decoder")
+	}
+	if c.IdleFlushAfter < 0 || c.IdleCloseAfter < 0 {
+		return errors.New("invalid idle policy: negative duration")
+	}
+	if c.PageSize < 0 {
+		return fmt.Errorf("invalid page size: %d", c.PageSize)
+	}
+	if c.PartitionCreatePolicy < PartitionCreateOnWrite || c.PartitionCreatePolicy > PartitionCreateNever {
+		return fmt.Errorf("invalid partition create policy: %d", c.PartitionCreatePolicy)
+	}
+	if c.FilenameNormalization&^(NormalizeFilenameNFC|NormalizeFilenameLowercase) != 0 {
+		return fmt.Errorf("invalid filename normalization: %d", c.FilenameNormalization)
+	}
+	for ext, codec := range c.Codecs {
+		if ext == "" || codec == nil {
+			return fmt.Errorf("invalid codec for extension %q", ext)
+		}
+	}
+	return nil
+}
+
+// dirOptions returns the config fields as options, followed by Options.
+func (c MapDirectoryStoreConfig) dirOptions() []DirOption {
+	var opts []DirOption
+	if c.PageSize > 0 {
+		opts = append(opts, WithDirPageSize(c.PageSize))
+	}
+	if c.PartitionCreatePolicy != PartitionCreateOnWrite {
+		opts = append(opts, WithDirPartitionCreatePolicy(c.PartitionCreatePolicy))
+	}
+	if c.FilenameNormalization != 0 {
+		opts = append(opts, WithDirFilenameNormalization(c.FilenameNormalization))
+	}
+	if len(c.Listeners) > 0 {
+		opts = append(opts, WithDirFileListeners(c.Listeners...))
+	}
+	if len(c.FileOptions) > 0 {
+		opts = append(opts, WithDirFileOptions(c.FileOptions...))
+	}
+	for ext, codec := range c.Codecs {
+		opts = append(opts, WithDirCodecForExtension(ext, codec))
+	}
+	if c.AccessChecker != nil {
+		opts = append(opts, WithDirAccessControl(c.AccessChecker))
+	}
+	if c.ResolveRefs {
+		opts = append(opts, WithDirRefResolution(true))
+	}
+	if c.BlobStore != nil {
+		opts = append(opts, WithDirAttachmentBlobStore(c.BlobStore))
+	}
+	if c.ContentHash {
+		opts = append(opts, WithDirContentHash(true))
+	}
+	if c.IdleFlushAfter > 0 || c.IdleCloseAfter > 0 {
+		opts = append(opts, WithIdlePolicy(c.IdleFlushAfter, c.IdleCloseAfter))
+	}
+	return append(opts, c.Options...)
+}
+
+// NewMapDirectoryStoreFromConfig validates cfg and opens the store it describes.
+func NewMapDirectoryStoreFromConfig(cfg MapDirectoryStoreConfig) (*MapDirectoryStore, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return newMapDirectoryStore(
+		cfg.BaseDir, cfg.CreateIfNotExists, cfg.PartitionProvider, cfg.FileEncoderDecoder, cfg.dirOptions(),
+	)
+}
@@ -0,0 +1,87 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// ErrRequiredPathMissing is returned by reads while a path set with WithRequiredPaths is missing.
+var ErrRequiredPathMissing = errors.New("required path missing")
+
+// ErrImmutable is returned by writes that would change or delete a path set with WithImmutablePaths.
+var ErrImmutable = errors.New("path is immutable")
+
+// WithRequiredPaths makes reads fail with ErrRequiredPathMissing, naming the path, while any of paths is missing
+// from the data, e.g. after loading a file that lacks a critical config field. Values supplied by environment
+// overrides count as present. Writes are not checked, so a missing path can be set to fix the data.
+func WithRequiredPaths(paths ...[]string) FileOption {
+	return func(store *MapFileStore) {
+		for _, p := range paths {
+			store.requiredPaths = append(store.requiredPaths, slices.Clone(p))
+		}
+	}
+}
+
+// WithImmutablePaths makes every write, from SetKey and SetAll to transactions, patches, merges, renames, CAS,
+// increments, Reset and Restore, return ErrImmutable, naming the path, instead of changing or deleting any of
+// paths once it has a value. Setting a parent is allowed when it keeps the value at the path, so whole sections
+// can still be rewritten. A path without a value can be set once. Expiry of a TTL set on the path still deletes
+// it.
+func WithImmutablePaths(paths ...[]string) FileOption {
+	return func(store *MapFileStore) {
+		for _, p := range paths {
+			store.immutablePaths = append(store.immutablePaths, slices.Clone(p))
+		}
+	}
+}
+
+// checkRequiredUnlocked returns an error for the first required path missing from the data.
+func (store *MapFileStore) checkRequiredUnlocked() error {
+	for _, p := range store.requiredPaths {
+		if _, err := maputil.GetValueAtPath(store.data, p); err == nil {
+			continue
+		}
+		if _, ok := store.overrideAtUnlocked(p, nil, false); ok {
+			continue
+		}
+		return fmt.Errorf("%w: %s in %s", ErrRequiredPathMissing, Path(p), store.filename)
+	}
+	return nil
+}
+
+// checkImmutableUnlocked returns an error if setting keys to value, or deleting keys if del is set, would
+// change an immutable path. Empty keys stand for the whole data.
+func (store *MapFileStore) checkImmutableUnlocked(keys []string, value any, del bool) error {
+	for _, p := range store.immutablePaths {
+		cur, err := maputil.GetValueAtPath(store.data, p)
+		if err != nil {
+			continue
+		}
+		switch {
+		case hasKeyPrefix(p, keys):
+			if del {
+				return fmt.Errorf("%w: %s", ErrImmutable, Path(p))
+			}
+			next := value
+			if rest := p[len(keys):]; len(rest) > 0 {
+				if next, err = maputil.GetValueAtPath(value, rest); err != nil {
+					return fmt.Errorf("%w: %s", ErrImmutable, Path(p))
+				}
+			}
+			if !maputil.Equal(cur, next, maputil.EqualOptions{NumericCoercion: true}) {
+				return fmt.Errorf("%w: %s", ErrImmutable, Path(p))
+			}
+		case hasKeyPrefix(keys, p):
+			return fmt.Errorf("%w: %s", ErrImmutable, Path(p))
+		}
+	}
+	return nil
+}
+
+// hasKeyPrefix reports whether keys starts with prefix.
+func hasKeyPrefix(keys, prefix []string) bool {
+	return len(keys) >= len(prefix) && slices.Equal(keys[:len(prefix)], prefix)
+}
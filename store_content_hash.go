@@ -0,0 +1,116 @@
+package mapstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// segmentHashesKey holds the content hashes of the segments in the manifest of a segmented store, so that the
+// hash of the main file changes with every segment.
+const segmentHashesKey = "mapstore.segmentHashes"
+
+// WithContentHash keeps the SHA-256 of the file as last written or read, in hex. It is exposed by ContentHash
+// and in FileEvent.ContentHash, so that replication, dedup and sync tools can detect real changes without
+// reading the file. Hashing is done while the bytes are written or read anyway.
+func WithContentHash(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.hashContent = enabled
+	}
+}
+
+// WithDirContentHash enables WithContentHash for every file, and fills FileEntry.ContentHash in listings.
+func WithDirContentHash(enabled bool) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.hashContent = enabled
+	}
+}
+
+// ContentHash returns the hex SHA-256 of the file as last written or read by this store, or "" if
+// WithContentHash is not set or the file was deleted. Changes not flushed yet are not included.
+func (store *MapFileStore) ContentHash() string {
+	if h := store.contentHash.Load(); h != nil {
+		return *h
+	}
+	return ""
+}
+
+func (store *MapFileStore) setContentHash(h string) {
+	store.contentHash.Store(&h)
+}
+
+// newContentHasher returns a hash for the content of a file, or nil if hashing is disabled.
+func (store *MapFileStore) newContentHasher() hash.Hash {
+	if !store.hashContent {
+		return nil
+	}
+	return sha256.New()
+}
+
+// teeHash returns r, reading through h if h is not nil.
+func teeHash(r io.Reader, h hash.Hash) io.Reader {
+	if h == nil {
+		return r
+	}
+	return io.TeeReader(r, h)
+}
+
+// finishHash reads what the decoder left of r into h and returns the hex sum, or "" if h is nil.
+func finishHash(r io.Reader, h hash.Hash) (string, error) {
+	if h == nil {
+		return "", nil
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fillContentHashes sets the content hash of listed entries, from the open store if it holds the listed
+// version of the file, else by hashing the file.
+func (mds *MapDirectoryStore) fillContentHashes(entries []FileEntry) error {
+	for i := range entries {
+		filePath, err := mds.entryFilePath(entries[i])
+		if err != nil {
+			return err
+		}
+		mds.openMu.Lock()
+		store := mds.openStores[filePath]
+		mds.openMu.Unlock()
+		if store != nil && store.hashContent {
+			store.mu.RLock()
+			same := isSameFileInfo(entries[i].FileInfo, store.lastStat)
+			store.mu.RUnlock()
+			if same {
+				entries[i].ContentHash = store.ContentHash()
+				continue
+			}
+		}
+		sum, err := hashFileContent(filePath)
+		if os.IsNotExist(err) {
+			// Removed since it was listed.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", entries[i].BaseRelativePath, err)
+		}
+		entries[i].ContentHash = sum
+	}
+	return nil
+}
+
+func hashFileContent(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
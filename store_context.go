@@ -0,0 +1,43 @@
+package mapstore
+
+import "context"
+
+// contextKey is the type of the context keys read by the store, so they cannot collide with keys of other
+// packages.
+type contextKey int
+
+const (
+	actorContextKey contextKey = iota
+	requestIDContextKey
+)
+
+// ContextWithActor returns a copy of ctx that attributes changes made with it to actor, e.g. a user or service
+// name. The store copies it to FileEvent.Actor, and access checkers can read it with ActorFromContext.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, or "".
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey).(string)
+	return actor
+}
+
+// ContextWithRequestID returns a copy of ctx that attributes changes made with it to the request with the
+// given ID. The store copies it to FileEvent.RequestID.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID set by ContextWithRequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// attributeEvent sets the actor and request ID of e from ctx.
+func attributeEvent(ctx context.Context, e FileEvent) FileEvent {
+	e.Actor = ActorFromContext(ctx)
+	e.RequestID = RequestIDFromContext(ctx)
+	return e
+}
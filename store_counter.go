@@ -0,0 +1,168 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// SequencesKey is the top level key under which named sequences are stored.
+const SequencesKey = "sequences"
+
+// Increment atomically adds delta to the integer stored at keys and returns the new value.
+// A missing key starts at zero.
+//
+// The read-modify-write runs under a cross-process lock file, the latest on-disk state is reloaded first,
+// and the result is always flushed, even when autoFlush is off.
+// Concurrent incrementers, in this or other processes, therefore never lose updates.
+func (store *MapFileStore) Increment(keys []string, delta int64) (int64, error) {
+	if len(keys) == 0 {
+		return 0, errors.New("cannot increment value at root")
+	}
+	if err := store.checkAccess(context.Background(), OpSetKey, keys); err != nil {
+		return 0, err
+	}
+
+	lock, err := acquireFileLock(store.filename, defaultLockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = lock.release() }()
+
+	for range maxSetAllRetries {
+		oldVal, newVal, copyAfter, seq, err := store.increment(keys, delta)
+		if err == nil {
+			store.fireEvent(FileEvent{
+				Op:        OpSetKey,
+				Seq:       seq,
+				File:      store.filename,
+				Keys:      slices.Clone(keys),
+				OldValue:  oldVal,
+				NewValue:  newVal,
+				Data:      copyAfter,
+				Timestamp: time.Now(),
+			})
+			return newVal, nil
+		}
+		if !errors.Is(err, ErrFileConflict) {
+			return 0, err
+		}
+		// A writer that does not take the lock won the race, reload and retry.
+		if loadErr := store.load(); loadErr != nil {
+			return 0, fmt.Errorf("Increment conflict reload failed: %w", loadErr)
+		}
+	}
+	return 0, fmt.Errorf("Increment: %w after %d retries", ErrFileConflict, maxSetAllRetries)
+}
+
+func (store *MapFileStore) increment(
+	keys []string,
+	delta int64,
+) (oldVal any, newVal int64, copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, nil, 0, ErrClosed
+	}
+
+	// Pick up writes from other processes before reading the current value.
+	if cur, statErr := store.fs.Stat(store.filename); statErr == nil && !isSameFileInfo(cur, store.lastStat) {
+		if err := store.loadUnlocked(); err != nil {
+			return nil, 0, nil, 0, err
+		}
+	}
+
+	oldVal, getErr := maputil.GetValueAtPath(store.data, keys)
+	var kne *maputil.KeyNotFoundError
+	switch {
+	case getErr == nil:
+	case errors.As(getErr, &kne):
+		oldVal = nil
+	default:
+		return nil, 0, nil, 0, getErr
+	}
+
+	cur, err := toInt64(oldVal)
+	if err != nil {
+		return nil, 0, nil, 0, fmt.Errorf("cannot increment key %v: %w", keys, err)
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return nil, 0, nil, 0, fmt.Errorf("cannot increment key %v: integer overflow", keys)
+	}
+	newVal = cur + delta
+	if err := store.checkImmutableUnlocked(keys, newVal, false); err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	if err := maputil.SetValueAtPath(store.data, keys, newVal); err != nil {
+		return nil, 0, nil, 0, fmt.Errorf("failed to set value at key %v: %w", keys, err)
+	}
+	store.touchUnlocked(store.data, keys[0])
+	if err := store.flushUnlocked(); err != nil {
+		// Keep memory in sync with disk.
+		if oldVal == nil {
+			_ = maputil.DeleteValueAtPath(store.data, keys)
+		} else {
+			_ = maputil.SetValueAtPath(store.data, keys, oldVal)
+		}
+		return nil, 0, nil, 0, err
+	}
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	store.seq++
+	return oldVal, newVal, copyAfter, store.seq, nil
+}
+
+// Sequence is a named, monotonically increasing counter stored under SequencesKey.
+type Sequence struct {
+	store *MapFileStore
+	keys  []string
+}
+
+// Sequence returns the named sequence helper for this store.
+func (store *MapFileStore) Sequence(name string) *Sequence {
+	return &Sequence{store: store, keys: []string{SequencesKey, name}}
+}
+
+// Next increments the sequence and returns the new value. The first value is 1.
+func (s *Sequence) Next() (int64, error) {
+	return s.store.Increment(s.keys, 1)
+}
+
+// Current returns the last value handed out by Next, or 0 if the sequence was never used.
+func (s *Sequence) Current() (int64, error) {
+	val, err := s.store.GetKey(s.keys)
+	if err != nil {
+		var kne *maputil.KeyNotFoundError
+		if errors.As(err, &kne) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return toInt64(val)
+}
+
+// toInt64 converts a stored numeric value to int64. Nil is treated as zero.
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case float64:
+		if n != math.Trunc(n) || n > math.MaxInt64 || n < math.MinInt64 {
+			return 0, fmt.Errorf("value %v is not an integer", n)
+		}
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("value of type %T is not an integer", v)
+	}
+}
@@ -0,0 +1,42 @@
+package mapstore
+
+import (
+	"errors"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// GetKeyOr is GetKey with fallbacks for a missing key: the value at keys in the default data of the store, or
+// else def. A map found at keys has the default data at keys merged beneath it, as in GetAllWithDefaults. Only
+// missing keys fall back; other errors, such as a denied access, are returned. The file is never changed.
+func (store *MapFileStore) GetKeyOr(keys []string, def any) (any, error) {
+	val, err := store.GetKey(keys)
+	var kne *maputil.KeyNotFoundError
+	if err != nil && !errors.As(err, &kne) {
+		return nil, err
+	}
+	dv, derr := maputil.GetValueAtPath(store.defaultData, keys)
+	switch {
+	case err == nil && derr == nil:
+		return mergeLayerValues([]any{val, dv}), nil
+	case err == nil:
+		return val, nil
+	case derr == nil:
+		return maputil.DeepCopyValue(dv), nil
+	default:
+		return def, nil
+	}
+}
+
+// GetAllWithDefaults is GetAll with the default data of the store deep-merged beneath the stored data, so every
+// default is present unless the data sets it. The file is never changed.
+func (store *MapFileStore) GetAllWithDefaults() (map[string]any, error) {
+	data, err := store.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]any, len(data))
+	deepMergeMaps(merged, store.defaultData)
+	deepMergeMaps(merged, data)
+	return merged, nil
+}
@@ -0,0 +1,134 @@
+package mapstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// EqualOptions configures Equal.
+type EqualOptions = maputil.EqualOptions
+
+// Equal reports whether the values a and b are deeply equal, element by element for maps and slices. Set
+// NumericCoercion to compare numbers by value whatever their Go type, so a value set as the int 2 equals the
+// float64 2 it decodes to after a reload.
+func Equal(a, b any, opts EqualOptions) bool {
+	return maputil.Equal(a, b, opts)
+}
+
+// ChangeKind classifies a Change.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference at one path between two versions of the data.
+// Nested maps are compared key by key, any other value, including lists, is compared as a whole. Numbers
+// compare by value, so 2 and 2.0 are not a change.
+type Change struct {
+	Path []string   `json:"path"`
+	Kind ChangeKind `json:"kind"`
+	Old  any        `json:"old,omitempty"`
+	New  any        `json:"new,omitempty"`
+}
+
+// Changes is a list of changes sorted by path. It marshals to a JSON array.
+type Changes []Change
+
+// String renders the changes one per line, as "+ path: new", "- path: old" or "~ path: old -> new".
+func (c Changes) String() string {
+	var sb strings.Builder
+	for _, ch := range c {
+		path := Path(ch.Path).String()
+		switch ch.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(&sb, "+ %s: %s\n", path, renderValue(ch.New))
+		case ChangeRemoved:
+			fmt.Fprintf(&sb, "- %s: %s\n", path, renderValue(ch.Old))
+		case ChangeChanged:
+			fmt.Fprintf(&sb, "~ %s: %s -> %s\n", path, renderValue(ch.Old), renderValue(ch.New))
+		}
+	}
+	return sb.String()
+}
+
+// DiffStores returns the changes that turn the data of a into the data of b.
+// Values redacted by either store are masked in the result.
+func DiffStores(a, b *MapFileStore) (Changes, error) {
+	oldData, err := a.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := b.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	return diffData(oldData, newData, a.redactor, b.redactor), nil
+}
+
+// DiffFiles returns the changes that turn the data of file keyA into the data of file keyB.
+// Both files must exist. Files that were not open are closed again.
+func (mds *MapDirectoryStore) DiffFiles(keyA, keyB FileKey) (changes Changes, err error) {
+	var stores [2]*MapFileStore
+	for i, key := range []FileKey{keyA, keyB} {
+		filePath, err := mds.validateAndGetFilePath(key)
+		if err != nil {
+			return nil, err
+		}
+		store, release, err := mds.borrowPath(context.Background(), filePath, false, map[string]any{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file store for %s: %w", key.FileName, err)
+		}
+		defer func() {
+			if closeErr := release(); err == nil {
+				err = closeErr
+			}
+		}()
+		stores[i] = store
+	}
+	return DiffStores(stores[0], stores[1])
+}
+
+func diffData(oldData, newData map[string]any, oldRedactor, newRedactor Redactor) Changes {
+	diffs := maputil.Diff(oldData, newData, maputil.EqualOptions{NumericCoercion: true})
+	if len(diffs) == 0 {
+		return nil
+	}
+	out := make(Changes, 0, len(diffs))
+	for _, d := range diffs {
+		ch := Change{Path: d.Path, Kind: ChangeChanged}
+		switch {
+		case !d.InOld:
+			ch.Kind = ChangeAdded
+		case !d.InNew:
+			ch.Kind = ChangeRemoved
+		}
+		if d.InOld && oldRedactor != nil {
+			ch.Old = redactAtKeys(d.Path, d.Old, oldRedactor)
+		} else {
+			ch.Old = d.Old
+		}
+		if d.InNew && newRedactor != nil {
+			ch.New = redactAtKeys(d.Path, d.New, newRedactor)
+		} else {
+			ch.New = d.New
+		}
+		out = append(out, ch)
+	}
+	return out
+}
+
+// renderValue formats v as JSON, falling back to Go syntax for values JSON cannot represent.
+func renderValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
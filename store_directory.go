@@ -1,6 +1,7 @@
 package mapstore
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -8,9 +9,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -20,6 +23,25 @@ const (
 
 var errCannotReadPartitionDir = errors.New("failed to read partition directory")
 
+// ErrPartitionNotFound is returned when a file is opened in a missing partition directory and the
+// PartitionCreatePolicy does not allow creating it.
+var ErrPartitionNotFound = errors.New("partition directory does not exist")
+
+// PartitionCreatePolicy controls when opening a file creates its partition directory.
+type PartitionCreatePolicy int
+
+const (
+	// PartitionCreateOnWrite creates the partition directory when a file is opened with createIfNotExists.
+	// It is the default.
+	PartitionCreateOnWrite PartitionCreatePolicy = iota
+	// PartitionCreateAlways creates the partition directory whenever a file is opened, also for reads.
+	PartitionCreateAlways
+	// PartitionCreateNever never creates partition directories. Opening a file in a missing partition fails with
+	// ErrPartitionNotFound, so read-only deployments fail loudly instead of creating stray directories.
+	// Partitions are provisioned with EnsurePartition.
+	PartitionCreateNever
+)
+
 type FileKey struct {
 	FileName string
 	XAttr    any
@@ -36,14 +58,23 @@ type PartitionProvider interface {
 type ListingConfig struct {
 	SortOrder        string
 	PageSize         int
-	FilterPartitions []string // If empty, list all partitions.
+	FilterPartitions []string // If empty, list all partitions. Invalid names fail with ErrInvalidPartitionName.
 	FilenamePrefix   string   // If non-empty, only return files with this prefix.
+	// ContentFilter, if set, lists only the files whose data it accepts, see ContentFilter.
+	ContentFilter ContentFilter
 }
 
+// ContentFilter selects files by their data, read like GetFileData. Filtering opens and reads every candidate
+// file, so narrow the listing with partitions or a prefix where possible; files that were not open are closed
+// again. A filter is not part of page tokens: pass the same filter with every page.
+type ContentFilter func(data map[string]any) bool
+
 type FileEntry struct {
 	BaseRelativePath string
 	PartitionName    string
 	FileInfo         os.FileInfo
+	// ContentHash is the hex SHA-256 of the file, only set with WithDirContentHash.
+	ContentHash string
 }
 
 // MapDirectoryStore manages multiple MapFileStores within a directory.
@@ -51,12 +82,35 @@ type MapDirectoryStore struct {
 	baseDir            string
 	pageSize           int
 	partitionProvider  PartitionProvider
-	listeners          []FileListener
+	listeners          listenerSet
 	fileEncoderDecoder IOEncoderDecoder
+	fileOptions        []FileOption
+	accessChecker      AccessChecker
+	resolveRefs        bool
+	createPolicy       PartitionCreatePolicy
+	filenameNorm       FilenameNormalization
+	hashContent        bool
+	// Content store of attachments, nil keeps attachment content in the sidecar directory.
+	blobs BlobStore
+	// Codecs by lower cased file extension, e.g. ".yaml" or ".json.gz".
+	codecs map[string]IOEncoderDecoder
 
 	// OpenStores caches open MapFileStore instances per file path.
 	openStores map[string]*MapFileStore
-	openMu     sync.Mutex
+	// Borrowed counts the internal users of cached stores that were opened only for them, see borrowPath.
+	borrowed map[*MapFileStore]int
+	openMu   sync.Mutex
+
+	// PartitionStats caches stats per partition name until the partition directory changes.
+	partitionStats map[string]cachedPartitionStats
+	statsMu        sync.Mutex
+
+	// Idle daemon settings and lifecycle, see WithIdlePolicy.
+	idleFlushAfter time.Duration
+	idleCloseAfter time.Duration
+	idleStop       chan struct{}
+	idleDone       chan struct{}
+	idleStopOnce   sync.Once
 }
 
 // DirOption is a functional option for configuring the MapDirectoryStore.
@@ -69,14 +123,40 @@ func WithDirPageSize(size int) DirOption {
 	}
 }
 
+// WithDirPartitionCreatePolicy sets when partition directories are created, PartitionCreateOnWrite by default.
+func WithDirPartitionCreatePolicy(p PartitionCreatePolicy) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.createPolicy = p
+	}
+}
+
 // WithDirFileListeners registers one or more listeners when the directory store is created.
 func WithDirFileListeners(ls ...FileListener) DirOption {
 	return func(mds *MapDirectoryStore) {
-		mds.listeners = append(mds.listeners, ls...)
+		mds.listeners.add(ls...)
+	}
+}
+
+// WithDirFileOptions sets extra options applied to every MapFileStore opened by the directory store.
+func WithDirFileOptions(opts ...FileOption) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.fileOptions = append(mds.fileOptions, opts...)
+	}
+}
+
+// WithDirCodecForExtension selects codec for files whose name ends with ext, e.g. ".yaml" or ".json.gz".
+// The longest matching extension wins, case-insensitively. Other files use the store's default codec.
+func WithDirCodecForExtension(ext string, codec IOEncoderDecoder) DirOption {
+	return func(mds *MapDirectoryStore) {
+		if mds.codecs == nil {
+			mds.codecs = make(map[string]IOEncoderDecoder)
+		}
+		mds.codecs[strings.ToLower(ext)] = codec
 	}
 }
 
 // NewMapDirectoryStore initializes a new MapDirectoryStore with the given base directory and options.
+// It is a thin wrapper around NewMapDirectoryStoreFromConfig.
 func NewMapDirectoryStore(
 	baseDir string,
 	createIfNotExists bool,
@@ -84,12 +164,23 @@ func NewMapDirectoryStore(
 	fileEncoderDecoder IOEncoderDecoder,
 	opts ...DirOption,
 ) (*MapDirectoryStore, error) {
-	if partitionProvider == nil {
-		return nil, errors.New("invalid partition provider")
-	}
-	if fileEncoderDecoder == nil {
-		return nil, errors.New("invalid file encoder decoder")
-	}
+	return NewMapDirectoryStoreFromConfig(MapDirectoryStoreConfig{
+		BaseDir:            baseDir,
+		CreateIfNotExists:  createIfNotExists,
+		PartitionProvider:  partitionProvider,
+		FileEncoderDecoder: fileEncoderDecoder,
+		Options:            opts,
+	})
+}
+
+// newMapDirectoryStore opens the store after the config was validated and turned into options.
+func newMapDirectoryStore(
+	baseDir string,
+	createIfNotExists bool,
+	partitionProvider PartitionProvider,
+	fileEncoderDecoder IOEncoderDecoder,
+	opts []DirOption,
+) (*MapDirectoryStore, error) {
 	// Resolve the base directory path.
 	baseDir, err := filepath.Abs(baseDir)
 	if err != nil {
@@ -118,48 +209,81 @@ func NewMapDirectoryStore(
 	for _, opt := range opts {
 		opt(mds)
 	}
+	mds.listeners.add(InvalidationListener(CacheInvalidatorFunc(mds.invalidatePartitionStats)))
+	mds.startIdleDaemon()
 
 	return mds, nil
 }
 
+// BaseDir returns the base directory of the store, e.g. for ListPartitions.
+func (mds *MapDirectoryStore) BaseDir() string {
+	return mds.baseDir
+}
+
 // SetFileData sets the provided data for the given file.
 // It is a thin wrapper around Open and SetAll.
 func (mds *MapDirectoryStore) SetFileData(fileKey FileKey, data map[string]any) error {
+	return mds.SetFileDataContext(context.Background(), fileKey, data)
+}
+
+// SetFileDataContext is SetFileData, attributing the event to the actor and request ID of ctx.
+func (mds *MapDirectoryStore) SetFileDataContext(ctx context.Context, fileKey FileKey, data map[string]any) error {
 	if data == nil {
 		return fmt.Errorf("invalid request for file: %s", fileKey.FileName)
 	}
-	store, err := mds.OpenFile(fileKey, true, data)
+	store, err := mds.OpenFileContext(ctx, fileKey, true, data)
 	if err != nil {
 		return err
 	}
-	return store.SetAll(data)
+	return store.SetAllContext(ctx, data)
 }
 
 // GetFileData returns the data from the specified file in the store.
-// It is a thin wrapper around Open and GetAll.
+// It is a thin wrapper around Open and GetAll, plus reference resolution if enabled.
 func (mds *MapDirectoryStore) GetFileData(
 	fileKey FileKey,
 	forceFetch bool,
+) (map[string]any, error) {
+	return mds.GetFileDataContext(context.Background(), fileKey, forceFetch)
+}
+
+// GetFileDataContext is GetFileData, passing ctx to the access checker.
+func (mds *MapDirectoryStore) GetFileDataContext(
+	ctx context.Context,
+	fileKey FileKey,
+	forceFetch bool,
 ) (map[string]any, error) {
 	// Use a dummy defaultData for opening if file exists.
-	store, err := mds.OpenFile(fileKey, false, map[string]any{})
+	store, err := mds.OpenFileContext(ctx, fileKey, false, map[string]any{})
 	if err != nil {
 		return nil, err
 	}
-	return store.GetAll(forceFetch)
+	data, err := store.GetAllContext(ctx, forceFetch)
+	if err != nil || !mds.resolveRefs {
+		return data, err
+	}
+	return mds.resolveRefsIn(ctx, store.filename, data)
 }
 
-// DeleteFile removes the file with the given filename from the base directory.
+// DeleteFile removes the file with the given filename from the base directory, together with its attachments.
 // It is a thin wrapper around Open and DeleteFile.
 func (mds *MapDirectoryStore) DeleteFile(fileKey FileKey) error {
-	store, err := mds.OpenFile(fileKey, false, map[string]any{})
+	return mds.DeleteFileContext(context.Background(), fileKey)
+}
+
+// DeleteFileContext is DeleteFile, attributing the event to the actor and request ID of ctx.
+func (mds *MapDirectoryStore) DeleteFileContext(ctx context.Context, fileKey FileKey) error {
+	store, err := mds.OpenFileContext(ctx, fileKey, false, map[string]any{})
 	if err != nil {
 		return err
 	}
 
-	if err := store.DeleteFile(); err != nil {
+	if err := store.DeleteFileContext(ctx); err != nil {
 		return err
 	}
+	if err := mds.removeAttachments(fileKey, store.filename); err != nil {
+		return fmt.Errorf("failed to remove attachments of %s: %w", fileKey.FileName, err)
+	}
 	return mds.CloseFile(fileKey)
 }
 
@@ -169,40 +293,158 @@ func (mds *MapDirectoryStore) OpenFile(
 	fileKey FileKey,
 	createIfNotExists bool,
 	defaultData map[string]any,
+) (*MapFileStore, error) {
+	return mds.OpenFileContext(context.Background(), fileKey, createIfNotExists, defaultData)
+}
+
+// OpenFileContext is OpenFile, passing ctx to the access checker if the file is created.
+func (mds *MapDirectoryStore) OpenFileContext(
+	ctx context.Context,
+	fileKey FileKey,
+	createIfNotExists bool,
+	defaultData map[string]any,
 ) (*MapFileStore, error) {
 	filePath, err := mds.validateAndGetFilePath(fileKey)
 	if err != nil {
 		return nil, err
 	}
+	store, err := mds.openPath(ctx, filePath, createIfNotExists, defaultData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file store for %s: %w", fileKey.FileName, err)
+	}
+	return store, nil
+}
+
+// OpenFileEntry returns a cached or newly opened MapFileStore for a FileEntry returned by ListFiles.
+// It is useful when the FileKey of a listed file cannot be reconstructed from its name.
+func (mds *MapDirectoryStore) OpenFileEntry(entry FileEntry) (*MapFileStore, error) {
+	filePath, err := mds.entryFilePath(entry)
+	if err != nil {
+		return nil, err
+	}
+	store, err := mds.openPath(context.Background(), filePath, false, map[string]any{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file store for %s: %w", entry.BaseRelativePath, err)
+	}
+	return store, nil
+}
+
+// CloseFileEntry closes the MapFileStore for a FileEntry (if it was opened) and removes it from the cache.
+func (mds *MapDirectoryStore) CloseFileEntry(entry FileEntry) error {
+	filePath, err := mds.entryFilePath(entry)
+	if err != nil {
+		return err
+	}
+	return mds.closePath(filePath)
+}
 
+// openPath returns the cached store for filePath, opening it if needed. The store stays cached until it is
+// closed, also if it was borrowed.
+func (mds *MapDirectoryStore) openPath(
+	ctx context.Context,
+	filePath string,
+	createIfNotExists bool,
+	defaultData map[string]any,
+) (*MapFileStore, error) {
 	mds.openMu.Lock()
 	defer mds.openMu.Unlock()
+	store, err := mds.openPathUnlocked(ctx, filePath, createIfNotExists, defaultData)
+	if err != nil {
+		return nil, err
+	}
+	delete(mds.borrowed, store)
+	return store, nil
+}
+
+// borrowPath returns the cached store for filePath for an internal operation, and a release func to call when
+// the operation is done. A store that was not cached before is closed by the last release, unless OpenFile or
+// another caller of openPath picked it up in the meantime, so concurrent operations never close a store that
+// someone else uses.
+func (mds *MapDirectoryStore) borrowPath(
+	ctx context.Context,
+	filePath string,
+	createIfNotExists bool,
+	defaultData map[string]any,
+) (store *MapFileStore, release func() error, err error) {
+	mds.openMu.Lock()
+	defer mds.openMu.Unlock()
+	_, wasOpen := mds.openStores[filePath]
+	store, err = mds.openPathUnlocked(ctx, filePath, createIfNotExists, defaultData)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := mds.borrowed[store]; ok {
+		mds.borrowed[store] = n + 1
+	} else if !wasOpen {
+		if mds.borrowed == nil {
+			mds.borrowed = make(map[*MapFileStore]int)
+		}
+		mds.borrowed[store] = 1
+	}
+	return store, func() error { return mds.releaseStore(filePath, store) }, nil
+}
+
+// releaseStore ends one borrow of store and closes it if it was the last one.
+func (mds *MapDirectoryStore) releaseStore(filePath string, store *MapFileStore) error {
+	mds.openMu.Lock()
+	n, ok := mds.borrowed[store]
+	switch {
+	case !ok:
+		mds.openMu.Unlock()
+		return nil
+	case n > 1:
+		mds.borrowed[store] = n - 1
+		mds.openMu.Unlock()
+		return nil
+	}
+	delete(mds.borrowed, store)
+	if mds.openStores[filePath] == store {
+		delete(mds.openStores, filePath)
+	}
+	mds.openMu.Unlock()
+	return store.Close()
+}
+
+// openPathUnlocked returns the cached store for filePath, opening it if needed. Ctx is passed to the access
+// checker if the file is created. The caller holds openMu.
+func (mds *MapDirectoryStore) openPathUnlocked(
+	ctx context.Context,
+	filePath string,
+	createIfNotExists bool,
+	defaultData map[string]any,
+) (*MapFileStore, error) {
 	store, ok := mds.openStores[filePath]
 	if ok {
 		return store, nil
 	}
 
-	// Ensure the partition directory exists if creating.
-	if createIfNotExists {
-		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-			return nil, fmt.Errorf(
-				"failed to create partition directory %s: %w",
-				filepath.Dir(filePath),
-				err,
-			)
+	// Ensure the partition directory exists as the policy requires.
+	partitionDir := filepath.Dir(filePath)
+	switch {
+	case mds.createPolicy == PartitionCreateAlways,
+		mds.createPolicy == PartitionCreateOnWrite && createIfNotExists:
+		if err := os.MkdirAll(partitionDir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create partition directory %s: %w", partitionDir, err)
+		}
+	case mds.createPolicy == PartitionCreateNever:
+		if fi, err := os.Stat(partitionDir); err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("%w: %s", ErrPartitionNotFound, partitionDir)
 		}
 	}
 
 	// Create a new Map.
-	store, err = NewMapFileStore(
-		filePath,
-		defaultData,
-		mds.fileEncoderDecoder,
+	opts := append(
+		slices.Clone(mds.fileOptions),
 		WithCreateIfNotExists(createIfNotExists),
-		WithFileListeners(mds.listeners...),
+		WithCreateContext(ctx),
+		withListenerEntries(mds.listeners.load()),
 	)
+	if mds.hashContent {
+		opts = append(opts, WithContentHash(true))
+	}
+	store, err := NewMapFileStore(filePath, defaultData, mds.codecFor(filePath), opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file store for %s: %w", fileKey.FileName, err)
+		return nil, err
 	}
 
 	mds.openStores[filePath] = store
@@ -210,17 +452,35 @@ func (mds *MapDirectoryStore) OpenFile(
 	return store, nil
 }
 
+// EnsurePartition creates the named partition directory if it does not exist, regardless of the
+// PartitionCreatePolicy. Name is relative to the base directory, as returned by ListPartitions.
+func (mds *MapDirectoryStore) EnsurePartition(name string) error {
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("invalid partition name: %q", name)
+	}
+	dir := filepath.Join(mds.baseDir, name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create partition directory %s: %w", dir, err)
+	}
+	return nil
+}
+
 // CloseFile closes the MapFileStore for the given FileKey (if it was opened) and removes it from the cache.
 func (mds *MapDirectoryStore) CloseFile(fileKey FileKey) error {
 	filePath, err := mds.validateAndGetFilePath(fileKey)
 	if err != nil {
 		return err
 	}
+	return mds.closePath(filePath)
+}
 
+// closePath closes and forgets the cached store for filePath, if any.
+func (mds *MapDirectoryStore) closePath(filePath string) error {
 	mds.openMu.Lock()
 	store, ok := mds.openStores[filePath]
 	if ok {
 		delete(mds.openStores, filePath)
+		delete(mds.borrowed, store)
 	}
 	mds.openMu.Unlock()
 
@@ -238,6 +498,7 @@ func (mds *MapDirectoryStore) CloseAll() error {
 		stores = append(stores, st)
 	}
 	mds.openStores = make(map[string]*MapFileStore)
+	mds.borrowed = nil
 	mds.openMu.Unlock()
 
 	var firstErr error
@@ -253,6 +514,18 @@ func (mds *MapDirectoryStore) ListPartitions(
 	baseDir, sortOrder, pageToken string,
 	pageSize int,
 ) (partitions []string, nextPageToken string, err error) {
+	return mds.ListPartitionsContext(context.Background(), baseDir, sortOrder, pageToken, pageSize)
+}
+
+// ListPartitionsContext is ListPartitions, passing ctx to the access checker.
+func (mds *MapDirectoryStore) ListPartitionsContext(
+	ctx context.Context,
+	baseDir, sortOrder, pageToken string,
+	pageSize int,
+) (partitions []string, nextPageToken string, err error) {
+	if err := mds.checkAccess(ctx, OpListFiles, baseDir); err != nil {
+		return nil, "", err
+	}
 	return mds.partitionProvider.ListPartitions(baseDir, sortOrder, pageToken, pageSize)
 }
 
@@ -277,6 +550,18 @@ func (mds *MapDirectoryStore) ListFiles(
 	config ListingConfig,
 	pageToken string,
 ) (fileEntries []FileEntry, nextPageToken string, err error) {
+	return mds.ListFilesContext(context.Background(), config, pageToken)
+}
+
+// ListFilesContext is ListFiles, passing ctx to the access checker, also for the reads of a ContentFilter.
+func (mds *MapDirectoryStore) ListFilesContext(
+	ctx context.Context,
+	config ListingConfig,
+	pageToken string,
+) (fileEntries []FileEntry, nextPageToken string, err error) {
+	if err := mds.checkAccess(ctx, OpListFiles, mds.baseDir); err != nil {
+		return nil, "", err
+	}
 	var token pageTokenData
 
 	// Decode page token or initialize.
@@ -308,6 +593,14 @@ func (mds *MapDirectoryStore) ListFiles(
 	}
 
 	isFiltered := token.PartitionFilterPageToken != nil
+	if isFiltered {
+		// Names come from the caller or from a page token the caller may have altered.
+		for _, name := range token.PartitionFilterPageToken.FilterPartitions {
+			if err := mds.validatePartitionName(name); err != nil {
+				return nil, "", err
+			}
+		}
+	}
 
 	for {
 		var partitionName string
@@ -351,14 +644,21 @@ func (mds *MapDirectoryStore) ListFiles(
 		}
 
 		for j := token.FileIndex; j < len(partitionFileInfos); j++ {
-			fileEntries = append(
-				fileEntries,
-				FileEntry{
-					BaseRelativePath: filepath.Join(partitionName, partitionFileInfos[j].Name()),
-					PartitionName:    partitionName,
-					FileInfo:         partitionFileInfos[j],
-				},
-			)
+			entry := FileEntry{
+				BaseRelativePath: filepath.Join(partitionName, partitionFileInfos[j].Name()),
+				PartitionName:    partitionName,
+				FileInfo:         partitionFileInfos[j],
+			}
+			if config.ContentFilter != nil {
+				ok, err := mds.matchContent(ctx, entry, config.ContentFilter)
+				if err != nil {
+					return nil, "", err
+				}
+				if !ok {
+					continue
+				}
+			}
+			fileEntries = append(fileEntries, entry)
 			if len(fileEntries) > token.PageSize {
 				// Prepare next page token.
 				nextToken := pageTokenData{
@@ -378,7 +678,13 @@ func (mds *MapDirectoryStore) ListFiles(
 				}
 				nextPageTokenBytes, _ := json.Marshal(nextToken)
 				nextPageToken = base64.StdEncoding.EncodeToString(nextPageTokenBytes)
-				return fileEntries[:token.PageSize], nextPageToken, nil
+				fileEntries = fileEntries[:token.PageSize]
+				if mds.hashContent {
+					if err := mds.fillContentHashes(fileEntries); err != nil {
+						return nil, "", err
+					}
+				}
+				return fileEntries, nextPageToken, nil
 			}
 		}
 		token.FileIndex = 0
@@ -393,9 +699,45 @@ func (mds *MapDirectoryStore) ListFiles(
 		}
 	}
 
+	if mds.hashContent {
+		if err := mds.fillContentHashes(fileEntries); err != nil {
+			return nil, "", err
+		}
+	}
 	return fileEntries, "", nil
 }
 
+// matchContent reports whether filter accepts the data of entry. A file that was not open is closed again.
+func (mds *MapDirectoryStore) matchContent(
+	ctx context.Context,
+	entry FileEntry,
+	filter ContentFilter,
+) (ok bool, err error) {
+	filePath, err := mds.entryFilePath(entry)
+	if err != nil {
+		return false, err
+	}
+	store, release, err := mds.borrowPath(ctx, filePath, false, map[string]any{})
+	if err != nil {
+		return false, fmt.Errorf("failed to open file store for %s: %w", entry.BaseRelativePath, err)
+	}
+	defer func() {
+		if closeErr := release(); err == nil {
+			err = closeErr
+		}
+	}()
+	data, err := store.GetAllContext(ctx, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", entry.BaseRelativePath, err)
+	}
+	if mds.resolveRefs {
+		if data, err = mds.resolveRefsIn(ctx, store.filename, data); err != nil {
+			return false, fmt.Errorf("failed to resolve references in %s: %w", entry.BaseRelativePath, err)
+		}
+	}
+	return filter(data), nil
+}
+
 // readPartitionFiles lists files in a partition, sorted and filtered by prefix.
 func (mds *MapDirectoryStore) readPartitionFiles(
 	partitionPath, sortOrder, filenamePrefix string,
@@ -411,6 +753,10 @@ func (mds *MapDirectoryStore) readPartitionFiles(
 			name := file.Name()
 			if filenamePrefix == "" || strings.HasPrefix(name, filenamePrefix) {
 				info, err := file.Info()
+				if os.IsNotExist(err) {
+					// Removed after the directory was read.
+					continue
+				}
 				if err != nil {
 					return nil, fmt.Errorf("cannot stat file %s: %w", name, err)
 				}
@@ -430,6 +776,26 @@ func (mds *MapDirectoryStore) readPartitionFiles(
 	return fileInfos, nil
 }
 
+// codecFor returns the codec registered for the longest matching extension of filePath, or the default codec.
+func (mds *MapDirectoryStore) codecFor(filePath string) IOEncoderDecoder {
+	name := strings.ToLower(filepath.Base(filePath))
+	best, bestLen := mds.fileEncoderDecoder, 0
+	for ext, codec := range mds.codecs {
+		if len(ext) > bestLen && strings.HasSuffix(name, ext) {
+			best, bestLen = codec, len(ext)
+		}
+	}
+	return best
+}
+
+// entryFilePath validates a FileEntry and returns the absolute file path.
+func (mds *MapDirectoryStore) entryFilePath(entry FileEntry) (string, error) {
+	if entry.BaseRelativePath == "" || !filepath.IsLocal(entry.BaseRelativePath) {
+		return "", fmt.Errorf("invalid file entry path: %q", entry.BaseRelativePath)
+	}
+	return filepath.Join(mds.baseDir, entry.BaseRelativePath), nil
+}
+
 // validateAndGetFilePath validates the FileKey and returns the absolute file path.
 func (mds *MapDirectoryStore) validateAndGetFilePath(fileKey FileKey) (string, error) {
 	if fileKey.FileName == "" {
@@ -442,6 +808,9 @@ func (mds *MapDirectoryStore) validateAndGetFilePath(fileKey FileKey) (string, e
 			fileKey.FileName,
 		)
 	}
+	if mds.filenameNorm != 0 {
+		fileKey.FileName = mds.normalizeFilename(fileKey.FileName)
+	}
 	partitionDir, err := mds.partitionProvider.GetPartitionDir(fileKey)
 	if err != nil {
 		return "", fmt.Errorf(
@@ -451,5 +820,8 @@ func (mds *MapDirectoryStore) validateAndGetFilePath(fileKey FileKey) (string, e
 		)
 	}
 	filePath := filepath.Join(mds.baseDir, partitionDir, fileKey.FileName)
+	if mds.filenameNorm != 0 {
+		return mds.resolveNormalizedPath(filePath)
+	}
 	return filePath, nil
 }
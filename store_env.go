@@ -0,0 +1,148 @@
+package mapstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// EnvPathSeparator separates nested key segments in environment variable names.
+const EnvPathSeparator = "__"
+
+// WithEnvOverrides applies environment variable overrides for prefix when the store is opened.
+// See ApplyEnvOverrides.
+func WithEnvOverrides(prefix string) FileOption {
+	return func(store *MapFileStore) {
+		store.envPrefix = prefix
+	}
+}
+
+// ApplyEnvOverrides layers environment variables named PREFIX__A__B onto the key path [a b].
+//
+// Segments match existing keys case-insensitively, unknown segments are lower cased.
+// Values are coerced to the type of the existing value, or to a bool or number when they parse as one.
+// Overrides live in memory only: GetKey and GetAll see them, flushes never write them to disk.
+// Calling it again replaces the previous overrides.
+func ApplyEnvOverrides(store *MapFileStore, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("invalid env override prefix %q", prefix)
+	}
+	envPrefix := prefix + EnvPathSeparator
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return ErrClosed
+	}
+
+	overrides := make(map[string]any)
+	for _, kv := range os.Environ() {
+		name, raw, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(name, envPrefix), EnvPathSeparator)
+		if slices.Contains(segments, "") {
+			continue
+		}
+		keys := resolveEnvKeys(store.data, segments)
+		existing, _ := maputil.GetValueAtPath(store.data, keys)
+		val, err := coerceEnvValue(raw, existing)
+		if err != nil {
+			return fmt.Errorf("invalid value for env %s: %w", name, err)
+		}
+		if err := maputil.SetValueAtPath(overrides, keys, val); err != nil {
+			return fmt.Errorf("cannot apply env %s: %w", name, err)
+		}
+	}
+	store.overrides = overrides
+	return nil
+}
+
+// overrideAtUnlocked layers env overrides onto val, the stored value at keys.
+// Ok is false when no override applies, the returned value is always a copy.
+func (store *MapFileStore) overrideAtUnlocked(keys []string, val any, found bool) (merged any, ok bool) {
+	if len(store.overrides) == 0 {
+		return nil, false
+	}
+	ov, err := maputil.GetValueAtPath(store.overrides, keys)
+	if len(keys) == 0 {
+		ov, err = store.overrides, nil
+	}
+	if err != nil {
+		return nil, false
+	}
+	ovMap, ovIsMap := ov.(map[string]any)
+	valMap, valIsMap := val.(map[string]any)
+	if !ovIsMap || !valIsMap || !found {
+		return maputil.DeepCopyValue(ov), true
+	}
+	out, _ := maputil.DeepCopyValue(valMap).(map[string]any)
+	deepMergeMaps(out, ovMap)
+	return out, true
+}
+
+// deepMergeMaps deep-merges src into dst, src wins on leaves.
+func deepMergeMaps(dst, src map[string]any) {
+	for k, v := range src {
+		if sm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				deepMergeMaps(dm, sm)
+				continue
+			}
+		}
+		dst[k] = maputil.DeepCopyValue(v)
+	}
+}
+
+// resolveEnvKeys maps env name segments onto existing keys, matching case-insensitively.
+func resolveEnvKeys(data map[string]any, segments []string) []string {
+	keys := make([]string, 0, len(segments))
+	cur := data
+	for _, seg := range segments {
+		key := strings.ToLower(seg)
+		for k := range cur {
+			if strings.EqualFold(k, seg) {
+				key = k
+				break
+			}
+		}
+		keys = append(keys, key)
+		next, _ := cur[key].(map[string]any)
+		cur = next
+	}
+	return keys
+}
+
+// coerceEnvValue converts raw to the type of existing. Without an existing value, bools and numbers are detected.
+func coerceEnvValue(raw string, existing any) (any, error) {
+	switch existing.(type) {
+	case string:
+		return raw, nil
+	case bool:
+		return strconv.ParseBool(raw)
+	case float64, float32:
+		return strconv.ParseFloat(raw, 64)
+	case int, int32, int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case map[string]any, []any:
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	// Only the literal words count as bools, so "1" stays a number.
+	if strings.EqualFold(raw, "true") || strings.EqualFold(raw, "false") {
+		return strings.EqualFold(raw, "true"), nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return raw, nil
+}
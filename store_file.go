@@ -2,17 +2,18 @@ package mapstore
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"maps"
 	"os"
 	"path/filepath"
-	"runtime/debug"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ppipada/mapstore-go/internal/maputil"
@@ -23,6 +24,9 @@ const maxSetAllRetries = 3
 // ErrFileConflict is when flush/delete detects that somebody modified the file since we last read/wrote it.
 var ErrFileConflict = errors.New("concurrent modification detected for a file")
 
+// ErrClosed is returned by operations on a closed MapFileStore.
+var ErrClosed = errors.New("store is closed")
+
 // IOEncoderDecoder is an interface that defines methods for encoding and decoding data.
 type IOEncoderDecoder interface {
 	Encode(w io.Writer, value any) error
@@ -43,7 +47,8 @@ type FileKeyEncDecGetter func(pathSoFar []string) StringEncoderDecoder
 // It encodes decodes: Value at the key i.e value at last part of the path array.
 type FileValueEncDecGetter func(pathSoFar []string) IOEncoderDecoder
 
-// Operation is the kind of mutation that happened on a file or a key.
+// Operation is the kind of operation performed on a file or a key.
+// Events are only emitted for mutations, read operations are seen by access checkers alone.
 type Operation string
 
 const (
@@ -52,15 +57,47 @@ const (
 	OpDeleteFile Operation = "deleteFile"
 	OpSetKey     Operation = "setKey"
 	OpDeleteKey  Operation = "deleteKey"
+
+	// OpExternalChange is emitted when Refresh reloaded a file changed on disk outside this store.
+	OpExternalChange Operation = "externalChange"
+	// OpExternalReload is emitted when the file watcher of WithWatchFile reloaded a file changed on disk outside
+	// this store.
+	OpExternalReload Operation = "externalReload"
+	// OpTransaction is emitted once per committed Tx, with its mutations in FileEvent.Changes.
+	OpTransaction Operation = "transaction"
+	// OpDiscardPending is emitted when DiscardPending dropped unflushed changes and reloaded the file.
+	OpDiscardPending Operation = "discardPending"
+
+	OpGetFile   Operation = "getFile"
+	OpGetKey    Operation = "getKey"
+	OpListFiles Operation = "listFiles"
+
+	// Attachment operations are only seen by access checkers, with the attachment name as the single key.
+	OpPutAttachment    Operation = "putAttachment"
+	OpGetAttachment    Operation = "getAttachment"
+	OpDeleteAttachment Operation = "deleteAttachment"
+	OpListAttachments  Operation = "listAttachments"
 )
 
 // FileEvent is delivered *after* a mutation has been written to disk.
+//
+// Seq orders the events of one store: it increases by one with every change, in the order the changes were
+// applied. Listeners run after the store lock is released, so events of concurrent writers may be delivered out
+// of order; consumers that need the exact order sort by Seq, and detect missed events by gaps. Events of a single
+// goroutine are delivered in order. Seq starts at 1 every time the file is opened.
+//
+// Listeners should read the data through Get rather than calling back into the store: Get sees the state right
+// after the change, consistent with the event, and takes no store lock, so it can neither deadlock nor observe a
+// later mutation.
 type FileEvent struct {
-	Op Operation
+	Op  Operation
+	Seq uint64
 	// Absolute path of the backing JSON file.
 	File string
 	// Nil for file-level ops.
 	Keys []string
+	// Mutations of an OpTransaction, in the order they were applied. Nil for other ops.
+	Changes []KeyChange
 	// Nil for OpSetFile / OpResetFile.
 	OldValue any
 	// Nil for delete.
@@ -68,11 +105,38 @@ type FileEvent struct {
 	// Deep-copy of the entire map after the change.
 	Data      map[string]any
 	Timestamp time.Time
+	// ContentHash is the store's ContentHash when the event is delivered, empty unless WithContentHash is set.
+	// With concurrent writers it may already reflect a later change.
+	ContentHash string
+	// Actor and RequestID attribute the change, from the context passed to a Context method, see
+	// ContextWithActor and ContextWithRequestID. Empty for changes made without one.
+	Actor     string
+	RequestID string
+}
+
+// Get returns a deep copy of the value at keys in Data, the data right after the change, as redacted for
+// listeners. Read processors and environment overrides are not applied. Events without data, such as
+// OpDeleteFile, report every key as missing.
+func (e FileEvent) Get(keys []string) (any, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("cannot get value at root")
+	}
+	val, err := maputil.GetValueAtPath(e.Data, keys)
+	if err != nil {
+		return nil, err
+	}
+	return maputil.DeepCopyValue(val), nil
 }
 
 // FileListener is a callback that observes mutations.
 type FileListener func(FileEvent)
 
+// DataMigrator upgrades data freshly loaded from disk to the current schema.
+// Changed reports whether newData differs from data, in which case the store persists the result.
+type DataMigrator interface {
+	Migrate(file string, data map[string]any) (newData map[string]any, changed bool, err error)
+}
+
 // MapFileStore is a file-backed implementation of a thread-safe key-value store.
 type MapFileStore struct {
 	filename    string
@@ -85,10 +149,78 @@ type MapFileStore struct {
 	fileEncoderDecoder IOEncoderDecoder
 	autoFlush          bool
 	createIfNotExists  bool
+	// CreateCtx is the context the creation of a missing file is access checked with, see WithCreateContext.
+	createCtx context.Context
 
 	getValueEncDec FileValueEncDecGetter
 	getKeyEncDec   FileKeyEncDecGetter
-	listeners      []FileListener
+	listeners      listenerSet
+	// Listener timeout settings and counters, see WithListenerTimeout.
+	listenerTimeout    time.Duration
+	listenerAsyncAfter int
+	// ListenerQueueSize is the queue of every listener with async dispatch, see WithAsyncListeners.
+	listenerQueueSize int
+	listenerStats     listenerStats
+	migrator          DataMigrator
+	accessChecker     AccessChecker
+	redactor          Redactor
+	readProcessor     ReadProcessor
+	// In memory only layer from ApplyEnvOverrides, never flushed.
+	overrides map[string]any
+	envPrefix string
+	// Closed is set by Close, every operation checks it under mu.
+	closed bool
+	// Seq numbers the events of this store, incremented under the write lock of each mutation.
+	seq uint64
+	// Expiry times of keys by expiryPath, see SetKeyWithTTL, and the sweeper settings.
+	expiry      map[string]keyExpiry
+	expirySweep time.Duration
+	sweepStop   chan struct{}
+	// Journaled appends mutations to the journal file, see WithJournal. JournalKeys and journalAll note the top
+	// level keys changed since the last entry.
+	journaled   bool
+	journal     *os.File
+	journalKeys map[string]struct{}
+	journalAll  bool
+	// WatchFile reloads the file on filesystem notifications until watchStop is closed, see WithWatchFile.
+	watchFile bool
+	watchStop chan struct{}
+	// Dirty is set while memory holds changes that were not flushed.
+	dirty atomic.Bool
+	// LastUsed is the UnixNano time of the last operation, for idle tracking by the directory store.
+	lastUsed atomic.Int64
+	// StrictReads revalidates every read against the file on disk, see WithStrictReads.
+	strictReads bool
+	// HashContent keeps contentHash, the hash of the file as last written or read, see WithContentHash.
+	// SegHashes holds the hashes of the segments of a segmented store by encoded key.
+	hashContent bool
+	contentHash atomic.Pointer[string]
+	segHashes   map[string]string
+	// Segmented stores every top level key in its own file, see WithSegmentedStorage. SegDirty holds the top
+	// level keys changed since the last flush, segAll marks all of them changed.
+	segmented bool
+	segDirty  map[string]struct{}
+	segAll    bool
+	// SliceMerge is how MergeAll and MergeKey merge slices, see WithSliceMergeStrategy.
+	sliceMerge SliceMergeStrategy
+	// Compression compresses the file encoder output, see WithCompression.
+	compression Compression
+	// OrderedKeys keeps the key order of the file in keyOrder, see WithOrderedKeys.
+	orderedKeys bool
+	keyOrder    *maputil.KeyOrder
+	// RequiredPaths and immutablePaths constrain the data, see WithRequiredPaths and WithImmutablePaths.
+	requiredPaths  [][]string
+	immutablePaths [][]string
+	// CreatedPath and updatedPath are the timestamps kept in the data, see WithTimestamps.
+	createdPath []string
+	updatedPath []string
+	// FileMode and dirMode are the permissions of created files and directories, see WithFileMode.
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	// ReadOnly rejects mutations and writes, see WithReadOnly.
+	readOnly bool
+	// Fs loads, writes and stats the file, see WithFileSystem.
+	fs FileSystem
 }
 
 // FileOption defines a function type that applies a configuration option to the MapFileStore.
@@ -129,37 +261,84 @@ func WithCreateIfNotExists(createIfNotExists bool) FileOption {
 	}
 }
 
+// WithCreateContext sets the context passed to the access checker when the store creates a missing file, so the
+// checker can see the actor and request ID of the caller. The store does not keep it after opening.
+func WithCreateContext(ctx context.Context) FileOption {
+	return func(store *MapFileStore) {
+		store.createCtx = ctx
+	}
+}
+
 // WithFileListeners registers one or more listeners during store creation.
 func WithFileListeners(ls ...FileListener) FileOption {
-	return func(s *MapFileStore) { s.listeners = append(s.listeners, ls...) }
+	return func(s *MapFileStore) { s.listeners.add(ls...) }
+}
+
+// WithStrictReads makes every GetAll and GetKey check whether the file changed on disk, with one stat call, and
+// reload it if it did, as if forceFetch was passed. Cooperating processes that share the file then read each
+// other's completed writes without remembering forceFetch.
+func WithStrictReads(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.strictReads = enabled
+	}
+}
+
+// WithDataMigrator runs the migrator every time the file is loaded from disk.
+func WithDataMigrator(m DataMigrator) FileOption {
+	return func(s *MapFileStore) { s.migrator = m }
 }
 
 // NewMapFileStore initializes a new MapFileStore.
 // If the file does not exist and createIfNotExists is false, it returns an error.
+// It is a thin wrapper around NewMapFileStoreFromConfig.
 func NewMapFileStore(
 	filename string,
 	defaultData map[string]any,
 	fileEncoderDecoder IOEncoderDecoder,
 	opts ...FileOption,
 ) (*MapFileStore, error) {
-	if fileEncoderDecoder == nil {
-		return nil, errors.New("invalid file encoder decoder")
-	}
+	return NewMapFileStoreFromConfig(MapFileStoreConfig{
+		Filename:           filename,
+		DefaultData:        defaultData,
+		FileEncoderDecoder: fileEncoderDecoder,
+		Options:            opts,
+	})
+}
+
+// newMapFileStore opens the store after the config was validated and turned into options.
+func newMapFileStore(
+	filename string,
+	defaultData map[string]any,
+	fileEncoderDecoder IOEncoderDecoder,
+	opts []FileOption,
+) (*MapFileStore, error) {
 	store := &MapFileStore{
 		data:               make(map[string]any),
 		defaultData:        defaultData,
-		filename:           filepath.Clean(filename),
+		filename:           longPathName(filepath.Clean(filename)),
 		autoFlush:          true,
 		fileEncoderDecoder: fileEncoderDecoder,
+		fileMode:           defaultFileMode,
+		dirMode:            defaultDirMode,
+		fs:                 osFileSystem{},
 	}
 
+	store.lastUsed.Store(time.Now().UnixNano())
+
 	// Apply options.
 	for _, opt := range opts {
 		opt(store)
 	}
+	if store.compression != CompressionNone {
+		store.fileEncoderDecoder = compressedEncoderDecoder{
+			inner:       store.fileEncoderDecoder,
+			compression: store.compression,
+		}
+	}
 
 	// Create file if not exists.
 	err := store.createFileIfNotExists(filename)
+	store.createCtx = nil
 	if err != nil {
 		return nil, err
 	}
@@ -173,76 +352,154 @@ func NewMapFileStore(
 		// File disappeared between load and stat, extremely unlikely.
 		return nil, err
 	}
+	if err := store.openJournal(); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	if store.envPrefix != "" {
+		if err := ApplyEnvOverrides(store, store.envPrefix); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	store.startExpirySweep()
+	if err := store.startWatch(); err != nil {
+		store.Close()
+		return nil, err
+	}
 
 	return store, nil
 }
 
 // Flush writes the current data to the file. No event is emitted for flush.
 func (store *MapFileStore) Flush() error {
-	store.mu.RLock()
-	defer store.mu.RUnlock()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return ErrClosed
+	}
 	return store.flushUnlocked()
 }
 
 // Reset removes all data from the store.
 func (store *MapFileStore) Reset() error {
-	copyAfter, err := store.reset()
+	return store.ResetContext(context.Background())
+}
+
+// ResetContext is Reset, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) ResetContext(ctx context.Context) error {
+	if err := store.checkAccess(ctx, OpResetFile, nil); err != nil {
+		return err
+	}
+	copyAfter, seq, err := store.reset()
 	if err != nil {
 		return err
 	}
-	store.fireEvent(FileEvent{
+	store.fireEvent(attributeEvent(ctx, FileEvent{
 		Op:        OpResetFile,
+		Seq:       seq,
 		File:      store.filename,
 		Data:      copyAfter,
 		Timestamp: time.Now(),
-	})
+	}))
 
 	return nil
 }
 
-// GetAll returns a copy of all data in the store, refreshing from the file first.
+// GetAll returns a deep copy of all data in the store.
+// With forceFetch, or always with WithStrictReads, the file is reloaded first if it changed on disk.
 func (store *MapFileStore) GetAll(forceFetch bool) (map[string]any, error) {
-	if forceFetch {
-		stat, err := os.Stat(store.filename)
-		if err != nil {
-			return nil, fmt.Errorf("failed to stat file: %w", err)
-		}
-		if !isSameFileInfo(stat, store.lastStat) {
-			if err := store.load(); err != nil {
-				return nil, fmt.Errorf("failed to reload file: %w", err)
+	return store.GetAllContext(context.Background(), forceFetch)
+}
+
+// GetAllContext is GetAll, passing ctx to the access checker.
+func (store *MapFileStore) GetAllContext(ctx context.Context, forceFetch bool) (map[string]any, error) {
+	if err := store.checkAccess(ctx, OpGetFile, nil); err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	err := store.read(forceFetch || store.strictReads, func() error {
+		var err error
+		data, err = store.snapshotUnlocked()
+		return err
+	})
+	return data, err
+}
+
+// read runs fn under the store lock. With fresh the file is reloaded first if it changed on disk. The check,
+// the reload and fn happen under the store lock, so concurrent fresh reads load at most once and never observe
+// a half-applied mutation.
+func (store *MapFileStore) read(fresh bool, fn func() error) error {
+	if len(store.requiredPaths) > 0 {
+		read := fn
+		fn = func() error {
+			if err := store.checkRequiredUnlocked(); err != nil {
+				return err
 			}
+			return read()
 		}
 	}
 	store.mu.RLock()
-	defer store.mu.RUnlock()
+	if store.closed {
+		store.mu.RUnlock()
+		return ErrClosed
+	}
+	// Fast path, nothing changed on disk.
+	if !fresh {
+		defer store.mu.RUnlock()
+		return fn()
+	}
+	stat, err := store.fs.Stat(store.filename)
+	if err == nil && isSameFileInfo(stat, store.lastStat) {
+		defer store.mu.RUnlock()
+		return fn()
+	}
+	store.mu.RUnlock()
 
-	// Return a copy of the in-memory data.
-	dataCopy := make(map[string]any)
-	maps.Copy(dataCopy, store.data)
-	return dataCopy, nil
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return ErrClosed
+	}
+	// Another caller may have reloaded while we waited for the lock, refreshUnlocked stats again.
+	if err := store.refreshUnlocked(); err != nil {
+		return err
+	}
+	return fn()
 }
 
 // SetAll overwrites all data in the store with the provided data.
 // It retries automatically if another writer wins the race and flushUnlocked returns ErrFileConflict.
 func (store *MapFileStore) SetAll(data map[string]any) error {
+	return store.SetAllContext(context.Background(), data)
+}
+
+// SetAllContext is SetAll, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) SetAllContext(ctx context.Context, data map[string]any) error {
 	if data == nil {
 		return errors.New("SetAll: nil data")
 	}
+	if err := store.checkAccess(ctx, OpSetFile, nil); err != nil {
+		return err
+	}
 
 	var (
 		copyAfter map[string]any
+		seq       uint64
 		err       error
 	)
 
 	for range maxSetAllRetries {
-		copyAfter, err = store.setAll(data)
+		copyAfter, seq, err = store.setAll(data)
 		if err == nil {
-			store.fireEvent(FileEvent{
+			store.fireEvent(attributeEvent(ctx, FileEvent{
 				Op:        OpSetFile,
+				Seq:       seq,
 				File:      store.filename,
 				Data:      copyAfter,
 				Timestamp: time.Now(),
-			})
+			}))
 			return nil
 		}
 
@@ -263,65 +520,113 @@ func (store *MapFileStore) SetAll(data map[string]any) error {
 // GetKey retrieves the value associated with the given key.
 // The key can be a dot-separated path to a nested value.
 func (store *MapFileStore) GetKey(keys []string) (any, error) {
+	return store.GetKeyContext(context.Background(), keys)
+}
+
+// GetKeyContext is GetKey, passing ctx to the access checker.
+func (store *MapFileStore) GetKeyContext(ctx context.Context, keys []string) (any, error) {
 	if len(keys) == 0 {
 		return nil, errors.New("cannot get value at root")
 	}
-	store.mu.RLock()
-	defer store.mu.RUnlock()
-
-	val, err := maputil.GetValueAtPath(store.data, keys)
-	if err != nil {
+	if err := store.checkAccess(ctx, OpGetKey, keys); err != nil {
 		return nil, err
 	}
-	return maputil.DeepCopyValue(val), nil
+	var out any
+	err := store.read(store.strictReads, func() error {
+		val, err := maputil.GetValueAtPath(store.data, keys)
+		if merged, ok := store.overrideAtUnlocked(keys, val, err == nil); ok {
+			out, err = store.processReadUnlocked(keys, merged)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		out, err = store.processReadUnlocked(keys, maputil.DeepCopyValue(val))
+		return err
+	})
+	return out, err
 }
 
 // SetKey sets the value for the given key.
 // The key can be a dot-separated path to a nested value.
 func (store *MapFileStore) SetKey(keys []string, value any) error {
-	oldVal, copyAfter, err := store.setKey(keys, value)
+	return store.SetKeyContext(context.Background(), keys, value)
+}
+
+// SetKeyContext is SetKey, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) SetKeyContext(ctx context.Context, keys []string, value any) error {
+	return store.setKeyContext(ctx, keys, value, time.Time{})
+}
+
+// setKeyContext sets the value at keys and its expiry, none if expireAt is zero.
+func (store *MapFileStore) setKeyContext(ctx context.Context, keys []string, value any, expireAt time.Time) error {
+	if err := store.checkAccess(ctx, OpSetKey, keys); err != nil {
+		return err
+	}
+	oldVal, copyAfter, seq, err := store.setKey(keys, value, expireAt)
 	if err != nil {
 		return err
 	}
-	store.fireEvent(FileEvent{
+	store.fireEvent(attributeEvent(ctx, FileEvent{
 		Op:        OpSetKey,
+		Seq:       seq,
 		File:      store.filename,
 		Keys:      slices.Clone(keys),
 		OldValue:  maputil.DeepCopyValue(oldVal),
 		NewValue:  maputil.DeepCopyValue(value),
 		Data:      copyAfter,
 		Timestamp: time.Now(),
-	})
+	}))
 	return nil
 }
 
 // DeleteKey deletes the value associated with the given key.
 // The key can be a dot-separated path to a nested value.
 func (store *MapFileStore) DeleteKey(keys []string) error {
-	oldVal, copyAfter, err := store.deleteKey(keys)
+	return store.DeleteKeyContext(context.Background(), keys)
+}
+
+// DeleteKeyContext is DeleteKey, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) DeleteKeyContext(ctx context.Context, keys []string) error {
+	if err := store.checkAccess(ctx, OpDeleteKey, keys); err != nil {
+		return err
+	}
+	oldVal, copyAfter, seq, err := store.deleteKey(keys)
 	if err != nil {
 		return err
 	}
-	store.fireEvent(FileEvent{
+	store.fireEvent(attributeEvent(ctx, FileEvent{
 		Op:        OpDeleteKey,
+		Seq:       seq,
 		File:      store.filename,
 		Keys:      slices.Clone(keys),
 		OldValue:  maputil.DeepCopyValue(oldVal),
 		NewValue:  nil,
 		Data:      copyAfter,
 		Timestamp: time.Now(),
-	})
+	}))
 	return nil
 }
 
 // DeleteFile removes the backing file atomically, emits an OpDeleteFile event and clears lastStat.
 // Returns ErrFileConflict if the file changed since we last observed it.
 func (store *MapFileStore) DeleteFile() error {
+	return store.DeleteFileContext(context.Background())
+}
+
+// DeleteFileContext is DeleteFile, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) DeleteFileContext(ctx context.Context) error {
+	if err := store.checkAccess(ctx, OpDeleteFile, nil); err != nil {
+		return err
+	}
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return ErrClosed
+	}
 
 	if store.lastStat != nil {
-		if cur, err := os.Stat(store.filename); err == nil {
+		if cur, err := store.fs.Stat(store.filename); err == nil {
 			if !isSameFileInfo(cur, store.lastStat) {
 				return ErrFileConflict
 			}
@@ -330,91 +635,144 @@ func (store *MapFileStore) DeleteFile() error {
 		}
 	}
 
-	if err := os.Remove(store.filename); err != nil && !os.IsNotExist(err) {
+	if err := store.fs.Remove(store.filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if store.segmented {
+		if err := store.fs.RemoveAll(store.segmentDir()); err != nil {
+			return fmt.Errorf("failed to remove segments of %s: %w", store.filename, err)
+		}
+	}
+	if err := store.removeJournalUnlocked(); err != nil {
 		return err
 	}
 
 	store.lastStat = nil
 	store.data = make(map[string]any)
+	store.dirty.Store(false)
+	store.setContentHash("")
+	store.seq++
 
-	store.fireEvent(FileEvent{
+	store.fireEvent(attributeEvent(ctx, FileEvent{
 		Op:        OpDeleteFile,
+		Seq:       store.seq,
 		File:      store.filename,
 		Timestamp: time.Now(),
-	})
+	}))
 	return nil
 }
 
+// Close marks the store closed. It waits for running operations to finish; all later calls fail with ErrClosed.
+// Close is idempotent. It does not flush, as the file may have been deleted.
 func (store *MapFileStore) Close() error {
-	// Should not flush here as file may be deleted.
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if !store.closed && store.sweepStop != nil {
+		close(store.sweepStop)
+	}
+	if !store.closed && store.watchStop != nil {
+		close(store.watchStop)
+	}
+	if store.journal != nil {
+		store.journal.Close()
+		store.journal = nil
+	}
+	store.closed = true
+	store.listeners.stopAll()
 	return nil
 }
 
-func (store *MapFileStore) setAll(data map[string]any) (copyAfter map[string]any, err error) {
+func (store *MapFileStore) setAll(data map[string]any) (copyAfter map[string]any, seq uint64, err error) {
 	if data == nil {
-		return nil, errors.New("SetAll: nil data")
+		return nil, 0, errors.New("SetAll: nil data")
 	}
 
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, ErrClosed
+	}
+	if err := store.checkImmutableUnlocked(nil, data, false); err != nil {
+		return nil, 0, err
+	}
 	// Deep copy the input data to prevent external modifications after setting.
+	before := store.data
 	store.data = make(map[string]any)
 	maps.Copy(store.data, data)
+	store.expiry = nil
+	store.touchUnlocked(before)
 	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
 
-	if store.autoFlush {
-		if err = store.flushUnlocked(); err != nil {
-			return nil, fmt.Errorf("failed to save data after SetAll: %w", err)
-		}
+	if err = store.persistUnlocked(); err != nil {
+		return nil, 0, fmt.Errorf("failed to save data after SetAll: %w", err)
 	}
-	return copyAfter, nil
+	store.seq++
+	return copyAfter, store.seq, nil
 }
 
-func (store *MapFileStore) reset() (copyAfter map[string]any, err error) {
+func (store *MapFileStore) reset() (copyAfter map[string]any, seq uint64, err error) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, ErrClosed
+	}
+	if err := store.checkImmutableUnlocked(nil, store.defaultData, false); err != nil {
+		return nil, 0, err
+	}
 
+	before := store.data
 	store.data = make(map[string]any)
 	maps.Copy(store.data, store.defaultData)
+	store.expiry = nil
+	store.touchUnlocked(before)
 	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
 
 	if err = store.flushUnlocked(); err != nil {
-		return nil, fmt.Errorf("failed to save data after Reset: %w", err)
+		return nil, 0, fmt.Errorf("failed to save data after Reset: %w", err)
 	}
-	return copyAfter, nil
+	store.seq++
+	return copyAfter, store.seq, nil
 }
 
 func (store *MapFileStore) setKey(
 	keys []string,
 	value any,
-) (oldVal any, copyAfter map[string]any, err error) {
+	expireAt time.Time,
+) (oldVal any, copyAfter map[string]any, seq uint64, err error) {
 	if len(keys) == 0 {
-		return nil, nil, errors.New("cannot set value at root")
+		return nil, nil, 0, errors.New("cannot set value at root")
 	}
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return nil, nil, 0, ErrClosed
+	}
 
+	if err := store.checkImmutableUnlocked(keys, value, false); err != nil {
+		return nil, nil, 0, err
+	}
 	oldVal, _ = maputil.GetValueAtPath(store.data, keys)
 	if err := maputil.SetValueAtPath(store.data, keys, value); err != nil {
-		return nil, nil, fmt.Errorf("failed to set value at key %v: %w", keys, err)
+		return nil, nil, 0, fmt.Errorf("failed to set value at key %v: %w", keys, err)
 	}
+	store.setExpiryUnlocked(keys, expireAt)
+	store.touchUnlocked(store.data, keys[0])
 	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
-	if store.autoFlush {
-		if err := store.flushUnlocked(); err != nil {
-			return nil, nil, fmt.Errorf(
-				"failed to save data after SetKey for keys %v: %w",
-				keys,
-				err,
-			)
-		}
+	if err := store.persistUnlocked(); err != nil {
+		return nil, nil, 0, fmt.Errorf(
+			"failed to save data after SetKey for keys %v: %w",
+			keys,
+			err,
+		)
 	}
-	return oldVal, copyAfter, nil
+	store.seq++
+	return oldVal, copyAfter, store.seq, nil
 }
 
 // createFileIfNotExists checks if a file exists and creates it if it doesn't.
 func (store *MapFileStore) createFileIfNotExists(filename string) error {
 	// Check if the file exists.
-	if _, err := os.Stat(filename); err == nil {
+	if _, err := store.fs.Stat(filename); err == nil {
 		// File exists, nothing to do.
 		return nil
 	} else if !os.IsNotExist(err) {
@@ -424,9 +782,17 @@ func (store *MapFileStore) createFileIfNotExists(filename string) error {
 	if !store.createIfNotExists {
 		return fmt.Errorf("file %s does not exist", filename)
 	}
+	// Creating the file writes the default data.
+	ctx := store.createCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := store.checkAccess(ctx, OpSetFile, nil); err != nil {
+		return err
+	}
 
 	// Try to create the file atomically.
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o666)
+	f, err := store.fs.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, store.fileMode)
 	if err != nil {
 		if os.IsExist(err) {
 			// Someone else created it first, nothing to do.
@@ -440,22 +806,58 @@ func (store *MapFileStore) createFileIfNotExists(filename string) error {
 	// Copy default data to store.
 	store.data = make(map[string]any)
 	maps.Copy(store.data, store.defaultData)
+	store.touchUnlocked(nil)
 
-	// Flush the store data to the file.
+	// Flush the store data to the file. An empty file would fail to load, so remove it again if that fails.
 	if err := store.flushUnlocked(); err != nil {
+		if st, statErr := store.fs.Stat(filename); statErr == nil && st.Size() == 0 {
+			_ = store.fs.Remove(filename)
+		}
 		return fmt.Errorf("failed to flush file %s: %w", filename, err)
 	}
 
 	return nil
 }
 
+// refreshUnlocked reloads the file if it changed since we last read or wrote it.
+func (store *MapFileStore) refreshUnlocked() error {
+	stat, err := store.fs.Stat(store.filename)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if isSameFileInfo(stat, store.lastStat) {
+		return nil
+	}
+	if err := store.loadUnlocked(); err != nil {
+		return fmt.Errorf("failed to reload file: %w", err)
+	}
+	return nil
+}
+
+// snapshotUnlocked returns a deep copy of the data as seen by readers, i.e. with overrides and read processing.
+func (store *MapFileStore) snapshotUnlocked() (map[string]any, error) {
+	if merged, ok := store.overrideAtUnlocked(nil, store.data, true); ok {
+		dataCopy, _ := merged.(map[string]any)
+		return store.processAllUnlocked(dataCopy)
+	}
+	dataCopy, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	return store.processAllUnlocked(dataCopy)
+}
+
 // load the data from the file into the in-memory store.
 func (store *MapFileStore) load() error {
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return ErrClosed
+	}
+	return store.loadUnlocked()
+}
 
+// loadUnlocked is load for callers that already hold the write lock.
+func (store *MapFileStore) loadUnlocked() error {
 	// Open the file.
-	f, err := os.Open(store.filename)
+	f, err := store.fs.Open(store.filename)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", store.filename, err)
 	}
@@ -463,9 +865,32 @@ func (store *MapFileStore) load() error {
 
 	// Decode the data from the file.
 	store.data = make(map[string]any)
-	if err := store.fileEncoderDecoder.Decode(f, &store.data); err != nil {
+	h := store.newContentHasher()
+	r := teeHash(f, h)
+	var raw *bytes.Buffer
+	if store.orderedKeys {
+		raw = &bytes.Buffer{}
+		r = io.TeeReader(r, raw)
+	}
+	if err := store.fileEncoderDecoder.Decode(r, &store.data); err != nil {
 		return fmt.Errorf("failed to decode data from file %s: %w", store.filename, err)
 	}
+	if raw != nil {
+		store.keyOrder = readKeyOrder(raw, store.compression != CompressionNone)
+	}
+	sum, err := finishHash(r, h)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", store.filename, err)
+	}
+	store.setContentHash(sum)
+	if store.expiry, err = takeExpiry(store.data); err != nil {
+		return fmt.Errorf("failed to decode data from file %s: %w", store.filename, err)
+	}
+	if store.segmented {
+		if store.data, err = store.loadSegmentsUnlocked(store.data); err != nil {
+			return err
+		}
+	}
 
 	// Do processing in place for load as you want loaded data to be non encoded decoded
 	// First process keys in decode mode.
@@ -486,70 +911,110 @@ func (store *MapFileStore) load() error {
 		return err
 	}
 	store.data, _ = newObj.(map[string]any)
+	store.dirty.Store(false)
+	// Unflushed changes are dropped with the old data.
+	if err := store.truncateJournalUnlocked(); err != nil {
+		return err
+	}
 
-	return store.rememberStat()
+	if err := store.rememberStat(); err != nil {
+		return err
+	}
+	return store.migrateUnlocked()
+}
+
+// migrateUnlocked runs the data migrator on the loaded data and persists the result if anything changed.
+func (store *MapFileStore) migrateUnlocked() error {
+	if store.migrator == nil {
+		return nil
+	}
+	migrated, changed, err := store.migrator.Migrate(store.filename, store.data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data in file %s: %w", store.filename, err)
+	}
+	if !changed {
+		return nil
+	}
+	if migrated == nil {
+		return fmt.Errorf("migration of file %s returned nil data", store.filename)
+	}
+	store.data = migrated
+	if store.readOnly {
+		return nil
+	}
+	store.markDirtyUnlocked()
+	if err := store.flushUnlocked(); err != nil {
+		return fmt.Errorf("failed to save migrated data in file %s: %w", store.filename, err)
+	}
+	return nil
 }
 
 func (store *MapFileStore) deleteKey(
 	keys []string,
-) (oldVal any, copyAfter map[string]any, err error) {
+) (oldVal any, copyAfter map[string]any, seq uint64, err error) {
 	if len(keys) == 0 {
-		return nil, nil, errors.New("cannot delete value at root")
+		return nil, nil, 0, errors.New("cannot delete value at root")
 	}
 	store.mu.Lock()
 	defer store.mu.Unlock()
+	if store.closed {
+		return nil, nil, 0, ErrClosed
+	}
 
+	if err := store.checkImmutableUnlocked(keys, nil, true); err != nil {
+		return nil, nil, 0, err
+	}
 	oldVal, _ = maputil.GetValueAtPath(store.data, keys)
 
 	if err := maputil.DeleteValueAtPath(store.data, keys); err != nil {
-		return nil, nil, fmt.Errorf("failed to delete key %v: %w", keys, err)
+		return nil, nil, 0, fmt.Errorf("failed to delete key %v: %w", keys, err)
 	}
+	store.setExpiryUnlocked(keys, time.Time{})
+	store.touchUnlocked(store.data, keys[0])
 	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
 
-	if store.autoFlush {
-		if err := store.flushUnlocked(); err != nil {
-			return nil, nil, fmt.Errorf(
-				"failed to save data after DeleteKey for key %v: %w",
-				keys,
-				err,
-			)
-		}
+	if err := store.persistUnlocked(); err != nil {
+		return nil, nil, 0, fmt.Errorf(
+			"failed to save data after DeleteKey for key %v: %w",
+			keys,
+			err,
+		)
 	}
-	return oldVal, copyAfter, nil
+	store.seq++
+	return oldVal, copyAfter, store.seq, nil
 }
 
 func (store *MapFileStore) flushUnlocked() error {
-	// We'll make a deep copy so we don't mutate in-memory.
-	// No error as store.data is always a map.
-	encodeMode := true
-	dataCopy, _ := maputil.DeepCopyValue(store.data).(map[string]any)
-
-	// First encode values so that all keys from in mem are non mutated.
-	// Encode KEYS next, so that on disk, the providers/modelnames become base64, etc.
-	tmpd, err := encodeDecodeAllValuesRecursively(
-		dataCopy,
-		[]string{},
-		store.getValueEncDec,
-		encodeMode,
-	)
-	if err != nil {
+	if err := store.checkWritable(OpSetFile); err != nil {
 		return err
 	}
-	dataCopy, _ = tmpd.(map[string]any)
-
-	// Encode KEYS next, so that on disk, the providers/modelnames become base64, etc.
-	err = encodeDecodeAllKeysRecursively(dataCopy, []string{}, store.getKeyEncDec, encodeMode)
+	var (
+		out      map[string]any
+		segments []segmentWrite
+		err      error
+	)
+	if store.segmented {
+		out, segments, err = store.encodeSegmentsUnlocked()
+	} else {
+		out, err = store.encodeAllUnlocked()
+	}
 	if err != nil {
 		return err
 	}
+	if _, ok := store.data[expiryKey]; ok {
+		return fmt.Errorf("key %q is reserved", expiryKey)
+	}
+	if len(store.expiry) > 0 {
+		out[expiryKey] = encodeExpiry(store.expiry)
+	}
 
 	if store.lastStat != nil {
 		// Optimistic CAS check.
-		if cur, err := os.Stat(store.filename); err == nil {
+		if cur, err := store.fs.Stat(store.filename); err == nil {
 			if !isSameFileInfo(cur, store.lastStat) {
 				return ErrFileConflict
 			}
-			f, permErr := os.OpenFile(store.filename, os.O_WRONLY, 0)
+			f, permErr := store.fs.OpenFile(store.filename, os.O_WRONLY, 0)
 			if permErr != nil {
 				return permErr
 			}
@@ -562,38 +1027,101 @@ func (store *MapFileStore) flushUnlocked() error {
 		}
 	}
 
-	if err := os.MkdirAll(filepath.Dir(store.filename), 0o770); err != nil {
+	if err := store.fs.MkdirAll(filepath.Dir(store.filename), store.dirMode); err != nil {
 		return fmt.Errorf(
 			"failed to ensure directory for file %s for flush: %w",
 			store.filename,
 			err,
 		)
 	}
-	tmpName := fmt.Sprintf("%s.tmp-%d", store.filename, time.Now().UnixNano())
-	tmpFile, err := os.Create(tmpName)
+	if store.segmented {
+		// Segments go first, the manifest in the main file commits them.
+		if err := store.writeSegmentsUnlocked(out, segments); err != nil {
+			return err
+		}
+	}
+	sum, err := store.writeFileUnlocked(store.filename, store.orderedUnlocked(out))
 	if err != nil {
-		return fmt.Errorf("failed to open file %s for flush: %w", store.filename, err)
+		return err
+	}
+	store.setContentHash(sum)
+
+	if err := store.rememberStat(); err != nil {
+		return err
 	}
-	if err := store.fileEncoderDecoder.Encode(tmpFile, dataCopy); err != nil {
+	store.dirty.Store(false)
+	store.segDirty, store.segAll = nil, false
+	return store.truncateJournalUnlocked()
+}
+
+// encodeAllUnlocked returns a copy of the data with values and keys encoded for disk.
+func (store *MapFileStore) encodeAllUnlocked() (map[string]any, error) {
+	// We'll make a deep copy so we don't mutate in-memory.
+	// No error as store.data is always a map.
+	encodeMode := true
+	dataCopy, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+
+	// First encode values so that all keys from in mem are non mutated.
+	// Encode KEYS next, so that on disk, the providers/modelnames become base64, etc.
+	tmpd, err := encodeDecodeAllValuesRecursively(
+		dataCopy,
+		[]string{},
+		store.getValueEncDec,
+		encodeMode,
+	)
+	if err != nil {
+		return nil, err
+	}
+	dataCopy, _ = tmpd.(map[string]any)
+
+	// Encode KEYS next, so that on disk, the providers/modelnames become base64, etc.
+	err = encodeDecodeAllKeysRecursively(dataCopy, []string{}, store.getKeyEncDec, encodeMode)
+	if err != nil {
+		return nil, err
+	}
+	return dataCopy, nil
+}
+
+// writeFileUnlocked atomically replaces path with data encoded by the file codec, through a hidden temp file and
+// rename.
+// It returns the content hash of the written bytes if hashing is enabled.
+func (store *MapFileStore) writeFileUnlocked(path string, data any) (string, error) {
+	dir, name := filepath.Split(path)
+	tmpName := filepath.Join(dir, fmt.Sprintf(".%s.tmp-%d", name, time.Now().UnixNano()))
+	tmpFile, err := store.fs.Create(tmpName, store.fileMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s for flush: %w", path, err)
+	}
+	var w io.Writer = tmpFile
+	h := store.newContentHasher()
+	if h != nil {
+		w = io.MultiWriter(tmpFile, h)
+	}
+	if err := store.fileEncoderDecoder.Encode(w, data); err != nil {
 		tmpFile.Close()
-		os.Remove(tmpName)
-		return fmt.Errorf("failed to encode data to file %s: %w", store.filename, err)
+		store.fs.Remove(tmpName)
+		return "", fmt.Errorf("failed to encode data to file %s: %w", path, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		store.fs.Remove(tmpName)
+		return "", fmt.Errorf("failed to write file %s: %w", path, err)
 	}
-	tmpFile.Close()
 	if store.lastStat != nil {
-		_ = os.Chmod(tmpName, store.lastStat.Mode().Perm())
+		_ = store.fs.Chmod(tmpName, store.lastStat.Mode().Perm())
 	}
 
-	if err := os.Rename(tmpName, store.filename); err != nil {
-		_ = os.Remove(tmpName)
-		return err
+	if err := store.fs.Rename(tmpName, path); err != nil {
+		_ = store.fs.Remove(tmpName)
+		return "", err
 	}
-
-	return store.rememberStat()
+	if h == nil {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func (s *MapFileStore) rememberStat() error {
-	st, err := os.Stat(s.filename)
+	st, err := s.fs.Stat(s.filename)
 	if err != nil {
 		// Caller decides whether ENOENT is fatal.
 		return err
@@ -605,27 +1133,17 @@ func (s *MapFileStore) rememberStat() error {
 // fireEvent delivers e to all listeners, recovering from panics so that a faulty
 // observer cannot crash the store.
 func (s *MapFileStore) fireEvent(e FileEvent) {
-	for _, l := range s.listeners {
-		if l == nil {
+	ls := s.listeners.load()
+	if len(ls) == 0 {
+		return
+	}
+	s.redactEvent(&e)
+	e.ContentHash = s.ContentHash()
+	for _, l := range ls {
+		if l.filter != nil && !l.filter.matches(e) {
 			continue
 		}
-		func(cb FileListener) {
-			defer func() {
-				if r := recover(); r != nil {
-					// Log.Printf("filestore: listener panic: %v", r).
-					slog.Error(
-						"filestore listener panic",
-						"err",
-						r,
-						"event",
-						e,
-						"stack",
-						string(debug.Stack()),
-					)
-				}
-			}()
-			cb(e)
-		}(l)
+		s.deliver(l, e)
 	}
 }
 
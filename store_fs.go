@@ -0,0 +1,73 @@
+package mapstore
+
+import (
+	"io"
+	"os"
+)
+
+// FileSystem is what a MapFileStore reads, writes, stats and removes its file and segments with, see
+// WithFileSystem.
+type FileSystem interface {
+	// Open opens name for reading.
+	Open(name string) (io.ReadCloser, error)
+	// Create creates or truncates name for writing, with perm before the umask if it is created.
+	Create(name string, perm os.FileMode) (io.WriteCloser, error)
+	// OpenFile opens name for writing with the flags of os.OpenFile, e.g. os.O_CREATE|os.O_EXCL to create it
+	// only if it does not exist.
+	OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error)
+	Rename(oldpath, newpath string) error
+	Stat(name string) (os.FileInfo, error)
+	Chmod(name string, mode os.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+// WithFileSystem replaces the operating system file system for creating, loading, flushing, deleting and change
+// detection, e.g. with a fault injecting one to test crash consistency. Writes stay atomic as long as Rename is:
+// a flush writes a temporary file and renames it over the file. Locks, journals, attachments and directory
+// listings always use the operating system.
+func WithFileSystem(fs FileSystem) FileOption {
+	return func(store *MapFileStore) {
+		store.fs = fs
+	}
+}
+
+// osFileSystem is the FileSystem of the operating system.
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (osFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
@@ -0,0 +1,135 @@
+package mapstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ServeHTTP serves the files of the store read-only, each under its BaseRelativePath, e.g.
+// "/202401/doc.json" for a file in partition "202401", so small apps can serve stored documents directly.
+//
+// A file is served like ExportContext with the request context: access checked, decoded and with redacted
+// values masked, in the file's codec. An open file is served from memory, with changes not flushed yet; any
+// other file is opened read-only for the request, so serving never writes to disk. The content type follows the
+// file extension. The weak ETag is the hash of the served body, so clients can revalidate cheaply; conditional
+// and range requests are handled by http.ServeContent. Only GET and HEAD are allowed. Sidecar directories,
+// hidden files, lock, journal and temp files are not served.
+func (mds *MapDirectoryStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	rel, ok := mds.servablePath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	filePath := filepath.Join(mds.baseDir, rel)
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, pending, err := mds.exportForHTTP(r.Context(), filePath)
+	switch {
+	case errors.Is(err, ErrAccessDenied):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	case os.IsNotExist(err):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		slog.Warn("mapstore: serving file failed", "file", rel, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `W/"`+hex.EncodeToString(sum[:])+`"`)
+	modTime := info.ModTime()
+	if pending {
+		// The body is newer than the file.
+		modTime = time.Time{}
+	}
+	http.ServeContent(w, r, filepath.Base(rel), modTime, bytes.NewReader(body))
+}
+
+// servablePath maps a URL path to a BaseRelativePath of a file in a partition, if it names one.
+func (mds *MapDirectoryStore) servablePath(urlPath string) (string, bool) {
+	p := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if p == "" {
+		return "", false
+	}
+	parts := strings.Split(p, "/")
+	if len(parts) > 2 {
+		return "", false
+	}
+	for _, part := range parts {
+		if strings.HasPrefix(part, ".") || strings.HasSuffix(part, attachmentsSuffix) ||
+			strings.HasSuffix(part, ".segments") {
+			return "", false
+		}
+	}
+	name := parts[len(parts)-1]
+	if strings.HasSuffix(name, lockFileSuffix) || strings.HasSuffix(name, journalSuffix) ||
+		tmpFilePattern.MatchString(name) {
+		return "", false
+	}
+	partition := ""
+	if len(parts) == 2 {
+		partition = parts[0]
+	}
+	if err := mds.validatePartitionName(partition); err != nil {
+		return "", false
+	}
+	rel := filepath.FromSlash(p)
+	if !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// exportForHTTP exports the file at filePath and reports whether the export has changes that are not flushed
+// yet. An open store is exported as is. Any other file is exported by a read-only store that is closed again,
+// so migrations apply in memory only and no journal is created.
+func (mds *MapDirectoryStore) exportForHTTP(
+	ctx context.Context,
+	filePath string,
+) (body []byte, pending bool, err error) {
+	mds.openMu.Lock()
+	store := mds.openStores[filePath]
+	mds.openMu.Unlock()
+	var buf bytes.Buffer
+	if store != nil {
+		pending = store.HasPending()
+		err = store.ExportContext(ctx, &buf)
+		if !errors.Is(err, ErrClosed) {
+			return buf.Bytes(), pending, err
+		}
+		// Closed since it was looked up, read the file instead.
+		buf.Reset()
+	}
+
+	opts := append(slices.Clone(mds.fileOptions), WithCreateIfNotExists(false), WithReadOnly(true))
+	store, err = NewMapFileStore(filePath, map[string]any{}, mds.codecFor(filePath), opts...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer store.Close()
+	if err := store.ExportContext(ctx, &buf); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), false, nil
+}
@@ -0,0 +1,94 @@
+package mapstore
+
+import (
+	"log/slog"
+	"time"
+)
+
+// minIdleInterval bounds how often the idle daemon scans the open stores.
+const minIdleInterval = 10 * time.Millisecond
+
+// WithIdlePolicy starts a background daemon that flushes open stores holding unflushed changes once they were
+// not used for flushAfter, and flushes and closes open stores not used for closeAfter. Zero disables either.
+//
+// It bounds memory and the data loss window of stores opened with WithFileAutoFlush(false) in long running
+// servers. Directory store methods reopen closed files transparently; a *MapFileStore handle kept across an
+// idle period returns ErrClosed. Stop the daemon with Close.
+func WithIdlePolicy(flushAfter, closeAfter time.Duration) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.idleFlushAfter = max(flushAfter, 0)
+		mds.idleCloseAfter = max(closeAfter, 0)
+	}
+}
+
+// Close stops the idle daemon, if any, and closes all open files. Unflushed changes are flushed first.
+func (mds *MapDirectoryStore) Close() error {
+	if mds.idleStop != nil {
+		mds.idleStopOnce.Do(func() {
+			close(mds.idleStop)
+			<-mds.idleDone
+		})
+	}
+	mds.openMu.Lock()
+	for _, st := range mds.openStores {
+		if st.dirty.Load() {
+			if err := st.Flush(); err != nil {
+				slog.Warn("mapstore: flush on close failed", "file", st.filename, "error", err)
+			}
+		}
+	}
+	mds.openMu.Unlock()
+	return mds.CloseAll()
+}
+
+// startIdleDaemon runs sweepIdle on a ticker until Close, if an idle policy is set.
+func (mds *MapDirectoryStore) startIdleDaemon() {
+	interval := mds.idleFlushAfter
+	if interval == 0 || (mds.idleCloseAfter > 0 && mds.idleCloseAfter < interval) {
+		interval = mds.idleCloseAfter
+	}
+	if interval == 0 {
+		return
+	}
+	interval = max(interval/2, minIdleInterval)
+
+	mds.idleStop = make(chan struct{})
+	mds.idleDone = make(chan struct{})
+	go func() {
+		defer close(mds.idleDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-mds.idleStop:
+				return
+			case now := <-ticker.C:
+				mds.sweepIdle(now)
+			}
+		}
+	}()
+}
+
+// sweepIdle flushes and closes the stores that were idle for long enough.
+// It holds openMu, so no store can be handed out of the cache while it is being closed.
+func (mds *MapDirectoryStore) sweepIdle(now time.Time) {
+	mds.openMu.Lock()
+	defer mds.openMu.Unlock()
+	for path, st := range mds.openStores {
+		idle := now.Sub(time.Unix(0, st.lastUsed.Load()))
+		closeIt := mds.idleCloseAfter > 0 && idle >= mds.idleCloseAfter
+		flushIt := closeIt || (mds.idleFlushAfter > 0 && idle >= mds.idleFlushAfter)
+		if flushIt && st.dirty.Load() {
+			if err := st.Flush(); err != nil {
+				// Keep the store open, its changes exist only in memory.
+				slog.Warn("mapstore: idle flush failed", "file", path, "error", err)
+				continue
+			}
+		}
+		if closeIt {
+			_ = st.Close()
+			delete(mds.openStores, path)
+			delete(mds.borrowed, st)
+		}
+	}
+}
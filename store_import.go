@@ -0,0 +1,220 @@
+package mapstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ImportOptions configures MapDirectoryStore.Import. The zero value imports with 4 workers in batches of 256.
+type ImportOptions struct {
+	// Concurrency is the number of files written in parallel.
+	Concurrency int
+	// BatchSize is the number of files written before they are synced to disk, the checkpoint is saved and
+	// Progress is called. Larger batches sync less often, smaller ones lose less work on a crash.
+	BatchSize int
+	// CheckpointFile, if set, records how far the source was imported after every batch. A later Import with the
+	// same file skips that many items of the source, which must then yield the same items in the same order.
+	// Delete the file to import from the start again.
+	CheckpointFile string
+	// Progress is called after every batch, from the goroutine that called Import.
+	Progress func(ImportProgress)
+}
+
+// ImportProgress reports the state of an import.
+type ImportProgress struct {
+	// Position is the number of source items that are done, including those skipped from a checkpoint.
+	Position int
+	// Imported counts the files written by this call.
+	Imported int
+	// Skipped counts the source items skipped from the checkpoint.
+	Skipped int
+}
+
+type importCheckpoint struct {
+	Position int `json:"position"`
+}
+
+type importItem struct {
+	key  FileKey
+	data map[string]any
+}
+
+// Import writes every file of src, the fast path for migrating data into a store. Files are written like
+// SetFileData, with events and access checks, by a bounded number of workers, so a fast source waits for the
+// disk instead of filling memory. Files that were not open are closed again after writing.
+//
+// Files are synced to disk in batches; after each batch the checkpoint is saved and Progress is called. On error
+// or cancellation Import stops, and returns the progress of the last complete batch together with the error.
+func (mds *MapDirectoryStore) Import(
+	ctx context.Context,
+	src iter.Seq2[FileKey, map[string]any],
+	opts ImportOptions,
+) (ImportProgress, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 256
+	}
+	var progress ImportProgress
+	if opts.CheckpointFile != "" {
+		cp, err := readImportCheckpoint(opts.CheckpointFile)
+		if err != nil {
+			return progress, err
+		}
+		progress.Skipped = cp.Position
+	}
+
+	batch := make([]importItem, 0, opts.BatchSize)
+	flush := func() error {
+		if err := mds.importBatch(ctx, batch, opts.Concurrency); err != nil {
+			return err
+		}
+		progress.Position += len(batch)
+		progress.Imported += len(batch)
+		batch = batch[:0]
+		if opts.CheckpointFile != "" {
+			if err := writeImportCheckpoint(opts.CheckpointFile, progress.Position); err != nil {
+				return err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return nil
+	}
+
+	for key, data := range src {
+		if progress.Position < progress.Skipped {
+			progress.Position++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		batch = append(batch, importItem{key: key, data: data})
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+// importBatch writes the items with up to concurrency workers and syncs the written files and their
+// directories.
+func (mds *MapDirectoryStore) importBatch(ctx context.Context, items []importItem, concurrency int) error {
+	paths := make([]string, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			paths[i], errs[i] = mds.importFile(ctx, item)
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		if err := syncPath(p); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", p, err)
+		}
+		dirs[filepath.Dir(p)] = true
+	}
+	for dir := range dirs {
+		// Directories cannot be synced on every platform, the files themselves are durable.
+		_ = syncPath(dir)
+	}
+	return nil
+}
+
+// importFile writes one item and returns the path of its file.
+func (mds *MapDirectoryStore) importFile(ctx context.Context, item importItem) (string, error) {
+	if item.data == nil {
+		return "", fmt.Errorf("invalid import data for file: %s", item.key.FileName)
+	}
+	filePath, err := mds.validateAndGetFilePath(item.key)
+	if err != nil {
+		return "", err
+	}
+	store, release, err := mds.borrowPath(ctx, filePath, true, item.data)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file store for %s: %w", item.key.FileName, err)
+	}
+	err = store.SetAllContext(ctx, item.data)
+	if closeErr := release(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to import %s: %w", item.key.FileName, err)
+	}
+	return store.filename, nil
+}
+
+func syncPath(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func readImportCheckpoint(p string) (importCheckpoint, error) {
+	var cp importCheckpoint
+	raw, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("failed to read import checkpoint %s: %w", p, err)
+	}
+	if err := json.Unmarshal(raw, &cp); err != nil {
+		return cp, fmt.Errorf("invalid import checkpoint %s: %w", p, err)
+	}
+	if cp.Position < 0 {
+		return cp, fmt.Errorf("invalid import checkpoint %s: negative position", p)
+	}
+	return cp, nil
+}
+
+// writeImportCheckpoint replaces the checkpoint durably, through a synced temp file and rename.
+func writeImportCheckpoint(p string, position int) error {
+	raw, _ := json.Marshal(importCheckpoint{Position: position})
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write import checkpoint %s: %w", p, err)
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write import checkpoint %s: %w", p, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync import checkpoint %s: %w", p, err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("failed to write import checkpoint %s: %w", p, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package mapstore
+
+import (
+	"path/filepath"
+	"slices"
+)
+
+// CacheInvalidator is the contract for state derived from store data, such as read caches, manifests, search
+// index bridges or HTTP ETags. Rather than interpreting FileEvents itself, derived state implements
+// CacheInvalidator and is plugged in with WithCacheInvalidators or WithDirCacheInvalidators, so every subsystem
+// invalidates on the same events with the same rules. The directory store drops its own partition stats this way.
+//
+// InvalidateCache is called after a change was written, or a change on disk was reloaded. Every value derived
+// from keys, from a path below keys or from a parent of keys is stale, see KeysOverlap. Nil keys means the whole
+// file changed or was deleted. It runs like a listener, after the store lock is released, so it must not call
+// back into the store with a write, and should only mark or drop state. Keys must not be modified.
+type CacheInvalidator interface {
+	InvalidateCache(file string, keys []string)
+}
+
+// CacheInvalidatorFunc adapts a function to CacheInvalidator.
+type CacheInvalidatorFunc func(file string, keys []string)
+
+// InvalidateCache implements CacheInvalidator.
+func (f CacheInvalidatorFunc) InvalidateCache(file string, keys []string) {
+	f(file, keys)
+}
+
+// InvalidationListener returns a listener that turns events into InvalidateCache calls, for stores configured
+// through listeners only.
+func InvalidationListener(invs ...CacheInvalidator) FileListener {
+	return func(e FileEvent) {
+		var keys []string
+		switch e.Op {
+		case OpSetKey, OpDeleteKey:
+			keys = e.Keys
+		case OpTransaction:
+			for _, c := range e.Changes {
+				for _, inv := range invs {
+					inv.InvalidateCache(e.File, c.Keys)
+				}
+			}
+			return
+		default:
+			// File level events, including OpSetFile, replace everything.
+		}
+		for _, inv := range invs {
+			inv.InvalidateCache(e.File, keys)
+		}
+	}
+}
+
+// WithCacheInvalidators registers cache invalidators on a file store.
+func WithCacheInvalidators(invs ...CacheInvalidator) FileOption {
+	return WithFileListeners(InvalidationListener(invs...))
+}
+
+// WithDirCacheInvalidators registers cache invalidators for every file of a directory store.
+func WithDirCacheInvalidators(invs ...CacheInvalidator) DirOption {
+	return WithDirFileListeners(InvalidationListener(invs...))
+}
+
+// KeysOverlap reports whether a change at changed invalidates a value derived from path, that is whether one is
+// a prefix of the other. Nil changed, a whole file change, overlaps every path.
+func KeysOverlap(changed, path []string) bool {
+	n := min(len(changed), len(path))
+	return changed == nil || slices.Equal(changed[:n], path[:n])
+}
+
+// invalidatePartitionStats drops the cached stats of the partition holding file.
+func (mds *MapDirectoryStore) invalidatePartitionStats(file string, _ []string) {
+	mds.statsMu.Lock()
+	defer mds.statsMu.Unlock()
+	rel, err := filepath.Rel(mds.baseDir, filepath.Dir(file))
+	if err != nil {
+		clear(mds.partitionStats)
+		return
+	}
+	if rel == "." {
+		rel = ""
+	}
+	delete(mds.partitionStats, rel)
+}
@@ -0,0 +1,254 @@
+package mapstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+)
+
+// journalSuffix is appended to the file name for the journal of a store, see WithJournal.
+const journalSuffix = ".wal"
+
+// WithJournal appends every mutation to a "<file>.wal" journal, synced to disk, before it returns, and replays
+// the journal when the store is opened. Together with WithFileAutoFlush(false) this keeps many cheap in memory
+// mutations without losing them on a crash: the journal is emptied by every flush, and by reloads that drop
+// unflushed changes. With auto flush on, every mutation is already written to the file.
+//
+// A journal entry holds the new values of the changed top level keys, encoded like the file, so encrypted
+// values stay encrypted. An entry cut short by a crash, and everything after it, is ignored on replay. After a
+// replay the store is dirty and, with auto flush, flushed right away.
+func WithJournal(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.journaled = enabled
+	}
+}
+
+// journalEntry is one line of the journal.
+type journalEntry struct {
+	// Reset replaces all data with Set, instead of setting the keys of Set.
+	Reset  bool           `json:"reset,omitempty"`
+	Set    map[string]any `json:"set,omitempty"`
+	Delete []string       `json:"delete,omitempty"`
+	Expiry []any          `json:"expiry,omitempty"`
+}
+
+func (store *MapFileStore) journalPath() string {
+	return store.filename + journalSuffix
+}
+
+// openJournal replays the journal left by an earlier run onto the loaded data and opens it for appending.
+func (store *MapFileStore) openJournal() error {
+	if !store.journaled || store.readOnly {
+		return nil
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	n, err := store.replayJournalUnlocked()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		store.markDirtyUnlocked()
+	}
+	if _, err := store.journalFileUnlocked(); err != nil {
+		return err
+	}
+	if n > 0 && store.autoFlush {
+		return store.flushUnlocked()
+	}
+	return nil
+}
+
+// journalFileUnlocked returns the journal file, opening it for appending if needed.
+func (store *MapFileStore) journalFileUnlocked() (*os.File, error) {
+	if store.journal != nil {
+		return store.journal, nil
+	}
+	f, err := os.OpenFile(store.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal of %s: %w", store.filename, err)
+	}
+	store.journal = f
+	return f, nil
+}
+
+// replayJournalUnlocked applies the entries of the journal to the data and returns how many it applied.
+func (store *MapFileStore) replayJournalUnlocked() (int, error) {
+	f, err := os.Open(store.journalPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal of %s: %w", store.filename, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	n := 0
+	for {
+		var e journalEntry
+		if err := dec.Decode(&e); err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Warn("mapstore: ignoring incomplete journal entry", "file", store.filename, "error", err)
+			}
+			return n, nil
+		}
+		if err := store.applyJournalEntryUnlocked(e); err != nil {
+			return n, fmt.Errorf("failed to replay journal of %s: %w", store.filename, err)
+		}
+		n++
+	}
+}
+
+// applyJournalEntryUnlocked decodes the keys and values of e like a loaded file and applies them to the data.
+func (store *MapFileStore) applyJournalEntryUnlocked(e journalEntry) error {
+	set := e.Set
+	if set == nil {
+		set = make(map[string]any)
+	}
+	if err := encodeDecodeAllKeysRecursively(set, []string{}, store.getKeyEncDec, false); err != nil {
+		return err
+	}
+	decoded, err := encodeDecodeAllValuesRecursively(set, []string{}, store.getValueEncDec, false)
+	if err != nil {
+		return err
+	}
+	set, _ = decoded.(map[string]any)
+	if e.Reset {
+		store.data = set
+	} else {
+		maps.Copy(store.data, set)
+	}
+
+	deleted := make(map[string]any, len(e.Delete))
+	for _, k := range e.Delete {
+		deleted[k] = nil
+	}
+	if err := encodeDecodeAllKeysRecursively(deleted, []string{}, store.getKeyEncDec, false); err != nil {
+		return err
+	}
+	for k := range deleted {
+		delete(store.data, k)
+	}
+
+	expiry := map[string]any{}
+	if e.Expiry != nil {
+		expiry[expiryKey] = e.Expiry
+	}
+	store.expiry, err = takeExpiry(expiry)
+	return err
+}
+
+// persistUnlocked makes a mutation durable: it flushes with auto flush, else appends it to the journal, if any.
+func (store *MapFileStore) persistUnlocked() error {
+	if store.autoFlush {
+		return store.flushUnlocked()
+	}
+	return store.appendJournalUnlocked()
+}
+
+// recordJournalUnlocked notes the top level keys changed since the last journal entry, all if none are given.
+func (store *MapFileStore) recordJournalUnlocked(topKeys []string) {
+	if !store.journaled {
+		return
+	}
+	if len(topKeys) == 0 {
+		store.journalAll = true
+		return
+	}
+	if store.journalKeys == nil {
+		store.journalKeys = make(map[string]struct{})
+	}
+	for _, k := range topKeys {
+		store.journalKeys[k] = struct{}{}
+	}
+}
+
+// appendJournalUnlocked writes the changes noted by recordJournalUnlocked to the journal and syncs it.
+func (store *MapFileStore) appendJournalUnlocked() error {
+	if !store.journaled || (!store.journalAll && len(store.journalKeys) == 0) {
+		return nil
+	}
+	e := journalEntry{Reset: store.journalAll}
+	if store.journalAll {
+		out, err := store.encodeAllUnlocked()
+		if err != nil {
+			return err
+		}
+		e.Set = out
+	} else {
+		e.Set = make(map[string]any, len(store.journalKeys))
+		for k := range store.journalKeys {
+			v, ok := store.data[k]
+			if !ok {
+				e.Delete = append(e.Delete, store.encodeTopKey(k))
+				continue
+			}
+			seg, err := store.encodeTopLevelUnlocked(k, v)
+			if err != nil {
+				return err
+			}
+			maps.Copy(e.Set, seg)
+		}
+		slices.Sort(e.Delete)
+	}
+	if len(store.expiry) > 0 {
+		e.Expiry = encodeExpiry(store.expiry)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	f, err := store.journalFileUnlocked()
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to write journal of %s: %w", store.filename, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		// Drop a partial entry, it would end the journal on replay.
+		_ = f.Truncate(info.Size())
+		return fmt.Errorf("failed to write journal of %s: %w", store.filename, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal of %s: %w", store.filename, err)
+	}
+	store.journalKeys, store.journalAll = nil, false
+	return nil
+}
+
+// truncateJournalUnlocked empties the journal once its changes are in the file or were dropped.
+func (store *MapFileStore) truncateJournalUnlocked() error {
+	store.journalKeys, store.journalAll = nil, false
+	if store.journal == nil {
+		return nil
+	}
+	if err := store.journal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal of %s: %w", store.filename, err)
+	}
+	if err := store.journal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal of %s: %w", store.filename, err)
+	}
+	return nil
+}
+
+// removeJournalUnlocked closes and removes the journal, with the file it belongs to.
+func (store *MapFileStore) removeJournalUnlocked() error {
+	store.journalKeys, store.journalAll = nil, false
+	if store.journal != nil {
+		store.journal.Close()
+		store.journal = nil
+	}
+	if err := os.Remove(store.journalPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal of %s: %w", store.filename, err)
+	}
+	return nil
+}
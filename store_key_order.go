@@ -0,0 +1,50 @@
+package mapstore
+
+import (
+	"io"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// WithOrderedKeys keeps the order of the object keys of the file across writes, for config files whose order
+// means something to the people editing them. The order is read from the file on every load; keys added since
+// are written after the existing ones, sorted. Without it keys are written sorted, as encoding/json does.
+//
+// The order is kept by handing the file codec a value that marshals itself to JSON, so it only applies to
+// codecs that encode with encoding/json, such as jsonencdec.JSONEncoderDecoder, optionally wrapped in
+// gzipencdec. Segment files of a segmented store keep sorted keys.
+func WithOrderedKeys(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.orderedKeys = enabled
+	}
+}
+
+// readKeyOrder reads the key order of the file content in r, decompressed first if compressed is set. Content
+// that is not a JSON object, such as the output of a non JSON codec, gives an empty order.
+func readKeyOrder(r io.Reader, compressed bool) *maputil.KeyOrder {
+	if compressed {
+		zr, err := decompressed(r)
+		if err != nil {
+			return &maputil.KeyOrder{}
+		}
+		defer zr.Close()
+		r = zr
+	}
+	o, err := maputil.ReadKeyOrder(r)
+	if err != nil {
+		return &maputil.KeyOrder{}
+	}
+	return o
+}
+
+// orderedUnlocked returns out as the file codec should encode it: as is, or wrapped to keep the key order.
+func (store *MapFileStore) orderedUnlocked(out map[string]any) any {
+	if !store.orderedKeys {
+		return out
+	}
+	if store.keyOrder == nil {
+		store.keyOrder = &maputil.KeyOrder{}
+	}
+	store.keyOrder.Reconcile(out)
+	return maputil.OrderedMap{Map: out, Order: store.keyOrder}
+}
@@ -0,0 +1,113 @@
+package mapstore
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// LayeredStore composes several MapFileStores into one view, e.g. "system defaults < user config < overrides".
+// Reads go through the layers from highest to lowest priority, nested maps are deep-merged so a higher layer
+// only needs to carry the keys it changes. Writes always go to the top layer.
+type LayeredStore struct {
+	// Ordered from lowest to highest priority.
+	layers []*MapFileStore
+}
+
+// NewLayeredStore creates a LayeredStore. Layers are given from lowest to highest priority,
+// the last one is the writable top layer.
+func NewLayeredStore(layers ...*MapFileStore) (*LayeredStore, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("layered store needs at least one layer")
+	}
+	if slices.Contains(layers, nil) {
+		return nil, errors.New("invalid nil layer")
+	}
+	return &LayeredStore{layers: slices.Clone(layers)}, nil
+}
+
+// Top returns the writable top layer.
+func (ls *LayeredStore) Top() *MapFileStore {
+	return ls.layers[len(ls.layers)-1]
+}
+
+// Layers returns the layers from lowest to highest priority.
+func (ls *LayeredStore) Layers() []*MapFileStore {
+	return slices.Clone(ls.layers)
+}
+
+// GetKey returns the value from the highest layer that has keys.
+// If that value is a map, maps found at keys in lower layers are merged beneath it.
+func (ls *LayeredStore) GetKey(keys []string) (any, error) {
+	var (
+		found   []any
+		lastErr error
+	)
+	for i := len(ls.layers) - 1; i >= 0; i-- {
+		val, err := ls.layers[i].GetKey(keys)
+		if err != nil {
+			var kne *maputil.KeyNotFoundError
+			if !errors.As(err, &kne) {
+				return nil, err
+			}
+			if lastErr == nil {
+				lastErr = err
+			}
+			continue
+		}
+		found = append(found, val)
+		if _, isMap := val.(map[string]any); !isMap {
+			// A scalar hides everything beneath it.
+			break
+		}
+	}
+	if len(found) == 0 {
+		return nil, lastErr
+	}
+	return mergeLayerValues(found), nil
+}
+
+// GetAll returns the deep-merged data of all layers.
+func (ls *LayeredStore) GetAll(forceFetch bool) (map[string]any, error) {
+	merged := make(map[string]any)
+	for _, layer := range ls.layers {
+		data, err := layer.GetAll(forceFetch)
+		if err != nil {
+			return nil, err
+		}
+		deepMergeMaps(merged, data)
+	}
+	return merged, nil
+}
+
+// SetKey sets the value in the top layer.
+func (ls *LayeredStore) SetKey(keys []string, value any) error {
+	return ls.Top().SetKey(keys, value)
+}
+
+// DeleteKey deletes the value from the top layer. Values in lower layers become visible again.
+func (ls *LayeredStore) DeleteKey(keys []string) error {
+	return ls.Top().DeleteKey(keys)
+}
+
+// SetAll overwrites the data of the top layer.
+func (ls *LayeredStore) SetAll(data map[string]any) error {
+	return ls.Top().SetAll(data)
+}
+
+// mergeLayerValues merges values ordered from highest to lowest priority.
+// A scalar can only be the last entry, it is hidden when a higher layer holds a map.
+func mergeLayerValues(values []any) any {
+	top := values[0]
+	if _, isMap := top.(map[string]any); !isMap {
+		return top
+	}
+	merged := make(map[string]any)
+	for i := len(values) - 1; i >= 0; i-- {
+		if m, ok := values[i].(map[string]any); ok {
+			deepMergeMaps(merged, m)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,126 @@
+package mapstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DecodeErrorPolicy decides what ListDecoded does when a file cannot be read or decoded.
+type DecodeErrorPolicy int
+
+const (
+	// DecodeFailFast returns the error of the first failing file, in listing order, and no entries.
+	// It is the default.
+	DecodeFailFast DecodeErrorPolicy = iota
+	// DecodeCollectErrors keeps failing files in the result with Err set and a zero Value.
+	DecodeCollectErrors
+)
+
+// ListDecodedConfig holds the options of ListDecoded.
+type ListDecodedConfig struct {
+	ListingConfig
+	// Concurrency is the number of files decoded in parallel, 4 if zero.
+	Concurrency int
+	ErrorPolicy DecodeErrorPolicy
+}
+
+// Decoded is a listed file together with its data decoded into T.
+type Decoded[T any] struct {
+	Entry FileEntry
+	Value T
+	// Err is only set with DecodeCollectErrors.
+	Err error
+}
+
+// ListDecoded lists one page of files like ListFiles and decodes the data of each into T, in parallel.
+// The data is read like GetFileData, so values are decoded and references resolved as configured, and then
+// converted to T by a round trip through encoding/json, honoring json tags. Keys without a field in T are
+// ignored. Files that were not open are closed again once decoded.
+// Go methods cannot have type parameters, hence the function form.
+func ListDecoded[T any](
+	mds *MapDirectoryStore,
+	cfg ListDecodedConfig,
+	pageToken string,
+) (items []Decoded[T], nextPageToken string, err error) {
+	return ListDecodedContext[T](context.Background(), mds, cfg, pageToken)
+}
+
+// ListDecodedContext is ListDecoded, passing ctx to the access checker for the listing and every read.
+func ListDecodedContext[T any](
+	ctx context.Context,
+	mds *MapDirectoryStore,
+	cfg ListDecodedConfig,
+	pageToken string,
+) (items []Decoded[T], nextPageToken string, err error) {
+	entries, nextPageToken, err := mds.ListFilesContext(ctx, cfg.ListingConfig, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	workers := cfg.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	workers = min(workers, len(entries))
+
+	items = make([]Decoded[T], len(entries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				items[i].Entry = entries[i]
+				items[i].Err = decodeEntry(ctx, mds, entries[i], &items[i].Value)
+			}
+		})
+	}
+	for i := range entries {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if cfg.ErrorPolicy == DecodeFailFast {
+		for _, item := range items {
+			if item.Err != nil {
+				return nil, "", item.Err
+			}
+		}
+	}
+	return items, nextPageToken, nil
+}
+
+// decodeEntry reads the data of entry and decodes it into out through encoding/json.
+func decodeEntry(ctx context.Context, mds *MapDirectoryStore, entry FileEntry, out any) (err error) {
+	filePath, err := mds.entryFilePath(entry)
+	if err != nil {
+		return err
+	}
+	store, release, err := mds.borrowPath(ctx, filePath, false, map[string]any{})
+	if err != nil {
+		return fmt.Errorf("failed to open file store for %s: %w", entry.BaseRelativePath, err)
+	}
+	defer func() {
+		if closeErr := release(); err == nil {
+			err = closeErr
+		}
+	}()
+	data, err := store.GetAllContext(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", entry.BaseRelativePath, err)
+	}
+	if mds.resolveRefs {
+		if data, err = mds.resolveRefsIn(ctx, store.filename, data); err != nil {
+			return fmt.Errorf("failed to resolve references in %s: %w", entry.BaseRelativePath, err)
+		}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", entry.BaseRelativePath, err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", entry.BaseRelativePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mapstore
+
+import "slices"
+
+// ListenerFilter selects the events a listener receives, see WithFilteredListener. The zero value selects every
+// event.
+type ListenerFilter struct {
+	// Ops are the operations delivered, all if empty. An OpTransaction event is matched by its own Op, not by the
+	// ops of its changes.
+	Ops []Operation
+	// Prefix is the key path whose changes are delivered, every path if empty. A change matches if its keys are
+	// at or below Prefix, or above it, such as a SetKey of a parent that replaces the value at Prefix; see
+	// KeysOverlap. Events without keys, such as OpSetFile, OpExternalChange or OpExternalReload, replace
+	// everything and always match. A transaction matches if one of its changes does, and is delivered with all of
+	// them.
+	Prefix []string
+}
+
+// matches reports whether e is selected by the filter.
+func (f *ListenerFilter) matches(e FileEvent) bool {
+	if len(f.Ops) > 0 && !slices.Contains(f.Ops, e.Op) {
+		return false
+	}
+	if len(f.Prefix) == 0 {
+		return true
+	}
+	if e.Op == OpTransaction {
+		return slices.ContainsFunc(e.Changes, func(c KeyChange) bool { return KeysOverlap(c.Keys, f.Prefix) })
+	}
+	return KeysOverlap(e.Keys, f.Prefix)
+}
+
+// clone returns a copy of the filter that does not share its slices with f.
+func (f ListenerFilter) clone() *ListenerFilter {
+	return &ListenerFilter{Ops: slices.Clone(f.Ops), Prefix: slices.Clone(f.Prefix)}
+}
+
+// WithFilteredListener registers a listener during store creation that only receives the events selected by
+// filter, e.g. ListenerFilter{Prefix: []string{"settings"}} for changes of the settings. Events that do not match
+// are skipped before dispatch, so they neither wait for the listener nor fill its async queue.
+func WithFilteredListener(filter ListenerFilter, fn FileListener) FileOption {
+	return func(s *MapFileStore) { s.listeners.addFiltered(filter.clone(), fn) }
+}
+
+// WithDirFilteredListener registers a filtered listener, see WithFilteredListener, for every file of the
+// directory store.
+func WithDirFilteredListener(filter ListenerFilter, fn FileListener) DirOption {
+	return func(mds *MapDirectoryStore) { mds.listeners.addFiltered(filter.clone(), fn) }
+}
+
+// AddFilteredListener registers a listener on a live store that only receives the events selected by filter,
+// and returns its ID for RemoveListener.
+func (store *MapFileStore) AddFilteredListener(filter ListenerFilter, fn FileListener) ListenerID {
+	return store.listeners.addFiltered(filter.clone(), fn).id
+}
+
+// AddFilteredListener registers a listener for the files of the directory store, the open ones and those opened
+// later, that only receives the events selected by filter, and returns its ID for RemoveListener.
+func (mds *MapDirectoryStore) AddFilteredListener(filter ListenerFilter, fn FileListener) ListenerID {
+	return mds.addListenerEntry(mds.listeners.addFiltered(filter.clone(), fn))
+}
@@ -0,0 +1,331 @@
+package mapstore
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// asyncListenerQueueSize is the number of events buffered for a listener switched to async dispatch, and the
+// default of WithAsyncListeners.
+const asyncListenerQueueSize = 256
+
+// ListenerID identifies a listener added with AddListener, for RemoveListener.
+type ListenerID uint64
+
+// nextListenerID is shared by all stores, so that a directory store can use the same ID in every file store.
+var nextListenerID atomic.Uint64
+
+type listenerEntry struct {
+	id ListenerID
+	fn FileListener
+	// Filter selects the events delivered, nil for all.
+	filter *ListenerFilter
+	// State is per store, a directory store listener gets a fresh state in every file store.
+	state *listenerState
+}
+
+// listenerState tracks the timeouts of one listener and, once it is switched to async dispatch, its queue.
+type listenerState struct {
+	// Overruns counts consecutive calls that exceeded the listener timeout.
+	overruns atomic.Int32
+	mu       sync.Mutex
+	// Queue is non nil once the listener is dispatched asynchronously, closed by stop.
+	queue   chan FileEvent
+	stopped bool
+}
+
+// ListenerStats reports how listeners of a store behaved under WithListenerTimeout.
+type ListenerStats struct {
+	// Slow counts listener calls that exceeded the timeout.
+	Slow uint64
+	// Async counts listeners switched to async dispatch after repeated timeouts.
+	Async uint64
+	// Dropped counts events not delivered because the queue of an async listener was full.
+	Dropped uint64
+}
+
+// listenerStats holds the counters behind ListenerStats.
+type listenerStats struct {
+	slow, async, dropped atomic.Uint64
+}
+
+// WithListenerTimeout bounds how long an event waits for each listener. A listener still running after timeout
+// is left to finish in the background, counted in ListenerStats.Slow and logged, and the next listener runs.
+// After asyncAfter consecutive timeouts, the listener is switched to async dispatch: events are queued for it and
+// delivered in order by its own goroutine, so it no longer delays writes; events are dropped while its queue is
+// full. An asyncAfter of 0 never switches. The default, a zero timeout, calls listeners synchronously without
+// bound. For a directory store, pass it through WithDirFileOptions.
+func WithListenerTimeout(timeout time.Duration, asyncAfter int) FileOption {
+	return func(store *MapFileStore) {
+		store.listenerTimeout = timeout
+		store.listenerAsyncAfter = asyncAfter
+	}
+}
+
+// WithAsyncListeners dispatches events to every listener asynchronously from the start: each listener has its
+// own queue of queueSize events, asyncListenerQueueSize if queueSize is below 1, and its own goroutine that
+// delivers them in order, so mutations never wait for listeners. Events are dropped, and counted in
+// ListenerStats.Dropped, while the queue of a listener is full. Events queued when a listener is removed or the
+// store is closed are still delivered. For a directory store, pass it through WithDirFileOptions.
+func WithAsyncListeners(queueSize int) FileOption {
+	return func(store *MapFileStore) {
+		if queueSize < 1 {
+			queueSize = asyncListenerQueueSize
+		}
+		store.listenerQueueSize = queueSize
+	}
+}
+
+// ListenerStats returns the counters of slow, async and dropped listener deliveries.
+func (store *MapFileStore) ListenerStats() ListenerStats {
+	return ListenerStats{
+		Slow:    store.listenerStats.slow.Load(),
+		Async:   store.listenerStats.async.Load(),
+		Dropped: store.listenerStats.dropped.Load(),
+	}
+}
+
+// listenerSet is a copy-on-write list of listeners. Events load the current list without locking, changes
+// replace it under mu, so listeners can be added and removed while events are delivered.
+type listenerSet struct {
+	mu   sync.Mutex
+	list atomic.Pointer[[]listenerEntry]
+}
+
+// load returns the current listeners. The result must not be modified.
+func (s *listenerSet) load() []listenerEntry {
+	if l := s.list.Load(); l != nil {
+		return *l
+	}
+	return nil
+}
+
+// add registers fns under new IDs and returns them. Nil listeners are ignored and get the zero ID.
+func (s *listenerSet) add(fns ...FileListener) []ListenerID {
+	ids := make([]ListenerID, len(fns))
+	entries := make([]listenerEntry, 0, len(fns))
+	for i, fn := range fns {
+		if fn == nil {
+			continue
+		}
+		ids[i] = ListenerID(nextListenerID.Add(1))
+		entries = append(entries, listenerEntry{id: ids[i], fn: fn})
+	}
+	s.addEntries(entries...)
+	return ids
+}
+
+// addFiltered registers fn with filter under a new ID and returns its entry. A nil fn is ignored and gets the
+// zero ID.
+func (s *listenerSet) addFiltered(filter *ListenerFilter, fn FileListener) listenerEntry {
+	if fn == nil {
+		return listenerEntry{}
+	}
+	e := listenerEntry{id: ListenerID(nextListenerID.Add(1)), fn: fn, filter: filter}
+	s.addEntries(e)
+	return e
+}
+
+// addEntries registers entries with existing IDs, skipping IDs that are already registered.
+func (s *listenerSet) addEntries(entries ...listenerEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur := s.load()
+	next := slices.Clone(cur)
+	for _, e := range entries {
+		if !slices.ContainsFunc(cur, func(c listenerEntry) bool { return c.id == e.id }) {
+			e.state = &listenerState{}
+			next = append(next, e)
+		}
+	}
+	s.list.Store(&next)
+}
+
+// remove unregisters the listener with the given ID and reports whether it was registered.
+func (s *listenerSet) remove(id ListenerID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur := s.load()
+	i := slices.IndexFunc(cur, func(c listenerEntry) bool { return c.id == id })
+	if i < 0 {
+		return false
+	}
+	cur[i].state.stop()
+	next := slices.Delete(slices.Clone(cur), i, i+1)
+	s.list.Store(&next)
+	return true
+}
+
+// stopAll ends the async dispatch of all listeners. Queued events are still delivered.
+func (s *listenerSet) stopAll() {
+	for _, e := range s.load() {
+		e.state.stop()
+	}
+}
+
+// AddListener registers a listener on a live store and returns its ID for RemoveListener.
+// It is safe to call while other goroutines mutate the store; events already being delivered may miss it.
+func (store *MapFileStore) AddListener(l FileListener) ListenerID {
+	return store.listeners.add(l)[0]
+}
+
+// RemoveListener unregisters a listener added with AddListener and reports whether it was registered.
+// Events already being delivered may still reach it.
+func (store *MapFileStore) RemoveListener(id ListenerID) bool {
+	return store.listeners.remove(id)
+}
+
+// AddListener registers a listener for the files of the directory store, the open ones and those opened later,
+// and returns its ID for RemoveListener.
+func (mds *MapDirectoryStore) AddListener(l FileListener) ListenerID {
+	return mds.addListenerEntry(mds.listeners.addFiltered(nil, l))
+}
+
+// addListenerEntry registers an entry of the directory store in all its open files and returns its ID.
+func (mds *MapDirectoryStore) addListenerEntry(entry listenerEntry) ListenerID {
+	if entry.fn == nil {
+		return entry.id
+	}
+	mds.openMu.Lock()
+	defer mds.openMu.Unlock()
+	for _, store := range mds.openStores {
+		store.listeners.addEntries(entry)
+	}
+	return entry.id
+}
+
+// RemoveListener unregisters a listener added with AddListener from the directory store and all its open files,
+// and reports whether it was registered.
+func (mds *MapDirectoryStore) RemoveListener(id ListenerID) bool {
+	if !mds.listeners.remove(id) {
+		return false
+	}
+	mds.openMu.Lock()
+	defer mds.openMu.Unlock()
+	for _, store := range mds.openStores {
+		store.listeners.remove(id)
+	}
+	return true
+}
+
+// withListenerEntries registers the listeners of a directory store in a file store, keeping their IDs.
+func withListenerEntries(entries []listenerEntry) FileOption {
+	return func(s *MapFileStore) { s.listeners.addEntries(entries...) }
+}
+
+// deliver calls the listener l with e, bounded by the listener timeout if one is set.
+func (s *MapFileStore) deliver(l listenerEntry, e FileEvent) {
+	if s.listenerQueueSize > 0 {
+		l.state.startAsync(l.fn, s.listenerQueueSize)
+	}
+	if async, dropped := l.state.enqueue(e); async {
+		if dropped {
+			s.listenerStats.dropped.Add(1)
+			slog.Warn("filestore async listener queue full, event dropped", "file", e.File, "op", e.Op, "seq", e.Seq)
+		}
+		return
+	}
+	if s.listenerTimeout <= 0 {
+		callListener(l.fn, e)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callListener(l.fn, e)
+	}()
+	timer := time.NewTimer(s.listenerTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		l.state.overruns.Store(0)
+		return
+	case <-timer.C:
+	}
+
+	s.listenerStats.slow.Add(1)
+	overruns := l.state.overruns.Add(1)
+	slog.Warn("filestore listener exceeded its timeout",
+		"file", e.File, "op", e.Op, "timeout", s.listenerTimeout, "consecutive", overruns)
+	if s.listenerAsyncAfter > 0 && int(overruns) >= s.listenerAsyncAfter &&
+		l.state.startAsync(l.fn, asyncListenerQueueSize) {
+		s.listenerStats.async.Add(1)
+		slog.Warn("filestore listener switched to async dispatch", "file", e.File, "timeouts", overruns)
+	}
+}
+
+// enqueue hands e to the async dispatch of the listener. It reports whether the listener is dispatched
+// asynchronously, and whether e was dropped because the queue is full or dispatch was stopped.
+func (st *listenerState) enqueue(e FileEvent) (async, dropped bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.queue == nil {
+		return false, false
+	}
+	if st.stopped {
+		return true, true
+	}
+	select {
+	case st.queue <- e:
+		return true, false
+	default:
+		return true, true
+	}
+}
+
+// startAsync switches the listener to async dispatch with a queue of size events and reports whether it was not
+// switched before.
+func (st *listenerState) startAsync(fn FileListener, size int) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.queue != nil || st.stopped {
+		return false
+	}
+	queue := make(chan FileEvent, size)
+	st.queue = queue
+	go func() {
+		for e := range queue {
+			callListener(fn, e)
+		}
+	}()
+	return true
+}
+
+// stop ends async dispatch after the queued events, and keeps later events from being queued.
+func (st *listenerState) stop() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.stopped {
+		return
+	}
+	st.stopped = true
+	if st.queue != nil {
+		close(st.queue)
+	}
+}
+
+// callListener calls fn, recovering from panics so that a faulty observer cannot crash the store.
+func callListener(fn FileListener, e FileEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error(
+				"filestore listener panic",
+				"err",
+				r,
+				"event",
+				e,
+				"stack",
+				string(debug.Stack()),
+			)
+		}
+	}()
+	fn(e)
+}
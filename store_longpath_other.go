@@ -0,0 +1,8 @@
+//go:build !windows
+
+package mapstore
+
+// longPathName returns p unchanged, only Windows limits path lengths below what the filesystem supports.
+func longPathName(p string) string {
+	return p
+}
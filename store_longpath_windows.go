@@ -0,0 +1,20 @@
+package mapstore
+
+import "path/filepath"
+
+// maxShortPath is the longest directory path Windows accepts without the \\?\ prefix (MAX_PATH less room for
+// an 8.3 file name).
+const maxShortPath = 248
+
+// longPathName makes long relative paths absolute. The os package transparently adds the \\?\ prefix that lifts
+// the MAX_PATH limit, but only to absolute paths, so deep trees opened through a relative path would fail.
+func longPathName(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil || len(abs) < maxShortPath {
+		return p
+	}
+	return abs
+}
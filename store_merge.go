@@ -0,0 +1,165 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// SliceMergeStrategy decides how MergeAll and MergeKey combine a slice with the slice stored at the same path.
+type SliceMergeStrategy int
+
+const (
+	// SliceMergeReplace replaces the stored slice.
+	SliceMergeReplace SliceMergeStrategy = iota
+	// SliceMergeAppend appends the elements to the stored slice.
+	SliceMergeAppend
+)
+
+// WithSliceMergeStrategy sets how MergeAll and MergeKey merge slices. The default is SliceMergeReplace.
+func WithSliceMergeStrategy(s SliceMergeStrategy) FileOption {
+	return func(store *MapFileStore) {
+		store.sliceMerge = s
+	}
+}
+
+// MergeAll deep-merges data into the stored data: nested maps are merged key by key, slices follow the
+// WithSliceMergeStrategy setting, every other value replaces the stored one. Keys not in data are kept, unlike
+// SetAll. Expiries of replaced and appended values are cleared.
+//
+// Listeners see one OpSetFile event.
+func (store *MapFileStore) MergeAll(data map[string]any) error {
+	return store.MergeAllContext(context.Background(), data)
+}
+
+// MergeAllContext is MergeAll, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) MergeAllContext(ctx context.Context, data map[string]any) error {
+	if data == nil {
+		return errors.New("MergeAll: nil data")
+	}
+	if err := store.checkAccess(ctx, OpSetFile, nil); err != nil {
+		return err
+	}
+	_, copyAfter, seq, err := store.merge(nil, data)
+	if err != nil {
+		return err
+	}
+	store.fireEvent(attributeEvent(ctx, FileEvent{
+		Op:        OpSetFile,
+		Seq:       seq,
+		File:      store.filename,
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	}))
+	return nil
+}
+
+// MergeKey deep-merges value into the value at keys like MergeAll does for the whole data. If either of them
+// is not a map or slice, value replaces the stored value as with SetKey; missing parents are created.
+//
+// Listeners see one OpSetKey event whose NewValue is the merged value.
+func (store *MapFileStore) MergeKey(keys []string, value any) error {
+	return store.MergeKeyContext(context.Background(), keys, value)
+}
+
+// MergeKeyContext is MergeKey, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) MergeKeyContext(ctx context.Context, keys []string, value any) error {
+	if len(keys) == 0 {
+		return errors.New("cannot merge value at root")
+	}
+	if err := store.checkAccess(ctx, OpSetKey, keys); err != nil {
+		return err
+	}
+	oldVal, copyAfter, seq, err := store.merge(keys, value)
+	if err != nil {
+		return err
+	}
+	newVal, _ := maputil.GetValueAtPath(copyAfter, keys)
+	store.fireEvent(attributeEvent(ctx, FileEvent{
+		Op:        OpSetKey,
+		Seq:       seq,
+		File:      store.filename,
+		Keys:      slices.Clone(keys),
+		OldValue:  oldVal,
+		NewValue:  maputil.DeepCopyValue(newVal),
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	}))
+	return nil
+}
+
+// merge merges value into the value at keys, the whole data for no keys, on a copy of the data. It returns a
+// copy of the value before the merge.
+func (store *MapFileStore) merge(
+	keys []string,
+	value any,
+) (oldVal any, copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, nil, 0, ErrClosed
+	}
+
+	data, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	var changed [][]string
+	if len(keys) == 0 {
+		mergeValue(data, value, store.sliceMerge, nil, &changed)
+	} else {
+		cur, _ := maputil.GetValueAtPath(data, keys)
+		oldVal = maputil.DeepCopyValue(cur)
+		merged := mergeValue(cur, value, store.sliceMerge, keys, &changed)
+		if err := maputil.SetValueAtPath(data, keys, merged); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to merge value at key %v: %w", keys, err)
+		}
+	}
+
+	if err := store.checkImmutableUnlocked(nil, data, false); err != nil {
+		return nil, nil, 0, err
+	}
+	prev, prevDirty, prevExpiry := store.data, store.dirty.Load(), maps.Clone(store.expiry)
+	store.data = data
+	topKeys := make([]string, 0, len(changed))
+	for _, p := range changed {
+		store.setExpiryUnlocked(p, time.Time{})
+		topKeys = append(topKeys, p[0])
+	}
+	if len(topKeys) > 0 {
+		store.touchUnlocked(prev, topKeys...)
+	}
+	if err := store.persistUnlocked(); err != nil {
+		store.data = prev
+		store.dirty.Store(prevDirty)
+		store.expiry = prevExpiry
+		return nil, nil, 0, fmt.Errorf("failed to save data after merge: %w", err)
+	}
+	store.seq++
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	return oldVal, copyAfter, store.seq, nil
+}
+
+// mergeValue merges src into dst, in place for maps, and returns the result. The paths below prefix whose value
+// was replaced or appended to are added to changed.
+func mergeValue(dst, src any, strategy SliceMergeStrategy, prefix []string, changed *[][]string) any {
+	switch s := src.(type) {
+	case map[string]any:
+		if d, ok := dst.(map[string]any); ok {
+			for k, v := range s {
+				d[k] = mergeValue(d[k], v, strategy, append(slices.Clone(prefix), k), changed)
+			}
+			return d
+		}
+	case []any:
+		if d, ok := dst.([]any); ok && strategy == SliceMergeAppend {
+			add, _ := maputil.DeepCopyValue(s).([]any)
+			*changed = append(*changed, prefix)
+			return append(d, add...)
+		}
+	}
+	*changed = append(*changed, prefix)
+	return maputil.DeepCopyValue(src)
+}
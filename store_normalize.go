@@ -0,0 +1,71 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrFilenameCollision is returned when several files in a partition normalize to the same name.
+var ErrFilenameCollision = errors.New("several files normalize to the same name")
+
+// FilenameNormalization selects how file names of FileKeys are normalized.
+// Flags combine, e.g. NormalizeFilenameNFC|NormalizeFilenameLowercase, so that a store behaves the same on
+// case-insensitive (macOS, Windows) and case-sensitive filesystems.
+type FilenameNormalization uint8
+
+const (
+	// NormalizeFilenameNFC applies Unicode NFC, macOS reports decomposed (NFD) file names.
+	NormalizeFilenameNFC FilenameNormalization = 1 << iota
+	// NormalizeFilenameLowercase lowercases file names.
+	NormalizeFilenameLowercase
+)
+
+// WithDirFilenameNormalization normalizes the file name of every FileKey before it is mapped to a path.
+// New files are created under the normalized name. An existing file whose name differs from the normalized one,
+// e.g. written in NFD on macOS, is still found; if several files match, ErrFilenameCollision is returned.
+func WithDirFilenameNormalization(n FilenameNormalization) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.filenameNorm = n
+	}
+}
+
+// normalizeFilename returns name as the store maps it to a path.
+func (mds *MapDirectoryStore) normalizeFilename(name string) string {
+	if mds.filenameNorm&NormalizeFilenameNFC != 0 {
+		name = norm.NFC.String(name)
+	}
+	if mds.filenameNorm&NormalizeFilenameLowercase != 0 {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// resolveNormalizedPath returns the path of the existing file whose normalized name is the base of filePath,
+// or filePath itself if no such file exists.
+func (mds *MapDirectoryStore) resolveNormalizedPath(filePath string) (string, error) {
+	if _, err := os.Lstat(filePath); err == nil || !os.IsNotExist(err) {
+		return filePath, nil
+	}
+	dir, base := filepath.Split(filePath)
+	// A missing or unreadable partition has no files to match, opening filePath reports the error.
+	entries, _ := os.ReadDir(dir)
+	var matches []string
+	for _, e := range entries {
+		if !e.IsDir() && mds.normalizeFilename(e.Name()) == base {
+			matches = append(matches, e.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return filePath, nil
+	case 1:
+		return filepath.Join(dir, matches[0]), nil
+	default:
+		return "", fmt.Errorf("%w: %s in %s", ErrFilenameCollision, strings.Join(matches, ", "), dir)
+	}
+}
@@ -0,0 +1,56 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidPartitionName matches every InvalidPartitionNameError with errors.Is.
+var ErrInvalidPartitionName = errors.New("invalid partition name")
+
+// InvalidPartitionNameError is returned when a partition name given by a caller, e.g. in
+// ListingConfig.FilterPartitions, is not a name the partition provider could have produced.
+type InvalidPartitionNameError struct {
+	Name   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *InvalidPartitionNameError) Error() string {
+	return fmt.Sprintf("invalid partition name %q: %s", e.Name, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidPartitionName.
+func (e *InvalidPartitionNameError) Is(target error) bool {
+	return target == ErrInvalidPartitionName
+}
+
+// PartitionNameValidator is implemented by partition providers that can tell whether a name follows their
+// partition naming. The directory store checks caller supplied partition names against it, after rejecting
+// names that would leave the base directory.
+type PartitionNameValidator interface {
+	// ValidatePartitionName returns a non nil error, typically an InvalidPartitionNameError, if name is not a
+	// partition of the provider.
+	ValidatePartitionName(name string) error
+}
+
+// validatePartitionName rejects names that are not a single directory below the base directory, and names
+// the partition provider does not accept. The empty name stands for the base directory itself.
+func (mds *MapDirectoryStore) validatePartitionName(name string) error {
+	switch {
+	case filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`):
+		return &InvalidPartitionNameError{Name: name, Reason: "absolute path"}
+	case strings.ContainsAny(name, `/\`):
+		return &InvalidPartitionNameError{Name: name, Reason: "contains a path separator"}
+	case name == "." || name == "..":
+		return &InvalidPartitionNameError{Name: name, Reason: "relative path element"}
+	case name != "" && !filepath.IsLocal(name):
+		return &InvalidPartitionNameError{Name: name, Reason: "not a local name"}
+	}
+	if v, ok := mds.partitionProvider.(PartitionNameValidator); ok {
+		return v.ValidatePartitionName(name)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mapstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dirMtimeGranularity is the coarsest directory mtime resolution we expect (FAT has 2 seconds).
+// Stats computed within this window of the last modification may miss a change that keeps the mtime.
+const dirMtimeGranularity = 2 * time.Second
+
+// PartitionStats summarizes the files of one partition.
+type PartitionStats struct {
+	Files      int
+	TotalBytes int64
+	// NewestModTime is the latest modification time of a file, zero for an empty partition.
+	NewestModTime time.Time
+}
+
+type cachedPartitionStats struct {
+	dirModTime time.Time
+	builtAt    time.Time
+	stats      PartitionStats
+}
+
+// PartitionStats returns the file count, total size and newest modification time of the named partition,
+// as returned by ListPartitions. Subdirectories such as attachment sidecars are not counted.
+//
+// Files are written by renaming a temporary file, which updates the partition directory mtime, so results are
+// cached until the directory changes and dashboards can poll without walking every partition.
+// Files modified in place by other programs are only seen once the directory changes.
+func (mds *MapDirectoryStore) PartitionStats(name string) (PartitionStats, error) {
+	if name != "" && !filepath.IsLocal(name) {
+		return PartitionStats{}, fmt.Errorf("invalid partition name: %q", name)
+	}
+	dir := filepath.Join(mds.baseDir, name)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return PartitionStats{}, fmt.Errorf("partition %q: %w", name, err)
+	}
+
+	mds.statsMu.Lock()
+	defer mds.statsMu.Unlock()
+	if c, ok := mds.partitionStats[name]; ok && c.dirModTime.Equal(info.ModTime()) &&
+		c.builtAt.Sub(c.dirModTime) > dirMtimeGranularity {
+		return c.stats, nil
+	}
+
+	builtAt := time.Now()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return PartitionStats{}, fmt.Errorf("partition %q: %w", name, err)
+	}
+	var stats PartitionStats
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		fi, err := e.Info()
+		if os.IsNotExist(err) {
+			// Removed after the directory was read.
+			continue
+		}
+		if err != nil {
+			return PartitionStats{}, fmt.Errorf("cannot stat file %s: %w", e.Name(), err)
+		}
+		stats.Files++
+		stats.TotalBytes += fi.Size()
+		if fi.ModTime().After(stats.NewestModTime) {
+			stats.NewestModTime = fi.ModTime()
+		}
+	}
+
+	if mds.partitionStats == nil {
+		mds.partitionStats = make(map[string]cachedPartitionStats)
+	}
+	mds.partitionStats[name] = cachedPartitionStats{dirModTime: info.ModTime(), builtAt: builtAt, stats: stats}
+	return stats, nil
+}
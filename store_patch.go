@@ -0,0 +1,378 @@
+package mapstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// ErrPatchTestFailed is returned by ApplyPatch when a "test" operation does not match.
+var ErrPatchTestFailed = errors.New("patch test failed")
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyPatch applies a JSON Patch (RFC 6902) document: a JSON array of add, remove, replace, move, copy and
+// test operations with JSON Pointer paths into the data. Array elements are addressed by index, "-" appends.
+//
+// The operations are applied in order to a copy of the data and flushed once. If any of them fails, including
+// a test that does not match (ErrPatchTestFailed), the store is left unchanged. Listeners see one event per
+// operation: OpSetKey for add, replace and copy, OpDeleteKey for remove, both for move, none for test; array
+// indexes appear as keys in decimal. Access checkers are asked for every changed path. The root itself cannot
+// be patched.
+func (store *MapFileStore) ApplyPatch(patch []byte) error {
+	return store.ApplyPatchContext(context.Background(), patch)
+}
+
+// ApplyPatchContext is ApplyPatch, attributing the events to the actor and request ID of ctx.
+func (store *MapFileStore) ApplyPatchContext(ctx context.Context, patch []byte) error {
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if op.op == "move" || op.op == "remove" {
+			keys := op.path
+			if op.op == "move" {
+				keys = op.from
+			}
+			if err := store.checkAccess(ctx, OpDeleteKey, keys); err != nil {
+				return err
+			}
+		}
+		if op.op != "remove" && op.op != "test" {
+			if err := store.checkAccess(ctx, OpSetKey, op.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	events, err := store.applyPatch(ops)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		store.fireEvent(attributeEvent(ctx, e))
+	}
+	return nil
+}
+
+// parsedPatchOp is a patch operation with its pointers split into keys.
+type parsedPatchOp struct {
+	op    string
+	path  []string
+	from  []string
+	value any
+}
+
+func parsePatch(patch []byte) ([]parsedPatchOp, error) {
+	var raw []patchOp
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	ops := make([]parsedPatchOp, 0, len(raw))
+	for i, r := range raw {
+		op := parsedPatchOp{op: r.Op}
+		var err error
+		if op.path, err = parsePointer(r.Path); err != nil {
+			return nil, fmt.Errorf("invalid patch operation %d: %w", i, err)
+		}
+		switch r.Op {
+		case "add", "replace", "test":
+			if r.Value == nil {
+				return nil, fmt.Errorf("invalid patch operation %d: %s without value", i, r.Op)
+			}
+			if err := json.Unmarshal(r.Value, &op.value); err != nil {
+				return nil, fmt.Errorf("invalid patch operation %d: %w", i, err)
+			}
+		case "move", "copy":
+			if op.from, err = parsePointer(r.From); err != nil {
+				return nil, fmt.Errorf("invalid patch operation %d: %w", i, err)
+			}
+			if len(op.from) == 0 {
+				return nil, fmt.Errorf("invalid patch operation %d: cannot %s the root", i, r.Op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("invalid patch operation %d: unknown op %q", i, r.Op)
+		}
+		if len(op.path) == 0 && r.Op != "test" {
+			return nil, fmt.Errorf("invalid patch operation %d: cannot %s the root", i, r.Op)
+		}
+		if r.Op == "move" && len(op.path) > len(op.from) && slices.Equal(op.path[:len(op.from)], op.from) {
+			return nil, fmt.Errorf("invalid patch operation %d: cannot move %q into itself", i, r.From)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", p)
+	}
+	keys := strings.Split(p[1:], "/")
+	for i, k := range keys {
+		keys[i] = strings.ReplaceAll(strings.ReplaceAll(k, "~1", "/"), "~0", "~")
+	}
+	return keys, nil
+}
+
+// applyPatch applies ops to a copy of the data and flushes once. It returns the events to emit.
+func (store *MapFileStore) applyPatch(ops []parsedPatchOp) ([]FileEvent, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, ErrClosed
+	}
+
+	data, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	var (
+		events  []FileEvent
+		changed []string
+	)
+	addEvent := func(op Operation, keys []string, oldValue, newValue any) {
+		events = append(events, FileEvent{
+			Op:       op,
+			File:     store.filename,
+			Keys:     slices.Clone(keys),
+			OldValue: maputil.DeepCopyValue(oldValue),
+			NewValue: maputil.DeepCopyValue(newValue),
+		})
+		changed = append(changed, keys[0])
+	}
+	for i, op := range ops {
+		var err error
+		switch op.op {
+		case "test":
+			var cur any
+			cur, err = pointerGet(data, op.path)
+			if err == nil && !Equal(cur, op.value, EqualOptions{NumericCoercion: true}) {
+				err = fmt.Errorf("%w: %q", ErrPatchTestFailed, pointerString(op.path))
+			}
+		case "remove":
+			var old any
+			if old, err = pointerRemove(data, op.path); err == nil {
+				addEvent(OpDeleteKey, op.path, old, nil)
+			}
+		case "add", "replace":
+			err = patchSet(data, op.path, op.value, op.op == "replace", addEvent)
+		case "copy":
+			var v any
+			if v, err = pointerGet(data, op.from); err == nil {
+				err = patchSet(data, op.path, maputil.DeepCopyValue(v), false, addEvent)
+			}
+		case "move":
+			var v any
+			if v, err = pointerRemove(data, op.from); err == nil {
+				addEvent(OpDeleteKey, op.from, v, nil)
+				err = patchSet(data, op.path, v, false, addEvent)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d (%s): %w", i, op.op, err)
+		}
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	if err := store.checkImmutableUnlocked(nil, data, false); err != nil {
+		return nil, err
+	}
+	prev, prevDirty, prevExpiry := store.data, store.dirty.Load(), maps.Clone(store.expiry)
+	store.data = data
+	for _, e := range events {
+		store.setExpiryUnlocked(e.Keys, time.Time{})
+	}
+	store.touchUnlocked(prev, changed...)
+	if err := store.persistUnlocked(); err != nil {
+		store.data = prev
+		store.dirty.Store(prevDirty)
+		store.expiry = prevExpiry
+		return nil, fmt.Errorf("failed to save data after ApplyPatch: %w", err)
+	}
+	now := time.Now()
+	copyAfter, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	for i := range events {
+		store.seq++
+		events[i].Seq = store.seq
+		events[i].Data = copyAfter
+		events[i].Timestamp = now
+	}
+	return events, nil
+}
+
+// patchSet adds or replaces the value at keys and reports the change. An appending "-" is reported as the
+// index it resolved to.
+func patchSet(
+	data map[string]any,
+	keys []string,
+	v any,
+	replace bool,
+	report func(op Operation, keys []string, oldValue, newValue any),
+) error {
+	if !replace && keys[len(keys)-1] == "-" {
+		if parent, err := pointerGet(data, keys[:len(keys)-1]); err == nil {
+			if arr, ok := parent.([]any); ok {
+				keys = append(slices.Clone(keys[:len(keys)-1]), strconv.Itoa(len(arr)))
+				if _, _, err := pointerAdd(data, keys[:len(keys)-1], keys[len(keys)-1], v, false); err != nil {
+					return err
+				}
+				report(OpSetKey, keys, nil, v)
+				return nil
+			}
+		}
+	}
+	_, old, err := pointerAdd(data, keys[:len(keys)-1], keys[len(keys)-1], v, replace)
+	if err != nil {
+		return err
+	}
+	report(OpSetKey, keys, old, v)
+	return nil
+}
+
+// pointerGet returns the value at keys.
+func pointerGet(node any, keys []string) (any, error) {
+	for i, k := range keys {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[k]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", pointerString(keys[:i+1]))
+			}
+			node = v
+		case []any:
+			idx, err := arrayIndex(k, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("path %q not found", pointerString(keys[:i+1]))
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd sets last in the container at parent to v. Inserting into an array shifts the elements after it,
+// replacing requires the member to exist. It returns the container, which is a new slice for arrays, and the
+// replaced value.
+func pointerAdd(node any, parent []string, last string, v any, replace bool) (container, old any, err error) {
+	if len(parent) > 0 {
+		child, err := pointerGet(node, parent[:1])
+		if err != nil {
+			return nil, nil, err
+		}
+		newChild, old, err := pointerAdd(child, parent[1:], last, v, replace)
+		if err != nil {
+			return nil, nil, err
+		}
+		return setChild(node, parent[0], newChild), old, nil
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		old, ok := n[last]
+		if replace && !ok {
+			return nil, nil, fmt.Errorf("member %q not found", last)
+		}
+		n[last] = v
+		return n, old, nil
+	case []any:
+		if replace {
+			idx, err := arrayIndex(last, len(n)-1)
+			if err != nil {
+				return nil, nil, err
+			}
+			old := n[idx]
+			n[idx] = v
+			return n, old, nil
+		}
+		idx, err := arrayIndex(last, len(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		return slices.Insert(n, idx, v), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot set %q in a %T", last, node)
+	}
+}
+
+// pointerRemove removes the value at keys and returns it.
+func pointerRemove(data map[string]any, keys []string) (any, error) {
+	old, err := pointerGet(data, keys)
+	if err != nil {
+		return nil, err
+	}
+	parentKeys, last := keys[:len(keys)-1], keys[len(keys)-1]
+	parent, err := pointerGet(data, parentKeys)
+	if err != nil {
+		return nil, err
+	}
+	switch n := parent.(type) {
+	case map[string]any:
+		delete(n, last)
+	case []any:
+		idx, _ := arrayIndex(last, len(n)-1)
+		shrunk := slices.Delete(n, idx, idx+1)
+		// The root is a map, so an array always has a parent.
+		grand, err := pointerGet(data, parentKeys[:len(parentKeys)-1])
+		if err != nil {
+			return nil, err
+		}
+		setChild(grand, parentKeys[len(parentKeys)-1], shrunk)
+	}
+	return old, nil
+}
+
+// setChild stores child under key in the map or array node and returns node.
+func setChild(node any, key string, child any) any {
+	switch n := node.(type) {
+	case map[string]any:
+		n[key] = child
+	case []any:
+		if idx, err := arrayIndex(key, len(n)-1); err == nil {
+			n[idx] = child
+		}
+	}
+	return node
+}
+
+// arrayIndex parses an array index token, which must be at most maxIdx.
+func arrayIndex(k string, maxIdx int) (int, error) {
+	idx, err := strconv.Atoi(k)
+	if err != nil || idx < 0 || (len(k) > 1 && k[0] == '0') || k[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", k)
+	}
+	if idx > maxIdx {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func pointerString(keys []string) string {
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
@@ -0,0 +1,15 @@
+package mapstore
+
+import "github.com/ppipada/mapstore-go/internal/maputil"
+
+// Path is a key path into the store data. It is a []string, so it can be passed to every method taking keys,
+// and has a dotted text form in which dots and backslashes inside keys are escaped with a backslash:
+// ParsePath(`a.b\.c.d`) is the keys {"a", "b.c", "d"}. Error messages, change listings and ${key:...}
+// templates use this form.
+type Path = maputil.Path
+
+// ParsePath parses the dotted form of a path, see Path. The empty string is the empty path; empty keys and
+// invalid escapes are errors.
+func ParsePath(s string) (Path, error) {
+	return maputil.ParsePath(s)
+}
@@ -0,0 +1,58 @@
+package mapstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// HasPending reports whether the store has changes that are not written to the file yet. With auto flush off,
+// see WithFileAutoFlush, mutations apply in memory and emit their events right away, and stay pending until
+// Flush writes them. An editable settings UI can show them at once, flush in the background, and call
+// DiscardPending if the flush fails.
+func (store *MapFileStore) HasPending() bool {
+	return store.dirty.Load()
+}
+
+// DiscardPending drops the pending changes and reloads the file, e.g. after Flush failed with ErrFileConflict
+// because another process wrote the file. It emits an OpDiscardPending event with the reloaded data, so views
+// of the data can roll back, and does nothing if no changes are pending. Dropped changes are also removed from
+// the journal, see WithJournal. DiscardPending replaces the data, so it is access checked as OpSetFile.
+func (store *MapFileStore) DiscardPending() error {
+	return store.DiscardPendingContext(context.Background())
+}
+
+// DiscardPendingContext is DiscardPending, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) DiscardPendingContext(ctx context.Context) error {
+	if err := store.checkAccess(ctx, OpSetFile, nil); err != nil {
+		return err
+	}
+	store.mu.Lock()
+	if store.closed {
+		store.mu.Unlock()
+		return ErrClosed
+	}
+	if !store.dirty.Load() {
+		store.mu.Unlock()
+		return nil
+	}
+	if err := store.loadUnlocked(); err != nil {
+		store.mu.Unlock()
+		return err
+	}
+	store.segDirty, store.segAll = nil, false
+	copyAfter, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	store.seq++
+	seq := store.seq
+	store.mu.Unlock()
+
+	store.fireEvent(attributeEvent(ctx, FileEvent{
+		Op:        OpDiscardPending,
+		Seq:       seq,
+		File:      store.filename,
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	}))
+	return nil
+}
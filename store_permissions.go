@@ -0,0 +1,26 @@
+package mapstore
+
+import "os"
+
+const (
+	// defaultFileMode and defaultDirMode are the modes of created files and directories, before the umask.
+	defaultFileMode os.FileMode = 0o666
+	defaultDirMode  os.FileMode = 0o770
+)
+
+// WithFileMode sets the permissions of the file when the store creates it, 0o666 before the umask by default.
+// Use 0o600 for files holding secrets, so they are never readable by others, not even briefly. Segment files
+// are created with the same mode. An existing file keeps its permissions.
+func WithFileMode(mode os.FileMode) FileOption {
+	return func(store *MapFileStore) {
+		store.fileMode = mode.Perm()
+	}
+}
+
+// WithDirMode sets the permissions of the directories the store creates for the file and its segments, 0o770
+// before the umask by default. Directories created by a MapDirectoryStore for its partitions are not affected.
+func WithDirMode(mode os.FileMode) FileOption {
+	return func(store *MapFileStore) {
+		store.dirMode = mode.Perm()
+	}
+}
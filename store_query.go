@@ -0,0 +1,83 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// PathMatch is a path found by Query and a copy of its value.
+type PathMatch struct {
+	Keys  []string
+	Value any
+}
+
+// Query returns the paths matching pattern with their values, sorted by path. A pattern segment is a key, "*"
+// for any one key, or "**" for any number of keys, including none. For example {"providers", "*", "apiKey"}
+// finds the apiKey of every provider and {"**", "apiKey"} every apiKey at any depth. Only nested maps are
+// descended into; the root itself never matches.
+//
+// Query reads the whole data, so it is access checked as OpGetFile. Values are returned as GetAll returns them,
+// with overrides and the read processor applied.
+func (store *MapFileStore) Query(pattern []string) ([]PathMatch, error) {
+	return store.QueryContext(context.Background(), pattern)
+}
+
+// QueryContext is Query, passing ctx to the access checker.
+func (store *MapFileStore) QueryContext(ctx context.Context, pattern []string) ([]PathMatch, error) {
+	if len(pattern) == 0 {
+		return nil, errors.New("empty query pattern")
+	}
+	if err := store.checkAccess(ctx, OpGetFile, nil); err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	err := store.read(store.strictReads, func() error {
+		var err error
+		data, err = store.snapshotUnlocked()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []PathMatch
+	seen := map[string]struct{}{}
+	matchPattern(data, pattern, nil, func(keys []string, v any) {
+		p := strings.Join(keys, "\x00")
+		if _, ok := seen[p]; ok || len(keys) == 0 {
+			return
+		}
+		seen[p] = struct{}{}
+		out = append(out, PathMatch{Keys: slices.Clone(keys), Value: maputil.DeepCopyValue(v)})
+	})
+	slices.SortFunc(out, func(a, b PathMatch) int { return slices.Compare(a.Keys, b.Keys) })
+	return out, nil
+}
+
+// matchPattern calls found for every path below node, prefixed by path, that matches pattern.
+func matchPattern(node any, pattern, path []string, found func(keys []string, v any)) {
+	if len(pattern) == 0 {
+		found(path, node)
+		return
+	}
+	m, _ := node.(map[string]any)
+	switch seg := pattern[0]; seg {
+	case "**":
+		matchPattern(node, pattern[1:], path, found)
+		for k, v := range m {
+			matchPattern(v, pattern, append(slices.Clip(path), k), found)
+		}
+	case "*":
+		for k, v := range m {
+			matchPattern(v, pattern[1:], append(slices.Clip(path), k), found)
+		}
+	default:
+		if v, ok := m[seg]; ok {
+			matchPattern(v, pattern[1:], append(slices.Clip(path), seg), found)
+		}
+	}
+}
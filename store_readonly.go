@@ -0,0 +1,54 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReadOnly is matched by the *ReadOnlyError returned by mutations of a read-only store, see WithReadOnly.
+var ErrReadOnly = errors.New("store is read-only")
+
+// ReadOnlyError is returned instead of performing Op on the File of a read-only store.
+type ReadOnlyError struct {
+	Op   Operation
+	File string
+}
+
+// Error implements the error interface.
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("cannot %s %s: store is read-only", e.Op, e.File)
+}
+
+// Is reports whether target is ErrReadOnly.
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// WithReadOnly opens the store read-only: every mutation, Flush and ExpireKeys return a *ReadOnlyError and the
+// store never writes to disk. A missing file is not created, so opening it fails. Data migrations apply in
+// memory only, the journal is neither replayed nor created, and expired keys are not swept. Reads, reloads and
+// WithWatchFile work as usual, so the store follows changes made by the processes that own the file.
+func WithReadOnly(readOnly bool) FileOption {
+	return func(store *MapFileStore) {
+		store.readOnly = readOnly
+	}
+}
+
+// mutates reports whether op changes data.
+func (op Operation) mutates() bool {
+	switch op {
+	case OpSetFile, OpResetFile, OpDeleteFile, OpSetKey, OpDeleteKey, OpTransaction, OpDiscardPending,
+		OpPutAttachment, OpDeleteAttachment:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkWritable returns a *ReadOnlyError for op if the store is read-only.
+func (store *MapFileStore) checkWritable(op Operation) error {
+	if store.readOnly {
+		return &ReadOnlyError{Op: op, File: store.filename}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package mapstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// RedactedValue replaces every redacted value in events and exports.
+const RedactedValue = "[REDACTED]"
+
+// Redactor reports whether the value at path must be masked before it leaves the store.
+// It sees decoded values, so it also protects values that are stored encrypted on disk. Elements of slices are
+// at their decimal index, e.g. {"users", "0", "password"}.
+type Redactor func(path []string) bool
+
+// WithRedactor masks matching values in FileEvent payloads and Export output.
+// The data on disk and the values returned by GetKey/GetAll are not affected.
+func WithRedactor(r Redactor) FileOption {
+	return func(store *MapFileStore) {
+		store.redactor = r
+	}
+}
+
+// RedactPaths returns a Redactor that masks the given paths and everything below them.
+// A "*" segment matches any single key or slice index, e.g. {"users", "*", "password"}.
+func RedactPaths(paths ...[]string) Redactor {
+	patterns := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		patterns = append(patterns, slices.Clone(p))
+	}
+	return func(path []string) bool {
+		for _, p := range patterns {
+			if pathMatches(p, path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Export writes the current data, with redacted values masked, using the store's file encoder.
+// Values are written decoded, i.e. without the key and value encoders applied.
+func (store *MapFileStore) Export(w io.Writer) error {
+	return store.ExportContext(context.Background(), w)
+}
+
+// ExportContext is Export, passing ctx to the access checker.
+func (store *MapFileStore) ExportContext(ctx context.Context, w io.Writer) error {
+	if err := store.checkAccess(ctx, OpGetFile, nil); err != nil {
+		return err
+	}
+	var dataCopy map[string]any
+	if err := store.read(store.strictReads, func() error {
+		dataCopy, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	out := redactValue(dataCopy, []string{}, store.redactor)
+	if err := store.fileEncoderDecoder.Encode(w, out); err != nil {
+		return fmt.Errorf("failed to export file %s: %w", store.filename, err)
+	}
+	return nil
+}
+
+// redactEvent masks the payload of e in place. Event values are already deep copies.
+func (store *MapFileStore) redactEvent(e *FileEvent) {
+	if store.redactor == nil {
+		return
+	}
+	if len(e.Keys) > 0 {
+		e.OldValue = redactAtKeys(e.Keys, e.OldValue, store.redactor)
+		e.NewValue = redactAtKeys(e.Keys, e.NewValue, store.redactor)
+	}
+	for i := range e.Changes {
+		c := &e.Changes[i]
+		c.OldValue = redactAtKeys(c.Keys, c.OldValue, store.redactor)
+		c.NewValue = redactAtKeys(c.Keys, c.NewValue, store.redactor)
+	}
+	if e.Data != nil {
+		e.Data, _ = redactValue(e.Data, []string{}, store.redactor).(map[string]any)
+	}
+}
+
+// redactAtKeys masks v, the value stored at keys, if keys or any of its parents are redacted.
+func redactAtKeys(keys []string, v any, r Redactor) any {
+	if v == nil {
+		return nil
+	}
+	for i := 1; i < len(keys); i++ {
+		if r(keys[:i]) {
+			return RedactedValue
+		}
+	}
+	return redactValue(v, slices.Clone(keys), r)
+}
+
+// redactValue returns v with every redacted sub path masked. Maps and slices are rebuilt, v is not mutated.
+func redactValue(v any, path []string, r Redactor) any {
+	if r == nil {
+		return v
+	}
+	if len(path) > 0 && r(path) {
+		return RedactedValue
+	}
+	switch tv := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(tv))
+		for k, child := range tv {
+			out[k] = redactValue(child, append(slices.Clone(path), k), r)
+		}
+		return out
+	case []any:
+		out := make([]any, len(tv))
+		for i, child := range tv {
+			out[i] = redactValue(child, append(slices.Clone(path), strconv.Itoa(i)), r)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func pathMatches(pattern, path []string) bool {
+	if len(pattern) == 0 || len(pattern) > len(path) {
+		return false
+	}
+	for i, seg := range pattern {
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package mapstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// Refresh stats every cached open store and reloads only those whose file changed on disk,
+// emitting an OpExternalChange event for each. Stores whose file was removed are dropped from the cache
+// and reported with a nil Data. It returns the number of stores that changed.
+func (mds *MapDirectoryStore) Refresh(ctx context.Context) (int, error) {
+	mds.openMu.Lock()
+	stores := make(map[string]*MapFileStore, len(mds.openStores))
+	for p, st := range mds.openStores {
+		stores[p] = st
+	}
+	mds.openMu.Unlock()
+
+	changed := 0
+	for filePath, st := range stores {
+		if err := ctx.Err(); err != nil {
+			return changed, err
+		}
+		reloaded, removed, err := st.reloadIfChanged(OpExternalChange)
+		if err != nil {
+			return changed, fmt.Errorf("failed to refresh %s: %w", filePath, err)
+		}
+		if removed {
+			if err := mds.closePath(filePath); err != nil {
+				return changed, err
+			}
+		}
+		if reloaded || removed {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// reloadIfChanged reloads the file if it changed on disk and emits an op event.
+func (store *MapFileStore) reloadIfChanged(op Operation) (reloaded, removed bool, err error) {
+	store.mu.Lock()
+	if store.closed {
+		store.mu.Unlock()
+		return false, false, ErrClosed
+	}
+	stat, err := store.fs.Stat(store.filename)
+	switch {
+	case os.IsNotExist(err):
+		if store.lastStat == nil {
+			// Deleted through this store, already reported as OpDeleteFile.
+			store.mu.Unlock()
+			return false, false, nil
+		}
+		store.lastStat = nil
+		store.data = make(map[string]any)
+		store.seq++
+		seq := store.seq
+		store.mu.Unlock()
+		store.fireEvent(FileEvent{Op: op, Seq: seq, File: store.filename, Timestamp: time.Now()})
+		return false, true, nil
+	case err != nil:
+		store.mu.Unlock()
+		return false, false, err
+	case isSameFileInfo(stat, store.lastStat):
+		store.mu.Unlock()
+		return false, false, nil
+	}
+
+	if err := store.loadUnlocked(); err != nil {
+		store.mu.Unlock()
+		return false, false, err
+	}
+	copyAfter, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	store.seq++
+	seq := store.seq
+	store.mu.Unlock()
+
+	store.fireEvent(FileEvent{
+		Op:        op,
+		Seq:       seq,
+		File:      store.filename,
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	})
+	return true, false, nil
+}
@@ -0,0 +1,148 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RefKey marks a reference value of the form {"$ref": "other.json#/path/to/key"}.
+const RefKey = "$ref"
+
+// maxRefDepth bounds chains of references that do not form a cycle.
+const maxRefDepth = 32
+
+// ErrRefCycle is returned when resolving references leads back to a reference already being resolved.
+var ErrRefCycle = errors.New("reference cycle detected")
+
+// WithDirRefResolution toggles lazy resolution of {"$ref": "file#/json/pointer"} values in GetFileData.
+//
+// The file part is relative to the base directory (including the partition dir) and may be empty
+// to point into the same file. The fragment is a JSON pointer, empty means the whole file.
+// Resolved values are never written back, the files keep the references.
+func WithDirRefResolution(enabled bool) DirOption {
+	return func(mds *MapDirectoryStore) {
+		mds.resolveRefs = enabled
+	}
+}
+
+// resolveRefsIn returns data with every reference replaced by its target. FilePath is the file data came from.
+func (mds *MapDirectoryStore) resolveRefsIn(
+	ctx context.Context,
+	filePath string,
+	data map[string]any,
+) (map[string]any, error) {
+	out, err := mds.resolveValue(ctx, filePath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := out.(map[string]any)
+	return m, nil
+}
+
+func (mds *MapDirectoryStore) resolveValue(ctx context.Context, filePath string, v any, stack []string) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		if ref, ok := refTarget(val); ok {
+			return mds.resolveRef(ctx, filePath, ref, stack)
+		}
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			resolved, err := mds.resolveValue(ctx, filePath, child, stack)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, child := range val {
+			resolved, err := mds.resolveValue(ctx, filePath, child, stack)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (mds *MapDirectoryStore) resolveRef(ctx context.Context, filePath, ref string, stack []string) (any, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	targetPath := filePath
+	if file != "" {
+		if !filepath.IsLocal(file) {
+			return nil, fmt.Errorf("invalid reference %q: file must be relative to the base directory", ref)
+		}
+		targetPath = filepath.Join(mds.baseDir, file)
+	}
+
+	id := targetPath + "#" + pointer
+	if slices.Contains(stack, id) {
+		return nil, fmt.Errorf("%w: %s", ErrRefCycle, strings.Join(append(stack, id), " -> "))
+	}
+	if len(stack) >= maxRefDepth {
+		return nil, fmt.Errorf("reference %q exceeds max depth %d", ref, maxRefDepth)
+	}
+
+	store, err := mds.openPath(ctx, targetPath, false, map[string]any{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve reference %q: %w", ref, err)
+	}
+	data, err := store.GetAllContext(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve reference %q: %w", ref, err)
+	}
+	target, err := jsonPointerGet(data, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve reference %q: %w", ref, err)
+	}
+	return mds.resolveValue(ctx, targetPath, target, append(slices.Clone(stack), id))
+}
+
+// refTarget reports whether m is a reference, i.e. a map whose only key is RefKey with a string value.
+func refTarget(m map[string]any) (string, bool) {
+	if len(m) != 1 {
+		return "", false
+	}
+	ref, ok := m[RefKey].(string)
+	return ref, ok
+}
+
+// jsonPointerGet returns the value at an RFC 6901 JSON pointer.
+func jsonPointerGet(data any, pointer string) (any, error) {
+	if pointer == "" {
+		return data, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	cur := data
+	for tok := range strings.SplitSeq(pointer[1:], "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", tok)
+			}
+			cur = next
+		case []any:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("invalid array index %q", tok)
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("cannot descend into %T at %q", cur, tok)
+		}
+	}
+	return cur, nil
+}
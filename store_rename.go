@@ -0,0 +1,179 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// ErrRenameCollision is returned by RenamePath with RenameFail when the destination path already exists.
+var ErrRenameCollision = errors.New("rename destination already exists")
+
+// RenamePolicy decides what RenamePath does when the destination path already exists.
+type RenamePolicy int
+
+const (
+	// RenameFail leaves the data unchanged and returns ErrRenameCollision.
+	RenameFail RenamePolicy = iota
+	// RenameSkip leaves the data unchanged and reports nothing renamed.
+	RenameSkip
+	// RenameOverwrite replaces the destination value.
+	RenameOverwrite
+)
+
+// RenamePath moves the value at oldKeys, including everything below it, to newKeys. Missing parents of newKeys
+// are created; the parent of oldKeys is kept, even if it becomes empty. It reports whether a value was moved,
+// a missing oldKeys is not an error, so a rename can be repeated safely.
+//
+// Listeners see an OpDeleteKey event for oldKeys followed by an OpSetKey event for newKeys, and access checkers
+// are asked for both operations.
+func (store *MapFileStore) RenamePath(oldKeys, newKeys []string, policy RenamePolicy) (bool, error) {
+	if len(oldKeys) == 0 || len(newKeys) == 0 {
+		return false, errors.New("cannot rename the root")
+	}
+	if len(newKeys) >= len(oldKeys) && slices.Equal(newKeys[:len(oldKeys)], oldKeys) {
+		return false, fmt.Errorf("cannot rename %v into itself at %v", oldKeys, newKeys)
+	}
+	if err := store.checkAccess(context.Background(), OpDeleteKey, oldKeys); err != nil {
+		return false, err
+	}
+	if err := store.checkAccess(context.Background(), OpSetKey, newKeys); err != nil {
+		return false, err
+	}
+
+	moved, replaced, copyAfter, seq, err := store.renamePath(oldKeys, newKeys, policy)
+	if err != nil || seq == 0 {
+		return false, err
+	}
+	now := time.Now()
+	dataCopy, _ := maputil.DeepCopyValue(copyAfter).(map[string]any)
+	store.fireEvent(FileEvent{
+		Op:        OpDeleteKey,
+		Seq:       seq - 1,
+		File:      store.filename,
+		Keys:      slices.Clone(oldKeys),
+		OldValue:  maputil.DeepCopyValue(moved),
+		Data:      copyAfter,
+		Timestamp: now,
+	})
+	store.fireEvent(FileEvent{
+		Op:        OpSetKey,
+		Seq:       seq,
+		File:      store.filename,
+		Keys:      slices.Clone(newKeys),
+		OldValue:  replaced,
+		NewValue:  maputil.DeepCopyValue(moved),
+		Data:      dataCopy,
+		Timestamp: now,
+	})
+	return true, nil
+}
+
+// renamePath applies a rename on a copy of the data, so that a failure leaves the store unchanged.
+// A zero seq means nothing was renamed.
+func (store *MapFileStore) renamePath(
+	oldKeys, newKeys []string,
+	policy RenamePolicy,
+) (moved, replaced any, copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, nil, nil, 0, ErrClosed
+	}
+
+	moved, err = maputil.GetValueAtPath(store.data, oldKeys)
+	if err != nil {
+		var kne *maputil.KeyNotFoundError
+		if errors.As(err, &kne) {
+			return nil, nil, nil, 0, nil
+		}
+		return nil, nil, nil, 0, err
+	}
+	replaced, getErr := maputil.GetValueAtPath(store.data, newKeys)
+	if getErr == nil {
+		switch policy {
+		case RenameSkip:
+			return nil, nil, nil, 0, nil
+		case RenameOverwrite:
+		default:
+			return nil, nil, nil, 0, fmt.Errorf("%w: %v", ErrRenameCollision, newKeys)
+		}
+	}
+
+	data, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	if err := maputil.DeleteValueAtPath(data, oldKeys); err != nil {
+		return nil, nil, nil, 0, fmt.Errorf("failed to delete key %v: %w", oldKeys, err)
+	}
+	if err := maputil.SetValueAtPath(data, newKeys, moved); err != nil {
+		return nil, nil, nil, 0, fmt.Errorf("failed to set value at key %v: %w", newKeys, err)
+	}
+	if err := store.checkImmutableUnlocked(nil, data, false); err != nil {
+		return nil, nil, nil, 0, err
+	}
+	prev, prevDirty := store.data, store.dirty.Load()
+	store.data = data
+	store.touchUnlocked(prev, oldKeys[0], newKeys[0])
+	if err := store.persistUnlocked(); err != nil {
+		store.data = prev
+		store.dirty.Store(prevDirty)
+		return nil, nil, nil, 0, fmt.Errorf("failed to save data after RenamePath: %w", err)
+	}
+	store.seq += 2
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	return moved, maputil.DeepCopyValue(replaced), copyAfter, store.seq, nil
+}
+
+// RenamePathInAll applies RenamePath to every file matched by cfg and returns how many files changed.
+// It stops at the first error; files renamed before stay renamed, so the call can be repeated.
+// Files that were not open before are closed again.
+func (mds *MapDirectoryStore) RenamePathInAll(
+	cfg ListingConfig,
+	oldKeys, newKeys []string,
+	policy RenamePolicy,
+) (int, error) {
+	renamed := 0
+	token := ""
+	for {
+		entries, next, err := mds.ListFiles(cfg, token)
+		if err != nil {
+			return renamed, err
+		}
+		for _, entry := range entries {
+			ok, err := mds.renamePathInEntry(entry, oldKeys, newKeys, policy)
+			if err != nil {
+				return renamed, fmt.Errorf("failed to rename path in %s: %w", entry.BaseRelativePath, err)
+			}
+			if ok {
+				renamed++
+			}
+		}
+		if next == "" {
+			return renamed, nil
+		}
+		token = next
+	}
+}
+
+func (mds *MapDirectoryStore) renamePathInEntry(
+	entry FileEntry,
+	oldKeys, newKeys []string,
+	policy RenamePolicy,
+) (bool, error) {
+	filePath, err := mds.entryFilePath(entry)
+	if err != nil {
+		return false, err
+	}
+	store, release, err := mds.borrowPath(context.Background(), filePath, false, map[string]any{})
+	if err != nil {
+		return false, fmt.Errorf("failed to open file store for %s: %w", entry.BaseRelativePath, err)
+	}
+	ok, err := store.RenamePath(oldKeys, newKeys, policy)
+	if closeErr := release(); err == nil {
+		err = closeErr
+	}
+	return ok, err
+}
@@ -0,0 +1,242 @@
+package mapstore
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// segmentManifestKey is the key of the manifest in the main file of a segmented store. It lists the encoded top
+// level keys that have a segment file.
+const segmentManifestKey = "mapstore.segments"
+
+// WithSegmentedStorage stores every top level key in its own file, in a "<file>.segments" directory next to
+// the file, which then only holds a manifest of the keys. A flush rewrites the segments of the top level keys
+// that changed and the small manifest, so a SetKey in a large document writes proportionally little data.
+//
+// Writes are atomic per segment, not across segments: a crash during a flush that changed several top level
+// keys can leave some of them updated. A file written without segments is read as is and converted by the next
+// flush. Once converted, the file must always be opened with segmented storage. The top level keys
+// "mapstore.segments" and "mapstore.segmentHashes" are reserved, and value encoders must not apply to the root
+// path.
+func WithSegmentedStorage(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.segmented = enabled
+	}
+}
+
+// segmentWrite is a pending change of one segment file. A nil data removes the segment.
+type segmentWrite struct {
+	name string
+	data map[string]any
+}
+
+// markDirtyUnlocked records that the given top level keys changed, or all of them if none are given.
+func (store *MapFileStore) markDirtyUnlocked(topKeys ...string) {
+	store.dirty.Store(true)
+	store.recordJournalUnlocked(topKeys)
+	if !store.segmented {
+		return
+	}
+	if len(topKeys) == 0 {
+		store.segAll = true
+		return
+	}
+	if store.segDirty == nil {
+		store.segDirty = make(map[string]struct{})
+	}
+	for _, k := range topKeys {
+		store.segDirty[k] = struct{}{}
+	}
+}
+
+func (store *MapFileStore) segmentDir() string {
+	return store.filename + ".segments"
+}
+
+// segmentPath returns the file of the segment with the given encoded key. The key is base64 encoded so that
+// any key is a valid file name.
+func (store *MapFileStore) segmentPath(name string) string {
+	return filepath.Join(
+		store.segmentDir(),
+		base64.RawURLEncoding.EncodeToString([]byte(name))+filepath.Ext(store.filename),
+	)
+}
+
+// encodeTopKey returns the on disk name of a top level key.
+func (store *MapFileStore) encodeTopKey(k string) string {
+	if store.getKeyEncDec == nil {
+		return k
+	}
+	if keyEncDec := store.getKeyEncDec([]string{k}); keyEncDec != nil {
+		return keyEncDec.Encode(k)
+	}
+	return k
+}
+
+// encodeSegmentsUnlocked returns the manifest and the segments to write for the keys changed since the last
+// flush. Only changed top level keys are copied and encoded.
+func (store *MapFileStore) encodeSegmentsUnlocked() (map[string]any, []segmentWrite, error) {
+	if store.getValueEncDec != nil && store.getValueEncDec([]string{}) != nil {
+		return nil, nil, errors.New("segmented storage does not support a value encoder at the root")
+	}
+	for _, reserved := range []string{segmentManifestKey, segmentHashesKey} {
+		if _, ok := store.data[reserved]; ok {
+			return nil, nil, fmt.Errorf("key %q is reserved in segmented storage", reserved)
+		}
+	}
+
+	names := make([]any, 0, len(store.data))
+	for _, k := range slices.Sorted(maps.Keys(store.data)) {
+		names = append(names, store.encodeTopKey(k))
+	}
+	manifest := map[string]any{segmentManifestKey: names}
+
+	changed := slices.Collect(maps.Keys(store.segDirty))
+	if store.segAll {
+		changed = slices.Collect(maps.Keys(store.data))
+	}
+	slices.Sort(changed)
+
+	segments := make([]segmentWrite, 0, len(changed))
+	for _, k := range changed {
+		v, ok := store.data[k]
+		if !ok {
+			segments = append(segments, segmentWrite{name: store.encodeTopKey(k)})
+			continue
+		}
+		seg, err := store.encodeTopLevelUnlocked(k, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		segments = append(segments, segmentWrite{name: store.encodeTopKey(k), data: seg})
+	}
+	return manifest, segments, nil
+}
+
+// encodeTopLevelUnlocked returns a map of the top level key k and its value v, with values and keys encoded
+// for disk.
+func (store *MapFileStore) encodeTopLevelUnlocked(k string, v any) (map[string]any, error) {
+	seg := map[string]any{k: maputil.DeepCopyValue(v)}
+	tmp, err := encodeDecodeAllValuesRecursively(seg, []string{}, store.getValueEncDec, true)
+	if err != nil {
+		return nil, err
+	}
+	seg, _ = tmp.(map[string]any)
+	if err := encodeDecodeAllKeysRecursively(seg, []string{}, store.getKeyEncDec, true); err != nil {
+		return nil, err
+	}
+	return seg, nil
+}
+
+// writeSegmentsUnlocked applies the segment changes. After a change of all keys it also removes segment files
+// that the manifest no longer lists.
+func (store *MapFileStore) writeSegmentsUnlocked(manifest map[string]any, segments []segmentWrite) error {
+	dir := store.segmentDir()
+	if err := store.fs.MkdirAll(dir, store.dirMode); err != nil {
+		return fmt.Errorf("failed to create segment directory %s: %w", dir, err)
+	}
+	for _, seg := range segments {
+		p := store.segmentPath(seg.name)
+		if seg.data == nil {
+			if err := store.fs.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove segment %s: %w", p, err)
+			}
+			delete(store.segHashes, seg.name)
+			continue
+		}
+		sum, err := store.writeFileUnlocked(p, seg.data)
+		if err != nil {
+			return err
+		}
+		if store.hashContent {
+			if store.segHashes == nil {
+				store.segHashes = make(map[string]string)
+			}
+			store.segHashes[seg.name] = sum
+		}
+	}
+	if store.hashContent {
+		hashes := make(map[string]any, len(store.segHashes))
+		names, _ := manifest[segmentManifestKey].([]any)
+		for _, name := range names {
+			s, _ := name.(string)
+			hashes[s] = store.segHashes[s]
+		}
+		manifest[segmentHashesKey] = hashes
+	}
+	if !store.segAll {
+		return nil
+	}
+
+	keep := make(map[string]bool)
+	names, _ := manifest[segmentManifestKey].([]any)
+	for _, name := range names {
+		s, _ := name.(string)
+		keep[filepath.Base(store.segmentPath(s))] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read segment directory %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && !keep[e.Name()] {
+			if err := store.fs.Remove(filepath.Join(dir, e.Name())); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale segment %s: %w", e.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadSegmentsUnlocked returns the data of the segments listed in the manifest decoded from the main file.
+// Data of a main file without a manifest is returned as is and marked for conversion by the next flush.
+func (store *MapFileStore) loadSegmentsUnlocked(main map[string]any) (map[string]any, error) {
+	raw, ok := main[segmentManifestKey]
+	if !ok {
+		store.segAll = true
+		return main, nil
+	}
+	names, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid segment manifest in file %s", store.filename)
+	}
+	data := make(map[string]any, len(names))
+	hashes := make(map[string]string, len(names))
+	for _, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid segment name %v in file %s", name, store.filename)
+		}
+		p := store.segmentPath(s)
+		f, err := store.fs.Open(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open segment %s: %w", p, err)
+		}
+		seg := make(map[string]any)
+		h := store.newContentHasher()
+		r := teeHash(f, h)
+		err = store.fileEncoderDecoder.Decode(r, &seg)
+		var sum string
+		if err == nil {
+			sum, err = finishHash(r, h)
+		}
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode segment %s: %w", p, err)
+		}
+		maps.Copy(data, seg)
+		hashes[s] = sum
+	}
+	if store.hashContent {
+		store.segHashes = hashes
+	}
+	store.segDirty, store.segAll = nil, false
+	return data, nil
+}
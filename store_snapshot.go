@@ -0,0 +1,101 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// Snapshot is the state of a MapFileStore at one point in time, taken with Snapshot and put back with Restore.
+// It holds a deep copy of the data and key expiries, so later changes to the store do not affect it.
+type Snapshot struct {
+	store  *MapFileStore
+	data   map[string]any
+	expiry map[string]keyExpiry
+}
+
+// Snapshot returns the current data and key expiries of the store, for edit, validate and revert workflows:
+//
+//	snap, _ := store.Snapshot()
+//	if err := edit(store); err != nil {
+//		return store.Restore(snap)
+//	}
+//
+// It is access checked as OpGetFile. Environment overrides and read processors are not applied, so a restore
+// puts back the stored data itself.
+func (store *MapFileStore) Snapshot() (*Snapshot, error) {
+	return store.SnapshotContext(context.Background())
+}
+
+// SnapshotContext is Snapshot, passing ctx to the access checker.
+func (store *MapFileStore) SnapshotContext(ctx context.Context) (*Snapshot, error) {
+	if err := store.checkAccess(ctx, OpGetFile, nil); err != nil {
+		return nil, err
+	}
+	var snap *Snapshot
+	err := store.read(false, func() error {
+		data, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+		snap = &Snapshot{store: store, data: data, expiry: maps.Clone(store.expiry)}
+		return nil
+	})
+	return snap, err
+}
+
+// Restore replaces the data and key expiries of the store with those of snap, flushing unless auto flush is
+// disabled, and emits an OpSetFile event. On a failed flush the store keeps its current data. Immutable paths
+// are checked as for SetAll. Restore can be called more than once with the same snapshot; snap must come from
+// this store.
+func (store *MapFileStore) Restore(snap *Snapshot) error {
+	return store.RestoreContext(context.Background(), snap)
+}
+
+// RestoreContext is Restore, attributing the event to the actor and request ID of ctx.
+func (store *MapFileStore) RestoreContext(ctx context.Context, snap *Snapshot) error {
+	if snap == nil || snap.store != store {
+		return errors.New("snapshot is not of this store")
+	}
+	if err := store.checkAccess(ctx, OpSetFile, nil); err != nil {
+		return err
+	}
+	copyAfter, seq, err := store.restore(snap)
+	if err != nil {
+		return err
+	}
+	store.fireEvent(attributeEvent(ctx, FileEvent{
+		Op:        OpSetFile,
+		Seq:       seq,
+		File:      store.filename,
+		Data:      copyAfter,
+		Timestamp: time.Now(),
+	}))
+	return nil
+}
+
+func (store *MapFileStore) restore(snap *Snapshot) (copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, ErrClosed
+	}
+	if err := store.checkImmutableUnlocked(nil, snap.data, false); err != nil {
+		return nil, 0, err
+	}
+
+	prev, prevDirty, prevExpiry := store.data, store.dirty.Load(), store.expiry
+	store.data, _ = maputil.DeepCopyValue(snap.data).(map[string]any)
+	store.expiry = maps.Clone(snap.expiry)
+	store.touchUnlocked(prev)
+	if err := store.persistUnlocked(); err != nil {
+		store.data = prev
+		store.dirty.Store(prevDirty)
+		store.expiry = prevExpiry
+		return nil, 0, fmt.Errorf("failed to save data after Restore: %w", err)
+	}
+	store.seq++
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	return copyAfter, store.seq, nil
+}
@@ -0,0 +1,73 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tmpFilePattern matches the temporary files of flushes and attachment writes, ".<name>.tmp-<digits>".
+var tmpFilePattern = regexp.MustCompile(`^\..+\.tmp-[0-9]+$`)
+
+// BreakStaleLocks removes lock files and orphaned temporary files under the base directory that were last
+// modified more than olderThan ago, and returns their paths relative to the base directory.
+//
+// Only files the store creates are candidates: a "<name>.lock" lock file next to the data file <name>, and a
+// ".<name>.tmp-<digits>" temporary file; other files ending in .lock or .tmp-<digits> are left alone. Lock files
+// are only held for the duration of one operation, and temporary files only until their rename, so old ones
+// were left behind by a crashed process. A left over lock file blocks Increment on its file until it is
+// removed. olderThan must be well above the longest expected operation, as a live lock is removed as well.
+func (mds *MapDirectoryStore) BreakStaleLocks(olderThan time.Duration) ([]string, error) {
+	if olderThan <= 0 {
+		return nil, fmt.Errorf("invalid stale lock age: %v", olderThan)
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var removed []string
+	err := filepath.WalkDir(mds.baseDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed while walking.
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !isStaleCandidate(filepath.Dir(p), d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale file %s: %w", p, err)
+		}
+		rel, _ := filepath.Rel(mds.baseDir, p)
+		removed = append(removed, rel)
+		return nil
+	})
+	return removed, err
+}
+
+// isStaleCandidate reports whether the file name in dir is a lock or temporary file of the store.
+func isStaleCandidate(dir, name string) bool {
+	if tmpFilePattern.MatchString(name) {
+		return true
+	}
+	dataName, ok := strings.CutSuffix(name, lockFileSuffix)
+	if !ok || dataName == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, dataName))
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,163 @@
+package mapstore
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// maxTemplateDepth bounds nested ${key:...} expansion and catches self references.
+const maxTemplateDepth = 10
+
+var templatePattern = regexp.MustCompile(`\$\{(env|key):([^}]+)\}`)
+
+// ValueLookup returns the stored value at keys.
+type ValueLookup func(keys []string) (any, bool)
+
+// ReadProcessor post-processes values returned by GetKey and GetAll. Path is where value is stored.
+// Lookup reads other values of the same store, it must not be retained after the call.
+// Processed values are never persisted.
+type ReadProcessor func(path []string, value any, lookup ValueLookup) (any, error)
+
+// WithReadProcessor registers a processor applied to values on read, e.g. ExpandTemplates.
+func WithReadProcessor(p ReadProcessor) FileOption {
+	return func(store *MapFileStore) {
+		store.readProcessor = p
+	}
+}
+
+// ExpandTemplates is a ReadProcessor that expands ${env:VAR} and ${key:path.to.other} placeholders in strings,
+// recursing into maps and slices. A string that is exactly one ${key:...} placeholder takes the referenced
+// value as is, keeping its type. Key paths are in the dotted form of Path, so ${key:a\.b} reads the key "a.b".
+// Undefined variables and keys are errors.
+func ExpandTemplates(path []string, value any, lookup ValueLookup) (any, error) {
+	return expandValue(path, value, lookup, 0)
+}
+
+// lookupUnlocked is the ValueLookup handed to read processors, the caller holds the read lock.
+func (store *MapFileStore) lookupUnlocked(keys []string) (any, bool) {
+	val, err := maputil.GetValueAtPath(store.data, keys)
+	if merged, ok := store.overrideAtUnlocked(keys, val, err == nil); ok {
+		return merged, true
+	}
+	if err != nil {
+		return nil, false
+	}
+	return maputil.DeepCopyValue(val), true
+}
+
+// processReadUnlocked applies the read processor, if any, to a copy of a stored value.
+func (store *MapFileStore) processReadUnlocked(path []string, value any) (any, error) {
+	if store.readProcessor == nil {
+		return value, nil
+	}
+	out, err := store.readProcessor(path, value, store.lookupUnlocked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process value at %v: %w", path, err)
+	}
+	return out, nil
+}
+
+func expandValue(path []string, value any, lookup ValueLookup, depth int) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandString(v, lookup, depth)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			expanded, err := expandValue(append(slices.Clone(path), k), child, lookup, depth)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			expanded, err := expandValue(path, child, lookup, depth)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+func expandString(s string, lookup ValueLookup, depth int) (any, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	if depth >= maxTemplateDepth {
+		return nil, fmt.Errorf("template %q nested too deep, possible self reference", s)
+	}
+
+	// A lone key placeholder keeps the type of the referenced value.
+	if m := templatePattern.FindStringSubmatch(s); m != nil && m[0] == s && m[1] == "key" {
+		return resolveTemplateKey(m[2], lookup, depth)
+	}
+
+	var firstErr error
+	out := templatePattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := templatePattern.FindStringSubmatch(match)
+		var (
+			val any
+			err error
+		)
+		if m[1] == "env" {
+			v, ok := os.LookupEnv(m[2])
+			if !ok {
+				err = fmt.Errorf("undefined environment variable %q", m[2])
+			}
+			val = v
+		} else {
+			val, err = resolveTemplateKey(m[2], lookup, depth)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return match
+		}
+		return fmt.Sprint(val)
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+func resolveTemplateKey(dotted string, lookup ValueLookup, depth int) (any, error) {
+	keys, err := ParsePath(dotted)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := lookup(keys)
+	if !ok {
+		return nil, fmt.Errorf("undefined key %q", dotted)
+	}
+	return expandValue(keys, val, lookup, depth+1)
+}
+
+// processAllUnlocked applies the read processor, if any, to the whole data map.
+func (store *MapFileStore) processAllUnlocked(data map[string]any) (map[string]any, error) {
+	if store.readProcessor == nil {
+		return data, nil
+	}
+	out, err := store.processReadUnlocked([]string{}, data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := out.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("read processor returned %T for the root map", out)
+	}
+	return m, nil
+}
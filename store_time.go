@@ -0,0 +1,63 @@
+package mapstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// SetTime sets the value at keys to t as an RFC 3339 string with nanoseconds and the time zone offset of t,
+// e.g. "2024-01-31T09:30:00.5+01:00", the format GetTime and the time filters read.
+func (store *MapFileStore) SetTime(keys []string, t time.Time) error {
+	return store.SetKey(keys, t.Format(time.RFC3339Nano))
+}
+
+// GetTime returns the time stored at keys as an RFC 3339 string, in the time zone offset it was stored with.
+func (store *MapFileStore) GetTime(keys []string) (time.Time, error) {
+	v, err := store.GetKey(keys)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseTimeValue(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("value at %s: %w", Path(keys), err)
+	}
+	return t, nil
+}
+
+// TimeAfter is a ContentFilter for files whose time at keys, as stored by SetTime, is after t.
+func TimeAfter(keys []string, t time.Time) ContentFilter {
+	return timeFilter(keys, func(v time.Time) bool { return v.After(t) })
+}
+
+// TimeBefore is a ContentFilter for files whose time at keys, as stored by SetTime, is before t.
+func TimeBefore(keys []string, t time.Time) ContentFilter {
+	return timeFilter(keys, func(v time.Time) bool { return v.Before(t) })
+}
+
+// TimeBetween is a ContentFilter for files whose time at keys, as stored by SetTime, is in [from, to).
+func TimeBetween(keys []string, from, to time.Time) ContentFilter {
+	return timeFilter(keys, func(v time.Time) bool { return !v.Before(from) && v.Before(to) })
+}
+
+// timeFilter accepts data whose value at keys is a time that match accepts. Missing and invalid values are
+// rejected.
+func timeFilter(keys []string, match func(time.Time) bool) ContentFilter {
+	return func(data map[string]any) bool {
+		v, err := maputil.GetValueAtPath(data, keys)
+		if err != nil {
+			return false
+		}
+		t, err := parseTimeValue(v)
+		return err == nil && match(t)
+	}
+}
+
+func parseTimeValue(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("not a time string: %T", v)
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
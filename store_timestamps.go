@@ -0,0 +1,56 @@
+package mapstore
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// WithTimestamps maintains a creation and a last update time in the data, stored like SetTime. Every change
+// made through the store, including creating the file, sets updatedPath to the current time and createdPath if
+// it has no value, keeping the value from before the change when SetAll, Reset or Restore replace all data.
+// The times are part of the data of the events. A nil path is not maintained. Expiring keys, reloads and data
+// migrations do not count as changes. A timestamp whose parent is not a map is left alone.
+func WithTimestamps(createdPath, updatedPath []string) FileOption {
+	return func(store *MapFileStore) {
+		store.createdPath = slices.Clone(createdPath)
+		store.updatedPath = slices.Clone(updatedPath)
+	}
+}
+
+// WithDirTimestamps applies WithTimestamps to every file of the directory store.
+func WithDirTimestamps(createdPath, updatedPath []string) DirOption {
+	return WithDirFileOptions(WithTimestamps(createdPath, updatedPath))
+}
+
+// touchUnlocked is markDirtyUnlocked for changes made through the store: it also sets the timestamps, see
+// WithTimestamps. before is the data before the change, nil for a new file.
+func (store *MapFileStore) touchUnlocked(before map[string]any, topKeys ...string) {
+	stamped := store.stampUnlocked(before)
+	if len(topKeys) > 0 {
+		topKeys = append(topKeys, stamped...)
+	}
+	store.markDirtyUnlocked(topKeys...)
+}
+
+// stampUnlocked sets the timestamps in the data and returns their top level keys.
+func (store *MapFileStore) stampUnlocked(before map[string]any) []string {
+	var stamped []string
+	now := time.Now().Format(time.RFC3339Nano)
+	if len(store.createdPath) > 0 {
+		if _, err := maputil.GetValueAtPath(store.data, store.createdPath); err != nil {
+			created := any(now)
+			if v, err := maputil.GetValueAtPath(before, store.createdPath); err == nil {
+				created = maputil.DeepCopyValue(v)
+			}
+			if maputil.SetValueAtPath(store.data, store.createdPath, created) == nil {
+				stamped = append(stamped, store.createdPath[0])
+			}
+		}
+	}
+	if len(store.updatedPath) > 0 && maputil.SetValueAtPath(store.data, store.updatedPath, now) == nil {
+		stamped = append(stamped, store.updatedPath[0])
+	}
+	return stamped
+}
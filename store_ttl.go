@@ -0,0 +1,207 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// expiryKey is the reserved top level key that persists the expiry times of keys set with SetKeyWithTTL. It is
+// kept out of the data, so reads never see it.
+const expiryKey = "mapstore.expiry"
+
+// keyExpiry is the expiry time of the value at keys.
+type keyExpiry struct {
+	keys []string
+	at   time.Time
+}
+
+// WithExpirySweep starts a background sweeper that calls ExpireKeys every interval until the store is closed.
+// Without it, expired keys stay readable until ExpireKeys is called.
+func WithExpirySweep(interval time.Duration) FileOption {
+	return func(store *MapFileStore) {
+		store.expirySweep = interval
+	}
+}
+
+// SetKeyWithTTL sets the value at keys like SetKey, and makes it expire after ttl. Expired keys are deleted by
+// ExpireKeys, with an OpDeleteKey event, see WithExpirySweep. Setting or deleting the key again, or a parent of
+// it, clears the expiry; so do SetAll and Reset.
+//
+// Expiry times are persisted in the file under the reserved top level key "mapstore.expiry", so they survive
+// a reopen.
+func (store *MapFileStore) SetKeyWithTTL(keys []string, value any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl: %v", ttl)
+	}
+	return store.setKeyContext(context.Background(), keys, value, time.Now().Add(ttl))
+}
+
+// ExpiresAt returns when the value at keys expires, if it was set with SetKeyWithTTL.
+func (store *MapFileStore) ExpiresAt(keys []string) (time.Time, bool) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	e, ok := store.expiry[expiryPath(keys)]
+	return e.at, ok
+}
+
+// ExpireKeys deletes the keys whose TTL has passed, emits an OpDeleteKey event for each and returns how many were
+// deleted. Expiry is not an access of the store, so access checkers are not called and idle tracking is not
+// affected.
+func (store *MapFileStore) ExpireKeys() (int, error) {
+	events, err := store.expireKeys(time.Now())
+	for _, e := range events {
+		store.fireEvent(e)
+	}
+	return len(events), err
+}
+
+func (store *MapFileStore) expireKeys(now time.Time) ([]FileEvent, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, ErrClosed
+	}
+	if err := store.checkWritable(OpDeleteKey); err != nil {
+		return nil, err
+	}
+
+	var (
+		events  []FileEvent
+		changed bool
+	)
+	for _, p := range slices.Sorted(maps.Keys(store.expiry)) {
+		e := store.expiry[p]
+		if e.at.After(now) {
+			continue
+		}
+		delete(store.expiry, p)
+		store.markDirtyUnlocked(e.keys[0])
+		changed = true
+		old, err := maputil.GetValueAtPath(store.data, e.keys)
+		if err != nil {
+			// Already gone, only the expiry is dropped.
+			continue
+		}
+		if err := maputil.DeleteValueAtPath(store.data, e.keys); err != nil {
+			return nil, fmt.Errorf("failed to delete expired key %v: %w", e.keys, err)
+		}
+		store.seq++
+		events = append(events, FileEvent{
+			Op:        OpDeleteKey,
+			Seq:       store.seq,
+			File:      store.filename,
+			Keys:      slices.Clone(e.keys),
+			OldValue:  maputil.DeepCopyValue(old),
+			Timestamp: now,
+		})
+	}
+	if !changed {
+		return nil, nil
+	}
+	if err := store.persistUnlocked(); err != nil {
+		return nil, fmt.Errorf("failed to save data after expiring keys: %w", err)
+	}
+	for i := range events {
+		events[i].Data, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	}
+	return events, nil
+}
+
+// startExpirySweep runs ExpireKeys on a ticker until Close, if WithExpirySweep is set.
+func (store *MapFileStore) startExpirySweep() {
+	if store.expirySweep <= 0 || store.readOnly {
+		return
+	}
+	store.sweepStop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(store.expirySweep)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-store.sweepStop:
+				return
+			case <-ticker.C:
+				if _, err := store.ExpireKeys(); errors.Is(err, ErrClosed) {
+					return
+				} else if err != nil {
+					slog.Warn("mapstore: expiring keys failed", "file", store.filename, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// setExpiryUnlocked clears the expiry of keys and the keys below it, and sets the expiry of keys to at unless at
+// is zero.
+func (store *MapFileStore) setExpiryUnlocked(keys []string, at time.Time) {
+	for p, e := range store.expiry {
+		if len(e.keys) >= len(keys) && slices.Equal(e.keys[:len(keys)], keys) {
+			delete(store.expiry, p)
+		}
+	}
+	if at.IsZero() {
+		return
+	}
+	if store.expiry == nil {
+		store.expiry = make(map[string]keyExpiry)
+	}
+	store.expiry[expiryPath(keys)] = keyExpiry{keys: slices.Clone(keys), at: at}
+}
+
+func expiryPath(keys []string) string {
+	return strings.Join(keys, "\x00")
+}
+
+// encodeExpiry returns the on disk form of the expiry times, a list sorted by path.
+func encodeExpiry(expiry map[string]keyExpiry) []any {
+	out := make([]any, 0, len(expiry))
+	for _, p := range slices.Sorted(maps.Keys(expiry)) {
+		e := expiry[p]
+		keys := make([]any, len(e.keys))
+		for i, k := range e.keys {
+			keys[i] = k
+		}
+		out = append(out, map[string]any{"keys": keys, "at": e.at.UTC().Format(time.RFC3339Nano)})
+	}
+	return out
+}
+
+// takeExpiry removes the expiry times from data decoded from the file and returns them.
+func takeExpiry(data map[string]any) (map[string]keyExpiry, error) {
+	raw, ok := data[expiryKey]
+	if !ok {
+		return map[string]keyExpiry{}, nil
+	}
+	delete(data, expiryKey)
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, errors.New("invalid expiry list")
+	}
+	expiry := make(map[string]keyExpiry, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]any)
+		rawKeys, _ := m["keys"].([]any)
+		at, err := time.Parse(time.RFC3339Nano, fmt.Sprint(m["at"]))
+		if len(rawKeys) == 0 || err != nil {
+			return nil, fmt.Errorf("invalid expiry entry %v", item)
+		}
+		keys := make([]string, len(rawKeys))
+		for i, k := range rawKeys {
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid expiry entry %v", item)
+			}
+			keys[i] = s
+		}
+		expiry[expiryPath(keys)] = keyExpiry{keys: keys, at: at}
+	}
+	return expiry, nil
+}
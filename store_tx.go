@@ -0,0 +1,194 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ppipada/mapstore-go/internal/maputil"
+)
+
+// ErrTxDone is returned by operations on a transaction that was already committed or rolled back.
+var ErrTxDone = errors.New("transaction already committed or rolled back")
+
+// KeyChange is one mutation of a transaction, as reported in FileEvent.Changes.
+type KeyChange struct {
+	// Op is OpSetKey or OpDeleteKey.
+	Op       Operation
+	Keys     []string
+	OldValue any
+	// Nil for delete.
+	NewValue any
+}
+
+// Tx collects SetKey and DeleteKey mutations of a MapFileStore, which Commit applies at once: either all of
+// them are written, in a single flush, or none. A Tx is not safe for concurrent use.
+//
+// The mutations are recorded, not applied, until Commit, so other writers are not blocked while a transaction
+// is open, and Commit applies the mutations to the data as it is then. Reads through the store do not see
+// uncommitted mutations.
+type Tx struct {
+	store   *MapFileStore
+	ctx     context.Context
+	changes []KeyChange
+	done    bool
+}
+
+// Begin starts a transaction on the store.
+func (store *MapFileStore) Begin() (*Tx, error) {
+	return store.BeginContext(context.Background())
+}
+
+// BeginContext is Begin, attributing the commit to the actor and request ID of ctx. Access checkers of the
+// mutations are called with ctx.
+func (store *MapFileStore) BeginContext(ctx context.Context) (*Tx, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if store.closed {
+		return nil, ErrClosed
+	}
+	return &Tx{store: store, ctx: ctx}, nil
+}
+
+// SetKey records setting the value at keys. The value is copied, later changes to it are not committed.
+func (tx *Tx) SetKey(keys []string, value any) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if len(keys) == 0 {
+		return errors.New("cannot set value at root")
+	}
+	if err := tx.store.checkAccess(tx.ctx, OpSetKey, keys); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, KeyChange{
+		Op:       OpSetKey,
+		Keys:     slices.Clone(keys),
+		NewValue: maputil.DeepCopyValue(value),
+	})
+	return nil
+}
+
+// DeleteKey records deleting the value at keys.
+func (tx *Tx) DeleteKey(keys []string) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if len(keys) == 0 {
+		return errors.New("cannot delete value at root")
+	}
+	if err := tx.store.checkAccess(tx.ctx, OpDeleteKey, keys); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, KeyChange{Op: OpDeleteKey, Keys: slices.Clone(keys)})
+	return nil
+}
+
+// Commit applies the recorded mutations in order and flushes them in one write, then emits a single
+// OpTransaction event listing them. If a mutation fails, the store is left unchanged. Like SetAll, Commit
+// retries when another writer changed the file in the meantime. The transaction is done afterwards, even on
+// error.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.changes) == 0 {
+		return nil
+	}
+	store := tx.store
+	for range maxSetAllRetries {
+		copyAfter, seq, err := store.commitTx(tx.changes)
+		if err == nil {
+			changes := make([]KeyChange, len(tx.changes))
+			for i, c := range tx.changes {
+				changes[i] = KeyChange{
+					Op:       c.Op,
+					Keys:     slices.Clone(c.Keys),
+					OldValue: c.OldValue,
+					NewValue: maputil.DeepCopyValue(c.NewValue),
+				}
+			}
+			store.fireEvent(attributeEvent(tx.ctx, FileEvent{
+				Op:        OpTransaction,
+				Seq:       seq,
+				File:      store.filename,
+				Changes:   changes,
+				Data:      copyAfter,
+				Timestamp: time.Now(),
+			}))
+			return nil
+		}
+		if !errors.Is(err, ErrFileConflict) {
+			return err
+		}
+		if loadErr := store.load(); loadErr != nil {
+			return fmt.Errorf("Commit conflict reload failed: %w", loadErr)
+		}
+	}
+	return fmt.Errorf("Commit: %w after %d retries", ErrFileConflict, maxSetAllRetries)
+}
+
+// Rollback discards the recorded mutations. It returns ErrTxDone if the transaction was already committed or
+// rolled back, so it can be deferred right after Begin and its error ignored.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.changes = nil
+	return nil
+}
+
+// commitTx applies changes on a copy of the data, so that a failure leaves the store unchanged, and records
+// the old value of every change.
+func (store *MapFileStore) commitTx(changes []KeyChange) (copyAfter map[string]any, seq uint64, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil, 0, ErrClosed
+	}
+
+	data, _ := maputil.DeepCopyValue(store.data).(map[string]any)
+	topKeys := make([]string, 0, len(changes))
+	for i := range changes {
+		c := &changes[i]
+		old, _ := maputil.GetValueAtPath(data, c.Keys)
+		c.OldValue = maputil.DeepCopyValue(old)
+		switch c.Op {
+		case OpSetKey:
+			if err := maputil.SetValueAtPath(data, c.Keys, maputil.DeepCopyValue(c.NewValue)); err != nil {
+				return nil, 0, fmt.Errorf("failed to set value at key %v: %w", c.Keys, err)
+			}
+		case OpDeleteKey:
+			if err := maputil.DeleteValueAtPath(data, c.Keys); err != nil {
+				return nil, 0, fmt.Errorf("failed to delete key %v: %w", c.Keys, err)
+			}
+		default:
+			return nil, 0, fmt.Errorf("invalid transaction operation: %s", c.Op)
+		}
+		topKeys = append(topKeys, c.Keys[0])
+	}
+
+	if err := store.checkImmutableUnlocked(nil, data, false); err != nil {
+		return nil, 0, err
+	}
+	prev, prevDirty, prevExpiry := store.data, store.dirty.Load(), maps.Clone(store.expiry)
+	store.data = data
+	for _, c := range changes {
+		store.setExpiryUnlocked(c.Keys, time.Time{})
+	}
+	store.touchUnlocked(prev, topKeys...)
+	if err := store.persistUnlocked(); err != nil {
+		store.data = prev
+		store.dirty.Store(prevDirty)
+		store.expiry = prevExpiry
+		return nil, 0, fmt.Errorf("failed to save data after Commit: %w", err)
+	}
+	store.seq++
+	copyAfter, _ = maputil.DeepCopyValue(store.data).(map[string]any)
+	return copyAfter, store.seq, nil
+}
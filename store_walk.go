@@ -0,0 +1,60 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+)
+
+// ErrStopWalk stops Walk when returned by its callback. Walk then returns nil.
+var ErrStopWalk = errors.New("stop walk")
+
+// Walk calls fn for every key of the data, depth first with the keys of a map in sorted order, a map before the
+// keys inside it. Only nested maps are descended into. An error from fn stops the walk and is returned, except
+// ErrStopWalk, which stops it without error.
+//
+// Walk holds the read lock while it runs and hands fn the stored values themselves, without copying them: fn
+// must not modify them or keep them after it returns, and must not call other methods of the store. With
+// environment overrides or a read processor the values are those of GetAll, which copies the data.
+// Walk is access checked as OpGetFile.
+func (store *MapFileStore) Walk(fn func(path []string, value any) error) error {
+	return store.WalkContext(context.Background(), fn)
+}
+
+// WalkContext is Walk, passing ctx to the access checker.
+func (store *MapFileStore) WalkContext(ctx context.Context, fn func(path []string, value any) error) error {
+	if err := store.checkAccess(ctx, OpGetFile, nil); err != nil {
+		return err
+	}
+	err := store.read(store.strictReads, func() error {
+		data := store.data
+		if len(store.overrides) > 0 || store.readProcessor != nil {
+			var err error
+			if data, err = store.snapshotUnlocked(); err != nil {
+				return err
+			}
+		}
+		return walkMap(data, nil, fn)
+	})
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
+
+// walkMap walks the keys of m, which is at path.
+func walkMap(m map[string]any, path []string, fn func(path []string, value any) error) error {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		p := append(slices.Clip(path), k)
+		if err := fn(p, m[k]); err != nil {
+			return err
+		}
+		if child, ok := m[k].(map[string]any); ok {
+			if err := walkMap(child, p, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
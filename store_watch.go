@@ -0,0 +1,79 @@
+package mapstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits for more notifications before reloading, as editors and atomic
+// replaces change a file in several steps.
+const watchDebounce = 50 * time.Millisecond
+
+// WithWatchFile subscribes the store to filesystem notifications for its file until Close. When another process
+// changes or removes the file, the store reloads it and emits an OpExternalReload event, without waiting for a
+// read with forceFetch or WithStrictReads. Refresh of a directory store emits OpExternalChange instead, so
+// listeners can tell the two apart. Writes of the store itself are recognized and not reloaded. Unflushed
+// changes are replaced by the reloaded data.
+//
+// The directory of the file is watched, so atomic replaces by editors and other stores are seen.
+func WithWatchFile(enabled bool) FileOption {
+	return func(store *MapFileStore) {
+		store.watchFile = enabled
+	}
+}
+
+// startWatch starts watching the file until Close, if WithWatchFile is set.
+func (store *MapFileStore) startWatch() error {
+	if !store.watchFile {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch file %s: %w", store.filename, err)
+	}
+	if err := w.Add(filepath.Dir(store.filename)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to watch file %s: %w", store.filename, err)
+	}
+	store.watchStop = make(chan struct{})
+	go store.watchLoop(w)
+	return nil
+}
+
+// watchLoop reloads the file after notifications for it have settled.
+func (store *MapFileStore) watchLoop(w *fsnotify.Watcher) {
+	defer w.Close()
+	name := filepath.Clean(store.filename)
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	for {
+		select {
+		case <-store.watchStop:
+			debounce.Stop()
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == name {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("mapstore: watching file failed", "file", store.filename, "error", err)
+		case <-debounce.C:
+			if _, _, err := store.reloadIfChanged(OpExternalReload); errors.Is(err, ErrClosed) {
+				return
+			} else if err != nil {
+				slog.Warn("mapstore: reloading changed file failed", "file", store.filename, "error", err)
+			}
+		}
+	}
+}